@@ -4,6 +4,7 @@ import (
 	"cloud.google.com/go/profiler"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
@@ -11,14 +12,22 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router"
 	"github.com/traPtitech/traQ/router/auth"
+	"github.com/traPtitech/traQ/service/bridge"
+	"github.com/traPtitech/traQ/service/call"
+	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ogp"
+	"github.com/traPtitech/traQ/service/sfu"
 	"github.com/traPtitech/traQ/service/variable"
+	"github.com/traPtitech/traQ/service/webpush"
 	"github.com/traPtitech/traQ/utils/storage"
 	"go.uber.org/zap"
 	"google.golang.org/api/option"
 	"image"
+	"io/ioutil"
 	"time"
 )
 
@@ -140,15 +149,40 @@ type Config struct {
 		} `mapstructure:"serviceAccount" yaml:"serviceAccount"`
 	} `mapstructure:"firebase" yaml:"firebase"`
 
+	// Bridge 複数インスタンス構成時のイベント中継設定
+	Bridge struct {
+		// Type 中継方式 (default: "")
+		// 	"": 中継しない (単一インスタンス構成)
+		// 	redis: Redis Pub/Sub
+		Type string `mapstructure:"type" yaml:"type"`
+		// Redis Redis Pub/Sub設定
+		Redis struct {
+			// Addr Redisサーバーのアドレス (default: 127.0.0.1:6379)
+			Addr string `mapstructure:"addr" yaml:"addr"`
+			// Password パスワード
+			Password string `mapstructure:"password" yaml:"password"`
+			// DB データベース番号 (default: 0)
+			DB int `mapstructure:"db" yaml:"db"`
+			// Channel Pub/Subチャンネル名 (default: traq)
+			Channel string `mapstructure:"channel" yaml:"channel"`
+		} `mapstructure:"redis" yaml:"redis"`
+	} `mapstructure:"bridge" yaml:"bridge"`
+
 	// OAuth2 OAuth2認可サーバー設定
 	OAuth2 struct {
 		// IsRefreshEnabled リフレッシュトークンを有効にするかどうか (default: false)
 		IsRefreshEnabled bool `mapstructure:"isRefreshEnabled" yaml:"isRefreshEnabled"`
 		// AccessTokenExpire アクセストークン有効期間(秒) (default: 31536000)
 		AccessTokenExpire int `mapstructure:"accessTokenExp" yaml:"accessTokenExp"`
+		// RefreshTokenAbsoluteExpire リフレッシュトークンの最初の発行からの有効期間(秒) 0の場合は無期限 (default: 7776000)
+		RefreshTokenAbsoluteExpire int `mapstructure:"refreshTokenAbsoluteExp" yaml:"refreshTokenAbsoluteExp"`
+		// RefreshTokenIdleExpire リフレッシュトークンを使用せずにいられる有効期間(秒) 0の場合は無期限 (default: 2592000)
+		RefreshTokenIdleExpire int `mapstructure:"refreshTokenIdleExp" yaml:"refreshTokenIdleExp"`
 	} `mapstructure:"oauth2" yaml:"oauth2"`
 
 	// ExternalAuthentication 外部認証設定
+	//
+	// Deprecated: パスワードをフォームでPOSTするだけの簡易的なものです。代わりにexternalAuth.ldapを使用してください。
 	ExternalAuthentication struct {
 		// Enabled 有効かどうか (default: false)
 		Enabled  bool `mapstructure:"enabled" yaml:"enabled"`
@@ -160,12 +194,87 @@ type Config struct {
 		} `mapstructure:"authPost" yaml:"authPost"`
 	} `mapstructure:"externalAuthentication" yaml:"externalAuthentication"`
 
+	// WebPush Web Push設定
+	WebPush struct {
+		// VAPID VAPID設定
+		VAPID struct {
+			// PrivateKey P-256 ECDSA秘密鍵ファイル (空の場合はWeb Pushを無効にする)
+			PrivateKey string `mapstructure:"privateKey" yaml:"privateKey"`
+			// Subject 連絡先の`mailto:`または`https:`のURL (default: Origin)
+			Subject string `mapstructure:"subject" yaml:"subject"`
+		} `mapstructure:"vapid" yaml:"vapid"`
+	} `mapstructure:"webPush" yaml:"webPush"`
+
+	// Email メール通知設定
+	Email struct {
+		// SMTP SMTPサーバー設定
+		SMTP struct {
+			// Host ホスト (空の場合はメール通知を無効にする)
+			Host string `mapstructure:"host" yaml:"host"`
+			// Port ポート (default: 587)
+			Port int `mapstructure:"port" yaml:"port"`
+			// Username 認証ユーザー名 (空の場合は認証しない)
+			Username string `mapstructure:"username" yaml:"username"`
+			// Password 認証パスワード
+			Password string `mapstructure:"password" yaml:"password"`
+		} `mapstructure:"smtp" yaml:"smtp"`
+		// From 送信元アドレス
+		From string `mapstructure:"from" yaml:"from"`
+		// Secret 配信停止リンクの署名鍵
+		Secret string `mapstructure:"secret" yaml:"secret"`
+		// DigestHour ダイジェストメールを送信する時刻 (default: 9)
+		DigestHour int `mapstructure:"digestHour" yaml:"digestHour"`
+	} `mapstructure:"email" yaml:"email"`
+
 	// SkyWay SkyWay設定
 	SkyWay struct {
 		// SecretKey シークレットキー
 		SecretKey string `mapstructure:"secretKey" yaml:"secretKey"`
 	} `mapstructure:"skyway" yaml:"skyway"`
 
+	// SFU 組み込みWebRTC SFU設定
+	SFU struct {
+		// ICEServers STUN/TURNサーバーのURL (default: [])
+		ICEServers []string `mapstructure:"iceServers" yaml:"iceServers"`
+		// NAT1To1IPs NAT環境下でICE candidateとして通知するIPアドレス (default: [])
+		NAT1To1IPs []string `mapstructure:"nat1To1IPs" yaml:"nat1To1IPs"`
+		// UDPPortMin メディア通信に使用するUDPポート範囲の最小値 (default: 0)
+		UDPPortMin uint16 `mapstructure:"udpPortMin" yaml:"udpPortMin"`
+		// UDPPortMax メディア通信に使用するUDPポート範囲の最大値 (default: 0)
+		UDPPortMax uint16 `mapstructure:"udpPortMax" yaml:"udpPortMax"`
+	} `mapstructure:"sfu" yaml:"sfu"`
+
+	// Call 通話設定
+	Call struct {
+		// SystemMessage 通話の開始・終了時にチャンネルにメッセージを投稿するかどうか (default: false)
+		SystemMessage bool `mapstructure:"systemMessage" yaml:"systemMessage"`
+	} `mapstructure:"call" yaml:"call"`
+
+	// OGP OGP取得設定
+	OGP struct {
+		// AllowHosts プライベートネットワーク空間のアドレスであってもOGPの取得を許可するホスト (default: [])
+		//
+		// `.example.com`のように先頭を`.`にすると、そのドメインとサブドメインにマッチします。
+		AllowHosts []string `mapstructure:"allowHosts" yaml:"allowHosts"`
+		// DenyHosts OGPの取得を拒否するホスト (default: [])
+		DenyHosts []string `mapstructure:"denyHosts" yaml:"denyHosts"`
+		// MaxRedirects リダイレクトの最大回数 (default: 5)
+		MaxRedirects int `mapstructure:"maxRedirects" yaml:"maxRedirects"`
+		// MaxBodySize レスポンスボディの最大バイト数 (default: 5242880)
+		MaxBodySize int64 `mapstructure:"maxBodySize" yaml:"maxBodySize"`
+		// OEmbedProviders 追加のoEmbedプロバイダ (default: [])
+		//
+		// 組み込みのプロバイダ(YouTube, Vimeo, SoundCloud, Spotifyなど)より優先されます。
+		OEmbedProviders []struct {
+			// Name プロバイダ名
+			Name string `mapstructure:"name" yaml:"name"`
+			// Endpoint oEmbed APIのエンドポイント
+			Endpoint string `mapstructure:"endpoint" yaml:"endpoint"`
+			// Schemes 対象URLのパターン (`*`は任意の文字列にマッチ)
+			Schemes []string `mapstructure:"schemes" yaml:"schemes"`
+		} `mapstructure:"oembedProviders" yaml:"oembedProviders"`
+	} `mapstructure:"ogp" yaml:"ogp"`
+
 	// JWT JsonWebToken設定
 	JWT struct {
 		// Keys 鍵設定
@@ -200,7 +309,88 @@ type Config struct {
 			AllowSignUp  bool     `mapstructure:"allowSignUp" yaml:"allowSignUp"`
 			Scopes       []string `mapstructure:"scopes" yaml:"scopes"`
 		} `mapstructure:"oidc" yaml:"oidc"`
+		LDAP struct {
+			// URL LDAPサーバーのURL (例: ldaps://ldap.example.com)
+			URL string `mapstructure:"url" yaml:"url"`
+			// StartTLS ldap://で接続した後にStartTLSを行うかどうか (default: false)
+			StartTLS bool `mapstructure:"startTLS" yaml:"startTLS"`
+			// InsecureSkipVerify TLSのサーバー証明書の検証を行わないかどうか (default: false)
+			InsecureSkipVerify bool `mapstructure:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+			// BindDN ユーザー・グループ検索に使用するDN 空の場合は匿名で検索
+			BindDN string `mapstructure:"bindDN" yaml:"bindDN"`
+			// BindPassword ユーザー・グループ検索に使用するDNのパスワード
+			BindPassword string `mapstructure:"bindPassword" yaml:"bindPassword"`
+			// UserDNTemplate ユーザーのDNのテンプレート 指定した場合は検索せずに直接バインド (例: uid=%s,ou=people,dc=example,dc=com)
+			UserDNTemplate string `mapstructure:"userDNTemplate" yaml:"userDNTemplate"`
+			// UserBaseDN ユーザー検索のベースDN
+			UserBaseDN string `mapstructure:"userBaseDN" yaml:"userBaseDN"`
+			// UserFilter ユーザー検索のフィルタ (例: (uid=%s))
+			UserFilter string `mapstructure:"userFilter" yaml:"userFilter"`
+			// Attributes 属性のマッピング
+			Attributes struct {
+				// ID ユーザーを一意に識別する属性 (default: uid)
+				ID string `mapstructure:"id" yaml:"id"`
+				// Name traQユーザー名にする属性 (default: uid)
+				Name string `mapstructure:"name" yaml:"name"`
+				// DisplayName 表示名にする属性 (default: cn)
+				DisplayName string `mapstructure:"displayName" yaml:"displayName"`
+				// Email メールアドレスにする属性 (default: mail)
+				Email string `mapstructure:"email" yaml:"email"`
+				// Icon アイコン画像にする属性 (default: jpegPhoto)
+				Icon string `mapstructure:"icon" yaml:"icon"`
+			} `mapstructure:"attributes" yaml:"attributes"`
+			// Group グループ同期設定
+			Group struct {
+				// Sync LDAPのグループの所属をユーザーグループに同期するかどうか (default: false)
+				Sync bool `mapstructure:"sync" yaml:"sync"`
+				// BaseDN グループ検索のベースDN
+				BaseDN string `mapstructure:"baseDN" yaml:"baseDN"`
+				// Filter ユーザーが所属するグループの検索フィルタ (例: (member=%s))
+				Filter string `mapstructure:"filter" yaml:"filter"`
+				// NameAttribute ユーザーグループ名にする属性 (default: cn)
+				NameAttribute string `mapstructure:"nameAttribute" yaml:"nameAttribute"`
+				// Type 同期対象のユーザーグループの種類 (default: ldap)
+				Type string `mapstructure:"type" yaml:"type"`
+			} `mapstructure:"group" yaml:"group"`
+			AllowSignUp bool `mapstructure:"allowSignUp" yaml:"allowSignUp"`
+		} `mapstructure:"ldap" yaml:"ldap"`
+		SAML struct {
+			// EntityID SPのエンティティID 空の場合はメタデータのURL
+			EntityID string `mapstructure:"entityId" yaml:"entityId"`
+			// Certificate SPの証明書ファイル (PEM)
+			Certificate string `mapstructure:"certificate" yaml:"certificate"`
+			// PrivateKey SPのRSA秘密鍵ファイル (PEM)
+			PrivateKey string `mapstructure:"privateKey" yaml:"privateKey"`
+			// IDPMetadataURL IdPメタデータのURL
+			IDPMetadataURL string `mapstructure:"idpMetadataUrl" yaml:"idpMetadataUrl"`
+			// IDPMetadataFile IdPメタデータのファイル 指定した場合はidpMetadataUrlより優先
+			IDPMetadataFile string `mapstructure:"idpMetadataFile" yaml:"idpMetadataFile"`
+			// AllowIDPInitiated IdP-initiated SSOを許可するかどうか (default: false)
+			AllowIDPInitiated bool `mapstructure:"allowIdpInitiated" yaml:"allowIdpInitiated"`
+			// Attributes 属性のマッピング
+			Attributes struct {
+				// ID ユーザーを一意に識別する属性 空の場合はNameID (default: "")
+				ID string `mapstructure:"id" yaml:"id"`
+				// Name traQユーザー名にする属性 (default: uid)
+				Name string `mapstructure:"name" yaml:"name"`
+				// DisplayName 表示名にする属性 (default: displayName)
+				DisplayName string `mapstructure:"displayName" yaml:"displayName"`
+				// Email メールアドレスにする属性 (default: mail)
+				Email string `mapstructure:"email" yaml:"email"`
+			} `mapstructure:"attributes" yaml:"attributes"`
+			AllowSignUp bool `mapstructure:"allowSignUp" yaml:"allowSignUp"`
+		} `mapstructure:"saml" yaml:"saml"`
 	} `mapstructure:"externalAuth" yaml:"externalAuth"`
+
+	// SCIM SCIMプロビジョニング設定
+	SCIM struct {
+		// Token SCIMクライアント用のBearerトークン 空の場合はSCIMを無効にします (default: "")
+		Token string `mapstructure:"token" yaml:"token"`
+		// ExternalProviderName externalIdを紐付ける外部認証プロバイダ名 (default: scim)
+		ExternalProviderName string `mapstructure:"externalProviderName" yaml:"externalProviderName"`
+		// GroupType SCIMで作成したユーザーグループの種類 (default: scim)
+		GroupType string `mapstructure:"groupType" yaml:"groupType"`
+	} `mapstructure:"scim" yaml:"scim"`
 }
 
 // Configのデフォルト値設定
@@ -236,8 +426,15 @@ func init() {
 	viper.SetDefault("gcp.serviceAccount.file", "")
 	viper.SetDefault("gcp.stackdriver.profiler.enabled", false)
 	viper.SetDefault("firebase.serviceAccount.file", "")
+	viper.SetDefault("bridge.type", "")
+	viper.SetDefault("bridge.redis.addr", "127.0.0.1:6379")
+	viper.SetDefault("bridge.redis.password", "")
+	viper.SetDefault("bridge.redis.db", 0)
+	viper.SetDefault("bridge.redis.channel", "traq")
 	viper.SetDefault("oauth2.isRefreshEnabled", false)
 	viper.SetDefault("oauth2.accessTokenExp", 60*60*24*365)
+	viper.SetDefault("oauth2.refreshTokenAbsoluteExp", 60*60*24*90)
+	viper.SetDefault("oauth2.refreshTokenIdleExp", 60*60*24*30)
 	viper.SetDefault("externalAuthentication.enabled", false)
 	viper.SetDefault("externalAuthentication.authPost.url", "")
 	viper.SetDefault("externalAuthentication.authPost.successfulCode", 0)
@@ -258,7 +455,59 @@ func init() {
 	viper.SetDefault("externalAuth.oidc.clientSecret", "")
 	viper.SetDefault("externalAuth.oidc.scopes", []string{})
 	viper.SetDefault("externalAuth.oidc.allowSignUp", false)
+	viper.SetDefault("externalAuth.ldap.url", "")
+	viper.SetDefault("externalAuth.ldap.startTLS", false)
+	viper.SetDefault("externalAuth.ldap.insecureSkipVerify", false)
+	viper.SetDefault("externalAuth.ldap.bindDN", "")
+	viper.SetDefault("externalAuth.ldap.bindPassword", "")
+	viper.SetDefault("externalAuth.ldap.userDNTemplate", "")
+	viper.SetDefault("externalAuth.ldap.userBaseDN", "")
+	viper.SetDefault("externalAuth.ldap.userFilter", "")
+	viper.SetDefault("externalAuth.ldap.attributes.id", "uid")
+	viper.SetDefault("externalAuth.ldap.attributes.name", "uid")
+	viper.SetDefault("externalAuth.ldap.attributes.displayName", "cn")
+	viper.SetDefault("externalAuth.ldap.attributes.email", "mail")
+	viper.SetDefault("externalAuth.ldap.attributes.icon", "jpegPhoto")
+	viper.SetDefault("externalAuth.ldap.group.sync", false)
+	viper.SetDefault("externalAuth.ldap.group.baseDN", "")
+	viper.SetDefault("externalAuth.ldap.group.filter", "")
+	viper.SetDefault("externalAuth.ldap.group.nameAttribute", "cn")
+	viper.SetDefault("externalAuth.ldap.group.type", "ldap")
+	viper.SetDefault("externalAuth.ldap.allowSignUp", false)
+	viper.SetDefault("externalAuth.saml.entityId", "")
+	viper.SetDefault("externalAuth.saml.certificate", "")
+	viper.SetDefault("externalAuth.saml.privateKey", "")
+	viper.SetDefault("externalAuth.saml.idpMetadataUrl", "")
+	viper.SetDefault("externalAuth.saml.idpMetadataFile", "")
+	viper.SetDefault("externalAuth.saml.allowIdpInitiated", false)
+	viper.SetDefault("externalAuth.saml.attributes.id", "")
+	viper.SetDefault("externalAuth.saml.attributes.name", "uid")
+	viper.SetDefault("externalAuth.saml.attributes.displayName", "displayName")
+	viper.SetDefault("externalAuth.saml.attributes.email", "mail")
+	viper.SetDefault("externalAuth.saml.allowSignUp", false)
+	viper.SetDefault("scim.token", "")
+	viper.SetDefault("scim.externalProviderName", "scim")
+	viper.SetDefault("scim.groupType", "scim")
+	viper.SetDefault("webPush.vapid.privateKey", "")
+	viper.SetDefault("webPush.vapid.subject", "")
+	viper.SetDefault("email.smtp.host", "")
+	viper.SetDefault("email.smtp.port", 587)
+	viper.SetDefault("email.smtp.username", "")
+	viper.SetDefault("email.smtp.password", "")
+	viper.SetDefault("email.from", "")
+	viper.SetDefault("email.secret", "")
+	viper.SetDefault("email.digestHour", 9)
 	viper.SetDefault("skyway.secretKey", "")
+	viper.SetDefault("sfu.iceServers", []string{})
+	viper.SetDefault("sfu.nat1To1IPs", []string{})
+	viper.SetDefault("sfu.udpPortMin", 0)
+	viper.SetDefault("sfu.udpPortMax", 0)
+	viper.SetDefault("call.systemMessage", false)
+	viper.SetDefault("ogp.allowHosts", []string{})
+	viper.SetDefault("ogp.denyHosts", []string{})
+	viper.SetDefault("ogp.maxRedirects", 5)
+	viper.SetDefault("ogp.maxBodySize", 5<<20)
+	viper.SetDefault("ogp.oembedProviders", []interface{}{})
 	viper.SetDefault("jwt.keys.private", "")
 }
 
@@ -323,11 +572,86 @@ func initStackdriverProfiler(c *Config) error {
 	}, option.WithCredentialsFile(c.GCP.ServiceAccount.File))
 }
 
-func newFCMClientIfAvailable(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, file variable.FirebaseCredentialsFilePathString) (fcm.Client, error) {
+func newFCMClientIfAvailable(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, file variable.FirebaseCredentialsFilePathString, wp *webpush.Client) (fcm.Client, error) {
+	var clients []fcm.Client
 	if len(file) > 0 {
-		return fcm.NewClientWithCredentialsFile(repo, logger, unreadCounter, file)
+		c, err := fcm.NewClientWithCredentialsFile(repo, logger, unreadCounter, file)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	if wp != nil {
+		clients = append(clients, wp)
+	}
+
+	switch len(clients) {
+	case 0:
+		return fcm.NewNullClient(), nil
+	case 1:
+		return clients[0], nil
+	default:
+		return fcm.NewMultiClient(clients...), nil
+	}
+}
+
+func newWebPushClientIfAvailable(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, c *Config) (*webpush.Client, error) {
+	if len(c.WebPush.VAPID.PrivateKey) == 0 {
+		return nil, nil
+	}
+	pem, err := ioutil.ReadFile(c.WebPush.VAPID.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VAPID private key: %w", err)
+	}
+	key, err := webpush.ParseVAPIDPrivateKey(pem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse VAPID private key: %w", err)
+	}
+	subject := c.WebPush.VAPID.Subject
+	if len(subject) == 0 {
+		subject = c.Origin
+	}
+	return webpush.NewClient(repo, logger, unreadCounter, webpush.Config{
+		PrivateKey: key,
+		Subject:    subject,
+	})
+}
+
+func newEmailServiceIfAvailable(repo repository.Repository, cm channel.Manager, oc *counter.OnlineCounter, logger *zap.Logger, c *Config) (*email.Service, error) {
+	if len(c.Email.SMTP.Host) == 0 {
+		return nil, nil
+	}
+	if len(c.Email.From) == 0 {
+		return nil, errors.New("email.from is required")
+	}
+	sender := email.NewSMTPSender(email.SMTPConfig{
+		Host:     c.Email.SMTP.Host,
+		Port:     c.Email.SMTP.Port,
+		Username: c.Email.SMTP.Username,
+		Password: c.Email.SMTP.Password,
+		From:     c.Email.From,
+	})
+	return email.NewService(repo, cm, oc, sender, email.Config{
+		Secret:     []byte(c.Email.Secret),
+		Origin:     c.Origin,
+		DigestHour: c.Email.DigestHour,
+	}, logger)
+}
+
+func newBridge(c *Config, logger *zap.Logger) (bridge.Bridge, error) {
+	switch c.Bridge.Type {
+	case "redis":
+		return bridge.NewRedisBridge(bridge.RedisConfig{
+			Addr:     c.Bridge.Redis.Addr,
+			Password: c.Bridge.Redis.Password,
+			DB:       c.Bridge.Redis.DB,
+			Channel:  c.Bridge.Redis.Channel,
+		}, logger)
+	case "":
+		return bridge.NewNullBridge(), nil
+	default:
+		return nil, fmt.Errorf("unknown bridge type: %s", c.Bridge.Type)
 	}
-	return fcm.NewNullClient(), nil
 }
 
 func provideMySQLConnector(c *Config) (driver.Connector, error) {
@@ -363,6 +687,39 @@ func provideImageProcessorConfig(c *Config) imaging.Config {
 	}
 }
 
+func provideSFUConfig(c *Config) sfu.Config {
+	return sfu.Config{
+		ICEServers: c.SFU.ICEServers,
+		NAT1To1IPs: c.SFU.NAT1To1IPs,
+		UDPPortMin: c.SFU.UDPPortMin,
+		UDPPortMax: c.SFU.UDPPortMax,
+	}
+}
+
+func provideCallConfig(c *Config) call.Config {
+	return call.Config{
+		SystemMessage: c.Call.SystemMessage,
+	}
+}
+
+func provideOGPConfig(c *Config) ogp.Config {
+	providers := make([]ogp.OEmbedProvider, len(c.OGP.OEmbedProviders))
+	for i, p := range c.OGP.OEmbedProviders {
+		providers[i] = ogp.OEmbedProvider{
+			Name:     p.Name,
+			Endpoint: p.Endpoint,
+			Schemes:  p.Schemes,
+		}
+	}
+	return ogp.Config{
+		AllowHosts:      c.OGP.AllowHosts,
+		DenyHosts:       c.OGP.DenyHosts,
+		MaxRedirects:    c.OGP.MaxRedirects,
+		MaxBodySize:     c.OGP.MaxBodySize,
+		OEmbedProviders: providers,
+	}
+}
+
 func provideAuthGithubProviderConfig(c *Config) auth.GithubProviderConfig {
 	return auth.GithubProviderConfig{
 		ClientID:               c.ExternalAuth.GitHub.ClientID,
@@ -401,25 +758,77 @@ func provideAuthTraQProviderConfig(c *Config) auth.TraQProviderConfig {
 	}
 }
 
+func provideAuthLDAPProviderConfig(c *Config) auth.LDAPProviderConfig {
+	return auth.LDAPProviderConfig{
+		URL:                    c.ExternalAuth.LDAP.URL,
+		StartTLS:               c.ExternalAuth.LDAP.StartTLS,
+		InsecureSkipVerify:     c.ExternalAuth.LDAP.InsecureSkipVerify,
+		BindDN:                 c.ExternalAuth.LDAP.BindDN,
+		BindPassword:           c.ExternalAuth.LDAP.BindPassword,
+		UserDNTemplate:         c.ExternalAuth.LDAP.UserDNTemplate,
+		UserBaseDN:             c.ExternalAuth.LDAP.UserBaseDN,
+		UserFilter:             c.ExternalAuth.LDAP.UserFilter,
+		IDAttribute:            c.ExternalAuth.LDAP.Attributes.ID,
+		NameAttribute:          c.ExternalAuth.LDAP.Attributes.Name,
+		DisplayNameAttribute:   c.ExternalAuth.LDAP.Attributes.DisplayName,
+		EmailAttribute:         c.ExternalAuth.LDAP.Attributes.Email,
+		IconAttribute:          c.ExternalAuth.LDAP.Attributes.Icon,
+		GroupSync:              c.ExternalAuth.LDAP.Group.Sync,
+		GroupBaseDN:            c.ExternalAuth.LDAP.Group.BaseDN,
+		GroupFilter:            c.ExternalAuth.LDAP.Group.Filter,
+		GroupNameAttribute:     c.ExternalAuth.LDAP.Group.NameAttribute,
+		GroupType:              c.ExternalAuth.LDAP.Group.Type,
+		RegisterUserIfNotFound: c.ExternalAuth.LDAP.AllowSignUp,
+	}
+}
+
+func provideAuthSAMLProviderConfig(c *Config) auth.SAMLProviderConfig {
+	return auth.SAMLProviderConfig{
+		EntityID:               c.ExternalAuth.SAML.EntityID,
+		MetadataURL:            c.Origin + "/api/auth/saml/metadata",
+		ACSURL:                 c.Origin + "/api/auth/saml/acs",
+		CertificateFile:        c.ExternalAuth.SAML.Certificate,
+		PrivateKeyFile:         c.ExternalAuth.SAML.PrivateKey,
+		IDPMetadataURL:         c.ExternalAuth.SAML.IDPMetadataURL,
+		IDPMetadataFile:        c.ExternalAuth.SAML.IDPMetadataFile,
+		AllowIDPInitiated:      c.ExternalAuth.SAML.AllowIDPInitiated,
+		IDAttribute:            c.ExternalAuth.SAML.Attributes.ID,
+		NameAttribute:          c.ExternalAuth.SAML.Attributes.Name,
+		DisplayNameAttribute:   c.ExternalAuth.SAML.Attributes.DisplayName,
+		EmailAttribute:         c.ExternalAuth.SAML.Attributes.Email,
+		RegisterUserIfNotFound: c.ExternalAuth.SAML.AllowSignUp,
+	}
+}
+
 func provideRouterExternalAuthConfig(c *Config) router.ExternalAuthConfig {
 	return router.ExternalAuthConfig{
 		GitHub: provideAuthGithubProviderConfig(c),
 		Google: provideAuthGoogleProviderConfig(c),
 		TraQ:   provideAuthTraQProviderConfig(c),
 		OIDC:   provideAuthOIDCProviderConfig(c),
+		LDAP:   provideAuthLDAPProviderConfig(c),
+		SAML:   provideAuthSAMLProviderConfig(c),
 	}
 }
 
 func provideRouterConfig(c *Config) *router.Config {
 	return &router.Config{
-		Development:      c.DevMode,
-		Version:          Version,
-		Revision:         Revision,
-		AccessLogging:    c.AccessLog.Enabled,
-		Gzipped:          c.Gzip,
-		AccessTokenExp:   c.OAuth2.AccessTokenExpire,
-		IsRefreshEnabled: c.OAuth2.IsRefreshEnabled,
-		SkyWaySecretKey:  c.SkyWay.SecretKey,
-		ExternalAuth:     provideRouterExternalAuthConfig(c),
+		Development:             c.DevMode,
+		Origin:                  c.Origin,
+		Version:                 Version,
+		Revision:                Revision,
+		AccessLogging:           c.AccessLog.Enabled,
+		Gzipped:                 c.Gzip,
+		AccessTokenExp:          c.OAuth2.AccessTokenExpire,
+		IsRefreshEnabled:        c.OAuth2.IsRefreshEnabled,
+		RefreshTokenAbsoluteExp: c.OAuth2.RefreshTokenAbsoluteExpire,
+		RefreshTokenIdleExp:     c.OAuth2.RefreshTokenIdleExpire,
+		SkyWaySecretKey:         c.SkyWay.SecretKey,
+		ExternalAuth:            provideRouterExternalAuthConfig(c),
+		SCIM: router.SCIMConfig{
+			Token:                c.SCIM.Token,
+			ExternalProviderName: c.SCIM.ExternalProviderName,
+			GroupType:            c.SCIM.GroupType,
+		},
 	}
 }
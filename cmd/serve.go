@@ -175,8 +175,10 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error { return s.Router.Shutdown(ctx) })
 	eg.Go(func() error { return s.SS.WS.Close() })
+	eg.Go(func() error { return s.SS.Relay.Close() })
 	eg.Go(func() error { return s.SS.BOT.Shutdown(ctx) })
 	eg.Go(func() error {
+		s.SS.Notification.Close()
 		s.SS.FCM.Close()
 		return nil
 	})
@@ -10,14 +10,21 @@ import (
 	"github.com/traPtitech/traQ/router"
 	"github.com/traPtitech/traQ/service"
 	"github.com/traPtitech/traQ/service/bot"
+	"github.com/traPtitech/traQ/service/bridge"
+	"github.com/traPtitech/traQ/service/call"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/dnd"
 	"github.com/traPtitech/traQ/service/exevent"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/keyword"
 	"github.com/traPtitech/traQ/service/message"
 	"github.com/traPtitech/traQ/service/notification"
+	"github.com/traPtitech/traQ/service/ogp"
 	rbac2 "github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/sfu"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
@@ -28,6 +35,8 @@ import (
 func newServer(hub *hub.Hub, db *gorm.DB, repo repository.Repository, fs storage.FileStorage, logger *zap.Logger, c *Config) (*Server, error) {
 	wire.Build(
 		bot.NewService,
+		bridge.NewRelay,
+		call.NewRecorder,
 		channel.InitChannelManager,
 		file.InitFileManager,
 		message.NewMessageManager,
@@ -35,18 +44,29 @@ func newServer(hub *hub.Hub, db *gorm.DB, repo repository.Repository, fs storage
 		counter.NewUnreadMessageCounter,
 		counter.NewMessageCounter,
 		counter.NewChannelCounter,
+		dnd.NewManager,
 		exevent.NewStampThrottler,
 		imaging.NewProcessor,
+		keyword.NewManager,
 		notification.NewService,
+		ogp.NewService,
 		rbac2.New,
+		sfu.NewSFU,
+		typing.NewManager,
 		viewer.NewManager,
 		webrtcv3.NewManager,
 		ws.NewStreamer,
 		router.Setup,
 		newFCMClientIfAvailable,
+		newWebPushClientIfAvailable,
+		newEmailServiceIfAvailable,
+		newBridge,
 		provideServerOriginString,
 		provideFirebaseCredentialsFilePathString,
 		provideImageProcessorConfig,
+		provideSFUConfig,
+		provideCallConfig,
+		provideOGPConfig,
 		provideRouterConfig,
 		wire.Struct(new(service.Services), "*"),
 		wire.Struct(new(Server), "*"),
@@ -12,14 +12,21 @@ import (
 	"github.com/traPtitech/traQ/router"
 	"github.com/traPtitech/traQ/service"
 	"github.com/traPtitech/traQ/service/bot"
+	"github.com/traPtitech/traQ/service/bridge"
+	"github.com/traPtitech/traQ/service/call"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/dnd"
 	"github.com/traPtitech/traQ/service/exevent"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/keyword"
 	"github.com/traPtitech/traQ/service/message"
 	"github.com/traPtitech/traQ/service/notification"
+	"github.com/traPtitech/traQ/service/ogp"
 	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/sfu"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
@@ -56,9 +63,15 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, fs storag
 	if err != nil {
 		return nil, err
 	}
+	callConfig := provideCallConfig(c2)
+	recorder := call.NewRecorder(hub2, repo, messageManager, callConfig, logger)
 	stampThrottler := exevent.NewStampThrottler(hub2, messageManager)
 	firebaseCredentialsFilePathString := provideFirebaseCredentialsFilePathString(c2)
-	client, err := newFCMClientIfAvailable(repo, logger, unreadMessageCounter, firebaseCredentialsFilePathString)
+	webpushClient, err := newWebPushClientIfAvailable(repo, logger, unreadMessageCounter, c2)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newFCMClientIfAvailable(repo, logger, unreadMessageCounter, firebaseCredentialsFilePathString, webpushClient)
 	if err != nil {
 		return nil, err
 	}
@@ -70,28 +83,58 @@ func newServer(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, fs storag
 	}
 	viewerManager := viewer.NewManager(hub2)
 	webrtcv3Manager := webrtcv3.NewManager(hub2)
-	streamer := ws.NewStreamer(hub2, viewerManager, webrtcv3Manager, logger)
+	typingManager := typing.NewManager(hub2, manager)
+	sfuConfig := provideSFUConfig(c2)
+	sfuSFU, err := sfu.NewSFU(hub2, webrtcv3Manager, manager, sfuConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+	streamer := ws.NewStreamer(hub2, viewerManager, webrtcv3Manager, typingManager, sfuSFU, logger)
+	emailService, err := newEmailServiceIfAvailable(repo, manager, onlineCounter, logger, c2)
+	if err != nil {
+		return nil, err
+	}
+	dndManager := dnd.NewManager(repo)
+	keywordManager, err := keyword.NewManager(repo, hub2, logger)
+	if err != nil {
+		return nil, err
+	}
 	serverOriginString := provideServerOriginString(c2)
-	notificationService := notification.NewService(repo, manager, messageManager, fileManager, hub2, logger, client, streamer, viewerManager, serverOriginString)
+	notificationService := notification.NewService(repo, manager, messageManager, fileManager, hub2, logger, client, emailService, keywordManager, dndManager, streamer, viewerManager, serverOriginString)
+	ogpConfig := provideOGPConfig(c2)
+	ogpService := ogp.NewService(ogpConfig, repo, fileManager, processor, serverOriginString, logger)
 	rbacRBAC, err := rbac.New(db)
 	if err != nil {
 		return nil, err
 	}
+	bridgeBridge, err := newBridge(c2, logger)
+	if err != nil {
+		return nil, err
+	}
+	relay := bridge.NewRelay(hub2, bridgeBridge, viewerManager, onlineCounter, webrtcv3Manager, logger)
 	services := &service.Services{
 		BOT:                  botService,
+		CallRecorder:         recorder,
 		ChannelManager:       manager,
 		OnlineCounter:        onlineCounter,
 		UnreadMessageCounter: unreadMessageCounter,
 		MessageCounter:       messageCounter,
 		ChannelCounter:       channelCounter,
+		DND:                  dndManager,
+		Email:                emailService,
 		StampThrottler:       stampThrottler,
 		FCM:                  client,
 		FileManager:          fileManager,
 		Imaging:              processor,
 		MessageManager:       messageManager,
 		Notification:         notificationService,
+		OGP:                  ogpService,
 		RBAC:                 rbacRBAC,
+		SFU:                  sfuSFU,
+		Relay:                relay,
+		Typing:               typingManager,
 		ViewerManager:        viewerManager,
+		WebPush:              webpushClient,
 		WebRTCv3:             webrtcv3Manager,
 		WS:                   streamer,
 	}
@@ -0,0 +1,15 @@
+package event
+
+import "github.com/leandro-lugaresi/hub"
+
+// FieldOrigin 他のインスタンスから中継されたイベントに付与される、中継元インスタンスのIDのフィールド名
+const FieldOrigin = "bridge_origin"
+
+// IsRemote 他のインスタンスから中継されたイベントかどうかを返します
+//
+// 中継されたイベントは中継元のインスタンスで既に処理されているため、
+// DBへの書き込みや外部への送信などの副作用を伴う購読者はこのイベントを無視する必要があります。
+func IsRemote(m hub.Message) bool {
+	_, ok := m.Fields[FieldOrigin]
+	return ok
+}
@@ -250,6 +250,42 @@ const (
 	// 		sessions: map[string]string
 	UserWebRTCv3StateChanged = "user.webrtc_v3.state_changed"
 
+	// WebRTCCallStarted チャンネルで通話が開始された
+	// 	Fields:
+	// 		call_id: uuid.UUID
+	// 		channel_id: uuid.UUID
+	// 		started_at: time.Time
+	WebRTCCallStarted = "webrtc.call_started"
+	// WebRTCCallEnded チャンネルの通話が終了した
+	// 	Fields:
+	// 		call_id: uuid.UUID
+	// 		channel_id: uuid.UUID
+	// 		started_at: time.Time
+	// 		ended_at: time.Time
+	// 		participants: []uuid.UUID
+	WebRTCCallEnded = "webrtc.call_ended"
+	// WebRTCCallParticipantJoined ユーザーが通話に参加した
+	// 	Fields:
+	// 		call_id: uuid.UUID
+	// 		channel_id: uuid.UUID
+	// 		user_id: uuid.UUID
+	// 		datetime: time.Time
+	WebRTCCallParticipantJoined = "webrtc.call_participant_joined"
+	// WebRTCCallParticipantLeft ユーザーが通話から退出した
+	// 	Fields:
+	// 		call_id: uuid.UUID
+	// 		channel_id: uuid.UUID
+	// 		user_id: uuid.UUID
+	// 		datetime: time.Time
+	WebRTCCallParticipantLeft = "webrtc.call_participant_left"
+
+	// UserTyping ユーザーの入力中状態が変化した
+	// 	Fields:
+	// 		user_id: uuid.UUID
+	// 		channel_id: uuid.UUID
+	// 		typing: bool
+	UserTyping = "user.typing"
+
 	// SSEConnected ユーザーがSSEストリームに接続した
 	// 	Fields:
 	// 		user_id: uuid.UUID
@@ -311,4 +347,26 @@ const (
 	// 		message_id: uuid.UUID
 	// 		message: message.Message
 	MessageStampsUpdated = "message.stamps.updated"
+
+	// NotificationCreated 通知受信箱に通知が追加された
+	// 	Fields:
+	// 		user_id: uuid.UUID
+	// 		notification: *model.Notification
+	NotificationCreated = "notification.created"
+
+	// KeywordAlertCreated キーワード通知が作成された
+	// 	Fields:
+	// 		user_id: uuid.UUID
+	// 		keyword_alert: *model.KeywordAlert
+	KeywordAlertCreated = "keyword_alert.created"
+	// KeywordAlertDeleted キーワード通知が削除された
+	// 	Fields:
+	// 		user_id: uuid.UUID
+	// 		keyword_alert_id: uuid.UUID
+	KeywordAlertDeleted = "keyword_alert.deleted"
+
+	// OAuth2TokensRevoked OAuth2トークン、またはパーソナルアクセストークンが無効化された
+	// 	Fields:
+	// 		token_ids: []uuid.UUID
+	OAuth2TokensRevoked = "oauth2_tokens.revoked"
 )
@@ -32,6 +32,19 @@ func Migrations() []*gormigrate.Migration {
 		v20(), // パーミッション周りの調整
 		v21(), // OGPキャッシュ追加
 		v22(), // BOTへのWebRTCパーミッションの付与
+		v23(), // 通話履歴
+		v24(), // Web Push購読
+		v25(), // メール通知設定
+		v26(), // 通知受信箱
+		v27(), // キーワード通知
+		v28(), // おやすみモード設定
+		v29(), // OAuth2デバイス認可
+		v30(), // パーソナルアクセストークン
+		v31(), // 細粒度OAuth2スコープ
+		v32(), // リフレッシュトークンのローテーション
+		v33(), // 緊急DMの履歴
+		v34(), // OAuth2リソースサーバー
+		v35(), // 使用済みSAMLアサーション
 	}
 }
 
@@ -40,6 +53,14 @@ func Migrations() []*gormigrate.Migration {
 // 最新のスキーマの全テーブルのモデル構造体を記述すること
 func AllTables() []interface{} {
 	return []interface{}{
+		&model.CallParticipant{},
+		&model.CallSession{},
+		&model.WebPushSubscription{},
+		&model.UserEmailSetting{},
+		&model.Notification{},
+		&model.KeywordAlert{},
+		&model.UserDNDSetting{},
+		&model.UrgentDNDOverride{},
 		&model.ChannelEvent{},
 		&model.RolePermission{},
 		&model.RoleInheritance{},
@@ -52,6 +73,8 @@ func AllTables() []interface{} {
 		&model.OAuth2Client{},
 		&model.OAuth2Authorize{},
 		&model.OAuth2Token{},
+		&model.OAuth2DeviceAuthorization{},
+		&model.PersonalAccessToken{},
 		&model.MessageReport{},
 		&model.WebhookBot{},
 		&model.MessageStamp{},
@@ -80,6 +103,7 @@ func AllTables() []interface{} {
 		&model.User{},
 		&model.SessionRecord{},
 		&model.OgpCache{},
+		&model.SAMLAssertion{},
 	}
 }
 
@@ -130,6 +154,17 @@ func AllForeignKeys() [][5]string {
 		{"stamp_palettes", "creator_id", "users(id)", "CASCADE", "CASCADE"},
 		{"external_provider_users", "user_id", "users(id)", "CASCADE", "CASCADE"},
 		{"user_profiles", "home_channel", "channels(id)", "CASCADE", "CASCADE"},
+		{"call_sessions", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"call_participants", "session_id", "call_sessions(id)", "CASCADE", "CASCADE"},
+		{"call_participants", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"webpush_subscriptions", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"user_email_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"notifications", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"notifications", "message_id", "messages(id)", "CASCADE", "CASCADE"},
+		{"keyword_alerts", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"keyword_alerts", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+		{"user_dnd_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+		{"personal_access_tokens", "user_id", "users(id)", "CASCADE", "CASCADE"},
 	}
 }
 
@@ -147,6 +182,9 @@ func AllCompositeIndexes() [][]string {
 		{"idx_messages_stamps_user_id_stamp_id_updated_at", "messages_stamps", "user_id", "stamp_id", "updated_at"},
 		{"idx_channel_channels_id_is_public_is_forced", "channels", "id", "is_public", "is_forced"},
 		{"idx_messages_deleted_at_created_at", "messages", "deleted_at", "created_at"},
+		{"idx_call_sessions_channel_id_started_at", "call_sessions", "channel_id", "started_at"},
+		{"idx_notifications_user_id_created_at", "notifications", "user_id", "created_at"},
+		{"idx_urgent_dnd_overrides_sender_id_recipient_id_created_at", "urgent_dnd_overrides", "sender_id", "recipient_id", "created_at"},
 	}
 }
 
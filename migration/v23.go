@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v23 通話履歴
+func v23() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "23",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v23CallSession{}, &v23CallParticipant{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"call_sessions", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+				{"call_participants", "session_id", "call_sessions(id)", "CASCADE", "CASCADE"},
+				{"call_participants", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			indexes := [][]string{
+				{"idx_call_sessions_channel_id_started_at", "call_sessions", "channel_id", "started_at"},
+			}
+			for _, v := range indexes {
+				if err := db.Table(v[1]).AddIndex(v[0], v[2:]...).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v23CallSession struct {
+	ID        uuid.UUID  `gorm:"type:char(36);not null;primary_key"`
+	ChannelID uuid.UUID  `gorm:"type:char(36);not null"`
+	StartedAt time.Time  `gorm:"precision:6"`
+	EndedAt   *time.Time `gorm:"precision:6"`
+}
+
+func (*v23CallSession) TableName() string {
+	return "call_sessions"
+}
+
+type v23CallParticipant struct {
+	ID        uuid.UUID  `gorm:"type:char(36);not null;primary_key"`
+	SessionID uuid.UUID  `gorm:"type:char(36);not null;index"`
+	UserID    uuid.UUID  `gorm:"type:char(36);not null"`
+	JoinedAt  time.Time  `gorm:"precision:6"`
+	LeftAt    *time.Time `gorm:"precision:6"`
+}
+
+func (*v23CallParticipant) TableName() string {
+	return "call_participants"
+}
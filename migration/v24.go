@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v24 Web Push購読
+func v24() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "24",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v24WebPushSubscription{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"webpush_subscriptions", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v24WebPushSubscription struct {
+	EndpointHash string    `gorm:"type:char(40);not null;primary_key"`
+	Endpoint     string    `gorm:"type:text;not null"`
+	UserID       uuid.UUID `gorm:"type:char(36);not null;index"`
+	P256dh       string    `gorm:"type:varchar(100);not null"`
+	Auth         string    `gorm:"type:varchar(30);not null"`
+	CreatedAt    time.Time `gorm:"precision:6"`
+	UpdatedAt    time.Time `gorm:"precision:6"`
+}
+
+func (*v24WebPushSubscription) TableName() string {
+	return "webpush_subscriptions"
+}
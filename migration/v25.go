@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v25 メール通知設定
+func v25() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "25",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v25UserEmailSetting{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_email_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v25UserEmailSetting struct {
+	UserID       uuid.UUID  `gorm:"type:char(36);not null;primary_key"`
+	Address      string     `gorm:"type:varchar(254);not null;default:''"`
+	Immediate    bool       `gorm:"type:boolean;not null;default:false"`
+	Digest       string     `gorm:"type:varchar(10);not null;default:'none'"`
+	DigestSentAt *time.Time `gorm:"precision:6"`
+	CreatedAt    time.Time  `gorm:"precision:6"`
+	UpdatedAt    time.Time  `gorm:"precision:6"`
+}
+
+func (*v25UserEmailSetting) TableName() string {
+	return "user_email_settings"
+}
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v26 通知受信箱
+func v26() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "26",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v26Notification{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"notifications", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"notifications", "message_id", "messages(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			indexes := [][]string{
+				{"idx_notifications_user_id_created_at", "notifications", "user_id", "created_at"},
+			}
+			for _, v := range indexes {
+				if err := db.Table(v[1]).AddIndex(v[0], v[2:]...).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v26Notification struct {
+	ID        uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID `gorm:"type:char(36);not null;unique_index:user_message"`
+	Type      string    `gorm:"type:varchar(20);not null"`
+	MessageID uuid.UUID `gorm:"type:char(36);not null;unique_index:user_message"`
+	ChannelID uuid.UUID `gorm:"type:char(36);not null"`
+	ActorID   uuid.UUID `gorm:"type:char(36);not null"`
+	IsRead    bool      `gorm:"type:boolean;not null;default:false"`
+	CreatedAt time.Time `gorm:"precision:6"`
+}
+
+func (*v26Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v27 キーワード通知
+func v27() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "27",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v27KeywordAlert{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"keyword_alerts", "user_id", "users(id)", "CASCADE", "CASCADE"},
+				{"keyword_alerts", "channel_id", "channels(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v27KeywordAlert struct {
+	ID        uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	UserID    uuid.UUID     `gorm:"type:char(36);not null;index"`
+	Keyword   string        `gorm:"type:varchar(100);not null"`
+	IsRegex   bool          `gorm:"type:boolean;not null;default:false"`
+	ChannelID optional.UUID `gorm:"type:char(36)"`
+	CreatedAt time.Time     `gorm:"precision:6"`
+}
+
+func (*v27KeywordAlert) TableName() string {
+	return "keyword_alerts"
+}
@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v28 おやすみモード設定
+func v28() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "28",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v28UserDNDSetting{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"user_dnd_settings", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v28UserDNDSetting struct {
+	UserID      uuid.UUID     `gorm:"type:char(36);not null;primary_key"`
+	SnoozeUntil optional.Time `gorm:"precision:6"`
+	Timezone    string        `gorm:"type:varchar(64);not null;default:'Asia/Tokyo'"`
+	Schedule    string        `gorm:"type:text"`
+	CreatedAt   time.Time     `gorm:"precision:6"`
+	UpdatedAt   time.Time     `gorm:"precision:6"`
+}
+
+func (*v28UserDNDSetting) TableName() string {
+	return "user_dnd_settings"
+}
@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v29 OAuth2デバイス認可
+func v29() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "29",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v29OAuth2DeviceAuthorization{}).Error
+		},
+	}
+}
+
+type v29OAuth2DeviceAuthorization struct {
+	DeviceCode     string    `gorm:"type:varchar(36);primary_key"`
+	UserCode       string    `gorm:"type:varchar(8);unique"`
+	ClientID       string    `gorm:"type:char(36)"`
+	UserID         uuid.UUID `gorm:"type:char(36)"`
+	Status         string    `gorm:"type:varchar(16)"`
+	Scopes         string    `gorm:"type:text"`
+	OriginalScopes string    `gorm:"type:text"`
+	ExpiresIn      int
+	Interval       int
+	LastPolledAt   *time.Time `gorm:"precision:6"`
+	CreatedAt      time.Time  `gorm:"precision:6"`
+}
+
+func (*v29OAuth2DeviceAuthorization) TableName() string {
+	return "oauth2_device_authorizations"
+}
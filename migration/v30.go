@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v30 パーソナルアクセストークン
+func v30() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "30",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v30PersonalAccessToken{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"personal_access_tokens", "user_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"create_my_token",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v30RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v30PersonalAccessToken struct {
+	ID         uuid.UUID  `gorm:"type:char(36);not null;primary_key"`
+	UserID     uuid.UUID  `gorm:"type:char(36);not null;index"`
+	Name       string     `gorm:"type:varchar(32);not null"`
+	TokenHash  string     `gorm:"type:char(64);not null;unique"`
+	Scopes     string     `gorm:"type:text"`
+	ExpiresAt  time.Time  `gorm:"precision:6"`
+	LastUsedAt *time.Time `gorm:"precision:6"`
+	LastUsedIP string     `gorm:"type:varchar(45);not null;default:''"`
+	CreatedAt  time.Time  `gorm:"precision:6"`
+}
+
+func (*v30PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+type v30RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v30RolePermission) TableName() string {
+	return "user_role_permissions"
+}
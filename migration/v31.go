@@ -0,0 +1,134 @@
+package migration
+
+import (
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+)
+
+// v31 細粒度OAuth2スコープ
+func v31() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "31",
+		Migrate: func(db *gorm.DB) error {
+			addedScopes := map[string][]string{
+				"channels:read": {
+					"get_channel",
+					"get_channel_subscription",
+					"get_channel_star",
+				},
+				"channels:write": {
+					"create_channel",
+					"edit_channel_topic",
+					"edit_channel_subscription",
+					"edit_channel_star",
+				},
+				"messages:read": {
+					"get_channel",
+					"get_message",
+					"get_unread",
+					"connect_notification_stream",
+				},
+				"messages:write": {
+					"post_message",
+					"edit_message",
+					"delete_message",
+					"report_message",
+					"create_message_pin",
+					"delete_message_pin",
+					"delete_unread",
+				},
+				"dms": {
+					"access_dm_channel",
+				},
+				"stamps:read": {
+					"get_stamp",
+					"get_my_stamp_history",
+					"get_stamp_palette",
+				},
+				"stamps:write": {
+					"create_stamp",
+					"edit_stamp",
+					"add_message_stamp",
+					"remove_message_stamp",
+					"create_stamp_palette",
+					"edit_stamp_palette",
+					"delete_stamp_palette",
+				},
+				"users:read": {
+					"get_user",
+					"get_me",
+					"get_user_tag",
+					"get_user_group",
+				},
+				"users:write": {
+					"edit_me",
+					"change_my_icon",
+					"edit_user_tag",
+					"create_user_group",
+					"edit_user_group",
+					"delete_user_group",
+				},
+				"files:read": {
+					"download_file",
+				},
+				"files:write": {
+					"upload_file",
+					"delete_file",
+				},
+			}
+			for scope, perms := range addedScopes {
+				if err := db.Create(&v31UserRole{Name: scope, Oauth2Scope: true, System: true}).Error; err != nil {
+					return err
+				}
+				for _, perm := range perms {
+					if err := db.Create(&v31RolePermission{Role: scope, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+
+			// 既存のロール・スコープには従来通りDMへのアクセスを許可する
+			addedRolePermissions := map[string][]string{
+				"user": {
+					"access_dm_channel",
+				},
+				"bot": {
+					"access_dm_channel",
+				},
+				"read": {
+					"access_dm_channel",
+				},
+				"write": {
+					"access_dm_channel",
+				},
+			}
+			for role, perms := range addedRolePermissions {
+				for _, perm := range perms {
+					if err := db.Create(&v31RolePermission{Role: role, Permission: perm}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v31UserRole struct {
+	Name        string `gorm:"type:varchar(30);not null;primary_key"`
+	Oauth2Scope bool   `gorm:"type:boolean;not null;default:false"`
+	System      bool   `gorm:"type:boolean;not null;default:false"`
+}
+
+func (*v31UserRole) TableName() string {
+	return "user_roles"
+}
+
+type v31RolePermission struct {
+	Role       string `gorm:"type:varchar(30);not null;primary_key"`
+	Permission string `gorm:"type:varchar(30);not null;primary_key"`
+}
+
+func (*v31RolePermission) TableName() string {
+	return "user_role_permissions"
+}
@@ -0,0 +1,52 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/utils/optional"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v32 リフレッシュトークンのローテーション
+func v32() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "32",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v32OAuth2Token{}).Error; err != nil {
+				return err
+			}
+
+			// 既存のトークンはそれぞれ単独のファミリーとする
+			if err := db.Table(v32OAuth2Token{}.TableName()).Where("family_id = ''").UpdateColumns(map[string]interface{}{
+				"family_id":         gorm.Expr("id"),
+				"family_created_at": gorm.Expr("created_at"),
+			}).Error; err != nil {
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+type v32OAuth2Token struct {
+	ID              uuid.UUID `gorm:"type:char(36);primary_key"`
+	ClientID        string    `gorm:"type:char(36)"`
+	UserID          uuid.UUID `gorm:"type:char(36)"`
+	RedirectURI     string    `gorm:"type:text"`
+	AccessToken     string    `gorm:"type:varchar(36);unique"`
+	RefreshToken    string    `gorm:"type:varchar(36);unique"`
+	RefreshEnabled  bool      `gorm:"type:boolean;default:false"`
+	Scopes          string    `gorm:"type:text"`
+	ExpiresIn       int
+	FamilyID        uuid.UUID     `gorm:"type:char(36);not null;index"`
+	ParentID        optional.UUID `gorm:"type:char(36)"`
+	FamilyCreatedAt time.Time     `gorm:"precision:6"`
+	RotatedAt       *time.Time    `gorm:"precision:6"`
+	CreatedAt       time.Time     `gorm:"precision:6"`
+	DeletedAt       *time.Time    `gorm:"precision:6"`
+}
+
+func (v32OAuth2Token) TableName() string {
+	return "oauth2_tokens"
+}
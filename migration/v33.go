@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v33 緊急DMの履歴
+func v33() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "33",
+		Migrate: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&v33UrgentDNDOverride{}).Error; err != nil {
+				return err
+			}
+
+			foreignKeys := [][5]string{
+				{"urgent_dnd_overrides", "sender_id", "users(id)", "CASCADE", "CASCADE"},
+				{"urgent_dnd_overrides", "recipient_id", "users(id)", "CASCADE", "CASCADE"},
+			}
+			for _, c := range foreignKeys {
+				if err := db.Table(c[0]).AddForeignKey(c[1], c[2], c[3], c[4]).Error; err != nil {
+					return err
+				}
+			}
+
+			indexes := [][]string{
+				{"idx_urgent_dnd_overrides_sender_id_recipient_id_created_at", "urgent_dnd_overrides", "sender_id", "recipient_id", "created_at"},
+			}
+			for _, v := range indexes {
+				if err := db.Table(v[1]).AddIndex(v[0], v[2:]...).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+type v33UrgentDNDOverride struct {
+	ID          uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	SenderID    uuid.UUID `gorm:"type:char(36);not null"`
+	RecipientID uuid.UUID `gorm:"type:char(36);not null"`
+	CreatedAt   time.Time `gorm:"precision:6"`
+}
+
+func (*v33UrgentDNDOverride) TableName() string {
+	return "urgent_dnd_overrides"
+}
@@ -0,0 +1,37 @@
+package migration
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v34 OAuth2リソースサーバー
+func v34() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "34",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v34OAuth2Client{}).Error
+		},
+	}
+}
+
+type v34OAuth2Client struct {
+	ID             string `gorm:"type:char(36);primary_key"`
+	Name           string `gorm:"type:varchar(32)"`
+	Description    string `gorm:"type:text"`
+	Confidential   bool
+	ResourceServer bool       `gorm:"type:boolean;not null;default:false"`
+	CreatorID      uuid.UUID  `gorm:"type:char(36)"`
+	Secret         string     `gorm:"type:varchar(36)"`
+	RedirectURI    string     `gorm:"type:text"`
+	Scopes         string     `gorm:"type:text"`
+	CreatedAt      time.Time  `gorm:"precision:6"`
+	UpdatedAt      time.Time  `gorm:"precision:6"`
+	DeletedAt      *time.Time `gorm:"precision:6"`
+}
+
+func (*v34OAuth2Client) TableName() string {
+	return "oauth2_clients"
+}
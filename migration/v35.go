@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"github.com/jinzhu/gorm"
+	"gopkg.in/gormigrate.v1"
+	"time"
+)
+
+// v35 使用済みSAMLアサーション
+func v35() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "35",
+		Migrate: func(db *gorm.DB) error {
+			return db.AutoMigrate(&v35SAMLAssertion{}).Error
+		},
+	}
+}
+
+type v35SAMLAssertion struct {
+	ID        string    `gorm:"type:varchar(190);primary_key"`
+	ExpiresAt time.Time `gorm:"precision:6;index"`
+}
+
+func (*v35SAMLAssertion) TableName() string {
+	return "saml_assertions"
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// CallSession 通話セッション構造体
+type CallSession struct {
+	ID        uuid.UUID  `gorm:"type:char(36);not null;primary_key" json:"id"`
+	ChannelID uuid.UUID  `gorm:"type:char(36);not null"             json:"channelId"`
+	StartedAt time.Time  `gorm:"precision:6"                        json:"startedAt"`
+	EndedAt   *time.Time `gorm:"precision:6"                        json:"endedAt"`
+
+	Participants []*CallParticipant `gorm:"association_autoupdate:false;association_autocreate:false;preload:false;foreignkey:SessionID" json:"participants"`
+}
+
+// TableName CallSession構造体のテーブル名
+func (*CallSession) TableName() string {
+	return "call_sessions"
+}
+
+// CallParticipant 通話参加記録構造体
+//
+// 同じユーザーが同じ通話に複数回参加した場合は、参加毎に記録されます。
+type CallParticipant struct {
+	ID        uuid.UUID  `gorm:"type:char(36);not null;primary_key" json:"-"`
+	SessionID uuid.UUID  `gorm:"type:char(36);not null;index"       json:"-"`
+	UserID    uuid.UUID  `gorm:"type:char(36);not null"             json:"userId"`
+	JoinedAt  time.Time  `gorm:"precision:6"                        json:"joinedAt"`
+	LeftAt    *time.Time `gorm:"precision:6"                        json:"leftAt"`
+}
+
+// TableName CallParticipant構造体のテーブル名
+func (*CallParticipant) TableName() string {
+	return "call_participants"
+}
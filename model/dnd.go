@@ -0,0 +1,181 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// DefaultDNDTimezone おやすみモードスケジュールのデフォルトのタイムゾーン
+const DefaultDNDTimezone = "Asia/Tokyo"
+
+// DNDPeriod 毎週繰り返すおやすみモードの期間
+//
+// StartとEndは0時からの経過分で表します。StartがEndより大きい場合は翌日のEndまでです。
+type DNDPeriod struct {
+	// Weekday 開始曜日 (0: 日曜日)
+	Weekday time.Weekday `json:"weekday"`
+	// Start 開始時刻 (0-1439)
+	Start int `json:"start"`
+	// End 終了時刻 (1-1440)
+	End int `json:"end"`
+}
+
+// Validate 有効な期間かどうかを検証します
+func (p DNDPeriod) Validate() error {
+	if p.Weekday < time.Sunday || p.Weekday > time.Saturday {
+		return fmt.Errorf("invalid weekday: %d", p.Weekday)
+	}
+	if p.Start < 0 || p.Start >= 24*60 {
+		return fmt.Errorf("invalid start: %d", p.Start)
+	}
+	if p.End <= 0 || p.End > 24*60 {
+		return fmt.Errorf("invalid end: %d", p.End)
+	}
+	if p.Start == p.End {
+		return errors.New("start and end must be different")
+	}
+	return nil
+}
+
+// end tを含む場合、期間の終了日時とtrueを返します
+func (p DNDPeriod) end(t time.Time) (time.Time, bool) {
+	m := t.Hour()*60 + t.Minute()
+	y, mo, d := t.Date()
+	at := func(dayOffset, minutes int) time.Time {
+		return time.Date(y, mo, d+dayOffset, minutes/60, minutes%60, 0, 0, t.Location())
+	}
+	switch {
+	case p.Start < p.End:
+		if t.Weekday() == p.Weekday && p.Start <= m && m < p.End {
+			return at(0, p.End), true
+		}
+	default: // 日跨ぎ
+		if t.Weekday() == p.Weekday && p.Start <= m {
+			return at(1, p.End), true
+		}
+		if t.Weekday() == (p.Weekday+1)%7 && m < p.End {
+			return at(0, p.End), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// DNDSchedule 毎週繰り返すおやすみモードのスケジュール
+type DNDSchedule []DNDPeriod
+
+// Validate 有効なスケジュールかどうかを検証します
+func (s DNDSchedule) Validate() error {
+	if len(s) > 7*4 {
+		return errors.New("too many periods")
+	}
+	for _, p := range s {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// end tがスケジュールに含まれる場合、連続する期間の終了日時とtrueを返します
+func (s DNDSchedule) end(t time.Time) (time.Time, bool) {
+	until := t
+	// 隣接する期間は連続しているものとして扱う
+	for i := 0; i <= len(s); i++ {
+		extended := false
+		for _, p := range s {
+			if e, ok := p.end(until); ok && e.After(until) {
+				until = e
+				extended = true
+			}
+		}
+		if !extended {
+			break
+		}
+	}
+	return until, until.After(t)
+}
+
+// Value database/sql/driver.Valuer 実装
+func (s DNDSchedule) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	return json.MarshalToString(s)
+}
+
+// Scan database/sql.Scanner 実装
+func (s *DNDSchedule) Scan(src interface{}) error {
+	*s = DNDSchedule{}
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case []byte:
+		return json.Unmarshal(v, s)
+	default:
+		return errors.New("failed to scan DNDSchedule")
+	}
+}
+
+// UserDNDSetting ユーザーのおやすみモード設定の構造体
+type UserDNDSetting struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// SnoozeUntil 手動で設定したおやすみモードの終了日時
+	SnoozeUntil optional.Time `gorm:"precision:6"`
+	// Timezone Scheduleを解釈するタイムゾーン (IANA Time Zone database名)
+	Timezone string `gorm:"type:varchar(64);not null;default:'Asia/Tokyo'"`
+	// Schedule 毎週繰り返すおやすみモードのスケジュール
+	Schedule  DNDSchedule `gorm:"type:text"`
+	CreatedAt time.Time   `gorm:"precision:6"`
+	UpdatedAt time.Time   `gorm:"precision:6"`
+}
+
+// TableName UserDNDSetting構造体のテーブル名
+func (*UserDNDSetting) TableName() string {
+	return "user_dnd_settings"
+}
+
+// ActiveUntil nowの時点でおやすみモード中の場合、おやすみモードの終了日時とtrueを返します
+func (s *UserDNDSetting) ActiveUntil(now time.Time) (time.Time, bool) {
+	var until time.Time
+	if s.SnoozeUntil.Valid && s.SnoozeUntil.Time.After(now) {
+		until = s.SnoozeUntil.Time
+	}
+
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	if e, ok := s.Schedule.end(now.In(loc)); ok && e.After(until) {
+		until = e
+	}
+
+	if until.IsZero() {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// IsActive nowの時点でおやすみモード中かどうか
+func (s *UserDNDSetting) IsActive(now time.Time) bool {
+	_, ok := s.ActiveUntil(now)
+	return ok
+}
+
+// UrgentDNDOverride おやすみモードを無視して通知された緊急DMの履歴の構造体
+type UrgentDNDOverride struct {
+	ID          uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	SenderID    uuid.UUID `gorm:"type:char(36);not null"`
+	RecipientID uuid.UUID `gorm:"type:char(36);not null"`
+	CreatedAt   time.Time `gorm:"precision:6"`
+}
+
+// TableName UrgentDNDOverride構造体のテーブル名
+func (*UrgentDNDOverride) TableName() string {
+	return "urgent_dnd_overrides"
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/utils/optional"
+	"testing"
+	"time"
+)
+
+func TestUserDNDSetting_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "user_dnd_settings", (&UserDNDSetting{}).TableName())
+}
+
+func TestUrgentDNDOverride_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "urgent_dnd_overrides", (&UrgentDNDOverride{}).TableName())
+}
+
+func TestDNDPeriod_Validate(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, DNDPeriod{Weekday: time.Monday, Start: 0, End: 1440}.Validate())
+	assert.NoError(t, DNDPeriod{Weekday: time.Saturday, Start: 22 * 60, End: 7 * 60}.Validate())
+	assert.Error(t, DNDPeriod{Weekday: 7, Start: 0, End: 60}.Validate())
+	assert.Error(t, DNDPeriod{Weekday: time.Monday, Start: -1, End: 60}.Validate())
+	assert.Error(t, DNDPeriod{Weekday: time.Monday, Start: 0, End: 1441}.Validate())
+	assert.Error(t, DNDPeriod{Weekday: time.Monday, Start: 60, End: 60}.Validate())
+}
+
+func TestDNDSchedule_Scan(t *testing.T) {
+	t.Parallel()
+
+	s := DNDSchedule{{Weekday: time.Friday, Start: 1320, End: 420}}
+	v, err := s.Value()
+	assert.NoError(t, err)
+
+	var scanned DNDSchedule
+	assert.NoError(t, scanned.Scan(v))
+	assert.Equal(t, s, scanned)
+	assert.NoError(t, scanned.Scan(nil))
+	assert.Len(t, scanned, 0)
+	assert.Error(t, scanned.Scan(1))
+}
+
+func TestUserDNDSetting_ActiveUntil(t *testing.T) {
+	t.Parallel()
+
+	jst := time.FixedZone("JST", 9*60*60)
+	s := &UserDNDSetting{
+		Timezone: "UTC",
+		Schedule: DNDSchedule{
+			{Weekday: time.Friday, Start: 22 * 60, End: 7 * 60}, // 金曜22時から土曜7時
+			{Weekday: time.Saturday, Start: 7 * 60, End: 12 * 60},
+			{Weekday: time.Monday, Start: 12 * 60, End: 13 * 60},
+		},
+	}
+	// 2020-06-05は金曜日
+	cases := []struct {
+		now    time.Time
+		active bool
+		until  time.Time
+	}{
+		{time.Date(2020, 6, 5, 21, 59, 0, 0, time.UTC), false, time.Time{}},
+		{time.Date(2020, 6, 5, 22, 0, 0, 0, time.UTC), true, time.Date(2020, 6, 6, 12, 0, 0, 0, time.UTC)},
+		{time.Date(2020, 6, 6, 3, 0, 0, 0, time.UTC), true, time.Date(2020, 6, 6, 12, 0, 0, 0, time.UTC)},
+		{time.Date(2020, 6, 6, 12, 0, 0, 0, time.UTC), false, time.Time{}},
+		{time.Date(2020, 6, 8, 12, 30, 0, 0, time.UTC), true, time.Date(2020, 6, 8, 13, 0, 0, 0, time.UTC)},
+		// タイムゾーンが異なっても同じ時刻として扱う
+		{time.Date(2020, 6, 8, 21, 30, 0, 0, jst), true, time.Date(2020, 6, 8, 13, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		until, ok := s.ActiveUntil(c.now)
+		assert.Equal(t, c.active, ok, c.now)
+		assert.True(t, c.until.Equal(until), c.now)
+	}
+
+	t.Run("snooze", func(t *testing.T) {
+		t.Parallel()
+
+		now := time.Date(2020, 6, 8, 9, 0, 0, 0, time.UTC)
+		s := &UserDNDSetting{Timezone: "UTC", SnoozeUntil: optional.TimeFrom(now.Add(time.Hour))}
+		until, ok := s.ActiveUntil(now)
+		assert.True(t, ok)
+		assert.True(t, now.Add(time.Hour).Equal(until))
+		assert.False(t, s.IsActive(now.Add(time.Hour)))
+	})
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// EmailDigest ダイジェストメールの送信頻度
+type EmailDigest string
+
+const (
+	// EmailDigestNone ダイジェストメールを送信しない
+	EmailDigestNone EmailDigest = "none"
+	// EmailDigestDaily 毎日送信する
+	EmailDigestDaily EmailDigest = "daily"
+	// EmailDigestWeekly 毎週送信する
+	EmailDigestWeekly EmailDigest = "weekly"
+)
+
+// Valid 有効な値かどうか
+func (d EmailDigest) Valid() bool {
+	switch d {
+	case EmailDigestNone, EmailDigestDaily, EmailDigestWeekly:
+		return true
+	default:
+		return false
+	}
+}
+
+// UserEmailSetting ユーザーのメール通知設定の構造体
+type UserEmailSetting struct {
+	UserID uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	// Address 通知先メールアドレス
+	Address string `gorm:"type:varchar(254);not null;default:''"`
+	// Immediate メンション・DMをオフライン時に即時通知するかどうか
+	Immediate bool `gorm:"type:boolean;not null;default:false"`
+	// Digest 未読ダイジェストの送信頻度
+	Digest EmailDigest `gorm:"type:varchar(10);not null;default:'none'"`
+	// DigestSentAt 最後にダイジェストを送信した日時
+	DigestSentAt *time.Time `gorm:"precision:6"`
+	CreatedAt    time.Time  `gorm:"precision:6"`
+	UpdatedAt    time.Time  `gorm:"precision:6"`
+}
+
+// TableName UserEmailSetting構造体のテーブル名
+func (*UserEmailSetting) TableName() string {
+	return "user_email_settings"
+}
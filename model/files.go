@@ -44,6 +44,8 @@ func (f FileType) String() string {
 		return "stamp"
 	case FileTypeThumbnail:
 		return "thumbnail"
+	case FileTypeOgpImage:
+		return "ogpImage"
 	default:
 		return "null"
 	}
@@ -59,6 +61,8 @@ func FileTypeFromString(s string) (FileType, error) {
 		return FileTypeStamp, nil
 	case "thumbnail":
 		return FileTypeThumbnail, nil
+	case "ogpimage":
+		return FileTypeOgpImage, nil
 	default:
 		return 0, errors.New("unknown FileType")
 	}
@@ -73,6 +77,8 @@ const (
 	FileTypeStamp
 	// FileTypeThumbnail サムネイルファイルタイプ
 	FileTypeThumbnail
+	// FileTypeOgpImage OGPプレビュー画像ファイルタイプ
+	FileTypeOgpImage
 )
 
 type File interface {
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// KeywordAlert キーワード通知の構造体
+type KeywordAlert struct {
+	ID     uuid.UUID `gorm:"type:char(36);not null;primary_key" json:"id"`
+	UserID uuid.UUID `gorm:"type:char(36);not null;index" json:"-"`
+	// Keyword キーワード (IsRegexがtrueの場合は正規表現)
+	Keyword string `gorm:"type:varchar(100);not null" json:"keyword"`
+	IsRegex bool   `gorm:"type:boolean;not null;default:false" json:"isRegex"`
+	// ChannelID 通知対象のチャンネルのID (無い場合は全ての公開チャンネル)
+	ChannelID optional.UUID `gorm:"type:char(36)" json:"channelId"`
+	CreatedAt time.Time     `gorm:"precision:6" json:"createdAt"`
+}
+
+// TableName KeywordAlert構造体のテーブル名
+func (*KeywordAlert) TableName() string {
+	return "keyword_alerts"
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// NotificationType 通知の種類
+type NotificationType string
+
+const (
+	// NotificationTypeMention ユーザーメンション
+	NotificationTypeMention NotificationType = "mention"
+	// NotificationTypeGroupMention グループメンション
+	NotificationTypeGroupMention NotificationType = "group_mention"
+	// NotificationTypeDM ダイレクトメッセージ
+	NotificationTypeDM NotificationType = "dm"
+	// NotificationTypeCitation 自分のメッセージの引用
+	NotificationTypeCitation NotificationType = "citation"
+	// NotificationTypeKeyword キーワード通知
+	NotificationTypeKeyword NotificationType = "keyword"
+)
+
+// Notification 通知受信箱の通知の構造体
+//
+// 1つのメッセージにつき、1ユーザーあたり最大1つ作成されます。
+type Notification struct {
+	ID     uuid.UUID        `gorm:"type:char(36);not null;primary_key" json:"id"`
+	UserID uuid.UUID        `gorm:"type:char(36);not null;unique_index:user_message" json:"-"`
+	Type   NotificationType `gorm:"type:varchar(20);not null" json:"type"`
+	// MessageID 通知の元となったメッセージのID
+	MessageID uuid.UUID `gorm:"type:char(36);not null;unique_index:user_message" json:"messageId"`
+	ChannelID uuid.UUID `gorm:"type:char(36);not null" json:"channelId"`
+	// ActorID 通知の元となったメッセージの投稿者のID
+	ActorID   uuid.UUID `gorm:"type:char(36);not null" json:"actorId"`
+	IsRead    bool      `gorm:"type:boolean;not null;default:false" json:"isRead"`
+	CreatedAt time.Time `gorm:"precision:6" json:"createdAt"`
+}
+
+// TableName Notification構造体のテーブル名
+func (*Notification) TableName() string {
+	return "notifications"
+}
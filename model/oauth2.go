@@ -8,6 +8,7 @@ import (
 	"fmt"
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/validator"
 	"strings"
 	"time"
@@ -22,6 +23,13 @@ import (
 // /と"は使えません。
 type AccessScope string
 
+const (
+	// ScopeOpenID OpenID Connectの認証リクエストであることを示すスコープ
+	ScopeOpenID AccessScope = "openid"
+	// ScopeProfile OpenID Connectでユーザーのプロフィール情報を要求するスコープ
+	ScopeProfile AccessScope = "profile"
+)
+
 // AccessScopes AccessScopeのセット
 type AccessScopes map[AccessScope]struct{}
 
@@ -109,7 +117,27 @@ func (arr AccessScopes) StringArray() (r []string) {
 // Validate github.com/go-ozzo/ozzo-validation.Validatable 実装
 func (arr AccessScopes) Validate() error {
 	// TODO カスタムスコープに対応
-	return vd.Validate(arr.StringArray(), vd.Each(vd.Required, vd.In("read", "write", "manage_bot")))
+	return vd.Validate(arr.StringArray(), vd.Each(vd.Required, vd.In(validAccessScopes...)))
+}
+
+// validAccessScopes 使用可能なスコープ (rbacのOAuth2スコープロールに対応)
+var validAccessScopes = []interface{}{
+	"read",
+	"write",
+	"manage_bot",
+	"channels:read",
+	"channels:write",
+	"messages:read",
+	"messages:write",
+	"dms",
+	"stamps:read",
+	"stamps:write",
+	"users:read",
+	"users:write",
+	"files:read",
+	"files:write",
+	string(ScopeOpenID),
+	string(ScopeProfile),
 }
 
 // OAuth2Authorize OAuth2 認可データの構造体
@@ -167,13 +195,15 @@ type OAuth2Client struct {
 	Name         string `gorm:"type:varchar(32)"`
 	Description  string `gorm:"type:text"`
 	Confidential bool
-	CreatorID    uuid.UUID    `gorm:"type:char(36)"`
-	Secret       string       `gorm:"type:varchar(36)"`
-	RedirectURI  string       `gorm:"type:text"`
-	Scopes       AccessScopes `gorm:"type:text"`
-	CreatedAt    time.Time    `gorm:"precision:6"`
-	UpdatedAt    time.Time    `gorm:"precision:6"`
-	DeletedAt    *time.Time   `gorm:"precision:6"`
+	// ResourceServer 他のクライアントに発行されたトークンをイントロスペクションできるかどうか
+	ResourceServer bool         `gorm:"type:boolean;not null;default:false"`
+	CreatorID      uuid.UUID    `gorm:"type:char(36)"`
+	Secret         string       `gorm:"type:varchar(36)"`
+	RedirectURI    string       `gorm:"type:text"`
+	Scopes         AccessScopes `gorm:"type:text"`
+	CreatedAt      time.Time    `gorm:"precision:6"`
+	UpdatedAt      time.Time    `gorm:"precision:6"`
+	DeletedAt      *time.Time   `gorm:"precision:6"`
 }
 
 // TableName OAuth2Clientのテーブル名
@@ -193,18 +223,25 @@ func (c *OAuth2Client) GetAvailableScopes(request AccessScopes) (result AccessSc
 }
 
 // OAuth2Token OAuth2 トークンの構造体
+//
+// リフレッシュトークンを使用すると、同じFamilyIDを持つ新しいトークンにローテーションされます。
+// FamilyIDとFamilyCreatedAtはファミリーの最初のトークンのIDと発行日時、ParentIDはローテーション元のトークンのIDです。
 type OAuth2Token struct {
-	ID             uuid.UUID    `gorm:"type:char(36);primary_key"`
-	ClientID       string       `gorm:"type:char(36)"`
-	UserID         uuid.UUID    `gorm:"type:char(36)"`
-	RedirectURI    string       `gorm:"type:text"`
-	AccessToken    string       `gorm:"type:varchar(36);unique"`
-	RefreshToken   string       `gorm:"type:varchar(36);unique"`
-	RefreshEnabled bool         `gorm:"type:boolean;default:false"`
-	Scopes         AccessScopes `gorm:"type:text"`
-	ExpiresIn      int
-	CreatedAt      time.Time  `gorm:"precision:6"`
-	DeletedAt      *time.Time `gorm:"precision:6"`
+	ID              uuid.UUID    `gorm:"type:char(36);primary_key"`
+	ClientID        string       `gorm:"type:char(36)"`
+	UserID          uuid.UUID    `gorm:"type:char(36)"`
+	RedirectURI     string       `gorm:"type:text"`
+	AccessToken     string       `gorm:"type:varchar(36);unique"`
+	RefreshToken    string       `gorm:"type:varchar(36);unique"`
+	RefreshEnabled  bool         `gorm:"type:boolean;default:false"`
+	Scopes          AccessScopes `gorm:"type:text"`
+	ExpiresIn       int
+	FamilyID        uuid.UUID     `gorm:"type:char(36);not null;index"`
+	ParentID        optional.UUID `gorm:"type:char(36)"`
+	FamilyCreatedAt time.Time     `gorm:"precision:6"`
+	RotatedAt       *time.Time    `gorm:"precision:6"`
+	CreatedAt       time.Time     `gorm:"precision:6"`
+	DeletedAt       *time.Time    `gorm:"precision:6"`
 }
 
 // TableName OAuth2Tokenのテーブル名
@@ -232,3 +269,69 @@ func (t *OAuth2Token) IsExpired() bool {
 func (t *OAuth2Token) IsRefreshEnabled() bool {
 	return t.RefreshEnabled && len(t.RefreshToken) != 0
 }
+
+// IsRefreshTokenExpired リフレッシュトークンの有効期限が切れているかどうか
+//
+// absoluteはファミリーの最初のトークンの発行からの有効時間(秒)、idleはこのトークンの発行からの有効時間(秒)です。
+// 0以下の場合は無期限です。
+func (t *OAuth2Token) IsRefreshTokenExpired(absolute, idle int) bool {
+	exp, ok := t.RefreshTokenExpiresAt(absolute, idle)
+	return ok && exp.Before(time.Now())
+}
+
+// RefreshTokenExpiresAt リフレッシュトークンの有効期限を返します
+//
+// absolute・idleはIsRefreshTokenExpiredと同じです。無期限の場合はfalseを返します。
+func (t *OAuth2Token) RefreshTokenExpiresAt(absolute, idle int) (exp time.Time, ok bool) {
+	if absolute > 0 {
+		exp, ok = t.FamilyCreatedAt.Add(time.Duration(absolute)*time.Second), true
+	}
+	if idle > 0 {
+		if e := t.CreatedAt.Add(time.Duration(idle) * time.Second); !ok || e.Before(exp) {
+			exp, ok = e, true
+		}
+	}
+	return exp, ok
+}
+
+// OAuth2DeviceAuthorizationStatus デバイス認可の状態
+type OAuth2DeviceAuthorizationStatus string
+
+const (
+	// DeviceAuthorizationPending ユーザーの承認待ち
+	DeviceAuthorizationPending OAuth2DeviceAuthorizationStatus = "pending"
+	// DeviceAuthorizationApproved ユーザーが承認した
+	DeviceAuthorizationApproved OAuth2DeviceAuthorizationStatus = "approved"
+	// DeviceAuthorizationDenied ユーザーが拒否した
+	DeviceAuthorizationDenied OAuth2DeviceAuthorizationStatus = "denied"
+)
+
+// OAuth2DeviceAuthorization OAuth2 デバイス認可データの構造体 (RFC 8628)
+type OAuth2DeviceAuthorization struct {
+	DeviceCode     string                          `gorm:"type:varchar(36);primary_key"`
+	UserCode       string                          `gorm:"type:varchar(8);unique"`
+	ClientID       string                          `gorm:"type:char(36)"`
+	UserID         uuid.UUID                       `gorm:"type:char(36)"`
+	Status         OAuth2DeviceAuthorizationStatus `gorm:"type:varchar(16)"`
+	Scopes         AccessScopes                    `gorm:"type:text"`
+	OriginalScopes AccessScopes                    `gorm:"type:text"`
+	ExpiresIn      int
+	Interval       int
+	LastPolledAt   *time.Time `gorm:"precision:6"`
+	CreatedAt      time.Time  `gorm:"precision:6"`
+}
+
+// TableName OAuth2DeviceAuthorizationのテーブル名
+func (*OAuth2DeviceAuthorization) TableName() string {
+	return "oauth2_device_authorizations"
+}
+
+// IsExpired 有効期限が切れているかどうか
+func (data *OAuth2DeviceAuthorization) IsExpired() bool {
+	return data.CreatedAt.Add(time.Duration(data.ExpiresIn) * time.Second).Before(time.Now())
+}
+
+// IsPollingTooFast 前回のポーリングからInterval秒経過していないかどうか
+func (data *OAuth2DeviceAuthorization) IsPollingTooFast(now time.Time) bool {
+	return data.LastPolledAt != nil && data.LastPolledAt.Add(time.Duration(data.Interval)*time.Second).After(now)
+}
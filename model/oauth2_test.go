@@ -21,6 +21,11 @@ func TestOAuth2Token_TableName(t *testing.T) {
 	assert.Equal(t, "oauth2_tokens", (&OAuth2Token{}).TableName())
 }
 
+func TestOAuth2DeviceAuthorization_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "oauth2_device_authorizations", (&OAuth2DeviceAuthorization{}).TableName())
+}
+
 func TestAccessScopes_Value(t *testing.T) {
 	t.Parallel()
 
@@ -93,6 +98,20 @@ func TestAccessScopes_String(t *testing.T) {
 	assert.EqualValues(t, "", AccessScopes{}.String())
 }
 
+func TestAccessScopes_Validate(t *testing.T) {
+	t.Parallel()
+
+	s := AccessScopes{}
+	s.Add("read", "write", "manage_bot", ScopeOpenID, ScopeProfile)
+	assert.NoError(t, s.Validate())
+
+	s.Add("channels:read", "messages:read", "messages:write", "dms", "stamps:write", "users:read", "files:write")
+	assert.NoError(t, s.Validate())
+
+	s.Add("private_read")
+	assert.Error(t, s.Validate())
+}
+
 func TestOAuth2Authorize_IsExpired(t *testing.T) {
 	t.Parallel()
 
@@ -253,3 +272,88 @@ func TestOAuth2Token_IsRefreshEnabled(t *testing.T) {
 	assert.False(t, (&OAuth2Token{RefreshToken: "test"}).IsRefreshEnabled())
 	assert.True(t, (&OAuth2Token{RefreshToken: "test", RefreshEnabled: true}).IsRefreshEnabled())
 }
+
+func TestOAuth2Token_IsRefreshTokenExpired(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	token := &OAuth2Token{
+		FamilyCreatedAt: now.Add(-10 * time.Hour),
+		CreatedAt:       now.Add(-1 * time.Hour),
+	}
+
+	t.Run("No limit", func(t *testing.T) {
+		t.Parallel()
+		assert.False(t, token.IsRefreshTokenExpired(0, 0))
+	})
+
+	t.Run("Absolute", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, token.IsRefreshTokenExpired(60*60*5, 0))
+		assert.False(t, token.IsRefreshTokenExpired(60*60*20, 0))
+	})
+
+	t.Run("Idle", func(t *testing.T) {
+		t.Parallel()
+		assert.True(t, token.IsRefreshTokenExpired(0, 60*30))
+		assert.False(t, token.IsRefreshTokenExpired(0, 60*60*2))
+	})
+}
+
+func TestOAuth2Token_RefreshTokenExpiresAt(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	token := &OAuth2Token{
+		FamilyCreatedAt: now.Add(-10 * time.Hour),
+		CreatedAt:       now.Add(-1 * time.Hour),
+	}
+
+	_, ok := token.RefreshTokenExpiresAt(0, 0)
+	assert.False(t, ok)
+
+	exp, ok := token.RefreshTokenExpiresAt(60*60*20, 0)
+	assert.True(t, ok)
+	assert.True(t, now.Add(10*time.Hour).Equal(exp))
+
+	exp, ok = token.RefreshTokenExpiresAt(60*60*20, 60*60*2)
+	assert.True(t, ok)
+	assert.True(t, now.Add(1*time.Hour).Equal(exp))
+
+	exp, ok = token.RefreshTokenExpiresAt(60*60*5, 60*60*10)
+	assert.True(t, ok)
+	assert.True(t, now.Add(-5*time.Hour).Equal(exp))
+}
+
+func TestOAuth2DeviceAuthorization_IsExpired(t *testing.T) {
+	t.Parallel()
+
+	t.Run("True", func(t *testing.T) {
+		t.Parallel()
+		data := &OAuth2DeviceAuthorization{
+			CreatedAt: time.Date(2000, 1, 1, 12, 0, 11, 0, time.UTC),
+			ExpiresIn: 10,
+		}
+		assert.True(t, data.IsExpired())
+	})
+
+	t.Run("False", func(t *testing.T) {
+		t.Parallel()
+		data := &OAuth2DeviceAuthorization{
+			CreatedAt: time.Date(2099, 1, 1, 12, 0, 11, 0, time.UTC),
+			ExpiresIn: 10,
+		}
+		assert.False(t, data.IsExpired())
+	})
+}
+
+func TestOAuth2DeviceAuthorization_IsPollingTooFast(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	polled := now.Add(-3 * time.Second)
+
+	assert.False(t, (&OAuth2DeviceAuthorization{Interval: 5}).IsPollingTooFast(now))
+	assert.True(t, (&OAuth2DeviceAuthorization{Interval: 5, LastPolledAt: &polled}).IsPollingTooFast(now))
+	assert.False(t, (&OAuth2DeviceAuthorization{Interval: 2, LastPolledAt: &polled}).IsPollingTooFast(now))
+}
@@ -16,6 +16,15 @@ type OgpMedia struct {
 	Height    optional.Int    `json:"height"`
 }
 
+// OgpEmbed oEmbedによる埋め込みに関する情報の構造体
+type OgpEmbed struct {
+	// Type 埋め込みの種類 (photo, video, link, rich)
+	Type         string       `json:"type"`
+	ProviderName string       `json:"providerName"`
+	Width        optional.Int `json:"width"`
+	Height       optional.Int `json:"height"`
+}
+
 // Ogp OGP情報の構造体
 type Ogp struct {
 	Type        string     `json:"type"`
@@ -24,6 +33,15 @@ type Ogp struct {
 	Images      []OgpMedia `json:"images"`
 	Description string     `json:"description"`
 	Videos      []OgpMedia `json:"videos"`
+	Embed       *OgpEmbed  `json:"embed"`
+}
+
+// OgpCacheHours OGPキャッシュの有効時間
+const OgpCacheHours = 7 * 24
+
+// OgpCacheExpireDate 現在時刻から計算したOGPキャッシュの有効期限を返します
+func OgpCacheExpireDate() time.Time {
+	return time.Now().Add(time.Duration(OgpCacheHours) * time.Hour)
 }
 
 // OgpCache Ogpのキャッシュ情報
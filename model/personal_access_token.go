@@ -0,0 +1,49 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gofrs/uuid"
+	"strings"
+	"time"
+)
+
+// PersonalAccessTokenPrefix パーソナルアクセストークンの接頭辞
+//
+// OAuth2のアクセストークンと区別するために付与します。
+const PersonalAccessTokenPrefix = "traq_pat_"
+
+// PersonalAccessToken パーソナルアクセストークンの構造体
+type PersonalAccessToken struct {
+	ID     uuid.UUID `gorm:"type:char(36);not null;primary_key"`
+	UserID uuid.UUID `gorm:"type:char(36);not null;index"`
+	Name   string    `gorm:"type:varchar(32);not null"`
+	// TokenHash トークンのSHA-256ハッシュ (トークン自体は保存しない)
+	TokenHash  string       `gorm:"type:char(64);not null;unique"`
+	Scopes     AccessScopes `gorm:"type:text"`
+	ExpiresAt  time.Time    `gorm:"precision:6"`
+	LastUsedAt *time.Time   `gorm:"precision:6"`
+	LastUsedIP string       `gorm:"type:varchar(45);not null;default:''"`
+	CreatedAt  time.Time    `gorm:"precision:6"`
+}
+
+// TableName PersonalAccessTokenのテーブル名
+func (*PersonalAccessToken) TableName() string {
+	return "personal_access_tokens"
+}
+
+// IsExpired 有効期限が切れているかどうか
+func (t *PersonalAccessToken) IsExpired() bool {
+	return t.ExpiresAt.Before(time.Now())
+}
+
+// IsPersonalAccessToken 文字列がパーソナルアクセストークンの形式かどうか
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+// HashPersonalAccessToken パーソナルアクセストークンのハッシュを計算します
+func HashPersonalAccessToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
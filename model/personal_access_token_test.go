@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPersonalAccessToken_TableName(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "personal_access_tokens", (&PersonalAccessToken{}).TableName())
+}
+
+func TestPersonalAccessToken_IsExpired(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, (&PersonalAccessToken{ExpiresAt: time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)}).IsExpired())
+	assert.False(t, (&PersonalAccessToken{ExpiresAt: time.Date(2099, 1, 1, 12, 0, 0, 0, time.UTC)}).IsExpired())
+}
+
+func TestIsPersonalAccessToken(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsPersonalAccessToken(PersonalAccessTokenPrefix+"abcdef"))
+	assert.False(t, IsPersonalAccessToken("abcdef"))
+}
+
+func TestHashPersonalAccessToken(t *testing.T) {
+	t.Parallel()
+
+	h := HashPersonalAccessToken("traq_pat_test")
+	assert.Len(t, h, 64)
+	assert.Equal(t, h, HashPersonalAccessToken("traq_pat_test"))
+	assert.NotEqual(t, h, HashPersonalAccessToken("traq_pat_test2"))
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// SAMLAssertion 使用済みのSAMLアサーション
+type SAMLAssertion struct {
+	ID        string    `gorm:"type:varchar(190);primary_key"`
+	ExpiresAt time.Time `gorm:"precision:6;index"`
+}
+
+// TableName SAMLAssertionのテーブル名
+func (*SAMLAssertion) TableName() string {
+	return "saml_assertions"
+}
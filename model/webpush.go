@@ -0,0 +1,25 @@
+package model
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// WebPushSubscription Web Push購読の構造体
+type WebPushSubscription struct {
+	// EndpointHash エンドポイントURLのSHA1ハッシュ
+	EndpointHash string    `gorm:"type:char(40);not null;primary_key"`
+	Endpoint     string    `gorm:"type:text;not null"`
+	UserID       uuid.UUID `gorm:"type:char(36);not null;index"`
+	// P256dh ユーザーエージェントのP-256公開鍵 (base64url)
+	P256dh string `gorm:"type:varchar(100);not null"`
+	// Auth 認証シークレット (base64url)
+	Auth      string    `gorm:"type:varchar(30);not null"`
+	CreatedAt time.Time `gorm:"precision:6"`
+	UpdatedAt time.Time `gorm:"precision:6"`
+}
+
+// TableName WebPushSubscription構造体のテーブル名
+func (*WebPushSubscription) TableName() string {
+	return "webpush_subscriptions"
+}
@@ -0,0 +1,64 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"time"
+)
+
+// CallSessionsQuery GetCallSessions用クエリ
+type CallSessionsQuery struct {
+	Channel   uuid.UUID
+	Since     optional.Time
+	Until     optional.Time
+	Inclusive bool
+	Limit     int
+	Offset    int
+	Asc       bool
+}
+
+// CallRepository 通話履歴リポジトリ
+type CallRepository interface {
+	// CreateCallSession 通話セッションを作成します
+	//
+	// 成功した場合、通話セッションとnilを返します。
+	// 既に同じIDの通話セッションが存在する場合、ErrAlreadyExistsを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	CreateCallSession(id, channelID uuid.UUID, startedAt time.Time) (*model.CallSession, error)
+	// EndCallSession 通話セッションを終了します
+	//
+	// 成功した場合、nilを返します。
+	// 退出していない参加者は、通話の終了日時に退出したものとして記録されます。
+	// 存在しない通話セッションを指定した場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	EndCallSession(id uuid.UUID, endedAt time.Time) error
+	// AddCallParticipant 通話セッションへのユーザーの参加を記録します
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	AddCallParticipant(sessionID, userID uuid.UUID, joinedAt time.Time) error
+	// RemoveCallParticipant 通話セッションからのユーザーの退出を記録します
+	//
+	// 成功した場合、nilを返します。
+	// ユーザーが通話セッションに参加していない場合は何もせず、nilを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	RemoveCallParticipant(sessionID, userID uuid.UUID, leftAt time.Time) error
+	// GetCallSession 指定したIDの通話セッションを参加記録と共に取得します
+	//
+	// 成功した場合、通話セッションとnilを返します。
+	// 存在しない通話セッションを指定した場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetCallSession(id uuid.UUID) (*model.CallSession, error)
+	// GetCallSessions 指定したクエリで通話セッションを参加記録と共に取得します
+	//
+	// 負のoffset, limitは無視されます。
+	// 指定した範囲内にlimitを超えて通話セッションが存在していた場合、trueを返します。
+	// DBによるエラーを返すことがあります。
+	GetCallSessions(query CallSessionsQuery) (sessions []*model.CallSession, more bool, err error)
+}
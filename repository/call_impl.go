@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"time"
+)
+
+func callSessionPreloads(db *gorm.DB) *gorm.DB {
+	return db.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("joined_at")
+	})
+}
+
+// CreateCallSession implements CallRepository interface.
+func (repo *GormRepository) CreateCallSession(id, channelID uuid.UUID, startedAt time.Time) (*model.CallSession, error) {
+	if id == uuid.Nil || channelID == uuid.Nil {
+		return nil, ErrNilID
+	}
+
+	s := &model.CallSession{
+		ID:           id,
+		ChannelID:    channelID,
+		StartedAt:    startedAt,
+		Participants: []*model.CallParticipant{},
+	}
+	if err := repo.db.Create(s).Error; err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// EndCallSession implements CallRepository interface.
+func (repo *GormRepository) EndCallSession(id uuid.UUID, endedAt time.Time) error {
+	if id == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.CallSession{ID: id}).Update("ended_at", endedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Model(&model.CallParticipant{}).
+			Where("session_id = ? AND left_at IS NULL", id).
+			Update("left_at", endedAt).
+			Error
+	})
+}
+
+// AddCallParticipant implements CallRepository interface.
+func (repo *GormRepository) AddCallParticipant(sessionID, userID uuid.UUID, joinedAt time.Time) error {
+	if sessionID == uuid.Nil || userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Create(&model.CallParticipant{
+		ID:        uuid.Must(uuid.NewV4()),
+		SessionID: sessionID,
+		UserID:    userID,
+		JoinedAt:  joinedAt,
+	}).Error
+}
+
+// RemoveCallParticipant implements CallRepository interface.
+func (repo *GormRepository) RemoveCallParticipant(sessionID, userID uuid.UUID, leftAt time.Time) error {
+	if sessionID == uuid.Nil || userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Model(&model.CallParticipant{}).
+		Where("session_id = ? AND user_id = ? AND left_at IS NULL", sessionID, userID).
+		Update("left_at", leftAt).
+		Error
+}
+
+// GetCallSession implements CallRepository interface.
+func (repo *GormRepository) GetCallSession(id uuid.UUID) (*model.CallSession, error) {
+	if id == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var s model.CallSession
+	if err := repo.db.Scopes(callSessionPreloads).First(&s, &model.CallSession{ID: id}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &s, nil
+}
+
+// GetCallSessions implements CallRepository interface.
+func (repo *GormRepository) GetCallSessions(query CallSessionsQuery) (sessions []*model.CallSession, more bool, err error) {
+	sessions = make([]*model.CallSession, 0)
+
+	tx := repo.db.Scopes(callSessionPreloads)
+	if query.Asc {
+		tx = tx.Order("started_at")
+	} else {
+		tx = tx.Order("started_at DESC")
+	}
+
+	if query.Channel != uuid.Nil {
+		tx = tx.Where("channel_id = ?", query.Channel)
+	}
+
+	if query.Inclusive {
+		if query.Since.Valid {
+			tx = tx.Where("started_at >= ?", query.Since.Time)
+		}
+		if query.Until.Valid {
+			tx = tx.Where("started_at <= ?", query.Until.Time)
+		}
+	} else {
+		if query.Since.Valid {
+			tx = tx.Where("started_at > ?", query.Since.Time)
+		}
+		if query.Until.Valid {
+			tx = tx.Where("started_at < ?", query.Until.Time)
+		}
+	}
+
+	if query.Offset > 0 {
+		tx = tx.Offset(query.Offset)
+	}
+
+	if query.Limit > 0 {
+		err = tx.Limit(query.Limit + 1).Find(&sessions).Error
+		if len(sessions) > query.Limit {
+			return sessions[:len(sessions)-1], true, err
+		}
+	} else {
+		err = tx.Find(&sessions).Error
+	}
+	return sessions, false, err
+}
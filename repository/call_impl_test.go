@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/utils/optional"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_CreateCallSession(t *testing.T) {
+	t.Parallel()
+	repo, _, _, _, channel := setupWithUserAndChannel(t, common3)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.CreateCallSession(uuid.Nil, channel.ID, time.Now())
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		id := uuid.Must(uuid.NewV4())
+
+		s, err := repo.CreateCallSession(id, channel.ID, time.Now())
+		if assert.NoError(t, err) {
+			assert.Equal(t, id, s.ID)
+			assert.Equal(t, channel.ID, s.ChannelID)
+			assert.Nil(t, s.EndedAt)
+		}
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		t.Parallel()
+		id := uuid.Must(uuid.NewV4())
+		_, err := repo.CreateCallSession(id, channel.ID, time.Now())
+		assert.NoError(t, err)
+
+		_, err = repo.CreateCallSession(id, channel.ID, time.Now())
+		assert.EqualError(t, err, ErrAlreadyExists.Error())
+	})
+}
+
+func TestRepositoryImpl_EndCallSession(t *testing.T) {
+	t.Parallel()
+	repo, _, require, user, channel := setupWithUserAndChannel(t, common3)
+	user2 := mustMakeUser(t, repo, rand)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.EndCallSession(uuid.Nil, time.Now()), ErrNilID.Error())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+
+		assert.EqualError(t, repo.EndCallSession(uuid.Must(uuid.NewV4()), time.Now()), ErrNotFound.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		id := uuid.Must(uuid.NewV4())
+		startedAt := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+		leftAt := startedAt.Add(time.Minute)
+		endedAt := startedAt.Add(time.Hour)
+
+		_, err := repo.CreateCallSession(id, channel.ID, startedAt)
+		require.NoError(err)
+		require.NoError(repo.AddCallParticipant(id, user.GetID(), startedAt))
+		require.NoError(repo.AddCallParticipant(id, user2.GetID(), startedAt))
+		require.NoError(repo.RemoveCallParticipant(id, user.GetID(), leftAt))
+		// 再参加
+		require.NoError(repo.AddCallParticipant(id, user.GetID(), leftAt))
+
+		if assert.NoError(t, repo.EndCallSession(id, endedAt)) {
+			s, err := repo.GetCallSession(id)
+			require.NoError(err)
+			if assert.NotNil(t, s.EndedAt) {
+				assert.True(t, endedAt.Equal(*s.EndedAt))
+			}
+			if assert.Len(t, s.Participants, 3) {
+				for _, p := range s.Participants {
+					if !assert.NotNil(t, p.LeftAt) {
+						continue
+					}
+					if p.UserID == user.GetID() && p.JoinedAt.Equal(startedAt) {
+						// 通話中に退出した参加者
+						assert.True(t, leftAt.Equal(*p.LeftAt))
+					} else {
+						assert.True(t, endedAt.Equal(*p.LeftAt))
+					}
+				}
+			}
+		}
+	})
+}
+
+func TestRepositoryImpl_GetCallSessions(t *testing.T) {
+	t.Parallel()
+	repo, _, require, user, channel := setupWithUserAndChannel(t, common3)
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	ids := make([]uuid.UUID, 3)
+	for i := range ids {
+		ids[i] = uuid.Must(uuid.NewV4())
+		_, err := repo.CreateCallSession(ids[i], channel.ID, base.Add(time.Duration(i)*time.Minute))
+		require.NoError(err)
+		require.NoError(repo.AddCallParticipant(ids[i], user.GetID(), base.Add(time.Duration(i)*time.Minute)))
+	}
+
+	t.Run("desc", func(t *testing.T) {
+		t.Parallel()
+
+		sessions, more, err := repo.GetCallSessions(CallSessionsQuery{Channel: channel.ID, Limit: 2})
+		if assert.NoError(t, err) {
+			assert.True(t, more)
+			if assert.Len(t, sessions, 2) {
+				assert.Equal(t, ids[2], sessions[0].ID)
+				assert.Equal(t, ids[1], sessions[1].ID)
+				assert.Len(t, sessions[0].Participants, 1)
+			}
+		}
+	})
+
+	t.Run("asc since", func(t *testing.T) {
+		t.Parallel()
+
+		sessions, more, err := repo.GetCallSessions(CallSessionsQuery{Channel: channel.ID, Since: optional.TimeFrom(base), Asc: true})
+		if assert.NoError(t, err) {
+			assert.False(t, more)
+			if assert.Len(t, sessions, 2) {
+				assert.Equal(t, ids[1], sessions[0].ID)
+				assert.Equal(t, ids[2], sessions[1].ID)
+			}
+		}
+	})
+
+	t.Run("other channel", func(t *testing.T) {
+		t.Parallel()
+
+		sessions, _, err := repo.GetCallSessions(CallSessionsQuery{Channel: uuid.Must(uuid.NewV4())})
+		if assert.NoError(t, err) {
+			assert.Empty(t, sessions)
+		}
+	})
+}
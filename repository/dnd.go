@@ -0,0 +1,60 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// UpdateUserDNDSettingArgs おやすみモード設定更新引数
+type UpdateUserDNDSettingArgs struct {
+	// SnoozeUntil Validがtrueの場合、手動おやすみモードの終了日時をUntilに更新します (Untilが無効な場合は解除)
+	SnoozeUntil struct {
+		Valid bool
+		Until optional.Time
+	}
+	Timezone optional.String
+	Schedule struct {
+		Valid    bool
+		Schedule model.DNDSchedule
+	}
+}
+
+// UserDNDSettingRepository おやすみモード設定リポジトリ
+type UserDNDSettingRepository interface {
+	// GetUserDNDSetting 指定したユーザーのおやすみモード設定を取得します
+	//
+	// 成功した場合、設定とnilを返します。
+	// 設定が存在しない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetUserDNDSetting(userID uuid.UUID) (*model.UserDNDSetting, error)
+	// GetUserDNDSettings 指定したユーザーのおやすみモード設定を取得します
+	//
+	// 成功した場合、設定の配列とnilを返します。設定が存在しないユーザーの設定は含まれません。
+	// DBによるエラーを返すことがあります。
+	GetUserDNDSettings(userIDs set.UUID) ([]*model.UserDNDSetting, error)
+	// UpdateUserDNDSetting 指定したユーザーのおやすみモード設定を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 設定が存在しない場合は作成します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 不正なTimezone・Scheduleを指定した場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateUserDNDSetting(userID uuid.UUID, args UpdateUserDNDSettingArgs) error
+	// GetUrgentDNDOverrideCount 送信者から受信者へのsince以降の緊急DMの通知数を取得します
+	//
+	// 成功した場合、通知数とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetUrgentDNDOverrideCount(senderID, recipientID uuid.UUID, since time.Time) (int, error)
+	// RecordUrgentDNDOverride 送信者から受信者への緊急DMの通知を記録します
+	//
+	// since以降の通知数がlimit未満の場合のみ記録し、trueとnilを返します。
+	// 上限に達している場合は記録せず、falseとnilを返します。
+	// since以前の記録は削除されます。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	RecordUrgentDNDOverride(senderID, recipientID uuid.UUID, since time.Time, limit int) (bool, error)
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// GetUserDNDSetting implements UserDNDSettingRepository interface.
+func (repo *GormRepository) GetUserDNDSetting(userID uuid.UUID) (*model.UserDNDSetting, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var s model.UserDNDSetting
+	if err := repo.db.First(&s, &model.UserDNDSetting{UserID: userID}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &s, nil
+}
+
+// GetUserDNDSettings implements UserDNDSettingRepository interface.
+func (repo *GormRepository) GetUserDNDSettings(userIDs set.UUID) (settings []*model.UserDNDSetting, err error) {
+	settings = make([]*model.UserDNDSetting, 0)
+	if len(userIDs) == 0 {
+		return settings, nil
+	}
+	return settings, repo.db.Where("user_id IN (?)", userIDs.StringArray()).Find(&settings).Error
+}
+
+// UpdateUserDNDSetting implements UserDNDSettingRepository interface.
+func (repo *GormRepository) UpdateUserDNDSetting(userID uuid.UUID, args UpdateUserDNDSettingArgs) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	if args.Timezone.Valid {
+		if _, err := time.LoadLocation(args.Timezone.String); err != nil || len(args.Timezone.String) == 0 {
+			return ArgError("args.Timezone", "invalid timezone")
+		}
+	}
+	if args.Schedule.Valid {
+		if err := args.Schedule.Schedule.Validate(); err != nil {
+			return ArgError("args.Schedule", err.Error())
+		}
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var s model.UserDNDSetting
+		if err := tx.First(&s, &model.UserDNDSetting{UserID: userID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			s = model.UserDNDSetting{
+				UserID:   userID,
+				Timezone: model.DefaultDNDTimezone,
+				Schedule: model.DNDSchedule{},
+			}
+			if args.SnoozeUntil.Valid {
+				s.SnoozeUntil = args.SnoozeUntil.Until
+			}
+			if args.Timezone.Valid {
+				s.Timezone = args.Timezone.String
+			}
+			if args.Schedule.Valid {
+				s.Schedule = args.Schedule.Schedule
+			}
+			return tx.Create(&s).Error
+		}
+
+		changes := map[string]interface{}{}
+		if args.SnoozeUntil.Valid {
+			changes["snooze_until"] = args.SnoozeUntil.Until
+		}
+		if args.Timezone.Valid {
+			changes["timezone"] = args.Timezone.String
+		}
+		if args.Schedule.Valid {
+			changes["schedule"] = args.Schedule.Schedule
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return tx.Model(&s).Updates(changes).Error
+	})
+}
+
+// GetUrgentDNDOverrideCount implements UserDNDSettingRepository interface.
+func (repo *GormRepository) GetUrgentDNDOverrideCount(senderID, recipientID uuid.UUID, since time.Time) (count int, err error) {
+	if senderID == uuid.Nil || recipientID == uuid.Nil {
+		return 0, nil
+	}
+	return count, repo.db.
+		Model(&model.UrgentDNDOverride{}).
+		Where("sender_id = ? AND recipient_id = ? AND created_at > ?", senderID, recipientID, since).
+		Count(&count).
+		Error
+}
+
+// RecordUrgentDNDOverride implements UserDNDSettingRepository interface.
+func (repo *GormRepository) RecordUrgentDNDOverride(senderID, recipientID uuid.UUID, since time.Time, limit int) (recorded bool, err error) {
+	if senderID == uuid.Nil || recipientID == uuid.Nil {
+		return false, ErrNilID
+	}
+	err = repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("sender_id = ? AND recipient_id = ? AND created_at <= ?", senderID, recipientID, since).
+			Delete(&model.UrgentDNDOverride{}).
+			Error; err != nil {
+			return err
+		}
+
+		// 複数のインスタンスから同時に記録される場合に上限を超えないように、既存の記録をロックする
+		var records []*model.UrgentDNDOverride
+		if err := tx.
+			Set("gorm:query_option", "FOR UPDATE").
+			Where("sender_id = ? AND recipient_id = ?", senderID, recipientID).
+			Find(&records).
+			Error; err != nil {
+			return err
+		}
+		if len(records) >= limit {
+			return nil
+		}
+
+		recorded = true
+		return tx.Create(&model.UrgentDNDOverride{
+			ID:          uuid.Must(uuid.NewV4()),
+			SenderID:    senderID,
+			RecipientID: recipientID,
+		}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return recorded, nil
+}
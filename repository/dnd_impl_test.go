@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_UpdateUserDNDSetting(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		assert.EqualError(repo.UpdateUserDNDSetting(uuid.Nil, UpdateUserDNDSettingArgs{}), ErrNilID.Error())
+	})
+
+	t.Run("invalid timezone", func(t *testing.T) {
+		t.Parallel()
+		assert.True(IsArgError(repo.UpdateUserDNDSetting(user.GetID(), UpdateUserDNDSettingArgs{Timezone: optional.StringFrom("Mars/Olympus")})))
+	})
+
+	t.Run("invalid schedule", func(t *testing.T) {
+		t.Parallel()
+		var args UpdateUserDNDSettingArgs
+		args.Schedule.Valid = true
+		args.Schedule.Schedule = model.DNDSchedule{{Weekday: time.Monday, Start: 60, End: 60}}
+		assert.True(IsArgError(repo.UpdateUserDNDSetting(user.GetID(), args)))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		user := mustMakeUser(t, repo, rand)
+
+		_, err := repo.GetUserDNDSetting(user.GetID())
+		assert.EqualError(err, ErrNotFound.Error())
+
+		// 作成
+		until := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+		var args UpdateUserDNDSettingArgs
+		args.SnoozeUntil.Valid = true
+		args.SnoozeUntil.Until = optional.TimeFrom(until)
+		require.NoError(repo.UpdateUserDNDSetting(user.GetID(), args))
+		s, err := repo.GetUserDNDSetting(user.GetID())
+		require.NoError(err)
+		assert.True(s.SnoozeUntil.Valid)
+		assert.True(until.Equal(s.SnoozeUntil.Time))
+		assert.Equal(model.DefaultDNDTimezone, s.Timezone)
+		assert.Len(s.Schedule, 0)
+
+		// 更新
+		args = UpdateUserDNDSettingArgs{Timezone: optional.StringFrom("UTC")}
+		args.SnoozeUntil.Valid = true
+		args.Schedule.Valid = true
+		args.Schedule.Schedule = model.DNDSchedule{{Weekday: time.Sunday, Start: 0, End: 1440}}
+		require.NoError(repo.UpdateUserDNDSetting(user.GetID(), args))
+		s, err = repo.GetUserDNDSetting(user.GetID())
+		require.NoError(err)
+		assert.False(s.SnoozeUntil.Valid)
+		assert.Equal("UTC", s.Timezone)
+		assert.Equal(args.Schedule.Schedule, s.Schedule)
+	})
+}
+
+func TestRepositoryImpl_GetUserDNDSettings(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	id2 := mustMakeUser(t, repo, rand).GetID()
+	id3 := mustMakeUser(t, repo, rand).GetID()
+	require.NoError(repo.UpdateUserDNDSetting(id1, UpdateUserDNDSettingArgs{Timezone: optional.StringFrom("UTC")}))
+	require.NoError(repo.UpdateUserDNDSetting(id2, UpdateUserDNDSettingArgs{Timezone: optional.StringFrom("UTC")}))
+
+	settings, err := repo.GetUserDNDSettings(set.UUIDSetFromArray([]uuid.UUID{id1, id3}))
+	if assert.NoError(err) {
+		assert.Len(settings, 1)
+	}
+
+	settings, err = repo.GetUserDNDSettings(set.UUID{})
+	if assert.NoError(err) {
+		assert.Len(settings, 0)
+	}
+}
+
+func TestRepositoryImpl_RecordUrgentDNDOverride(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		_, err := repo.RecordUrgentDNDOverride(uuid.Nil, user.GetID(), time.Now().Add(-time.Hour), 3)
+		assert.EqualError(err, ErrNilID.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		recipient := mustMakeUser(t, repo, rand)
+		since := time.Now().Add(-time.Hour)
+
+		for i := 0; i < 2; i++ {
+			ok, err := repo.RecordUrgentDNDOverride(user.GetID(), recipient.GetID(), since, 2)
+			require.NoError(err)
+			assert.True(ok)
+		}
+		ok, err := repo.RecordUrgentDNDOverride(user.GetID(), recipient.GetID(), since, 2)
+		require.NoError(err)
+		assert.False(ok)
+
+		count, err := repo.GetUrgentDNDOverrideCount(user.GetID(), recipient.GetID(), since)
+		require.NoError(err)
+		assert.Equal(2, count)
+		count, err = repo.GetUrgentDNDOverrideCount(recipient.GetID(), user.GetID(), since)
+		require.NoError(err)
+		assert.Equal(0, count)
+
+		// 期間外の記録は削除され、数えない
+		ok, err = repo.RecordUrgentDNDOverride(user.GetID(), recipient.GetID(), time.Now().Add(time.Second), 2)
+		require.NoError(err)
+		assert.True(ok)
+		count, err = repo.GetUrgentDNDOverrideCount(user.GetID(), recipient.GetID(), since)
+		require.NoError(err)
+		assert.Equal(1, count)
+	})
+}
@@ -0,0 +1,59 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// UpdateUserEmailSettingArgs メール通知設定更新引数
+type UpdateUserEmailSettingArgs struct {
+	Address   optional.String
+	Immediate optional.Bool
+	Digest    struct {
+		Valid  bool
+		Digest model.EmailDigest
+	}
+}
+
+// UserEmailSettingRepository メール通知設定リポジトリ
+type UserEmailSettingRepository interface {
+	// GetUserEmailSetting 指定したユーザーのメール通知設定を取得します
+	//
+	// 成功した場合、設定とnilを返します。
+	// 設定が存在しない場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetUserEmailSetting(userID uuid.UUID) (*model.UserEmailSetting, error)
+	// GetUserEmailSettings 指定したユーザーのメール通知設定を取得します
+	//
+	// 成功した場合、設定の配列とnilを返します。設定が存在しないユーザーは含まれません。
+	// DBによるエラーを返すことがあります。
+	GetUserEmailSettings(userIDs set.UUID) ([]*model.UserEmailSetting, error)
+	// UpdateUserEmailSetting 指定したユーザーのメール通知設定を更新します
+	//
+	// 成功した場合、nilを返します。
+	// 設定が存在しない場合は作成します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// 不正なDigestを指定した場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateUserEmailSetting(userID uuid.UUID, args UpdateUserEmailSettingArgs) error
+	// GetImmediateEmailRecipients 指定したユーザーのうち、即時メール通知が有効なユーザーの設定を取得します
+	//
+	// 成功した場合、設定の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetImmediateEmailRecipients(userIDs set.UUID) ([]*model.UserEmailSetting, error)
+	// GetDigestEmailRecipients 指定した頻度のダイジェストメールが有効で、sentBefore以降に送信していないユーザーの設定を取得します
+	//
+	// 成功した場合、設定の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetDigestEmailRecipients(digest model.EmailDigest, sentBefore time.Time) ([]*model.UserEmailSetting, error)
+	// SetEmailDigestSentAt 指定したユーザーのダイジェストメール送信日時を記録します
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定した場合、ErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	SetEmailDigestSentAt(userID uuid.UUID, sentAt time.Time) error
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+	"time"
+)
+
+// GetUserEmailSetting implements UserEmailSettingRepository interface.
+func (repo *GormRepository) GetUserEmailSetting(userID uuid.UUID) (*model.UserEmailSetting, error) {
+	if userID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	var s model.UserEmailSetting
+	if err := repo.db.First(&s, &model.UserEmailSetting{UserID: userID}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return &s, nil
+}
+
+// GetUserEmailSettings implements UserEmailSettingRepository interface.
+func (repo *GormRepository) GetUserEmailSettings(userIDs set.UUID) (settings []*model.UserEmailSetting, err error) {
+	settings = make([]*model.UserEmailSetting, 0)
+	if len(userIDs) == 0 {
+		return settings, nil
+	}
+	return settings, repo.db.Where("user_id IN (?)", userIDs.StringArray()).Find(&settings).Error
+}
+
+// UpdateUserEmailSetting implements UserEmailSettingRepository interface.
+func (repo *GormRepository) UpdateUserEmailSetting(userID uuid.UUID, args UpdateUserEmailSettingArgs) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	if args.Digest.Valid && !args.Digest.Digest.Valid() {
+		return ArgError("args.Digest", "invalid digest")
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var s model.UserEmailSetting
+		if err := tx.First(&s, &model.UserEmailSetting{UserID: userID}).Error; err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			s = model.UserEmailSetting{
+				UserID:    userID,
+				Address:   args.Address.String,
+				Immediate: args.Immediate.Bool,
+				Digest:    model.EmailDigestNone,
+			}
+			if args.Digest.Valid {
+				s.Digest = args.Digest.Digest
+			}
+			return tx.Create(&s).Error
+		}
+
+		changes := map[string]interface{}{}
+		if args.Address.Valid {
+			changes["address"] = args.Address.String
+		}
+		if args.Immediate.Valid {
+			changes["immediate"] = args.Immediate.Bool
+		}
+		if args.Digest.Valid {
+			changes["digest"] = args.Digest.Digest
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return tx.Model(&s).Updates(changes).Error
+	})
+}
+
+// GetImmediateEmailRecipients implements UserEmailSettingRepository interface.
+func (repo *GormRepository) GetImmediateEmailRecipients(userIDs set.UUID) (settings []*model.UserEmailSetting, err error) {
+	settings = make([]*model.UserEmailSetting, 0)
+	if len(userIDs) == 0 {
+		return settings, nil
+	}
+	return settings, repo.db.
+		Where("user_id IN (?) AND immediate = TRUE AND address <> ''", userIDs.StringArray()).
+		Find(&settings).
+		Error
+}
+
+// GetDigestEmailRecipients implements UserEmailSettingRepository interface.
+func (repo *GormRepository) GetDigestEmailRecipients(digest model.EmailDigest, sentBefore time.Time) (settings []*model.UserEmailSetting, err error) {
+	settings = make([]*model.UserEmailSetting, 0)
+	if digest == model.EmailDigestNone {
+		return settings, nil
+	}
+	return settings, repo.db.
+		Where("digest = ? AND address <> '' AND (digest_sent_at IS NULL OR digest_sent_at < ?)", digest, sentBefore).
+		Find(&settings).
+		Error
+}
+
+// SetEmailDigestSentAt implements UserEmailSettingRepository interface.
+func (repo *GormRepository) SetEmailDigestSentAt(userID uuid.UUID, sentAt time.Time) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Model(&model.UserEmailSetting{UserID: userID}).Update("digest_sent_at", sentAt).Error
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_UpdateUserEmailSetting(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		assert.EqualError(repo.UpdateUserEmailSetting(uuid.Nil, UpdateUserEmailSettingArgs{}), ErrNilID.Error())
+	})
+
+	t.Run("invalid digest", func(t *testing.T) {
+		t.Parallel()
+		var args UpdateUserEmailSettingArgs
+		args.Digest.Valid = true
+		args.Digest.Digest = "monthly"
+		assert.True(IsArgError(repo.UpdateUserEmailSetting(user.GetID(), args)))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := repo.GetUserEmailSetting(user.GetID())
+		assert.EqualError(err, ErrNotFound.Error())
+
+		// 作成
+		require.NoError(repo.UpdateUserEmailSetting(user.GetID(), UpdateUserEmailSettingArgs{
+			Address:   optional.StringFrom("user@example.com"),
+			Immediate: optional.BoolFrom(true),
+		}))
+		s, err := repo.GetUserEmailSetting(user.GetID())
+		require.NoError(err)
+		assert.Equal("user@example.com", s.Address)
+		assert.True(s.Immediate)
+		assert.Equal(model.EmailDigestNone, s.Digest)
+
+		// 更新
+		var args UpdateUserEmailSettingArgs
+		args.Digest.Valid = true
+		args.Digest.Digest = model.EmailDigestWeekly
+		require.NoError(repo.UpdateUserEmailSetting(user.GetID(), args))
+		s, err = repo.GetUserEmailSetting(user.GetID())
+		require.NoError(err)
+		assert.Equal("user@example.com", s.Address)
+		assert.True(s.Immediate)
+		assert.Equal(model.EmailDigestWeekly, s.Digest)
+	})
+}
+
+func TestRepositoryImpl_GetUserEmailSettings(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	withAddress := mustMakeUser(t, repo, rand).GetID()
+	noSetting := mustMakeUser(t, repo, rand).GetID()
+	require.NoError(repo.UpdateUserEmailSetting(withAddress, UpdateUserEmailSettingArgs{Address: optional.StringFrom("a@example.com")}))
+
+	settings, err := repo.GetUserEmailSettings(set.UUIDSetFromArray([]uuid.UUID{withAddress, noSetting}))
+	if assert.NoError(err) && assert.Len(settings, 1) {
+		assert.Equal(withAddress, settings[0].UserID)
+		assert.Equal("a@example.com", settings[0].Address)
+	}
+
+	settings, err = repo.GetUserEmailSettings(set.UUID{})
+	if assert.NoError(err) {
+		assert.Len(settings, 0)
+	}
+}
+
+func TestRepositoryImpl_GetImmediateEmailRecipients(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	enabled := mustMakeUser(t, repo, rand).GetID()
+	disabled := mustMakeUser(t, repo, rand).GetID()
+	noAddress := mustMakeUser(t, repo, rand).GetID()
+	require.NoError(repo.UpdateUserEmailSetting(enabled, UpdateUserEmailSettingArgs{Address: optional.StringFrom("a@example.com"), Immediate: optional.BoolFrom(true)}))
+	require.NoError(repo.UpdateUserEmailSetting(disabled, UpdateUserEmailSettingArgs{Address: optional.StringFrom("b@example.com"), Immediate: optional.BoolFrom(false)}))
+	require.NoError(repo.UpdateUserEmailSetting(noAddress, UpdateUserEmailSettingArgs{Immediate: optional.BoolFrom(true)}))
+
+	settings, err := repo.GetImmediateEmailRecipients(set.UUIDSetFromArray([]uuid.UUID{enabled, disabled, noAddress}))
+	if assert.NoError(err) && assert.Len(settings, 1) {
+		assert.Equal(enabled, settings[0].UserID)
+	}
+
+	settings, err = repo.GetImmediateEmailRecipients(set.UUID{})
+	if assert.NoError(err) {
+		assert.Len(settings, 0)
+	}
+}
+
+func TestRepositoryImpl_GetDigestEmailRecipients(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, ex1)
+
+	daily := mustMakeUser(t, repo, rand).GetID()
+	sent := mustMakeUser(t, repo, rand).GetID()
+	weekly := mustMakeUser(t, repo, rand).GetID()
+	for _, id := range []uuid.UUID{daily, sent, weekly} {
+		var args UpdateUserEmailSettingArgs
+		args.Address = optional.StringFrom("user@example.com")
+		args.Digest.Valid = true
+		args.Digest.Digest = model.EmailDigestDaily
+		if id == weekly {
+			args.Digest.Digest = model.EmailDigestWeekly
+		}
+		require.NoError(repo.UpdateUserEmailSetting(id, args))
+	}
+	now := time.Now()
+	require.NoError(repo.SetEmailDigestSentAt(sent, now))
+	assert.EqualError(repo.SetEmailDigestSentAt(uuid.Nil, now), ErrNilID.Error())
+
+	settings, err := repo.GetDigestEmailRecipients(model.EmailDigestDaily, now.Add(-time.Hour))
+	if assert.NoError(err) && assert.Len(settings, 1) {
+		assert.Equal(daily, settings[0].UserID)
+	}
+
+	settings, err = repo.GetDigestEmailRecipients(model.EmailDigestNone, now)
+	if assert.NoError(err) {
+		assert.Len(settings, 0)
+	}
+}
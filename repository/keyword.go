@@ -0,0 +1,45 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+)
+
+// CreateKeywordAlertArgs キーワード通知作成引数
+type CreateKeywordAlertArgs struct {
+	UserID    uuid.UUID
+	Keyword   string
+	IsRegex   bool
+	ChannelID optional.UUID
+}
+
+// KeywordAlertRepository キーワード通知リポジトリ
+type KeywordAlertRepository interface {
+	// CreateKeywordAlert キーワード通知を作成します
+	//
+	// 成功した場合、キーワード通知とnilを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	CreateKeywordAlert(args CreateKeywordAlertArgs) (*model.KeywordAlert, error)
+	// GetKeywordAlerts 指定したユーザーのキーワード通知を全て取得します
+	//
+	// 成功した場合、キーワード通知の配列とnilを返します。
+	// 存在しないユーザーを指定した場合は空配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetKeywordAlerts(userID uuid.UUID) ([]*model.KeywordAlert, error)
+	// GetAllKeywordAlerts 全てのユーザーのキーワード通知を取得します
+	//
+	// 成功した場合、キーワード通知の配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetAllKeywordAlerts() ([]*model.KeywordAlert, error)
+	// DeleteKeywordAlert 指定したユーザーのキーワード通知を削除します
+	//
+	// 成功した場合、nilを返します。
+	// キーワード通知が存在しない場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteKeywordAlert(userID, alertID uuid.UUID) error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"unicode/utf8"
+)
+
+// CreateKeywordAlert implements KeywordAlertRepository interface.
+func (repo *GormRepository) CreateKeywordAlert(args CreateKeywordAlertArgs) (*model.KeywordAlert, error) {
+	if args.UserID == uuid.Nil || (args.ChannelID.Valid && args.ChannelID.UUID == uuid.Nil) {
+		return nil, ErrNilID
+	}
+	if l := utf8.RuneCountInString(args.Keyword); l == 0 || l > 100 {
+		return nil, ArgError("args.Keyword", "Keyword must be non-empty and shorter than 101 characters")
+	}
+
+	a := &model.KeywordAlert{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    args.UserID,
+		Keyword:   args.Keyword,
+		IsRegex:   args.IsRegex,
+		ChannelID: args.ChannelID,
+	}
+	if err := repo.db.Create(a).Error; err != nil {
+		return nil, err
+	}
+	repo.hub.Publish(hub.Message{
+		Name: event.KeywordAlertCreated,
+		Fields: hub.Fields{
+			"user_id":       a.UserID,
+			"keyword_alert": a,
+		},
+	})
+	return a, nil
+}
+
+// GetKeywordAlerts implements KeywordAlertRepository interface.
+func (repo *GormRepository) GetKeywordAlerts(userID uuid.UUID) ([]*model.KeywordAlert, error) {
+	alerts := make([]*model.KeywordAlert, 0)
+	if userID == uuid.Nil {
+		return alerts, nil
+	}
+	return alerts, repo.db.Where(&model.KeywordAlert{UserID: userID}).Order("created_at").Find(&alerts).Error
+}
+
+// GetAllKeywordAlerts implements KeywordAlertRepository interface.
+func (repo *GormRepository) GetAllKeywordAlerts() ([]*model.KeywordAlert, error) {
+	alerts := make([]*model.KeywordAlert, 0)
+	return alerts, repo.db.Find(&alerts).Error
+}
+
+// DeleteKeywordAlert implements KeywordAlertRepository interface.
+func (repo *GormRepository) DeleteKeywordAlert(userID, alertID uuid.UUID) error {
+	if userID == uuid.Nil || alertID == uuid.Nil {
+		return ErrNilID
+	}
+	result := repo.db.Delete(&model.KeywordAlert{}, &model.KeywordAlert{ID: alertID, UserID: userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	repo.hub.Publish(hub.Message{
+		Name: event.KeywordAlertDeleted,
+		Fields: hub.Fields{
+			"user_id":          userID,
+			"keyword_alert_id": alertID,
+		},
+	})
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"strings"
+	"testing"
+)
+
+func TestRepositoryImpl_CreateKeywordAlert(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user, channel := setupWithUserAndChannel(t, common)
+
+	_, err := repo.CreateKeywordAlert(CreateKeywordAlertArgs{Keyword: "traQ"})
+	assert.EqualError(err, ErrNilID.Error())
+	_, err = repo.CreateKeywordAlert(CreateKeywordAlertArgs{UserID: user.GetID(), Keyword: ""})
+	assert.True(IsArgError(err))
+	_, err = repo.CreateKeywordAlert(CreateKeywordAlertArgs{UserID: user.GetID(), Keyword: strings.Repeat("a", 101)})
+	assert.True(IsArgError(err))
+
+	a, err := repo.CreateKeywordAlert(CreateKeywordAlertArgs{
+		UserID:    user.GetID(),
+		Keyword:   "障害",
+		ChannelID: optional.UUIDFrom(channel.ID),
+	})
+	require.NoError(err)
+	assert.NotEqual(uuid.Nil, a.ID)
+	assert.Equal("障害", a.Keyword)
+	assert.False(a.IsRegex)
+	assert.Equal(channel.ID, a.ChannelID.UUID)
+	assert.EqualValues(1, count(t, getDB(repo).Model(model.KeywordAlert{}).Where("user_id = ?", user.GetID())))
+}
+
+func TestRepositoryImpl_GetKeywordAlerts(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	other := mustMakeUser(t, repo, rand).GetID()
+	_, err := repo.CreateKeywordAlert(CreateKeywordAlertArgs{UserID: user.GetID(), Keyword: "a"})
+	require.NoError(err)
+	_, err = repo.CreateKeywordAlert(CreateKeywordAlertArgs{UserID: user.GetID(), Keyword: "b.*c", IsRegex: true})
+	require.NoError(err)
+	_, err = repo.CreateKeywordAlert(CreateKeywordAlertArgs{UserID: other, Keyword: "a"})
+	require.NoError(err)
+
+	alerts, err := repo.GetKeywordAlerts(user.GetID())
+	if assert.NoError(err) {
+		assert.Len(alerts, 2)
+	}
+
+	alerts, err = repo.GetKeywordAlerts(uuid.Nil)
+	if assert.NoError(err) {
+		assert.Len(alerts, 0)
+	}
+
+	alerts, err = repo.GetAllKeywordAlerts()
+	if assert.NoError(err) {
+		assert.GreaterOrEqual(len(alerts), 3)
+	}
+}
+
+func TestRepositoryImpl_DeleteKeywordAlert(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	other := mustMakeUser(t, repo, rand).GetID()
+	a, err := repo.CreateKeywordAlert(CreateKeywordAlertArgs{UserID: user.GetID(), Keyword: "a"})
+	require.NoError(err)
+
+	assert.EqualError(repo.DeleteKeywordAlert(uuid.Nil, a.ID), ErrNilID.Error())
+	// 他人のキーワード通知は削除できない
+	assert.EqualError(repo.DeleteKeywordAlert(other, a.ID), ErrNotFound.Error())
+	assert.NoError(repo.DeleteKeywordAlert(user.GetID(), a.ID))
+	assert.EqualError(repo.DeleteKeywordAlert(user.GetID(), a.ID), ErrNotFound.Error())
+}
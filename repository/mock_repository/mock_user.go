@@ -9,6 +9,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/traPtitech/traQ/model"
 	repository "github.com/traPtitech/traQ/repository"
+	set "github.com/traPtitech/traQ/utils/set"
 	reflect "reflect"
 )
 
@@ -125,6 +126,21 @@ func (mr *MockUserRepositoryMockRecorder) GetUserIDs(query interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserIDs", reflect.TypeOf((*MockUserRepository)(nil).GetUserIDs), query)
 }
 
+// CountUsers mocks base method
+func (m *MockUserRepository) CountUsers(query repository.UsersQuery) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", query)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsers indicates an expected call of CountUsers
+func (mr *MockUserRepositoryMockRecorder) CountUsers(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockUserRepository)(nil).CountUsers), query)
+}
+
 // UserExists mocks base method
 func (m *MockUserRepository) UserExists(id uuid.UUID) (bool, error) {
 	m.ctrl.T.Helper()
@@ -183,6 +199,21 @@ func (mr *MockUserRepositoryMockRecorder) GetLinkedExternalUserAccounts(userID i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLinkedExternalUserAccounts", reflect.TypeOf((*MockUserRepository)(nil).GetLinkedExternalUserAccounts), userID)
 }
 
+// GetExternalUserAccounts mocks base method
+func (m *MockUserRepository) GetExternalUserAccounts(providerName string, userIDs set.UUID) ([]*model.ExternalProviderUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetExternalUserAccounts", providerName, userIDs)
+	ret0, _ := ret[0].([]*model.ExternalProviderUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetExternalUserAccounts indicates an expected call of GetExternalUserAccounts
+func (mr *MockUserRepositoryMockRecorder) GetExternalUserAccounts(providerName, userIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetExternalUserAccounts", reflect.TypeOf((*MockUserRepository)(nil).GetExternalUserAccounts), providerName, userIDs)
+}
+
 // UnlinkExternalUserAccount mocks base method
 func (m *MockUserRepository) UnlinkExternalUserAccount(userID uuid.UUID, providerName string) error {
 	m.ctrl.T.Helper()
@@ -0,0 +1,67 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
+
+// CreateNotificationArgs 通知作成引数
+type CreateNotificationArgs struct {
+	UserID    uuid.UUID
+	Type      model.NotificationType
+	MessageID uuid.UUID
+	ChannelID uuid.UUID
+	ActorID   uuid.UUID
+}
+
+// NotificationCursor 通知一覧のカーソル
+//
+// 指定した通知より古い通知を取得します。
+type NotificationCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// NotificationsQuery GetNotifications用クエリ
+type NotificationsQuery struct {
+	User       uuid.UUID
+	Before     *NotificationCursor
+	UnreadOnly bool
+	Limit      int
+}
+
+// NotificationRepository 通知受信箱リポジトリ
+type NotificationRepository interface {
+	// CreateNotification 通知を作成します
+	//
+	// 成功した場合、通知とnilを返します。
+	// 既に同じユーザー・メッセージの通知が存在する場合、ErrAlreadyExistsを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	CreateNotification(args CreateNotificationArgs) (*model.Notification, error)
+	// GetNotifications 指定したユーザーの通知を新しい順に取得します
+	//
+	// 成功した場合、通知の配列とまだ通知が存在するかどうかとnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetNotifications(query NotificationsQuery) (notifications []*model.Notification, more bool, err error)
+	// GetUnreadNotificationCount 指定したユーザーの未読通知数を取得します
+	//
+	// 成功した場合、未読通知数とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetUnreadNotificationCount(userID uuid.UUID) (int, error)
+	// ReadNotification 指定したユーザーの通知を既読にします
+	//
+	// 成功した、或いは既に既読だった場合、nilを返します。
+	// 通知が存在しない場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	ReadNotification(userID, notificationID uuid.UUID) error
+	// ReadAllNotifications 指定したユーザーの全ての通知を既読にします
+	//
+	// 成功した場合、nilを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	ReadAllNotifications(userID uuid.UUID) error
+}
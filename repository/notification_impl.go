@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+)
+
+// CreateNotification implements NotificationRepository interface.
+func (repo *GormRepository) CreateNotification(args CreateNotificationArgs) (*model.Notification, error) {
+	if args.UserID == uuid.Nil || args.MessageID == uuid.Nil || args.ChannelID == uuid.Nil || args.ActorID == uuid.Nil {
+		return nil, ErrNilID
+	}
+
+	n := &model.Notification{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    args.UserID,
+		Type:      args.Type,
+		MessageID: args.MessageID,
+		ChannelID: args.ChannelID,
+		ActorID:   args.ActorID,
+	}
+	if err := repo.db.Create(n).Error; err != nil {
+		if gormutil.IsMySQLDuplicatedRecordErr(err) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, err
+	}
+	repo.hub.Publish(hub.Message{
+		Name: event.NotificationCreated,
+		Fields: hub.Fields{
+			"user_id":      n.UserID,
+			"notification": n,
+		},
+	})
+	return n, nil
+}
+
+// GetNotifications implements NotificationRepository interface.
+func (repo *GormRepository) GetNotifications(query NotificationsQuery) (notifications []*model.Notification, more bool, err error) {
+	notifications = make([]*model.Notification, 0)
+	if query.User == uuid.Nil {
+		return notifications, false, nil
+	}
+
+	tx := repo.db.
+		Where("user_id = ?", query.User).
+		Order("created_at DESC, id DESC")
+	if query.UnreadOnly {
+		tx = tx.Where("is_read = FALSE")
+	}
+	if query.Before != nil {
+		tx = tx.Where("created_at < ? OR (created_at = ? AND id < ?)", query.Before.CreatedAt, query.Before.CreatedAt, query.Before.ID)
+	}
+
+	if query.Limit > 0 {
+		err = tx.Limit(query.Limit + 1).Find(&notifications).Error
+		if len(notifications) > query.Limit {
+			return notifications[:len(notifications)-1], true, err
+		}
+	} else {
+		err = tx.Find(&notifications).Error
+	}
+	return notifications, false, err
+}
+
+// GetUnreadNotificationCount implements NotificationRepository interface.
+func (repo *GormRepository) GetUnreadNotificationCount(userID uuid.UUID) (count int, err error) {
+	if userID == uuid.Nil {
+		return 0, nil
+	}
+	return count, repo.db.Model(&model.Notification{}).Where("user_id = ? AND is_read = FALSE", userID).Count(&count).Error
+}
+
+// ReadNotification implements NotificationRepository interface.
+func (repo *GormRepository) ReadNotification(userID, notificationID uuid.UUID) error {
+	if userID == uuid.Nil || notificationID == uuid.Nil {
+		return ErrNilID
+	}
+	var n model.Notification
+	if err := repo.db.First(&n, &model.Notification{ID: notificationID, UserID: userID}).Error; err != nil {
+		return convertError(err)
+	}
+	if n.IsRead {
+		return nil
+	}
+	return repo.db.Model(&n).Update("is_read", true).Error
+}
+
+// ReadAllNotifications implements NotificationRepository interface.
+func (repo *GormRepository) ReadAllNotifications(userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Model(&model.Notification{}).Where("user_id = ? AND is_read = FALSE", userID).Update("is_read", true).Error
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"testing"
+)
+
+func TestRepositoryImpl_CreateNotification(t *testing.T) {
+	t.Parallel()
+	repo, _, require, user, channel := setupWithUserAndChannel(t, common)
+	actor := mustMakeUser(t, repo, rand)
+	m := mustMakeMessage(t, repo, actor.GetID(), channel.ID)
+
+	t.Run("nil id", func(t *testing.T) {
+		t.Parallel()
+		_, err := repo.CreateNotification(CreateNotificationArgs{Type: model.NotificationTypeMention})
+		assert.EqualError(t, err, ErrNilID.Error())
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		args := CreateNotificationArgs{
+			UserID:    user.GetID(),
+			Type:      model.NotificationTypeMention,
+			MessageID: m.ID,
+			ChannelID: channel.ID,
+			ActorID:   actor.GetID(),
+		}
+		n, err := repo.CreateNotification(args)
+		require.NoError(err)
+		assert.NotEqual(t, uuid.Nil, n.ID)
+		assert.Equal(t, model.NotificationTypeMention, n.Type)
+		assert.False(t, n.IsRead)
+
+		// 同じメッセージの通知は1つのみ
+		args.Type = model.NotificationTypeGroupMention
+		_, err = repo.CreateNotification(args)
+		assert.EqualError(t, err, ErrAlreadyExists.Error())
+	})
+}
+
+func TestRepositoryImpl_GetNotifications(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user, channel := setupWithUserAndChannel(t, common)
+	actor := mustMakeUser(t, repo, rand)
+
+	ids := make([]uuid.UUID, 5)
+	for i := range ids {
+		m := mustMakeMessage(t, repo, actor.GetID(), channel.ID)
+		n, err := repo.CreateNotification(CreateNotificationArgs{
+			UserID:    user.GetID(),
+			Type:      model.NotificationTypeMention,
+			MessageID: m.ID,
+			ChannelID: channel.ID,
+			ActorID:   actor.GetID(),
+		})
+		require.NoError(err)
+		ids[i] = n.ID
+	}
+
+	// 新しい順にカーソルで辿れる
+	var (
+		got    []uuid.UUID
+		before *NotificationCursor
+	)
+	for {
+		ns, more, err := repo.GetNotifications(NotificationsQuery{User: user.GetID(), Before: before, Limit: 2})
+		require.NoError(err)
+		for _, n := range ns {
+			got = append(got, n.ID)
+		}
+		if !more {
+			break
+		}
+		last := ns[len(ns)-1]
+		before = &NotificationCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	assert.Equal([]uuid.UUID{ids[4], ids[3], ids[2], ids[1], ids[0]}, got)
+
+	count, err := repo.GetUnreadNotificationCount(user.GetID())
+	require.NoError(err)
+	assert.Equal(5, count)
+
+	// 既読化
+	assert.EqualError(repo.ReadNotification(uuid.Nil, ids[0]), ErrNilID.Error())
+	assert.EqualError(repo.ReadNotification(actor.GetID(), ids[0]), ErrNotFound.Error())
+	require.NoError(repo.ReadNotification(user.GetID(), ids[0]))
+	require.NoError(repo.ReadNotification(user.GetID(), ids[0]))
+
+	ns, more, err := repo.GetNotifications(NotificationsQuery{User: user.GetID(), UnreadOnly: true})
+	require.NoError(err)
+	assert.False(more)
+	assert.Len(ns, 4)
+
+	require.NoError(repo.ReadAllNotifications(user.GetID()))
+	count, err = repo.GetUnreadNotificationCount(user.GetID())
+	require.NoError(err)
+	assert.Equal(0, count)
+	assert.EqualError(repo.ReadAllNotifications(uuid.Nil), ErrNilID.Error())
+}
@@ -4,16 +4,18 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
+	"time"
 )
 
 type UpdateClientArgs struct {
-	Name         optional.String
-	Description  optional.String
-	Confidential optional.Bool
-	DeveloperID  optional.UUID
-	Secret       optional.String
-	CallbackURL  optional.String
-	Scopes       model.AccessScopes
+	Name           optional.String
+	Description    optional.String
+	Confidential   optional.Bool
+	ResourceServer optional.Bool
+	DeveloperID    optional.UUID
+	Secret         optional.String
+	CallbackURL    optional.String
+	Scopes         model.AccessScopes
 }
 
 type GetClientsQuery struct {
@@ -54,6 +56,7 @@ type OAuth2Repository interface {
 	// DeleteClient 指定したクライアントを削除します
 	//
 	// 成功した、或いは既に存在しない場合、nilを返します。
+	// トークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
 	// DBによるエラーを返すことがあります。
 	DeleteClient(id string) error
 	// SaveAuthorize 認可データを保存します
@@ -72,6 +75,46 @@ type OAuth2Repository interface {
 	// 成功した、或いは既に存在しない場合、nilを返します。
 	// DBによるエラーを返すことがあります。
 	DeleteAuthorize(code string) error
+	// SaveDeviceAuthorization デバイス認可データを保存します
+	//
+	// 成功した場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	SaveDeviceAuthorization(data *model.OAuth2DeviceAuthorization) error
+	// GetDeviceAuthorizationByDeviceCode 指定したデバイスコードのデバイス認可データを取得します
+	//
+	// 成功した場合、デバイス認可データとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetDeviceAuthorizationByDeviceCode(deviceCode string) (*model.OAuth2DeviceAuthorization, error)
+	// GetDeviceAuthorizationByUserCode 指定したユーザーコードのデバイス認可データを取得します
+	//
+	// 成功した場合、デバイス認可データとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetDeviceAuthorizationByUserCode(userCode string) (*model.OAuth2DeviceAuthorization, error)
+	// DecideDeviceAuthorization 承認待ちのデバイス認可データを承認、または拒否します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しない、或いは既に承認・拒否されている場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	DecideDeviceAuthorization(deviceCode string, userID uuid.UUID, approve bool) error
+	// UpdateDeviceAuthorizationPolling デバイス認可データのポーリング間隔と最終ポーリング日時を更新します
+	//
+	// 成功した、或いは存在しない場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	UpdateDeviceAuthorizationPolling(deviceCode string, interval int, polledAt time.Time) error
+	// DeleteDeviceAuthorization 指定したデバイスコードのデバイス認可データを削除します
+	//
+	// 成功した、或いは既に存在しない場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteDeviceAuthorization(deviceCode string) error
+	// ConsumeApprovedDeviceAuthorization 承認済みのデバイス認可データを削除して消費します
+	//
+	// 同じデバイスコードが同時に使用された場合は、どれか1つのみが成功します。
+	// 成功した場合、nilを返します。
+	// 存在しない、承認済みでない、或いは既に消費されている場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	ConsumeApprovedDeviceAuthorization(deviceCode string) error
 	// IssueToken トークンを発行します
 	//
 	// 成功した場合、トークンとnilを返します。
@@ -86,6 +129,7 @@ type OAuth2Repository interface {
 	// DeleteTokenByID 指定したIDのトークンを削除します
 	//
 	// 成功した、或いは既に存在しない場合、nilを返します。
+	// トークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
 	// DBによるエラーを返すことがあります。
 	DeleteTokenByID(id uuid.UUID) error
 	// GetTokenByAccess 指定したアクセストークンのトークンを取得します
@@ -97,6 +141,7 @@ type OAuth2Repository interface {
 	// DeleteTokenByAccess 指定したアクセストークンのトークンを削除します
 	//
 	// 成功した、或いは既に存在しない場合、nilを返します。
+	// トークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
 	// DBによるエラーを返すことがあります。
 	DeleteTokenByAccess(access string) error
 	// GetTokenByRefresh 指定したリフレッシュトークンのトークンを取得します
@@ -108,22 +153,51 @@ type OAuth2Repository interface {
 	// DeleteTokenByRefresh 指定したリフレッシュトークンのトークンを削除します
 	//
 	// 成功した、或いは既に存在しない場合、nilを返します。
+	// トークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
 	// DBによるエラーを返すことがあります。
 	DeleteTokenByRefresh(refresh string) error
+	// RotateToken 指定したトークンのリフレッシュトークンを使用済みにして削除し、同じファミリーの新しいトークンを発行します
+	//
+	// 成功した場合、新しいトークンとnilを返します。
+	// 指定したトークンが既に使用済み、或いは削除されていた場合、ErrNotFoundを返します。
+	// 元のトークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
+	// DBによるエラーを返すことがあります。
+	RotateToken(token *model.OAuth2Token, scope model.AccessScopes, expire int, refresh bool) (*model.OAuth2Token, error)
+	// GetRotatedTokenByRefresh 指定したリフレッシュトークンの使用済みのトークンを取得します
+	//
+	// リフレッシュトークンの再利用の検知に使用します。
+	// 成功した場合、トークンとnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetRotatedTokenByRefresh(refresh string) (*model.OAuth2Token, error)
+	// DeleteTokenFamily 指定したファミリーのトークンを全て削除します
+	//
+	// 成功した、或いは既に存在しない場合、nilを返します。
+	// トークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
+	// DBによるエラーを返すことがあります。
+	DeleteTokenFamily(familyID uuid.UUID) error
 	// GetTokensByUser 指定したユーザーのトークンを全て取得します
 	//
 	// 成功した場合、トークンの配列とnilを返します。
 	// 存在しないユーザーを指定した場合、空配列とnilを返します。
 	// DBによるエラーを返すことがあります。
 	GetTokensByUser(userID uuid.UUID) ([]*model.OAuth2Token, error)
+	// GetTokensByClient 指定したクライアントのトークンを全て取得します
+	//
+	// 成功した場合、トークンの配列とnilを返します。
+	// 存在しないクライアントを指定した場合、空配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetTokensByClient(clientID string) ([]*model.OAuth2Token, error)
 	// DeleteTokenByUser 指定したユーザーのトークンを全て削除します
 	//
 	// 成功した場合、nilを返します。
+	// トークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
 	// DBによるエラーを返すことがあります。
 	DeleteTokenByUser(userID uuid.UUID) error
 	// DeleteTokenByClient 指定したクライアントのトークンを全て削除します
 	//
 	// 成功した場合、nilを返します。
+	// トークンを削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
 	// DBによるエラーを返すことがあります。
 	DeleteTokenByClient(clientID string) error
 }
@@ -3,7 +3,10 @@ package repository
 import (
 	"github.com/gofrs/uuid"
 	"github.com/jinzhu/gorm"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/random"
 	"time"
 )
@@ -59,6 +62,9 @@ func (repo *GormRepository) UpdateClient(clientID string, args UpdateClientArgs)
 		if args.Confidential.Valid {
 			changes["confidential"] = args.Confidential.Bool
 		}
+		if args.ResourceServer.Valid {
+			changes["resource_server"] = args.ResourceServer.Bool
+		}
 		if args.Scopes != nil {
 			changes["scopes"] = args.Scopes
 		}
@@ -95,17 +101,23 @@ func (repo *GormRepository) DeleteClient(id string) error {
 	if len(id) == 0 {
 		return nil
 	}
+	var revoked []uuid.UUID
 	err := repo.db.Transaction(func(tx *gorm.DB) error {
 		errs := tx.Delete(&model.OAuth2Client{ID: id}).
 			Delete(&model.OAuth2Authorize{}, &model.OAuth2Authorize{ClientID: id}).
-			Delete(&model.OAuth2Token{}, &model.OAuth2Token{ClientID: id}).
 			GetErrors()
 		if len(errs) > 0 {
 			return errs[0]
 		}
-		return nil
+		ids, err := deleteTokens(tx, &model.OAuth2Token{ClientID: id})
+		revoked = ids
+		return err
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	repo.publishTokensRevoked(revoked)
+	return nil
 }
 
 // SaveAuthorize implements OAuth2Repository interface.
@@ -133,6 +145,94 @@ func (repo *GormRepository) DeleteAuthorize(code string) error {
 	return repo.db.Delete(&model.OAuth2Authorize{Code: code}).Error
 }
 
+// SaveDeviceAuthorization implements OAuth2Repository interface.
+func (repo *GormRepository) SaveDeviceAuthorization(data *model.OAuth2DeviceAuthorization) error {
+	return repo.db.Create(data).Error
+}
+
+// GetDeviceAuthorizationByDeviceCode implements OAuth2Repository interface.
+func (repo *GormRepository) GetDeviceAuthorizationByDeviceCode(deviceCode string) (*model.OAuth2DeviceAuthorization, error) {
+	if len(deviceCode) == 0 {
+		return nil, ErrNotFound
+	}
+	da := &model.OAuth2DeviceAuthorization{}
+	if err := repo.db.Take(da, &model.OAuth2DeviceAuthorization{DeviceCode: deviceCode}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return da, nil
+}
+
+// GetDeviceAuthorizationByUserCode implements OAuth2Repository interface.
+func (repo *GormRepository) GetDeviceAuthorizationByUserCode(userCode string) (*model.OAuth2DeviceAuthorization, error) {
+	if len(userCode) == 0 {
+		return nil, ErrNotFound
+	}
+	da := &model.OAuth2DeviceAuthorization{}
+	if err := repo.db.Take(da, &model.OAuth2DeviceAuthorization{UserCode: userCode}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return da, nil
+}
+
+// DecideDeviceAuthorization implements OAuth2Repository interface.
+func (repo *GormRepository) DecideDeviceAuthorization(deviceCode string, userID uuid.UUID, approve bool) error {
+	if len(deviceCode) == 0 {
+		return ErrNotFound
+	}
+	status := model.DeviceAuthorizationDenied
+	if approve {
+		status = model.DeviceAuthorizationApproved
+	}
+	result := repo.db.
+		Model(&model.OAuth2DeviceAuthorization{}).
+		Where(&model.OAuth2DeviceAuthorization{DeviceCode: deviceCode, Status: model.DeviceAuthorizationPending}).
+		Updates(map[string]interface{}{"user_id": userID, "status": status})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateDeviceAuthorizationPolling implements OAuth2Repository interface.
+func (repo *GormRepository) UpdateDeviceAuthorizationPolling(deviceCode string, interval int, polledAt time.Time) error {
+	if len(deviceCode) == 0 {
+		return nil
+	}
+	return repo.db.
+		Model(&model.OAuth2DeviceAuthorization{}).
+		Where(&model.OAuth2DeviceAuthorization{DeviceCode: deviceCode}).
+		Updates(map[string]interface{}{"interval": interval, "last_polled_at": polledAt}).
+		Error
+}
+
+// DeleteDeviceAuthorization implements OAuth2Repository interface.
+func (repo *GormRepository) DeleteDeviceAuthorization(deviceCode string) error {
+	if len(deviceCode) == 0 {
+		return nil
+	}
+	return repo.db.Delete(&model.OAuth2DeviceAuthorization{DeviceCode: deviceCode}).Error
+}
+
+// ConsumeApprovedDeviceAuthorization implements OAuth2Repository interface.
+func (repo *GormRepository) ConsumeApprovedDeviceAuthorization(deviceCode string) error {
+	if len(deviceCode) == 0 {
+		return ErrNotFound
+	}
+	result := repo.db.
+		Where(&model.OAuth2DeviceAuthorization{DeviceCode: deviceCode, Status: model.DeviceAuthorizationApproved}).
+		Delete(&model.OAuth2DeviceAuthorization{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // IssueToken implements OAuth2Repository interface.
 func (repo *GormRepository) IssueToken(client *model.OAuth2Client, userID uuid.UUID, redirectURI string, scope model.AccessScopes, expire int, refresh bool) (*model.OAuth2Token, error) {
 	newToken := &model.OAuth2Token{
@@ -146,6 +246,8 @@ func (repo *GormRepository) IssueToken(client *model.OAuth2Client, userID uuid.U
 		ExpiresIn:      expire,
 		Scopes:         scope,
 	}
+	newToken.FamilyID = newToken.ID
+	newToken.FamilyCreatedAt = newToken.CreatedAt
 
 	if client != nil {
 		newToken.ClientID = client.ID
@@ -171,7 +273,7 @@ func (repo *GormRepository) DeleteTokenByID(id uuid.UUID) error {
 	if id == uuid.Nil {
 		return nil
 	}
-	return repo.db.Delete(&model.OAuth2Token{}, &model.OAuth2Token{ID: id}).Error
+	return repo.deleteTokens(&model.OAuth2Token{ID: id})
 }
 
 // GetTokenByAccess implements OAuth2Repository interface.
@@ -191,7 +293,7 @@ func (repo *GormRepository) DeleteTokenByAccess(access string) error {
 	if len(access) == 0 {
 		return nil
 	}
-	return repo.db.Delete(&model.OAuth2Token{}, &model.OAuth2Token{AccessToken: access}).Error
+	return repo.deleteTokens(&model.OAuth2Token{AccessToken: access})
 }
 
 // GetTokenByRefresh implements OAuth2Repository interface.
@@ -211,7 +313,68 @@ func (repo *GormRepository) DeleteTokenByRefresh(refresh string) error {
 	if len(refresh) == 0 {
 		return nil
 	}
-	return repo.db.Delete(&model.OAuth2Token{}, &model.OAuth2Token{RefreshToken: refresh, RefreshEnabled: true}).Error
+	return repo.deleteTokens(&model.OAuth2Token{RefreshToken: refresh, RefreshEnabled: true})
+}
+
+// RotateToken implements OAuth2Repository interface.
+func (repo *GormRepository) RotateToken(token *model.OAuth2Token, scope model.AccessScopes, expire int, refresh bool) (*model.OAuth2Token, error) {
+	if token == nil || token.ID == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	now := time.Now()
+	newToken := &model.OAuth2Token{
+		ID:              uuid.Must(uuid.NewV4()),
+		ClientID:        token.ClientID,
+		UserID:          token.UserID,
+		RedirectURI:     token.RedirectURI,
+		AccessToken:     random.SecureAlphaNumeric(36),
+		RefreshToken:    random.SecureAlphaNumeric(36),
+		RefreshEnabled:  refresh,
+		Scopes:          scope,
+		ExpiresIn:       expire,
+		FamilyID:        token.FamilyID,
+		ParentID:        optional.UUIDFrom(token.ID),
+		FamilyCreatedAt: token.FamilyCreatedAt,
+		CreatedAt:       now,
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		// 同じリフレッシュトークンが同時に使用された場合に、片方のみ成功させる
+		result := tx.Model(&model.OAuth2Token{}).
+			Where("id = ? AND rotated_at IS NULL", token.ID).
+			Updates(map[string]interface{}{"rotated_at": now, "deleted_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Create(newToken).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	repo.publishTokensRevoked([]uuid.UUID{token.ID})
+	return newToken, nil
+}
+
+// GetRotatedTokenByRefresh implements OAuth2Repository interface.
+func (repo *GormRepository) GetRotatedTokenByRefresh(refresh string) (*model.OAuth2Token, error) {
+	if len(refresh) == 0 {
+		return nil, ErrNotFound
+	}
+	ot := &model.OAuth2Token{}
+	if err := repo.db.Unscoped().Where("refresh_token = ? AND rotated_at IS NOT NULL", refresh).Take(ot).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return ot, nil
+}
+
+// DeleteTokenFamily implements OAuth2Repository interface.
+func (repo *GormRepository) DeleteTokenFamily(familyID uuid.UUID) error {
+	if familyID == uuid.Nil {
+		return nil
+	}
+	return repo.deleteTokens(&model.OAuth2Token{FamilyID: familyID})
 }
 
 // GetTokensByUser implements OAuth2Repository interface.
@@ -223,12 +386,21 @@ func (repo *GormRepository) GetTokensByUser(userID uuid.UUID) ([]*model.OAuth2To
 	return ts, repo.db.Where(&model.OAuth2Token{UserID: userID}).Find(&ts).Error
 }
 
+// GetTokensByClient implements OAuth2Repository interface.
+func (repo *GormRepository) GetTokensByClient(clientID string) ([]*model.OAuth2Token, error) {
+	ts := make([]*model.OAuth2Token, 0)
+	if len(clientID) == 0 {
+		return ts, nil
+	}
+	return ts, repo.db.Where(&model.OAuth2Token{ClientID: clientID}).Find(&ts).Error
+}
+
 // DeleteTokenByUser implements OAuth2Repository interface.
 func (repo *GormRepository) DeleteTokenByUser(userID uuid.UUID) error {
 	if userID == uuid.Nil {
 		return nil
 	}
-	return repo.db.Delete(&model.OAuth2Token{}, &model.OAuth2Token{UserID: userID}).Error
+	return repo.deleteTokens(&model.OAuth2Token{UserID: userID})
 }
 
 // DeleteTokenByClient implements OAuth2Repository interface.
@@ -236,5 +408,45 @@ func (repo *GormRepository) DeleteTokenByClient(clientID string) error {
 	if len(clientID) == 0 {
 		return nil
 	}
-	return repo.db.Delete(&model.OAuth2Token{}, &model.OAuth2Token{ClientID: clientID}).Error
+	return repo.deleteTokens(&model.OAuth2Token{ClientID: clientID})
+}
+
+// deleteTokens 条件に一致するトークンを削除し、無効化イベントを発行します
+func (repo *GormRepository) deleteTokens(where *model.OAuth2Token) error {
+	var revoked []uuid.UUID
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		ids, err := deleteTokens(tx, where)
+		revoked = ids
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	repo.publishTokensRevoked(revoked)
+	return nil
+}
+
+// deleteTokens 条件に一致するトークンを削除し、削除したトークンのIDを返します
+func deleteTokens(tx *gorm.DB, where *model.OAuth2Token) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := tx.Model(&model.OAuth2Token{}).Where(where).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return ids, tx.Where("id IN (?)", ids).Delete(&model.OAuth2Token{}).Error
+}
+
+// publishTokensRevoked トークン無効化イベントを発行します
+func (repo *GormRepository) publishTokensRevoked(ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	repo.hub.Publish(hub.Message{
+		Name: event.OAuth2TokensRevoked,
+		Fields: hub.Fields{
+			"token_ids": ids,
+		},
+	})
 }
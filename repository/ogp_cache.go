@@ -1,6 +1,9 @@
 package repository
 
-import "github.com/traPtitech/traQ/model"
+import (
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
 
 type OgpCacheRepository interface {
 	// CreateOgpCache OGPキャッシュを作成します
@@ -28,4 +31,10 @@ type OgpCacheRepository interface {
 	// 成功した場合、nilを返します。
 	// DBによるエラーを返すことがあります。
 	DeleteOgpCache(url string) error
+
+	// DeleteExpiredOgpCaches 指定した日時より前に有効期限が切れたOGPキャッシュを最大limit件削除します
+	//
+	// 成功した場合、削除したOGPキャッシュとnilを返します。
+	// DBによるエラーを返すことがあります。
+	DeleteExpiredOgpCaches(before time.Time, limit int) ([]*model.OgpCache, error)
 }
@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"github.com/jinzhu/gorm"
 	"github.com/traPtitech/traQ/model"
-	"github.com/traPtitech/traQ/service/ogp"
 	"reflect"
+	"time"
 )
 
 func getURLHash(url string) (string, error) {
@@ -27,7 +27,7 @@ func (repo *GormRepository) CreateOgpCache(url string, content *model.Ogp) (c *m
 		URLHash:   urlHash,
 		Content:   model.Ogp{},
 		Valid:     content != nil,
-		ExpiresAt: ogp.GetCacheExpireDate(),
+		ExpiresAt: model.OgpCacheExpireDate(),
 	}
 
 	if content != nil {
@@ -60,13 +60,13 @@ func (repo *GormRepository) UpdateOgpCache(url string, content *model.Ogp) error
 		if content == nil {
 			changes["valid"] = false
 			changes["content"] = model.Ogp{}
-			changes["expires_at"] = ogp.GetCacheExpireDate()
+			changes["expires_at"] = model.OgpCacheExpireDate()
 			return tx.Model(&c).Updates(changes).Error
 		}
 		if !reflect.DeepEqual(c.Content, content) {
 			changes["valid"] = true
 			changes["content"] = content
-			changes["expires_at"] = ogp.GetCacheExpireDate()
+			changes["expires_at"] = model.OgpCacheExpireDate()
 			return tx.Model(&c).Updates(changes).Error
 		}
 		return nil
@@ -102,3 +102,25 @@ func (repo *GormRepository) DeleteOgpCache(url string) error {
 	}
 	return nil
 }
+
+// DeleteExpiredOgpCaches implements OgpRepository interface.
+func (repo *GormRepository) DeleteExpiredOgpCaches(before time.Time, limit int) ([]*model.OgpCache, error) {
+	caches := make([]*model.OgpCache, 0)
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", before).Limit(limit).Find(&caches).Error; err != nil {
+			return err
+		}
+		if len(caches) == 0 {
+			return nil
+		}
+		ids := make([]int, len(caches))
+		for i, c := range caches {
+			ids[i] = c.ID
+		}
+		return tx.Where("id IN (?)", ids).Delete(&model.OgpCache{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return caches, nil
+}
@@ -0,0 +1,65 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
+
+// CreatePersonalAccessTokenArgs パーソナルアクセストークン作成引数
+type CreatePersonalAccessTokenArgs struct {
+	UserID    uuid.UUID
+	Name      string
+	Scopes    model.AccessScopes
+	ExpiresAt time.Time
+}
+
+// PersonalAccessTokenRepository パーソナルアクセストークンリポジトリ
+type PersonalAccessTokenRepository interface {
+	// CreatePersonalAccessToken パーソナルアクセストークンを発行します
+	//
+	// 成功した場合、トークン情報とトークン文字列とnilを返します。
+	// トークン文字列はハッシュ化して保存されるため、この返り値以外から取得することはできません。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// DBによるエラーを返すことがあります。
+	CreatePersonalAccessToken(args CreatePersonalAccessTokenArgs) (*model.PersonalAccessToken, string, error)
+	// GetPersonalAccessToken 指定したIDのパーソナルアクセストークンを取得します
+	//
+	// 成功した場合、トークン情報とnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetPersonalAccessToken(id uuid.UUID) (*model.PersonalAccessToken, error)
+	// GetPersonalAccessTokenByToken 指定したトークン文字列のパーソナルアクセストークンを取得します
+	//
+	// 成功した場合、トークン情報とnilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// DBによるエラーを返すことがあります。
+	GetPersonalAccessTokenByToken(token string) (*model.PersonalAccessToken, error)
+	// GetPersonalAccessTokensByUser 指定したユーザーのパーソナルアクセストークンを全て取得します
+	//
+	// 成功した場合、トークン情報の配列とnilを返します。
+	// 存在しないユーザーを指定した場合、空配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetPersonalAccessTokensByUser(userID uuid.UUID) ([]*model.PersonalAccessToken, error)
+	// UpdatePersonalAccessTokenLastUsed パーソナルアクセストークンの最終使用日時とIPアドレスを更新します
+	//
+	// 成功した、或いは存在しない場合、nilを返します。
+	// DBによるエラーを返すことがあります。
+	UpdatePersonalAccessTokenLastUsed(id uuid.UUID, ip string, usedAt time.Time) error
+	// DeletePersonalAccessToken 指定したユーザーのパーソナルアクセストークンを削除します
+	//
+	// 成功した場合、nilを返します。
+	// 存在しなかった場合、ErrNotFoundを返します。
+	// 引数にuuid.Nilを指定するとErrNilIDを返します。
+	// 削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
+	// DBによるエラーを返すことがあります。
+	DeletePersonalAccessToken(userID, id uuid.UUID) error
+	// DeletePersonalAccessTokensByUser 指定したユーザーのパーソナルアクセストークンを全て削除します
+	//
+	// 成功した、或いは存在しない場合、nilを返します。
+	// 削除した場合、event.OAuth2TokensRevokedイベントが発行されます。
+	// DBによるエラーを返すことがあります。
+	DeletePersonalAccessTokensByUser(userID uuid.UUID) error
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/random"
+	"time"
+	"unicode/utf8"
+)
+
+// CreatePersonalAccessToken implements PersonalAccessTokenRepository interface.
+func (repo *GormRepository) CreatePersonalAccessToken(args CreatePersonalAccessTokenArgs) (*model.PersonalAccessToken, string, error) {
+	if args.UserID == uuid.Nil {
+		return nil, "", ErrNilID
+	}
+	if l := utf8.RuneCountInString(args.Name); l == 0 || l > 32 {
+		return nil, "", ArgError("args.Name", "Name must be non-empty and shorter than 33 characters")
+	}
+	if len(args.Scopes) == 0 {
+		return nil, "", ArgError("args.Scopes", "Scopes must not be empty")
+	}
+	if !args.ExpiresAt.After(time.Now()) {
+		return nil, "", ArgError("args.ExpiresAt", "ExpiresAt must be in the future")
+	}
+
+	token := model.PersonalAccessTokenPrefix + random.SecureAlphaNumeric(40)
+	t := &model.PersonalAccessToken{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    args.UserID,
+		Name:      args.Name,
+		TokenHash: model.HashPersonalAccessToken(token),
+		Scopes:    args.Scopes,
+		ExpiresAt: args.ExpiresAt,
+	}
+	if err := repo.db.Create(t).Error; err != nil {
+		return nil, "", err
+	}
+	return t, token, nil
+}
+
+// GetPersonalAccessToken implements PersonalAccessTokenRepository interface.
+func (repo *GormRepository) GetPersonalAccessToken(id uuid.UUID) (*model.PersonalAccessToken, error) {
+	if id == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	t := &model.PersonalAccessToken{}
+	if err := repo.db.Take(t, &model.PersonalAccessToken{ID: id}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return t, nil
+}
+
+// GetPersonalAccessTokenByToken implements PersonalAccessTokenRepository interface.
+func (repo *GormRepository) GetPersonalAccessTokenByToken(token string) (*model.PersonalAccessToken, error) {
+	if len(token) == 0 {
+		return nil, ErrNotFound
+	}
+	t := &model.PersonalAccessToken{}
+	if err := repo.db.Take(t, &model.PersonalAccessToken{TokenHash: model.HashPersonalAccessToken(token)}).Error; err != nil {
+		return nil, convertError(err)
+	}
+	return t, nil
+}
+
+// GetPersonalAccessTokensByUser implements PersonalAccessTokenRepository interface.
+func (repo *GormRepository) GetPersonalAccessTokensByUser(userID uuid.UUID) ([]*model.PersonalAccessToken, error) {
+	ts := make([]*model.PersonalAccessToken, 0)
+	if userID == uuid.Nil {
+		return ts, nil
+	}
+	return ts, repo.db.Where(&model.PersonalAccessToken{UserID: userID}).Order("created_at").Find(&ts).Error
+}
+
+// UpdatePersonalAccessTokenLastUsed implements PersonalAccessTokenRepository interface.
+func (repo *GormRepository) UpdatePersonalAccessTokenLastUsed(id uuid.UUID, ip string, usedAt time.Time) error {
+	if id == uuid.Nil {
+		return nil
+	}
+	return repo.db.
+		Model(&model.PersonalAccessToken{}).
+		Where(&model.PersonalAccessToken{ID: id}).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).
+		Error
+}
+
+// DeletePersonalAccessToken implements PersonalAccessTokenRepository interface.
+func (repo *GormRepository) DeletePersonalAccessToken(userID, id uuid.UUID) error {
+	if userID == uuid.Nil || id == uuid.Nil {
+		return ErrNilID
+	}
+	result := repo.db.Delete(&model.PersonalAccessToken{}, &model.PersonalAccessToken{ID: id, UserID: userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	// OAuth2トークンと同様に、このトークンで接続しているWebSocketセッションを切断させる
+	repo.publishTokensRevoked([]uuid.UUID{id})
+	return nil
+}
+
+// DeletePersonalAccessTokensByUser implements PersonalAccessTokenRepository interface.
+func (repo *GormRepository) DeletePersonalAccessTokensByUser(userID uuid.UUID) error {
+	if userID == uuid.Nil {
+		return nil
+	}
+	var revoked []uuid.UUID
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.PersonalAccessToken{}).Where(&model.PersonalAccessToken{UserID: userID}).Pluck("id", &revoked).Error; err != nil {
+			return err
+		}
+		if len(revoked) == 0 {
+			return nil
+		}
+		return tx.Where("id IN (?)", revoked).Delete(&model.PersonalAccessToken{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	repo.publishTokensRevoked(revoked)
+	return nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_CreatePersonalAccessToken(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	_, _, err := repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{Name: "cli", Scopes: scopes, ExpiresAt: expiresAt})
+	assert.EqualError(err, ErrNilID.Error())
+	_, _, err = repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: strings.Repeat("a", 33), Scopes: scopes, ExpiresAt: expiresAt})
+	assert.True(IsArgError(err))
+	_, _, err = repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: "cli", ExpiresAt: expiresAt})
+	assert.True(IsArgError(err))
+	_, _, err = repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: "cli", Scopes: scopes, ExpiresAt: time.Now().Add(-time.Hour)})
+	assert.True(IsArgError(err))
+
+	pat, token, err := repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: "cli", Scopes: scopes, ExpiresAt: expiresAt})
+	require.NoError(err)
+	assert.NotEqual(uuid.Nil, pat.ID)
+	assert.True(model.IsPersonalAccessToken(token))
+	assert.NotContains(pat.TokenHash, token)
+	assert.Equal(model.HashPersonalAccessToken(token), pat.TokenHash)
+	assert.EqualValues(1, count(t, getDB(repo).Model(model.PersonalAccessToken{}).Where("user_id = ?", user.GetID())))
+}
+
+func TestRepositoryImpl_GetPersonalAccessTokenByToken(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read", "write")
+	pat, token, err := repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: "cli", Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(err)
+
+	_, err = repo.GetPersonalAccessTokenByToken("")
+	assert.EqualError(err, ErrNotFound.Error())
+	_, err = repo.GetPersonalAccessTokenByToken(model.PersonalAccessTokenPrefix + "unknown")
+	assert.EqualError(err, ErrNotFound.Error())
+
+	actual, err := repo.GetPersonalAccessTokenByToken(token)
+	if assert.NoError(err) {
+		assert.Equal(pat.ID, actual.ID)
+		assert.Equal(user.GetID(), actual.UserID)
+		assert.ElementsMatch(scopes.StringArray(), actual.Scopes.StringArray())
+		assert.Nil(actual.LastUsedAt)
+	}
+}
+
+func TestRepositoryImpl_GetPersonalAccessTokensByUser(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	other := mustMakeUser(t, repo, rand).GetID()
+	for _, uid := range []uuid.UUID{user.GetID(), user.GetID(), other} {
+		_, _, err := repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: uid, Name: "cli", Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(err)
+	}
+
+	ts, err := repo.GetPersonalAccessTokensByUser(user.GetID())
+	if assert.NoError(err) {
+		assert.Len(ts, 2)
+	}
+	ts, err = repo.GetPersonalAccessTokensByUser(uuid.Nil)
+	if assert.NoError(err) {
+		assert.Len(ts, 0)
+	}
+}
+
+func TestRepositoryImpl_UpdatePersonalAccessTokenLastUsed(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	pat, _, err := repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: "cli", Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(err)
+
+	usedAt := time.Now()
+	assert.NoError(repo.UpdatePersonalAccessTokenLastUsed(uuid.Nil, "192.0.2.1", usedAt))
+	require.NoError(repo.UpdatePersonalAccessTokenLastUsed(pat.ID, "192.0.2.1", usedAt))
+
+	actual, err := repo.GetPersonalAccessToken(pat.ID)
+	require.NoError(err)
+	if assert.NotNil(actual.LastUsedAt) {
+		assert.WithinDuration(usedAt, *actual.LastUsedAt, time.Millisecond)
+	}
+	assert.Equal("192.0.2.1", actual.LastUsedIP)
+}
+
+func TestRepositoryImpl_DeletePersonalAccessToken(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	pat, _, err := repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: "cli", Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(err)
+
+	assert.EqualError(repo.DeletePersonalAccessToken(uuid.Nil, pat.ID), ErrNilID.Error())
+	assert.EqualError(repo.DeletePersonalAccessToken(mustMakeUser(t, repo, rand).GetID(), pat.ID), ErrNotFound.Error())
+	if assert.NoError(repo.DeletePersonalAccessToken(user.GetID(), pat.ID)) {
+		_, err := repo.GetPersonalAccessToken(pat.ID)
+		assert.EqualError(err, ErrNotFound.Error())
+	}
+	assert.EqualError(repo.DeletePersonalAccessToken(user.GetID(), pat.ID), ErrNotFound.Error())
+}
+
+func TestRepositoryImpl_DeletePersonalAccessTokensByUser(t *testing.T) {
+	t.Parallel()
+	repo, assert, require, user := setupWithUser(t, common)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	other := mustMakeUser(t, repo, rand).GetID()
+	ids := make([]uuid.UUID, 0, 2)
+	for _, uid := range []uuid.UUID{user.GetID(), user.GetID(), other} {
+		pat, _, err := repo.CreatePersonalAccessToken(CreatePersonalAccessTokenArgs{UserID: uid, Name: "cli", Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(err)
+		if uid == user.GetID() {
+			ids = append(ids, pat.ID)
+		}
+	}
+
+	sub := repo.(*GormRepository).hub.Subscribe(10, event.OAuth2TokensRevoked)
+	defer repo.(*GormRepository).hub.Unsubscribe(sub)
+
+	assert.NoError(repo.DeletePersonalAccessTokensByUser(uuid.Nil))
+	if assert.NoError(repo.DeletePersonalAccessTokensByUser(user.GetID())) {
+		assert.EqualValues(0, count(t, getDB(repo).Model(model.PersonalAccessToken{}).Where("user_id = ?", user.GetID())))
+		assert.EqualValues(1, count(t, getDB(repo).Model(model.PersonalAccessToken{}).Where("user_id = ?", other)))
+	}
+
+	// 他のテストの無効化イベントが混ざることがある
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case ev := <-sub.Receiver:
+			revoked := set.UUIDSetFromArray(ev.Fields["token_ids"].([]uuid.UUID))
+			if len(revoked) == len(ids) && revoked.Contains(ids[0]) && revoked.Contains(ids[1]) {
+				return
+			}
+		case <-timeout:
+			t.Fatal("OAuth2TokensRevoked event was not published")
+		}
+	}
+}
@@ -24,4 +24,12 @@ type Repository interface {
 	BotRepository
 	ClipRepository
 	OgpCacheRepository
+	CallRepository
+	WebPushSubscriptionRepository
+	UserEmailSettingRepository
+	NotificationRepository
+	KeywordAlertRepository
+	UserDNDSettingRepository
+	PersonalAccessTokenRepository
+	SAMLAssertionRepository
 }
@@ -0,0 +1,15 @@
+package repository
+
+import "time"
+
+// SAMLAssertionRepository 使用済みSAMLアサーションリポジトリ
+type SAMLAssertionRepository interface {
+	// ConsumeSAMLAssertion 指定したIDのSAMLアサーションを使用済みにします
+	//
+	// 有効期限が切れた使用済みアサーションは同時に削除されます。
+	// 成功した場合、nilを返します。
+	// 既に使用済みの場合、ErrAlreadyExistsを返します。
+	// 引数に問題がある場合、ArgumentErrorを返します。
+	// DBによるエラーを返すことがあります。
+	ConsumeSAMLAssertion(id string, expiresAt time.Time) error
+}
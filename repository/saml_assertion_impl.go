@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/gormutil"
+	"time"
+)
+
+// ConsumeSAMLAssertion implements SAMLAssertionRepository interface.
+func (repo *GormRepository) ConsumeSAMLAssertion(id string, expiresAt time.Time) error {
+	if len(id) == 0 || len(id) > 190 {
+		return ArgError("id", "id must be 1-190 characters")
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&model.SAMLAssertion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&model.SAMLAssertion{ID: id, ExpiresAt: expiresAt}).Error; err != nil {
+			if gormutil.IsMySQLDuplicatedRecordErr(err) {
+				return ErrAlreadyExists
+			}
+			return err
+		}
+		return nil
+	})
+}
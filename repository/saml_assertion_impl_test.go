@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepositoryImpl_ConsumeSAMLAssertion(t *testing.T) {
+	t.Parallel()
+	repo, _, _ := setup(t, common)
+
+	t.Run("invalid id", func(t *testing.T) {
+		t.Parallel()
+
+		assert.True(t, IsArgError(repo.ConsumeSAMLAssertion("", time.Now().Add(time.Hour))))
+		assert.True(t, IsArgError(repo.ConsumeSAMLAssertion(strings.Repeat("a", 191), time.Now().Add(time.Hour))))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		id := "id-" + uuid.Must(uuid.NewV4()).String()
+
+		assert.NoError(t, repo.ConsumeSAMLAssertion(id, time.Now().Add(time.Hour)))
+		assert.Equal(t, 1, count(t, getDB(repo).Model(&model.SAMLAssertion{}).Where(&model.SAMLAssertion{ID: id})))
+	})
+
+	t.Run("already consumed", func(t *testing.T) {
+		t.Parallel()
+		id := "id-" + uuid.Must(uuid.NewV4()).String()
+
+		assert.NoError(t, repo.ConsumeSAMLAssertion(id, time.Now().Add(time.Hour)))
+		assert.EqualError(t, repo.ConsumeSAMLAssertion(id, time.Now().Add(time.Hour)), ErrAlreadyExists.Error())
+	})
+
+	t.Run("delete expired", func(t *testing.T) {
+		t.Parallel()
+		id := "id-" + uuid.Must(uuid.NewV4()).String()
+
+		assert.NoError(t, repo.ConsumeSAMLAssertion(id, time.Now().Add(-time.Hour)))
+		assert.NoError(t, repo.ConsumeSAMLAssertion("id-"+uuid.Must(uuid.NewV4()).String(), time.Now().Add(time.Hour)))
+		assert.Equal(t, 0, count(t, getDB(repo).Model(&model.SAMLAssertion{}).Where(&model.SAMLAssertion{ID: id})))
+	})
+}
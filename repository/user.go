@@ -5,6 +5,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
 )
 
 // CreateUserArgs ユーザー作成引数
@@ -48,7 +49,15 @@ type UsersQuery struct {
 	IsGMemberOf                 optional.UUID
 	IsSubscriberAtMarkLevelOf   optional.UUID
 	IsSubscriberAtNotifyLevelOf optional.UUID
-	EnableProfileLoading        bool
+	IsNamed                     optional.String
+	IsExternalUserOf            struct {
+		Valid        bool
+		ProviderName string
+		ExternalID   string
+	}
+	EnableProfileLoading bool
+	Offset               int
+	Limit                int
 }
 
 // NotBot Botでない
@@ -87,6 +96,27 @@ func (q UsersQuery) SubscriberAtNotifyLevelOf(channelID uuid.UUID) UsersQuery {
 	return q
 }
 
+// Named ユーザー名がnameである
+func (q UsersQuery) Named(name string) UsersQuery {
+	q.IsNamed = optional.StringFrom(name)
+	return q
+}
+
+// ExternalUserOf providerNameの外部アカウントexternalIDに関連付けられている
+func (q UsersQuery) ExternalUserOf(providerName, externalID string) UsersQuery {
+	q.IsExternalUserOf.Valid = true
+	q.IsExternalUserOf.ProviderName = providerName
+	q.IsExternalUserOf.ExternalID = externalID
+	return q
+}
+
+// Paginate ユーザー名順に並べ、offset件目からlimit件を取得する
+func (q UsersQuery) Paginate(offset, limit int) UsersQuery {
+	q.Offset = offset
+	q.Limit = limit
+	return q
+}
+
 // LoadProfile ユーザーの追加プロファイル情報を読み込むかどうか
 func (q UsersQuery) LoadProfile() UsersQuery {
 	q.EnableProfileLoading = true
@@ -129,6 +159,12 @@ type UserRepository interface {
 	// 成功した場合、UUIDの配列とnilを返します。
 	// DBによるエラーを返すことがあります。
 	GetUserIDs(query UsersQuery) ([]uuid.UUID, error)
+	// CountUsers 指定した条件を満たすユーザーの数を取得します
+	//
+	// queryのOffset, Limitは無視されます。
+	// 成功した場合、ユーザーの数とnilを返します。
+	// DBによるエラーを返すことがあります。
+	CountUsers(query UsersQuery) (int, error)
 	// UserExists 指定したIDのユーザーが存在するかどうかを返します
 	//
 	// 存在する場合、trueとnilを返します。
@@ -156,6 +192,11 @@ type UserRepository interface {
 	// 成功した場合、外部ログインアカウントの配列とnilを返します。
 	// DBによるエラーを返すことがあります。
 	GetLinkedExternalUserAccounts(userID uuid.UUID) ([]*model.ExternalProviderUser, error)
+	// GetExternalUserAccounts 指定したユーザーに関連づけられている、指定したプロバイダの外部ログインアカウントの配列を返します
+	//
+	// 成功した場合、外部ログインアカウントの配列とnilを返します。
+	// DBによるエラーを返すことがあります。
+	GetExternalUserAccounts(providerName string, userIDs set.UUID) ([]*model.ExternalProviderUser, error)
 	// UnlinkExternalUserAccount 指定したユーザーに関連づけられている指定した外部ログインアカウントの関連付けを解除します
 	//
 	// 成功した場合、nilを返します。
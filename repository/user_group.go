@@ -1,3 +1,4 @@
+//go:generate mockgen -source=$GOFILE -destination=mock_$GOPACKAGE/mock_$GOFILE
 package repository
 
 import (
@@ -18,6 +19,7 @@ type UserGroupRepository interface {
 	// CreateUserGroup ユーザーグループを作成します
 	//
 	// 成功した場合、ユーザーグループとnilを返します。
+	// adminIDにuuid.Nilを指定した場合、管理者の居ないグループを作成します。
 	// 引数に問題がある場合、ArgumentErrorを返します。
 	// 既にNameが使われている場合、ErrAlreadyExistsを返します。
 	// DBによるエラーを返すことがあります。
@@ -33,15 +33,23 @@ func (repo *GormRepository) CreateUserGroup(name, description, gType string, adm
 		err := tx.Create(g).Error
 		if gormutil.IsMySQLDuplicatedRecordErr(err) {
 			return ErrAlreadyExists
+		} else if err != nil {
+			return err
 		}
 
+		if adminID == uuid.Nil {
+			return nil
+		}
 		return tx.Create(&model.UserGroupAdmin{GroupID: g.ID, UserID: adminID}).Error
 	})
 	if err != nil {
 		return nil, err
 	}
 	g.Members = make([]*model.UserGroupMember, 0)
-	g.Admins = []*model.UserGroupAdmin{{GroupID: g.ID, UserID: adminID}}
+	g.Admins = make([]*model.UserGroupAdmin, 0)
+	if adminID != uuid.Nil {
+		g.Admins = append(g.Admins, &model.UserGroupAdmin{GroupID: g.ID, UserID: adminID})
+	}
 	repo.hub.Publish(hub.Message{
 		Name: event.UserGroupCreated,
 		Fields: hub.Fields{
@@ -26,6 +26,19 @@ func TestRepositoryImpl_CreateUserGroup(t *testing.T) {
 		assert.EqualError(t, err, ErrAlreadyExists.Error())
 	})
 
+	t.Run("without admin", func(t *testing.T) {
+		t.Parallel()
+
+		g, err := repo.CreateUserGroup(random2.AlphaNumeric(20), "", "", uuid.Nil)
+		if assert.NoError(t, err) {
+			assert.Empty(t, g.Admins)
+			g, err := repo.GetUserGroup(g.ID)
+			if assert.NoError(t, err) {
+				assert.Empty(t, g.Admins)
+			}
+		}
+	})
+
 	t.Run("invalid name", func(t *testing.T) {
 		t.Parallel()
 
@@ -11,6 +11,7 @@ import (
 	"github.com/traPtitech/traQ/utils/gormutil"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/set"
 	"github.com/traPtitech/traQ/utils/validator"
 	"unicode/utf8"
 )
@@ -132,6 +133,14 @@ func (repo *GormRepository) GetUserIDs(query UsersQuery) (ids []uuid.UUID, err e
 	return ids, err
 }
 
+// CountUsers implements UserRepository interface.
+func (repo *GormRepository) CountUsers(query UsersQuery) (count int, err error) {
+	query.Offset, query.Limit = 0, 0
+	query.EnableProfileLoading = false
+	err = repo.makeGetUsersTx(query).Count(&count).Error
+	return count, err
+}
+
 func (repo *GormRepository) makeGetUsersTx(query UsersQuery) *gorm.DB {
 	tx := repo.db.Table("users")
 
@@ -157,9 +166,18 @@ func (repo *GormRepository) makeGetUsersTx(query UsersQuery) *gorm.DB {
 	if query.IsGMemberOf.Valid {
 		tx = tx.Joins("INNER JOIN user_group_members ON user_group_members.user_id = users.id AND user_group_members.group_id = ?", query.IsGMemberOf.UUID)
 	}
+	if query.IsNamed.Valid {
+		tx = tx.Where("users.name = ?", query.IsNamed.String)
+	}
+	if query.IsExternalUserOf.Valid {
+		tx = tx.Joins("INNER JOIN external_provider_users ON external_provider_users.user_id = users.id AND external_provider_users.provider_name = ? AND external_provider_users.external_id = ?", query.IsExternalUserOf.ProviderName, query.IsExternalUserOf.ExternalID)
+	}
 	if query.EnableProfileLoading {
 		tx = tx.Preload("Profile")
 	}
+	if query.Limit > 0 {
+		tx = tx.Order("users.name").Offset(query.Offset).Limit(query.Limit)
+	}
 
 	return tx
 }
@@ -325,6 +343,18 @@ func (repo *GormRepository) GetLinkedExternalUserAccounts(userID uuid.UUID) ([]*
 	return result, repo.db.Find(&result, &model.ExternalProviderUser{UserID: userID}).Error
 }
 
+// GetExternalUserAccounts implements UserRepository interface.
+func (repo *GormRepository) GetExternalUserAccounts(providerName string, userIDs set.UUID) ([]*model.ExternalProviderUser, error) {
+	result := make([]*model.ExternalProviderUser, 0)
+	if len(providerName) == 0 || len(userIDs) == 0 {
+		return result, nil
+	}
+	return result, repo.db.
+		Where("provider_name = ? AND user_id IN (?)", providerName, userIDs.StringArray()).
+		Find(&result).
+		Error
+}
+
 // UnlinkExternalUserAccount implements UserRepository interface.
 func (repo *GormRepository) UnlinkExternalUserAccount(userID uuid.UUID, providerName string) error {
 	if userID == uuid.Nil || len(providerName) == 0 {
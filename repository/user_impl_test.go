@@ -7,6 +7,7 @@ import (
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/utils/optional"
 	random2 "github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/set"
 	"strings"
 	"testing"
 )
@@ -117,6 +118,84 @@ func TestRepositoryImpl_GetUsers(t *testing.T) {
 	})
 }
 
+func TestRepositoryImpl_CountUsers(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common2)
+
+	u0 := mustMakeUser(t, repo, rand)
+	g := mustMakeUserGroup(t, repo, rand, u0.GetID())
+	names := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		// 照合順序に依らずに名前順が決まるようにする
+		u := mustMakeUser(t, repo, fmt.Sprintf("%d%s", i, strings.ToLower(random2.AlphaNumeric(20))))
+		mustAddUserToGroup(t, repo, u.GetID(), g.ID)
+		names = append(names, u.GetName())
+	}
+	require.NoError(repo.LinkExternalUserAccount(u0.GetID(), LinkExternalUserAccountArgs{ProviderName: "test", ExternalID: u0.GetName(), Extra: model.JSON{}}))
+
+	t.Run("GMemberOf", func(t *testing.T) {
+		t.Parallel()
+
+		count, err := repo.CountUsers(UsersQuery{}.GMemberOf(g.ID).Paginate(1, 1))
+		if assert.NoError(err) {
+			assert.Equal(3, count)
+		}
+	})
+
+	t.Run("Paginate", func(t *testing.T) {
+		t.Parallel()
+
+		users, err := repo.GetUsers(UsersQuery{}.GMemberOf(g.ID).Paginate(1, 1))
+		if assert.NoError(err) && assert.Len(users, 1) {
+			assert.Equal(names[1], users[0].GetName())
+		}
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		t.Parallel()
+
+		users, err := repo.GetUsers(UsersQuery{}.Named(u0.GetName()))
+		if assert.NoError(err) && assert.Len(users, 1) {
+			assert.Equal(u0.GetID(), users[0].GetID())
+		}
+	})
+
+	t.Run("ExternalUserOf", func(t *testing.T) {
+		t.Parallel()
+
+		users, err := repo.GetUsers(UsersQuery{}.ExternalUserOf("test", u0.GetName()))
+		if assert.NoError(err) && assert.Len(users, 1) {
+			assert.Equal(u0.GetID(), users[0].GetID())
+		}
+		count, err := repo.CountUsers(UsersQuery{}.ExternalUserOf("other", u0.GetName()))
+		if assert.NoError(err) {
+			assert.Equal(0, count)
+		}
+	})
+}
+
+func TestRepositoryImpl_GetExternalUserAccounts(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common2)
+
+	linked := mustMakeUser(t, repo, rand)
+	other := mustMakeUser(t, repo, rand)
+	notLinked := mustMakeUser(t, repo, rand)
+	require.NoError(repo.LinkExternalUserAccount(linked.GetID(), LinkExternalUserAccountArgs{ProviderName: "test", ExternalID: linked.GetName(), Extra: model.JSON{}}))
+	require.NoError(repo.LinkExternalUserAccount(other.GetID(), LinkExternalUserAccountArgs{ProviderName: "other", ExternalID: other.GetName(), Extra: model.JSON{}}))
+
+	links, err := repo.GetExternalUserAccounts("test", set.UUIDSetFromArray([]uuid.UUID{linked.GetID(), other.GetID(), notLinked.GetID()}))
+	if assert.NoError(err) && assert.Len(links, 1) {
+		assert.Equal(linked.GetID(), links[0].UserID)
+		assert.Equal(linked.GetName(), links[0].ExternalID)
+	}
+
+	links, err = repo.GetExternalUserAccounts("test", set.UUID{})
+	if assert.NoError(err) {
+		assert.Len(links, 0)
+	}
+}
+
 func TestRepositoryImpl_GetUser(t *testing.T) {
 	t.Parallel()
 	repo, assert, _, user := setupWithUser(t, common2)
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/set"
+)
+
+func hashWebPushEndpoint(endpoint string) string {
+	h := sha1.Sum([]byte(endpoint))
+	return hex.EncodeToString(h[:])
+}
+
+// RegisterWebPushSubscription implements WebPushSubscriptionRepository interface.
+func (repo *GormRepository) RegisterWebPushSubscription(userID uuid.UUID, endpoint, p256dh, auth string) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	if len(endpoint) == 0 {
+		return ArgError("Endpoint", "endpoint is empty")
+	}
+	if len(p256dh) == 0 {
+		return ArgError("P256dh", "p256dh is empty")
+	}
+	if len(auth) == 0 {
+		return ArgError("Auth", "auth is empty")
+	}
+
+	hash := hashWebPushEndpoint(endpoint)
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var s model.WebPushSubscription
+		if err := tx.First(&s, &model.WebPushSubscription{EndpointHash: hash}).Error; err == nil {
+			// 同じブラウザで別のユーザーがログインした場合などは、同じエンドポイントになる
+			return tx.Model(&s).Updates(map[string]interface{}{
+				"user_id": userID,
+				"p256dh":  p256dh,
+				"auth":    auth,
+			}).Error
+		} else if !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+
+		return tx.Create(&model.WebPushSubscription{
+			EndpointHash: hash,
+			Endpoint:     endpoint,
+			UserID:       userID,
+			P256dh:       p256dh,
+			Auth:         auth,
+		}).Error
+	})
+}
+
+// GetWebPushSubscriptions implements WebPushSubscriptionRepository interface.
+func (repo *GormRepository) GetWebPushSubscriptions(userIDs set.UUID) (subscriptions []*model.WebPushSubscription, err error) {
+	subscriptions = make([]*model.WebPushSubscription, 0)
+	if len(userIDs) == 0 {
+		return subscriptions, nil
+	}
+	return subscriptions, repo.db.Where("user_id IN (?)", userIDs.StringArray()).Find(&subscriptions).Error
+}
+
+// DeleteWebPushSubscription implements WebPushSubscriptionRepository interface.
+func (repo *GormRepository) DeleteWebPushSubscription(userID uuid.UUID, endpoint string) error {
+	if userID == uuid.Nil {
+		return ErrNilID
+	}
+	return repo.db.Where(&model.WebPushSubscription{EndpointHash: hashWebPushEndpoint(endpoint), UserID: userID}).Delete(&model.WebPushSubscription{}).Error
+}
+
+// DeleteWebPushSubscriptions implements WebPushSubscriptionRepository interface.
+func (repo *GormRepository) DeleteWebPushSubscriptions(endpoints []string) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	hashes := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		hashes[i] = hashWebPushEndpoint(endpoint)
+	}
+	return repo.db.Where("endpoint_hash IN (?)", hashes).Delete(&model.WebPushSubscription{}).Error
+}
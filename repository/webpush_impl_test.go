@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	random2 "github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+)
+
+func TestRepositoryImpl_RegisterWebPushSubscription(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	id2 := mustMakeUser(t, repo, rand).GetID()
+	endpoint := "https://push.example.com/" + random2.AlphaNumeric(20)
+
+	assert.EqualError(repo.RegisterWebPushSubscription(uuid.Nil, endpoint, "key", "auth"), ErrNilID.Error())
+	assert.True(IsArgError(repo.RegisterWebPushSubscription(id1, "", "key", "auth")))
+	assert.True(IsArgError(repo.RegisterWebPushSubscription(id1, endpoint, "", "auth")))
+	assert.True(IsArgError(repo.RegisterWebPushSubscription(id1, endpoint, "key", "")))
+
+	require.NoError(repo.RegisterWebPushSubscription(id1, endpoint, "key", "auth"))
+	// 同じエンドポイントを別のユーザーが登録すると付け替えられる
+	require.NoError(repo.RegisterWebPushSubscription(id2, endpoint, "key2", "auth2"))
+
+	var s model.WebPushSubscription
+	require.NoError(getDB(repo).First(&s, &model.WebPushSubscription{EndpointHash: hashWebPushEndpoint(endpoint)}).Error)
+	assert.Equal(id2, s.UserID)
+	assert.Equal(endpoint, s.Endpoint)
+	assert.Equal("key2", s.P256dh)
+	assert.Equal("auth2", s.Auth)
+	assert.EqualValues(1, count(t, getDB(repo).Model(model.WebPushSubscription{}).Where("user_id IN (?, ?)", id1, id2)))
+}
+
+func TestRepositoryImpl_GetWebPushSubscriptions(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	id2 := mustMakeUser(t, repo, rand).GetID()
+	id3 := mustMakeUser(t, repo, rand).GetID()
+	require.NoError(repo.RegisterWebPushSubscription(id1, "https://push.example.com/"+random2.AlphaNumeric(20), "key", "auth"))
+	require.NoError(repo.RegisterWebPushSubscription(id1, "https://push.example.com/"+random2.AlphaNumeric(20), "key", "auth"))
+	require.NoError(repo.RegisterWebPushSubscription(id2, "https://push.example.com/"+random2.AlphaNumeric(20), "key", "auth"))
+
+	subs, err := repo.GetWebPushSubscriptions(set.UUIDSetFromArray([]uuid.UUID{id1, id3}))
+	if assert.NoError(err) {
+		assert.Len(subs, 2)
+	}
+
+	subs, err = repo.GetWebPushSubscriptions(set.UUID{})
+	if assert.NoError(err) {
+		assert.Len(subs, 0)
+	}
+}
+
+func TestRepositoryImpl_DeleteWebPushSubscription(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	id2 := mustMakeUser(t, repo, rand).GetID()
+	endpoint1 := "https://push.example.com/" + random2.AlphaNumeric(20)
+	endpoint2 := "https://push.example.com/" + random2.AlphaNumeric(20)
+	require.NoError(repo.RegisterWebPushSubscription(id1, endpoint1, "key", "auth"))
+	require.NoError(repo.RegisterWebPushSubscription(id2, endpoint2, "key", "auth"))
+
+	assert.EqualError(repo.DeleteWebPushSubscription(uuid.Nil, endpoint1), ErrNilID.Error())
+	// 他人の購読は解除できない
+	assert.NoError(repo.DeleteWebPushSubscription(id1, endpoint2))
+	assert.EqualValues(2, count(t, getDB(repo).Model(model.WebPushSubscription{}).Where("user_id IN (?, ?)", id1, id2)))
+
+	assert.NoError(repo.DeleteWebPushSubscription(id1, endpoint1))
+	assert.EqualValues(1, count(t, getDB(repo).Model(model.WebPushSubscription{}).Where("user_id IN (?, ?)", id1, id2)))
+}
+
+func TestRepositoryImpl_DeleteWebPushSubscriptions(t *testing.T) {
+	t.Parallel()
+	repo, assert, require := setup(t, common)
+
+	id1 := mustMakeUser(t, repo, rand).GetID()
+	endpoint1 := "https://push.example.com/" + random2.AlphaNumeric(20)
+	endpoint2 := "https://push.example.com/" + random2.AlphaNumeric(20)
+	endpoint3 := "https://push.example.com/" + random2.AlphaNumeric(20)
+	require.NoError(repo.RegisterWebPushSubscription(id1, endpoint1, "key", "auth"))
+	require.NoError(repo.RegisterWebPushSubscription(id1, endpoint2, "key", "auth"))
+	require.NoError(repo.RegisterWebPushSubscription(id1, endpoint3, "key", "auth"))
+
+	assert.NoError(repo.DeleteWebPushSubscriptions(nil))
+	assert.NoError(repo.DeleteWebPushSubscriptions([]string{endpoint1, endpoint2}))
+	assert.EqualValues(1, count(t, getDB(repo).Model(model.WebPushSubscription{}).Where("user_id = ?", id1)))
+}
@@ -0,0 +1,452 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/utils/optional"
+	"go.uber.org/zap"
+	"golang.org/x/exp/utf8string"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	LDAPProviderName       = "ldap"
+	ldapRequestErrorFormat = "ldap request error: %w"
+	ldapTimeout            = 10 * time.Second
+)
+
+var (
+	// ErrLDAPInvalidCredentials LDAPにユーザーが存在しない、或いはパスワードが間違っている
+	ErrLDAPInvalidCredentials = errors.New("invalid ldap credentials")
+	// ErrLDAPSignUpNotAllowed LDAPユーザーに対応するtraQユーザーが存在せず、新規登録も許可されていない
+	ErrLDAPSignUpNotAllowed = errors.New("sign up by ldap is not allowed")
+)
+
+type LDAPProvider struct {
+	config LDAPProviderConfig
+	repo   repository.Repository
+	fm     file.Manager
+	logger *zap.Logger
+}
+
+type LDAPProviderConfig struct {
+	// URL LDAPサーバーのURL (例: ldaps://ldap.example.com)
+	URL string
+	// StartTLS ldap://で接続した後にStartTLSを行うかどうか
+	StartTLS bool
+	// InsecureSkipVerify TLSのサーバー証明書の検証を行わないかどうか
+	InsecureSkipVerify bool
+	// BindDN ユーザー・グループ検索に使用するDN 空の場合は匿名で検索します
+	BindDN string
+	// BindPassword ユーザー・グループ検索に使用するDNのパスワード
+	BindPassword string
+	// UserDNTemplate ユーザーのDNのテンプレート (例: uid=%s,ou=people,dc=example,dc=com)
+	//
+	// 指定した場合、ユーザーを検索せずにこのDNでバインドします。
+	UserDNTemplate string
+	// UserBaseDN ユーザー検索のベースDN
+	UserBaseDN string
+	// UserFilter ユーザー検索のフィルタ (例: (uid=%s))
+	UserFilter string
+	// IDAttribute ユーザーを一意に識別する属性
+	IDAttribute string
+	// NameAttribute traQユーザー名にする属性
+	NameAttribute string
+	// DisplayNameAttribute 表示名にする属性
+	DisplayNameAttribute string
+	// EmailAttribute メールアドレスにする属性
+	EmailAttribute string
+	// IconAttribute アイコン画像にする属性
+	IconAttribute string
+	// GroupSync LDAPのグループの所属をユーザーグループに同期するかどうか
+	GroupSync bool
+	// GroupBaseDN グループ検索のベースDN
+	GroupBaseDN string
+	// GroupFilter ユーザーが所属するグループの検索フィルタ ユーザーのDNが埋め込まれます (例: (member=%s))
+	GroupFilter string
+	// GroupNameAttribute ユーザーグループ名にする属性
+	GroupNameAttribute string
+	// GroupType 同期対象のユーザーグループの種類
+	//
+	// この種類のユーザーグループのうち、LDAPのグループと同名のものに所属を同期します。
+	GroupType string
+	// RegisterUserIfNotFound traQユーザーが存在しない場合に作成するかどうか
+	RegisterUserIfNotFound bool
+}
+
+func (c LDAPProviderConfig) Valid() bool {
+	return len(c.URL) > 0 && (len(c.UserDNTemplate) > 0 || (len(c.UserBaseDN) > 0 && len(c.UserFilter) > 0))
+}
+
+type ldapUserInfo struct {
+	dn          string
+	id          string
+	name        string
+	displayName string
+	email       string
+	icon        []byte
+	groups      []string
+}
+
+func (u *ldapUserInfo) GetProviderName() string {
+	return LDAPProviderName
+}
+
+func (u *ldapUserInfo) GetID() string {
+	return u.id
+}
+
+func (u *ldapUserInfo) GetRawName() string {
+	return u.name
+}
+
+func (u *ldapUserInfo) GetName() string {
+	regex := regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	s := regex.ReplaceAllLiteralString(u.name, "_")
+	if us := utf8string.NewString(s); us.RuneCount() > 32 {
+		s = us.Slice(0, 32)
+	}
+	return s
+}
+
+func (u *ldapUserInfo) GetDisplayName() string {
+	if s := utf8string.NewString(u.displayName); s.RuneCount() > 64 {
+		return s.Slice(0, 64)
+	}
+	return u.displayName
+}
+
+func (u *ldapUserInfo) GetProfileImage() ([]byte, error) {
+	return u.icon, nil
+}
+
+func (u *ldapUserInfo) IsLoginAllowedUser() bool {
+	return true
+}
+
+func NewLDAPProvider(repo repository.Repository, fm file.Manager, logger *zap.Logger, config LDAPProviderConfig) *LDAPProvider {
+	return &LDAPProvider{
+		repo:   repo,
+		fm:     fm,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Login LDAPにバインドしてユーザー名とパスワードを検証し、対応するtraQユーザーを返します
+//
+// LDAPにユーザーが存在しない、或いはパスワードが間違っている場合、ErrLDAPInvalidCredentialsを返します。
+// traQユーザーが存在しない場合、RegisterUserIfNotFoundが有効であれば作成し、無効であればErrLDAPSignUpNotAllowedを返します。
+// 既存のtraQユーザーの表示名とメールアドレスは、バインドに成功する度にLDAPの属性に同期します。
+// 同名のtraQユーザーが既に存在する場合、repository.ErrAlreadyExistsを返します。
+func (p *LDAPProvider) Login(name, password string) (model.UserInfo, error) {
+	lu, err := p.authenticate(name, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := p.repo.GetUserByExternalID(LDAPProviderName, lu.GetID(), false)
+	if err != nil {
+		if err != repository.ErrNotFound {
+			return nil, err
+		}
+		if !p.config.RegisterUserIfNotFound {
+			return nil, ErrLDAPSignUpNotAllowed
+		}
+
+		user, err = createExternalUser(p.repo, p.fm, lu)
+		if err != nil {
+			return nil, err
+		}
+		if len(lu.email) > 0 {
+			if err := p.repo.UpdateUserEmailSetting(user.GetID(), repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom(lu.email)}); err != nil {
+				return nil, err
+			}
+		}
+		p.L().Info("New user was created by external auth",
+			zap.Stringer("id", user.GetID()),
+			zap.String("name", user.GetName()),
+			zap.String("providerName", lu.GetProviderName()),
+			zap.String("externalId", lu.GetID()),
+			zap.String("externalName", lu.GetRawName()))
+	} else if err := p.syncAttributes(user, lu); err != nil {
+		return nil, err
+	}
+
+	if p.config.GroupSync {
+		if err := p.syncGroups(user.GetID(), lu.groups); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// authenticate LDAPにユーザーのDNでバインドし、ユーザーの情報を取得します
+func (p *LDAPProvider) authenticate(name, password string) (*ldapUserInfo, error) {
+	if len(name) == 0 || len(password) == 0 {
+		// パスワードが空の場合は匿名バインドとして成功してしまうため弾く
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	attrs := p.userAttributes()
+	var entry *ldap.Entry
+	if len(p.config.UserDNTemplate) > 0 {
+		// テンプレートのDNで直接バインドして、自分のエントリを取得する
+		dn := fmt.Sprintf(p.config.UserDNTemplate, escapeDNValue(name))
+		if err := p.bind(conn, dn, password); err != nil {
+			return nil, err
+		}
+		entry, err = p.searchOne(conn, ldap.NewSearchRequest(
+			dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(ldapTimeout.Seconds()), false,
+			"(objectClass=*)", attrs, nil,
+		))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// サービスアカウント(または匿名)でユーザーを検索してから、そのDNでバインドする
+		if err := p.bindServiceAccount(conn); err != nil {
+			return nil, err
+		}
+		entry, err = p.searchOne(conn, ldap.NewSearchRequest(
+			p.config.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(ldapTimeout.Seconds()), false,
+			fmt.Sprintf(p.config.UserFilter, ldap.EscapeFilter(name)), attrs, nil,
+		))
+		if err != nil {
+			return nil, err
+		}
+		if err := p.bind(conn, entry.DN, password); err != nil {
+			return nil, err
+		}
+	}
+
+	u := &ldapUserInfo{
+		dn:          entry.DN,
+		id:          entry.GetAttributeValue(p.config.IDAttribute),
+		name:        entry.GetAttributeValue(p.config.NameAttribute),
+		displayName: entry.GetAttributeValue(p.config.DisplayNameAttribute),
+		email:       entry.GetAttributeValue(p.config.EmailAttribute),
+	}
+	if len(p.config.IconAttribute) > 0 {
+		if b := entry.GetRawAttributeValue(p.config.IconAttribute); len(b) > 0 {
+			u.icon = b
+		}
+	}
+	if len(u.id) == 0 {
+		u.id = entry.DN
+	}
+	if len(u.name) == 0 {
+		u.name = name
+	}
+	if len(u.displayName) == 0 {
+		u.displayName = u.name
+	}
+
+	if p.config.GroupSync {
+		u.groups, err = p.searchGroups(conn, entry.DN)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// syncAttributes LDAPの表示名とメールアドレスをtraQユーザーに同期します
+//
+// LDAPにメールアドレスが設定されていない場合、traQのメールアドレスは変更しません。
+func (p *LDAPProvider) syncAttributes(user model.UserInfo, lu *ldapUserInfo) error {
+	if displayName := lu.GetDisplayName(); user.GetDisplayName() != displayName {
+		if err := p.repo.UpdateUser(user.GetID(), repository.UpdateUserArgs{DisplayName: optional.StringFrom(displayName)}); err != nil {
+			return err
+		}
+	}
+
+	if len(lu.email) == 0 {
+		return nil
+	}
+	s, err := p.repo.GetUserEmailSetting(user.GetID())
+	if err != nil && err != repository.ErrNotFound {
+		return err
+	}
+	if s != nil && s.Address == lu.email {
+		return nil
+	}
+	return p.repo.UpdateUserEmailSetting(user.GetID(), repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom(lu.email)})
+}
+
+// searchGroups ユーザーが所属するLDAPのグループ名を取得します
+func (p *LDAPProvider) searchGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	// ユーザー自身の権限ではグループを参照できない場合があるため、サービスアカウントでバインドし直す
+	if len(p.config.BindDN) > 0 {
+		if err := p.bindServiceAccount(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		p.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(ldapTimeout.Seconds()), false,
+		fmt.Sprintf(p.config.GroupFilter, ldap.EscapeFilter(userDN)), []string{p.config.GroupNameAttribute}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf(ldapRequestErrorFormat, err)
+	}
+	groups := make([]string, 0, len(res.Entries))
+	for _, e := range res.Entries {
+		if name := e.GetAttributeValue(p.config.GroupNameAttribute); len(name) > 0 {
+			groups = append(groups, name)
+		}
+	}
+	return groups, nil
+}
+
+// syncGroups LDAPのグループの所属をユーザーグループに同期します
+//
+// 同期対象は種類がGroupTypeのユーザーグループのみで、存在しないユーザーグループは作成しません。
+func (p *LDAPProvider) syncGroups(userID uuid.UUID, groups []string) error {
+	names := make(map[string]bool, len(groups))
+	for _, name := range groups {
+		names[name] = true
+	}
+
+	// LDAPで所属しているグループに追加
+	for name := range names {
+		g, err := p.repo.GetUserGroupByName(name)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				continue
+			}
+			return err
+		}
+		if g.Type != p.config.GroupType || g.IsMember(userID) {
+			continue
+		}
+		if err := p.repo.AddUserToGroup(userID, g.ID, ""); err != nil {
+			return err
+		}
+	}
+
+	// LDAPで所属していないグループから削除
+	ids, err := p.repo.GetUserBelongingGroupIDs(userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		g, err := p.repo.GetUserGroup(id)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				continue
+			}
+			return err
+		}
+		if g.Type != p.config.GroupType || names[g.Name] {
+			continue
+		}
+		if err := p.repo.RemoveUserFromGroup(userID, g.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *LDAPProvider) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.config.InsecureSkipVerify} // nolint:gosec
+	conn, err := ldap.DialURL(p.config.URL, ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf(ldapRequestErrorFormat, err)
+	}
+	conn.SetTimeout(ldapTimeout)
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf(ldapRequestErrorFormat, err)
+		}
+	}
+	return conn, nil
+}
+
+func (p *LDAPProvider) bind(conn *ldap.Conn, dn, password string) error {
+	if err := conn.Bind(dn, password); err != nil {
+		if ldap.IsErrorAnyOf(err, ldap.LDAPResultInvalidCredentials, ldap.LDAPResultNoSuchObject, ldap.LDAPResultInvalidDNSyntax) {
+			return ErrLDAPInvalidCredentials
+		}
+		return fmt.Errorf(ldapRequestErrorFormat, err)
+	}
+	return nil
+}
+
+func (p *LDAPProvider) bindServiceAccount(conn *ldap.Conn) error {
+	var err error
+	if len(p.config.BindDN) > 0 {
+		err = conn.Bind(p.config.BindDN, p.config.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		return fmt.Errorf(ldapRequestErrorFormat, err)
+	}
+	return nil
+}
+
+// searchOne 検索結果がちょうど1件の場合にそのエントリを返します
+func (p *LDAPProvider) searchOne(conn *ldap.Conn, req *ldap.SearchRequest) (*ldap.Entry, error) {
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorAnyOf(err, ldap.LDAPResultNoSuchObject, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf(ldapRequestErrorFormat, err)
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrLDAPInvalidCredentials
+	}
+	return res.Entries[0], nil
+}
+
+func (p *LDAPProvider) userAttributes() []string {
+	attrs := make([]string, 0, 5)
+	for _, a := range []string{p.config.IDAttribute, p.config.NameAttribute, p.config.DisplayNameAttribute, p.config.EmailAttribute, p.config.IconAttribute} {
+		if len(a) > 0 {
+			attrs = append(attrs, a)
+		}
+	}
+	return attrs
+}
+
+func (p *LDAPProvider) L() *zap.Logger {
+	return p.logger
+}
+
+// escapeDNValue DNの属性値に含まれる特殊文字をエスケープします (RFC 4514)
+func escapeDNValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '+' || c == ',' || c == ';' || c == '<' || c == '>' || c == '\\' || c == '=':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case (c == ' ' || c == '#') && i == 0, c == ' ' && i == len(s)-1:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,666 @@
+package auth
+
+import (
+	"errors"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/utils/optional"
+	"go.uber.org/zap"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testLDAPEntry struct {
+	password string
+	attrs    map[string][]string
+}
+
+// testLDAPServer テスト用のインメモリLDAPサーバー
+//
+// Bind, Search, Unbindのみに対応しています。検索フィルタは(attr=value)の形式のみ解釈します。
+type testLDAPServer struct {
+	ln      net.Listener
+	entries map[string]*testLDAPEntry
+}
+
+func newTestLDAPServer(t *testing.T, entries map[string]*testLDAPEntry) *testLDAPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &testLDAPServer{ln: ln, entries: entries}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *testLDAPServer) URL() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+func (s *testLDAPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		p, err := ber.ReadPacket(conn)
+		if err != nil || len(p.Children) < 2 {
+			return
+		}
+		id := p.Children[0].Value.(int64)
+		op := p.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			code := int64(ldap.LDAPResultSuccess)
+			if len(dn) > 0 || len(password) > 0 {
+				if e, ok := s.entries[dn]; !ok || e.password != password {
+					code = ldap.LDAPResultInvalidCredentials
+				}
+			}
+			_, _ = conn.Write(testLDAPResult(id, ldap.ApplicationBindResponse, code).Bytes())
+		case ldap.ApplicationSearchRequest:
+			base := op.Children[0].Data.String()
+			scope := op.Children[1].Value.(int64)
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			var attrs []string
+			for _, a := range op.Children[7].Children {
+				attrs = append(attrs, a.Data.String())
+			}
+
+			code := int64(ldap.LDAPResultSuccess)
+			if scope == ldap.ScopeBaseObject {
+				if e, ok := s.entries[base]; ok {
+					_, _ = conn.Write(testLDAPEntryResult(id, base, e, attrs).Bytes())
+				} else {
+					code = ldap.LDAPResultNoSuchObject
+				}
+			} else {
+				for dn, e := range s.entries {
+					if strings.HasSuffix(dn, ","+base) && testLDAPMatch(e, filter) {
+						_, _ = conn.Write(testLDAPEntryResult(id, dn, e, attrs).Bytes())
+					}
+				}
+			}
+			_, _ = conn.Write(testLDAPResult(id, ldap.ApplicationSearchResultDone, code).Bytes())
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func testLDAPMatch(e *testLDAPEntry, filter string) bool {
+	kv := strings.SplitN(strings.TrimSuffix(strings.TrimPrefix(filter, "("), ")"), "=", 2)
+	if len(kv) != 2 {
+		return false
+	}
+	for _, v := range e.attrs[kv[0]] {
+		if v == kv[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func testLDAPMessage(id int64, op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	p.AppendChild(op)
+	return p
+}
+
+func testLDAPResult(id int64, tag ber.Tag, code int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return testLDAPMessage(id, op)
+}
+
+func testLDAPEntryResult(id int64, dn string, e *testLDAPEntry, attrs []string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "objectName"))
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for _, name := range attrs {
+		values, ok := e.attrs[name]
+		if !ok {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	op.AppendChild(list)
+	return testLDAPMessage(id, op)
+}
+
+func testLDAPDirectory() map[string]*testLDAPEntry {
+	return map[string]*testLDAPEntry{
+		"cn=admin,dc=example,dc=com": {
+			password: "adminpass",
+		},
+		"uid=alice,ou=people,dc=example,dc=com": {
+			password: "alicepass",
+			attrs: map[string][]string{
+				"uid":         {"alice"},
+				"cn":          {"Alice Liddell"},
+				"mail":        {"alice@example.com"},
+				"jpegPhoto":   {"\xff\xd8\xff\xe0"},
+				"objectClass": {"person"},
+			},
+		},
+		"uid=bob,ou=people,dc=example,dc=com": {
+			password: "bobpass",
+			attrs: map[string][]string{
+				"uid":         {"bob"},
+				"objectClass": {"person"},
+			},
+		},
+		"cn=developers,ou=groups,dc=example,dc=com": {
+			attrs: map[string][]string{
+				"cn":     {"developers"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+			},
+		},
+		"cn=admins,ou=groups,dc=example,dc=com": {
+			attrs: map[string][]string{
+				"cn":     {"admins"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com"},
+			},
+		},
+	}
+}
+
+func testLDAPConfig(url string) LDAPProviderConfig {
+	return LDAPProviderConfig{
+		URL:                  url,
+		IDAttribute:          "uid",
+		NameAttribute:        "uid",
+		DisplayNameAttribute: "cn",
+		EmailAttribute:       "mail",
+		IconAttribute:        "jpegPhoto",
+		GroupBaseDN:          "ou=groups,dc=example,dc=com",
+		GroupFilter:          "(member=%s)",
+		GroupNameAttribute:   "cn",
+		GroupType:            "ldap",
+	}
+}
+
+func TestLDAPProviderConfig_Valid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.False(LDAPProviderConfig{}.Valid())
+	assert.False(LDAPProviderConfig{UserDNTemplate: "uid=%s,dc=example,dc=com"}.Valid())
+	assert.False(LDAPProviderConfig{URL: "ldap://localhost"}.Valid())
+	assert.False(LDAPProviderConfig{URL: "ldap://localhost", UserBaseDN: "dc=example,dc=com"}.Valid())
+	assert.True(LDAPProviderConfig{URL: "ldap://localhost", UserDNTemplate: "uid=%s,dc=example,dc=com"}.Valid())
+	assert.True(LDAPProviderConfig{URL: "ldap://localhost", UserBaseDN: "dc=example,dc=com", UserFilter: "(uid=%s)"}.Valid())
+}
+
+func TestLDAPProvider_authenticate(t *testing.T) {
+	t.Parallel()
+	s := newTestLDAPServer(t, testLDAPDirectory())
+
+	t.Run("dn template", func(t *testing.T) {
+		t.Parallel()
+		config := testLDAPConfig(s.URL())
+		config.UserDNTemplate = "uid=%s,ou=people,dc=example,dc=com"
+		p := NewLDAPProvider(nil, nil, nil, config)
+
+		t.Run("success", func(t *testing.T) {
+			t.Parallel()
+			assert := assert.New(t)
+
+			u, err := p.authenticate("alice", "alicepass")
+			if assert.NoError(err) {
+				assert.Equal("uid=alice,ou=people,dc=example,dc=com", u.dn)
+				assert.Equal(LDAPProviderName, u.GetProviderName())
+				assert.Equal("alice", u.GetID())
+				assert.Equal("alice", u.GetName())
+				assert.Equal("Alice Liddell", u.GetDisplayName())
+				assert.Equal("alice@example.com", u.email)
+				assert.Equal([]byte("\xff\xd8\xff\xe0"), u.icon)
+				assert.Empty(u.groups)
+			}
+		})
+
+		t.Run("missing attributes", func(t *testing.T) {
+			t.Parallel()
+			assert := assert.New(t)
+
+			u, err := p.authenticate("bob", "bobpass")
+			if assert.NoError(err) {
+				assert.Equal("bob", u.GetID())
+				assert.Equal("bob", u.GetName())
+				assert.Equal("bob", u.GetDisplayName())
+				assert.Empty(u.email)
+				assert.Nil(u.icon)
+			}
+		})
+
+		t.Run("wrong password", func(t *testing.T) {
+			t.Parallel()
+			_, err := p.authenticate("alice", "wrong")
+			assert.Equal(t, ErrLDAPInvalidCredentials, err)
+		})
+
+		t.Run("unknown user", func(t *testing.T) {
+			t.Parallel()
+			_, err := p.authenticate("carol", "carolpass")
+			assert.Equal(t, ErrLDAPInvalidCredentials, err)
+		})
+
+		t.Run("empty password", func(t *testing.T) {
+			t.Parallel()
+			_, err := p.authenticate("alice", "")
+			assert.Equal(t, ErrLDAPInvalidCredentials, err)
+		})
+	})
+
+	t.Run("search filter", func(t *testing.T) {
+		t.Parallel()
+		config := testLDAPConfig(s.URL())
+		config.BindDN = "cn=admin,dc=example,dc=com"
+		config.BindPassword = "adminpass"
+		config.UserBaseDN = "ou=people,dc=example,dc=com"
+		config.UserFilter = "(uid=%s)"
+		config.GroupSync = true
+		p := NewLDAPProvider(nil, nil, nil, config)
+
+		t.Run("success", func(t *testing.T) {
+			t.Parallel()
+			assert := assert.New(t)
+
+			u, err := p.authenticate("alice", "alicepass")
+			if assert.NoError(err) {
+				assert.Equal("uid=alice,ou=people,dc=example,dc=com", u.dn)
+				assert.Equal("alice", u.GetID())
+				assert.Equal("Alice Liddell", u.GetDisplayName())
+				assert.ElementsMatch([]string{"developers", "admins"}, u.groups)
+			}
+		})
+
+		t.Run("wrong password", func(t *testing.T) {
+			t.Parallel()
+			_, err := p.authenticate("bob", "alicepass")
+			assert.Equal(t, ErrLDAPInvalidCredentials, err)
+		})
+
+		t.Run("unknown user", func(t *testing.T) {
+			t.Parallel()
+			_, err := p.authenticate("carol", "carolpass")
+			assert.Equal(t, ErrLDAPInvalidCredentials, err)
+		})
+
+		t.Run("wrong bind password", func(t *testing.T) {
+			t.Parallel()
+			config := config
+			config.BindPassword = "wrong"
+			_, err := NewLDAPProvider(nil, nil, nil, config).authenticate("alice", "alicepass")
+			assert.Error(t, err)
+			assert.NotEqual(t, ErrLDAPInvalidCredentials, err)
+		})
+	})
+}
+
+// testLDAPRepository ログインに使うメソッドのみモックに委譲するリポジトリ
+type testLDAPRepository struct {
+	repository.Repository
+	user  *mock_repository.MockUserRepository
+	email *mock_repository.MockUserEmailSettingRepository
+	group *mock_repository.MockUserGroupRepository
+}
+
+func newTestLDAPRepository(t *testing.T) *testLDAPRepository {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+	return &testLDAPRepository{
+		user:  mock_repository.NewMockUserRepository(ctrl),
+		email: mock_repository.NewMockUserEmailSettingRepository(ctrl),
+		group: mock_repository.NewMockUserGroupRepository(ctrl),
+	}
+}
+
+func (r *testLDAPRepository) CreateUser(args repository.CreateUserArgs) (model.UserInfo, error) {
+	return r.user.CreateUser(args)
+}
+
+func (r *testLDAPRepository) GetUserByExternalID(providerName, externalID string, withProfile bool) (model.UserInfo, error) {
+	return r.user.GetUserByExternalID(providerName, externalID, withProfile)
+}
+
+func (r *testLDAPRepository) UpdateUser(id uuid.UUID, args repository.UpdateUserArgs) error {
+	return r.user.UpdateUser(id, args)
+}
+
+func (r *testLDAPRepository) GetUserEmailSetting(userID uuid.UUID) (*model.UserEmailSetting, error) {
+	return r.email.GetUserEmailSetting(userID)
+}
+
+func (r *testLDAPRepository) UpdateUserEmailSetting(userID uuid.UUID, args repository.UpdateUserEmailSettingArgs) error {
+	return r.email.UpdateUserEmailSetting(userID, args)
+}
+
+func (r *testLDAPRepository) GetUserGroup(id uuid.UUID) (*model.UserGroup, error) {
+	return r.group.GetUserGroup(id)
+}
+
+func (r *testLDAPRepository) GetUserGroupByName(name string) (*model.UserGroup, error) {
+	return r.group.GetUserGroupByName(name)
+}
+
+func (r *testLDAPRepository) GetUserBelongingGroupIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	return r.group.GetUserBelongingGroupIDs(userID)
+}
+
+func (r *testLDAPRepository) AddUserToGroup(userID, groupID uuid.UUID, role string) error {
+	return r.group.AddUserToGroup(userID, groupID, role)
+}
+
+func (r *testLDAPRepository) RemoveUserFromGroup(userID, groupID uuid.UUID) error {
+	return r.group.RemoveUserFromGroup(userID, groupID)
+}
+
+type testLDAPFile struct {
+	model.File
+	id uuid.UUID
+}
+
+func (f *testLDAPFile) GetID() uuid.UUID {
+	return f.id
+}
+
+// testLDAPFileManager 保存したファイルを記録するだけのファイルマネージャー
+type testLDAPFileManager struct {
+	file.Manager
+	sync.Mutex
+	saved []uuid.UUID
+}
+
+func (m *testLDAPFileManager) Save(file.SaveArgs) (model.File, error) {
+	m.Lock()
+	defer m.Unlock()
+	id := uuid.Must(uuid.NewV4())
+	m.saved = append(m.saved, id)
+	return &testLDAPFile{id: id}, nil
+}
+
+func newTestLDAPGroup(name, gType string, members ...uuid.UUID) *model.UserGroup {
+	g := &model.UserGroup{ID: uuid.Must(uuid.NewV4()), Name: name, Type: gType}
+	for _, id := range members {
+		g.Members = append(g.Members, &model.UserGroupMember{GroupID: g.ID, UserID: id})
+	}
+	return g
+}
+
+func TestLDAPProvider_Login(t *testing.T) {
+	t.Parallel()
+	s := newTestLDAPServer(t, testLDAPDirectory())
+
+	setup := func(t *testing.T, register, groupSync bool) (*LDAPProvider, *testLDAPRepository, *testLDAPFileManager) {
+		t.Helper()
+		config := testLDAPConfig(s.URL())
+		config.BindDN = "cn=admin,dc=example,dc=com"
+		config.BindPassword = "adminpass"
+		config.UserBaseDN = "ou=people,dc=example,dc=com"
+		config.UserFilter = "(uid=%s)"
+		config.GroupSync = groupSync
+		config.RegisterUserIfNotFound = register
+		repo := newTestLDAPRepository(t)
+		fm := &testLDAPFileManager{}
+		return NewLDAPProvider(repo, fm, zap.NewNop(), config), repo, fm
+	}
+
+	t.Run("first login", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		p, repo, fm := setup(t, true, true)
+		user := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "alice", DisplayName: "Alice Liddell"}
+		developers := newTestLDAPGroup("developers", "ldap")
+
+		var created repository.CreateUserArgs
+		repo.user.EXPECT().GetUserByExternalID(LDAPProviderName, "alice", false).Return(nil, repository.ErrNotFound)
+		repo.user.EXPECT().CreateUser(gomock.Any()).DoAndReturn(func(args repository.CreateUserArgs) (model.UserInfo, error) {
+			created = args
+			return user, nil
+		})
+		repo.email.EXPECT().UpdateUserEmailSetting(user.ID, repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom("alice@example.com")}).Return(nil)
+		repo.group.EXPECT().GetUserGroupByName("developers").Return(developers, nil)
+		repo.group.EXPECT().GetUserGroupByName("admins").Return(nil, repository.ErrNotFound)
+		repo.group.EXPECT().AddUserToGroup(user.ID, developers.ID, "").Return(nil)
+		repo.group.EXPECT().GetUserBelongingGroupIDs(user.ID).Return([]uuid.UUID{developers.ID}, nil)
+		repo.group.EXPECT().GetUserGroup(developers.ID).Return(newTestLDAPGroup("developers", "ldap", user.ID), nil)
+
+		u, err := p.Login("alice", "alicepass")
+		if assert.NoError(err) {
+			assert.Equal(user.ID, u.GetID())
+			assert.Equal("alice", created.Name)
+			assert.Equal("Alice Liddell", created.DisplayName)
+			if assert.NotNil(created.ExternalLogin) {
+				assert.Equal(LDAPProviderName, created.ExternalLogin.ProviderName)
+				assert.Equal("alice", created.ExternalLogin.ExternalID)
+				assert.Equal(model.JSON{"externalName": "alice"}, created.ExternalLogin.Extra)
+			}
+			if assert.Len(fm.saved, 1) {
+				assert.Equal(fm.saved[0], created.IconFileID)
+			}
+		}
+	})
+
+	t.Run("first login (sign up not allowed)", func(t *testing.T) {
+		t.Parallel()
+		p, repo, _ := setup(t, false, true)
+
+		repo.user.EXPECT().GetUserByExternalID(LDAPProviderName, "alice", false).Return(nil, repository.ErrNotFound)
+
+		_, err := p.Login("alice", "alicepass")
+		assert.Equal(t, ErrLDAPSignUpNotAllowed, err)
+	})
+
+	t.Run("existing user", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		p, repo, _ := setup(t, false, true)
+		user := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "alice", DisplayName: "Alice Liddell"}
+		developers := newTestLDAPGroup("developers", "ldap", user.ID)
+		admins := newTestLDAPGroup("admins", "ldap")
+		old := newTestLDAPGroup("old", "ldap", user.ID)
+		club := newTestLDAPGroup("club", "", user.ID)
+
+		repo.user.EXPECT().GetUserByExternalID(LDAPProviderName, "alice", false).Return(user, nil)
+		repo.email.EXPECT().GetUserEmailSetting(user.ID).Return(&model.UserEmailSetting{UserID: user.ID, Address: "alice@example.com"}, nil)
+		repo.group.EXPECT().GetUserGroupByName("developers").Return(developers, nil)
+		repo.group.EXPECT().GetUserGroupByName("admins").Return(admins, nil)
+		repo.group.EXPECT().AddUserToGroup(user.ID, admins.ID, "").Return(nil)
+		repo.group.EXPECT().GetUserBelongingGroupIDs(user.ID).Return([]uuid.UUID{developers.ID, admins.ID, old.ID, club.ID}, nil)
+		repo.group.EXPECT().GetUserGroup(developers.ID).Return(developers, nil)
+		repo.group.EXPECT().GetUserGroup(admins.ID).Return(admins, nil)
+		repo.group.EXPECT().GetUserGroup(old.ID).Return(old, nil)
+		repo.group.EXPECT().GetUserGroup(club.ID).Return(club, nil)
+		repo.group.EXPECT().RemoveUserFromGroup(user.ID, old.ID).Return(nil)
+
+		u, err := p.Login("alice", "alicepass")
+		if assert.NoError(err) {
+			assert.Equal(user.ID, u.GetID())
+		}
+	})
+
+	t.Run("existing user (group sync disabled)", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		p, repo, _ := setup(t, false, false)
+		user := &model.User{ID: uuid.Must(uuid.NewV4()), Name: "alice", DisplayName: "Alice Liddell"}
+
+		repo.user.EXPECT().GetUserByExternalID(LDAPProviderName, "alice", false).Return(user, nil)
+		repo.email.EXPECT().GetUserEmailSetting(user.ID).Return(&model.UserEmailSetting{UserID: user.ID, Address: "alice@example.com"}, nil)
+
+		u, err := p.Login("alice", "alicepass")
+		if assert.NoError(err) {
+			assert.Equal(user.ID, u.GetID())
+		}
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		t.Parallel()
+		p, _, _ := setup(t, true, true)
+
+		_, err := p.Login("alice", "wrong")
+		assert.Equal(t, ErrLDAPInvalidCredentials, err)
+	})
+}
+
+func TestLDAPProvider_syncGroups(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) (*LDAPProvider, *testLDAPRepository) {
+		t.Helper()
+		repo := newTestLDAPRepository(t)
+		return NewLDAPProvider(repo, nil, nil, LDAPProviderConfig{GroupType: "ldap"}), repo
+	}
+
+	t.Run("add and remove", func(t *testing.T) {
+		t.Parallel()
+		p, repo := setup(t)
+		userID := uuid.Must(uuid.NewV4())
+		joined := newTestLDAPGroup("joined", "ldap", userID)
+		added := newTestLDAPGroup("added", "ldap")
+		otherType := newTestLDAPGroup("other-type", "")
+		removed := newTestLDAPGroup("removed", "ldap", userID)
+		kept := newTestLDAPGroup("kept", "", userID)
+
+		repo.group.EXPECT().GetUserGroupByName("joined").Return(joined, nil)
+		repo.group.EXPECT().GetUserGroupByName("added").Return(added, nil)
+		repo.group.EXPECT().GetUserGroupByName("other-type").Return(otherType, nil)
+		repo.group.EXPECT().GetUserGroupByName("unknown").Return(nil, repository.ErrNotFound)
+		repo.group.EXPECT().AddUserToGroup(userID, added.ID, "").Return(nil)
+		repo.group.EXPECT().GetUserBelongingGroupIDs(userID).Return([]uuid.UUID{joined.ID, added.ID, removed.ID, kept.ID}, nil)
+		repo.group.EXPECT().GetUserGroup(joined.ID).Return(joined, nil)
+		repo.group.EXPECT().GetUserGroup(added.ID).Return(added, nil)
+		repo.group.EXPECT().GetUserGroup(removed.ID).Return(removed, nil)
+		repo.group.EXPECT().GetUserGroup(kept.ID).Return(kept, nil)
+		repo.group.EXPECT().RemoveUserFromGroup(userID, removed.ID).Return(nil)
+
+		assert.NoError(t, p.syncGroups(userID, []string{"joined", "added", "other-type", "unknown", "joined"}))
+	})
+
+	t.Run("no groups", func(t *testing.T) {
+		t.Parallel()
+		p, repo := setup(t)
+		userID := uuid.Must(uuid.NewV4())
+		removed := newTestLDAPGroup("removed", "ldap", userID)
+
+		repo.group.EXPECT().GetUserBelongingGroupIDs(userID).Return([]uuid.UUID{removed.ID}, nil)
+		repo.group.EXPECT().GetUserGroup(removed.ID).Return(removed, nil)
+		repo.group.EXPECT().RemoveUserFromGroup(userID, removed.ID).Return(nil)
+
+		assert.NoError(t, p.syncGroups(userID, nil))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		t.Parallel()
+		p, repo := setup(t)
+		userID := uuid.Must(uuid.NewV4())
+		errMock := errors.New("mock error")
+
+		repo.group.EXPECT().GetUserGroupByName("joined").Return(nil, errMock)
+
+		assert.Equal(t, errMock, p.syncGroups(userID, []string{"joined"}))
+	})
+}
+
+func TestLDAPProvider_syncAttributes(t *testing.T) {
+	t.Parallel()
+
+	setup := func(t *testing.T) (*LDAPProvider, *testLDAPRepository) {
+		t.Helper()
+		repo := newTestLDAPRepository(t)
+		return NewLDAPProvider(repo, nil, nil, LDAPProviderConfig{}), repo
+	}
+
+	t.Run("changed", func(t *testing.T) {
+		t.Parallel()
+		p, repo := setup(t)
+		user := &model.User{ID: uuid.Must(uuid.NewV4()), DisplayName: "Alice"}
+
+		repo.user.EXPECT().UpdateUser(user.ID, repository.UpdateUserArgs{DisplayName: optional.StringFrom("Alice Liddell")}).Return(nil)
+		repo.email.EXPECT().GetUserEmailSetting(user.ID).Return(&model.UserEmailSetting{UserID: user.ID, Address: "old@example.com"}, nil)
+		repo.email.EXPECT().UpdateUserEmailSetting(user.ID, repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom("alice@example.com")}).Return(nil)
+
+		assert.NoError(t, p.syncAttributes(user, &ldapUserInfo{displayName: "Alice Liddell", email: "alice@example.com"}))
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		t.Parallel()
+		p, repo := setup(t)
+		user := &model.User{ID: uuid.Must(uuid.NewV4()), DisplayName: "Alice Liddell"}
+
+		repo.email.EXPECT().GetUserEmailSetting(user.ID).Return(&model.UserEmailSetting{UserID: user.ID, Address: "alice@example.com"}, nil)
+
+		assert.NoError(t, p.syncAttributes(user, &ldapUserInfo{displayName: "Alice Liddell", email: "alice@example.com"}))
+	})
+
+	t.Run("no email", func(t *testing.T) {
+		t.Parallel()
+		p, _ := setup(t)
+		user := &model.User{ID: uuid.Must(uuid.NewV4()), DisplayName: "bob"}
+
+		assert.NoError(t, p.syncAttributes(user, &ldapUserInfo{displayName: "bob"}))
+	})
+
+	t.Run("email setting not found", func(t *testing.T) {
+		t.Parallel()
+		p, repo := setup(t)
+		user := &model.User{ID: uuid.Must(uuid.NewV4()), DisplayName: "Alice Liddell"}
+
+		repo.email.EXPECT().GetUserEmailSetting(user.ID).Return(nil, repository.ErrNotFound)
+		repo.email.EXPECT().UpdateUserEmailSetting(user.ID, repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom("alice@example.com")}).Return(nil)
+
+		assert.NoError(t, p.syncAttributes(user, &ldapUserInfo{displayName: "Alice Liddell", email: "alice@example.com"}))
+	})
+}
+
+func TestLdapUserInfo_GetName(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal("alice", (&ldapUserInfo{name: "alice"}).GetName())
+	assert.Equal("alice_liddell", (&ldapUserInfo{name: "alice.liddell"}).GetName())
+	assert.Equal(strings.Repeat("a", 32), (&ldapUserInfo{name: strings.Repeat("a", 40)}).GetName())
+}
+
+func TestEscapeDNValue(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	assert.Equal("alice", escapeDNValue("alice"))
+	assert.Equal(`a\,b\+c\=d`, escapeDNValue("a,b+c=d"))
+	assert.Equal(`\#a\\b\ `, escapeDNValue("#a\\b "))
+	assert.Equal(`\ a`, escapeDNValue(" a"))
+	assert.Equal(`a\00b`, escapeDNValue("a\x00b"))
+}
@@ -184,32 +184,7 @@ func defaultCallbackHandler(p Provider, oac *oauth2.Config, repo repository.Repo
 				return herror.Unauthorized("You are not a member of traQ")
 			}
 
-			args := repository.CreateUserArgs{
-				Name:        tu.GetName(),
-				DisplayName: tu.GetDisplayName(),
-				Role:        role.User,
-				ExternalLogin: &model.ExternalProviderUser{
-					ProviderName: tu.GetProviderName(),
-					ExternalID:   tu.GetID(),
-					Extra:        model.JSON{"externalName": tu.GetRawName()},
-				},
-			}
-
-			if b, err := tu.GetProfileImage(); err == nil && b != nil {
-				fid, err := processProfileIcon(fm, b)
-				if err == nil {
-					args.IconFileID = fid
-				}
-			}
-			if args.IconFileID == uuid.Nil {
-				fid, err := file.GenerateIconFile(fm, tu.GetName())
-				if err != nil {
-					return herror.InternalServerError(err)
-				}
-				args.IconFileID = fid
-			}
-
-			user, err = repo.CreateUser(args)
+			user, err = createExternalUser(repo, fm, tu)
 			if err != nil {
 				if err == repository.ErrAlreadyExists {
 					return herror.Conflict("name conflicts") // TODO 名前被りをどうするか
@@ -243,6 +218,36 @@ func defaultCallbackHandler(p Provider, oac *oauth2.Config, repo repository.Repo
 	}
 }
 
+// createExternalUser 外部認証のユーザー情報からtraQユーザーを作成します
+func createExternalUser(repo repository.Repository, fm file.Manager, tu UserInfo) (model.UserInfo, error) {
+	args := repository.CreateUserArgs{
+		Name:        tu.GetName(),
+		DisplayName: tu.GetDisplayName(),
+		Role:        role.User,
+		ExternalLogin: &model.ExternalProviderUser{
+			ProviderName: tu.GetProviderName(),
+			ExternalID:   tu.GetID(),
+			Extra:        model.JSON{"externalName": tu.GetRawName()},
+		},
+	}
+
+	if b, err := tu.GetProfileImage(); err == nil && b != nil {
+		fid, err := processProfileIcon(fm, b)
+		if err == nil {
+			args.IconFileID = fid
+		}
+	}
+	if args.IconFileID == uuid.Nil {
+		fid, err := file.GenerateIconFile(fm, tu.GetName())
+		if err != nil {
+			return nil, err
+		}
+		args.IconFileID = fid
+	}
+
+	return repo.CreateUser(args)
+}
+
 func processProfileIcon(m file.Manager, src []byte) (uuid.UUID, error) {
 	const maxImageSize = 256
 
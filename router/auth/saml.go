@@ -0,0 +1,383 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/utils/optional"
+	"go.uber.org/zap"
+	"golang.org/x/exp/utf8string"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const (
+	SAMLProviderName       = "saml"
+	samlRequestErrorFormat = "saml request error: %w"
+	samlCookieName         = "traq_saml_request"
+	samlMetadataTimeout    = 10 * time.Second
+)
+
+type SAMLProvider struct {
+	config    SAMLProviderConfig
+	repo      repository.Repository
+	fm        file.Manager
+	logger    *zap.Logger
+	sessStore session.Store
+	sp        saml.ServiceProvider
+}
+
+type SAMLProviderConfig struct {
+	// EntityID SPのエンティティID 空の場合はメタデータのURL
+	EntityID string
+	// MetadataURL SPメタデータのURL
+	MetadataURL string
+	// ACSURL アサーションコンシューマーサービスのURL
+	ACSURL string
+	// CertificateFile SPの証明書ファイル (PEM)
+	CertificateFile string
+	// PrivateKeyFile SPのRSA秘密鍵ファイル (PEM)
+	PrivateKeyFile string
+	// IDPMetadataURL IdPメタデータのURL
+	IDPMetadataURL string
+	// IDPMetadataFile IdPメタデータのファイル IDPMetadataURLより優先されます
+	IDPMetadataFile string
+	// AllowIDPInitiated IdP-initiated SSOを許可するかどうか
+	AllowIDPInitiated bool
+	// IDAttribute ユーザーを一意に識別する属性 空の場合はNameID
+	IDAttribute string
+	// NameAttribute traQユーザー名にする属性
+	NameAttribute string
+	// DisplayNameAttribute 表示名にする属性
+	DisplayNameAttribute string
+	// EmailAttribute メールアドレスにする属性
+	EmailAttribute string
+	// RegisterUserIfNotFound traQユーザーが存在しない場合に作成するかどうか
+	RegisterUserIfNotFound bool
+}
+
+func (c SAMLProviderConfig) Valid() bool {
+	return len(c.MetadataURL) > 0 && len(c.ACSURL) > 0 && len(c.CertificateFile) > 0 && len(c.PrivateKeyFile) > 0 && (len(c.IDPMetadataURL) > 0 || len(c.IDPMetadataFile) > 0)
+}
+
+type samlUserInfo struct {
+	id          string
+	name        string
+	displayName string
+	email       string
+}
+
+func (u *samlUserInfo) GetProviderName() string {
+	return SAMLProviderName
+}
+
+func (u *samlUserInfo) GetID() string {
+	return u.id
+}
+
+func (u *samlUserInfo) GetRawName() string {
+	return u.name
+}
+
+func (u *samlUserInfo) GetName() string {
+	regex := regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	s := regex.ReplaceAllLiteralString(u.name, "_")
+	if us := utf8string.NewString(s); us.RuneCount() > 32 {
+		s = us.Slice(0, 32)
+	}
+	return s
+}
+
+func (u *samlUserInfo) GetDisplayName() string {
+	if s := utf8string.NewString(u.displayName); s.RuneCount() > 64 {
+		return s.Slice(0, 64)
+	}
+	return u.displayName
+}
+
+func (u *samlUserInfo) GetProfileImage() ([]byte, error) {
+	return nil, nil
+}
+
+func (u *samlUserInfo) IsLoginAllowedUser() bool {
+	return true
+}
+
+func NewSAMLProvider(repo repository.Repository, fm file.Manager, logger *zap.Logger, sessStore session.Store, config SAMLProviderConfig) (*SAMLProvider, error) {
+	keyPair, err := tls.LoadX509KeyPair(config.CertificateFile, config.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saml sp key pair: %w", err)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse saml sp certificate: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("saml sp private key must be RSA")
+	}
+
+	var idpMetadata *saml.EntityDescriptor
+	if len(config.IDPMetadataFile) > 0 {
+		b, err := ioutil.ReadFile(config.IDPMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read saml idp metadata: %w", err)
+		}
+		idpMetadata, err = samlsp.ParseMetadata(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse saml idp metadata: %w", err)
+		}
+	} else {
+		u, err := url.Parse(config.IDPMetadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid saml idp metadata url: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), samlMetadataTimeout)
+		defer cancel()
+		idpMetadata, err = samlsp.FetchMetadata(ctx, http.DefaultClient, *u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch saml idp metadata: %w", err)
+		}
+	}
+
+	return newSAMLProvider(repo, fm, logger, sessStore, config, key, cert, idpMetadata)
+}
+
+func newSAMLProvider(repo repository.Repository, fm file.Manager, logger *zap.Logger, sessStore session.Store, config SAMLProviderConfig, key *rsa.PrivateKey, cert *x509.Certificate, idpMetadata *saml.EntityDescriptor) (*SAMLProvider, error) {
+	metadataURL, err := url.Parse(config.MetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid saml sp metadata url: %w", err)
+	}
+	acsURL, err := url.Parse(config.ACSURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid saml acs url: %w", err)
+	}
+
+	return &SAMLProvider{
+		config:    config,
+		repo:      repo,
+		fm:        fm,
+		logger:    logger,
+		sessStore: sessStore,
+		sp: saml.ServiceProvider{
+			EntityID:          config.EntityID,
+			Key:               key,
+			Certificate:       cert,
+			MetadataURL:       *metadataURL,
+			AcsURL:            *acsURL,
+			IDPMetadata:       idpMetadata,
+			AllowIDPInitiated: config.AllowIDPInitiated,
+		},
+	}, nil
+}
+
+// MetadataHandler SPメタデータのハンドラ
+func (p *SAMLProvider) MetadataHandler(c echo.Context) error {
+	b, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.Blob(http.StatusOK, "application/samlmetadata+xml", b)
+}
+
+// LoginHandler SP-initiated SSOを開始するハンドラ
+//
+// IdPのHTTP-Redirectバインディングにリダイレクトします。
+// SAMLではアカウント関連付けには対応していません。
+func (p *SAMLProvider) LoginHandler(c echo.Context) error {
+	if len(c.Request().Header.Get(echo.HeaderAuthorization)) > 0 {
+		return herror.BadRequest("Authorization Header must not be set.")
+	}
+	if isTrue(c.QueryParam("link")) {
+		return herror.BadRequest("account linking is not supported by saml")
+	}
+
+	sess, err := p.sessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if sess != nil && sess.UserID() != uuid.Nil {
+		return herror.BadRequest("You have already logged in. Please logout once.")
+	}
+
+	req, err := p.sp.MakeAuthenticationRequest(p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return herror.InternalServerError(fmt.Errorf(samlRequestErrorFormat, err))
+	}
+	redirectURL, err := req.Redirect("", &p.sp)
+	if err != nil {
+		return herror.InternalServerError(fmt.Errorf(samlRequestErrorFormat, err))
+	}
+
+	// ACSへのPOSTはIdPからのクロスサイトリクエストになるため、SameSite=Noneで発行する
+	cookie := &http.Cookie{
+		Name:     samlCookieName,
+		Value:    req.ID,
+		Path:     p.sp.AcsURL.Path,
+		Expires:  time.Now().Add(cookieMaxAge * time.Second),
+		MaxAge:   cookieMaxAge,
+		HttpOnly: true,
+	}
+	if p.sp.AcsURL.Scheme == "https" {
+		cookie.Secure = true
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	c.SetCookie(cookie)
+	return c.Redirect(http.StatusFound, redirectURL.String())
+}
+
+// ACSHandler アサーションコンシューマーサービスのハンドラ
+//
+// IdPからHTTP-POSTバインディングで送られたSAMLレスポンスを検証し、ログインします。
+// IdP-initiated SSOはAllowIDPInitiatedが有効な場合のみ受け付けます。
+func (p *SAMLProvider) ACSHandler(c echo.Context) error {
+	if err := c.Request().ParseForm(); err != nil {
+		return herror.BadRequest(err)
+	}
+
+	var possibleRequestIDs []string
+	if cookie, err := c.Cookie(samlCookieName); err == nil && len(cookie.Value) > 0 {
+		possibleRequestIDs = append(possibleRequestIDs, cookie.Value)
+		c.SetCookie(&http.Cookie{
+			Name:   samlCookieName,
+			Path:   p.sp.AcsURL.Path,
+			MaxAge: -1,
+		})
+	}
+
+	assertion, err := p.sp.ParseResponse(c.Request(), possibleRequestIDs)
+	if err != nil {
+		var ire *saml.InvalidResponseError
+		if errors.As(err, &ire) {
+			p.L().Info("invalid saml response", zap.Error(ire.PrivateErr))
+		}
+		return herror.Forbidden("invalid saml response")
+	}
+	if err := p.consumeAssertion(assertion); err != nil {
+		if err == repository.ErrAlreadyExists {
+			p.L().Info("saml assertion replay detected", zap.String("assertionId", assertion.ID))
+			return herror.Forbidden("invalid saml response")
+		}
+		if repository.IsArgError(err) {
+			return herror.Forbidden("invalid saml response")
+		}
+		return herror.InternalServerError(err)
+	}
+
+	tu := p.userInfoFromAssertion(assertion)
+	if len(tu.GetID()) == 0 || len(tu.GetName()) == 0 {
+		return herror.BadRequest("required saml attributes are missing")
+	}
+	if !tu.IsLoginAllowedUser() {
+		return c.String(http.StatusForbidden, "You are not permitted to access traQ")
+	}
+
+	user, err := p.repo.GetUserByExternalID(tu.GetProviderName(), tu.GetID(), false)
+	if err != nil {
+		if err != repository.ErrNotFound {
+			return herror.InternalServerError(err)
+		}
+
+		if !p.config.RegisterUserIfNotFound {
+			return herror.Unauthorized("You are not a member of traQ")
+		}
+
+		user, err = createExternalUser(p.repo, p.fm, tu)
+		if err != nil {
+			if err == repository.ErrAlreadyExists {
+				return herror.Conflict("name conflicts") // TODO 名前被りをどうするか
+			}
+			return herror.InternalServerError(err)
+		}
+		if len(tu.email) > 0 {
+			if err := p.repo.UpdateUserEmailSetting(user.GetID(), repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom(tu.email)}); err != nil {
+				return herror.InternalServerError(err)
+			}
+		}
+		p.L().Info("New user was created by external auth",
+			zap.Stringer("id", user.GetID()),
+			zap.String("name", user.GetName()),
+			zap.String("providerName", tu.GetProviderName()),
+			zap.String("externalId", tu.GetID()),
+			zap.String("externalName", tu.GetRawName()))
+	}
+
+	// ユーザーのアカウント状態の確認
+	if !user.IsActive() {
+		return herror.Forbidden("this account is currently suspended")
+	}
+
+	if _, err := p.sessStore.RenewSession(c, user.GetID()); err != nil {
+		return herror.InternalServerError(err)
+	}
+	p.L().Info("User was logged in by external auth",
+		zap.Stringer("id", user.GetID()),
+		zap.String("name", user.GetName()),
+		zap.String("providerName", tu.GetProviderName()),
+		zap.String("externalId", tu.GetID()),
+		zap.String("externalName", tu.GetRawName()))
+
+	return c.Redirect(http.StatusFound, "/")
+}
+
+// userInfoFromAssertion アサーションの属性をユーザー情報に変換します
+func (p *SAMLProvider) userInfoFromAssertion(assertion *saml.Assertion) *samlUserInfo {
+	attrs := map[string]string{}
+	for _, st := range assertion.AttributeStatements {
+		for _, attr := range st.Attributes {
+			if len(attr.Values) == 0 {
+				continue
+			}
+			attrs[attr.Name] = attr.Values[0].Value
+			if len(attr.FriendlyName) > 0 {
+				attrs[attr.FriendlyName] = attr.Values[0].Value
+			}
+		}
+	}
+
+	u := &samlUserInfo{
+		name:        attrs[p.config.NameAttribute],
+		displayName: attrs[p.config.DisplayNameAttribute],
+		email:       attrs[p.config.EmailAttribute],
+	}
+	if len(p.config.IDAttribute) > 0 {
+		u.id = attrs[p.config.IDAttribute]
+	} else if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		u.id = assertion.Subject.NameID.Value
+	}
+	if len(u.displayName) == 0 {
+		u.displayName = u.name
+	}
+	return u
+}
+
+// consumeAssertion アサーションを使用済みにします (リプレイ攻撃対策)
+//
+// 使用済みアサーションはDBに保存されるため、全インスタンスで共有されます。
+// 既に使用済みの場合、repository.ErrAlreadyExistsを返します。
+func (p *SAMLProvider) consumeAssertion(assertion *saml.Assertion) error {
+	expires := time.Now().Add(saml.MaxIssueDelay + saml.MaxClockSkew)
+	if assertion.Conditions != nil && assertion.Conditions.NotOnOrAfter.Add(saml.MaxClockSkew).After(expires) {
+		expires = assertion.Conditions.NotOnOrAfter.Add(saml.MaxClockSkew)
+	}
+	return p.repo.ConsumeSAMLAssertion(assertion.ID, expires)
+}
+
+func (p *SAMLProvider) L() *zap.Logger {
+	return p.logger
+}
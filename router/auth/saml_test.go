@@ -0,0 +1,425 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/logger"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"go.uber.org/zap"
+	"html"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	samlTestIDPOrigin = "https://idp.example.com"
+	samlTestSPOrigin  = "https://traq.example.com"
+)
+
+// samlTestRepository テスト用のリポジトリ GetUserByExternalIDとConsumeSAMLAssertionのみ実装しています
+type samlTestRepository struct {
+	repository.Repository
+	users      map[string]*model.User
+	assertions map[string]time.Time
+}
+
+func (r *samlTestRepository) ConsumeSAMLAssertion(id string, expiresAt time.Time) error {
+	if _, ok := r.assertions[id]; ok {
+		return repository.ErrAlreadyExists
+	}
+	r.assertions[id] = expiresAt
+	return nil
+}
+
+func (r *samlTestRepository) GetUserByExternalID(providerName, externalID string, _ bool) (model.UserInfo, error) {
+	if providerName != SAMLProviderName {
+		return nil, repository.ErrNotFound
+	}
+	u, ok := r.users[externalID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return u, nil
+}
+
+type samlTestSessionProvider struct {
+	session *saml.Session
+}
+
+func (p *samlTestSessionProvider) GetSession(http.ResponseWriter, *http.Request, *saml.IdpAuthnRequest) *saml.Session {
+	return p.session
+}
+
+type samlTestServiceProviderProvider struct {
+	sp *SAMLProvider
+}
+
+func (p *samlTestServiceProviderProvider) GetServiceProvider(*http.Request, string) (*saml.EntityDescriptor, error) {
+	return p.sp.sp.Metadata(), nil
+}
+
+// generateTestKeyPair テスト用のRSA鍵と自己署名証明書を生成します
+func generateTestKeyPair(t *testing.T, cn string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return key, cert
+}
+
+type samlTestEnv struct {
+	e     *echo.Echo
+	idp   *saml.IdentityProvider
+	sp    *SAMLProvider
+	sess  *samlTestSessionProvider
+	users map[string]*model.User
+}
+
+func setupSAMLTest(t *testing.T, config SAMLProviderConfig) *samlTestEnv {
+	t.Helper()
+	idpKey, idpCert := generateTestKeyPair(t, "idp.example.com")
+	spKey, spCert := generateTestKeyPair(t, "traq.example.com")
+
+	env := &samlTestEnv{
+		sess: &samlTestSessionProvider{
+			session: &saml.Session{
+				ID:         "session",
+				CreateTime: time.Now(),
+				ExpireTime: time.Now().Add(time.Hour),
+				NameID:     "alice@example.com",
+				UserName:   "alice",
+				CustomAttributes: []saml.Attribute{
+					{Name: "displayName", Values: []saml.AttributeValue{{Type: "xs:string", Value: "Alice Liddell"}}},
+					{Name: "mail", Values: []saml.AttributeValue{{Type: "xs:string", Value: "alice@example.com"}}},
+				},
+			},
+		},
+		users: map[string]*model.User{},
+	}
+	env.idp = &saml.IdentityProvider{
+		Key:             idpKey,
+		Certificate:     idpCert,
+		Logger:          logger.DefaultLogger,
+		MetadataURL:     mustParseURL(t, samlTestIDPOrigin+"/metadata"),
+		SSOURL:          mustParseURL(t, samlTestIDPOrigin+"/sso"),
+		SessionProvider: env.sess,
+		SignatureMethod: "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256",
+	}
+
+	config.MetadataURL = samlTestSPOrigin + "/api/auth/saml/metadata"
+	config.ACSURL = samlTestSPOrigin + "/api/auth/saml/acs"
+	config.NameAttribute = "uid"
+	config.DisplayNameAttribute = "displayName"
+	config.EmailAttribute = "mail"
+	repo := &samlTestRepository{users: env.users, assertions: map[string]time.Time{}}
+	sp, err := newSAMLProvider(repo, nil, zap.NewNop(), session.NewMemorySessionStore(), config, spKey, spCert, env.idp.Metadata())
+	require.NoError(t, err)
+	env.sp = sp
+	env.idp.ServiceProviderProvider = &samlTestServiceProviderProvider{sp: sp}
+
+	env.e = echo.New()
+	env.e.GET("/api/auth/saml", sp.LoginHandler)
+	env.e.POST("/api/auth/saml/acs", sp.ACSHandler)
+	env.e.GET("/api/auth/saml/metadata", sp.MetadataHandler)
+	return env
+}
+
+func mustParseURL(t *testing.T, s string) url.URL {
+	t.Helper()
+	u, err := url.Parse(s)
+	require.NoError(t, err)
+	return *u
+}
+
+var samlResponseInputRegex = regexp.MustCompile(`name="SAMLResponse" value="([^"]+)"`)
+
+// extractSAMLResponse IdPが返したHTMLフォームからSAMLResponseを取り出します
+func extractSAMLResponse(t *testing.T, body string) string {
+	t.Helper()
+	m := samlResponseInputRegex.FindStringSubmatch(body)
+	require.Len(t, m, 2, body)
+	return html.UnescapeString(m[1])
+}
+
+// startSPInitiated SP-initiated SSOを開始し、IdPのレスポンスとリクエストIDのクッキーを返します
+func (env *samlTestEnv) startSPInitiated(t *testing.T) (string, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	env.e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/saml", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == samlCookieName {
+			cookie = c
+		}
+	}
+	require.NotNil(t, cookie)
+
+	loc := rec.Header().Get(echo.HeaderLocation)
+	require.True(t, strings.HasPrefix(loc, samlTestIDPOrigin+"/sso?"), loc)
+	idpRec := httptest.NewRecorder()
+	env.idp.ServeSSO(idpRec, httptest.NewRequest(http.MethodGet, loc, nil))
+	require.Equal(t, http.StatusOK, idpRec.Code, idpRec.Body.String())
+	return extractSAMLResponse(t, idpRec.Body.String()), cookie
+}
+
+// startIDPInitiated IdP-initiated SSOのIdPのレスポンスを返します
+func (env *samlTestEnv) startIDPInitiated(t *testing.T) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	env.idp.ServeIDPInitiated(rec, httptest.NewRequest(http.MethodGet, samlTestIDPOrigin+"/login", nil), env.sp.sp.MetadataURL.String(), "")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	return extractSAMLResponse(t, rec.Body.String())
+}
+
+func (env *samlTestEnv) postACS(samlResponse string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/saml/acs", strings.NewReader(url.Values{"SAMLResponse": {samlResponse}}.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	env.e.ServeHTTP(rec, req)
+	return rec
+}
+
+func hasSessionCookie(rec *httptest.ResponseRecorder) bool {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == session.CookieName && len(c.Value) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSAMLProviderConfig_Valid(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	c := SAMLProviderConfig{
+		MetadataURL:     "https://traq.example.com/api/auth/saml/metadata",
+		ACSURL:          "https://traq.example.com/api/auth/saml/acs",
+		CertificateFile: "sp.crt",
+		PrivateKeyFile:  "sp.key",
+	}
+	assert.False(c.Valid())
+	c.IDPMetadataURL = "https://idp.example.com/metadata"
+	assert.True(c.Valid())
+	c.IDPMetadataURL = ""
+	c.IDPMetadataFile = "idp.xml"
+	assert.True(c.Valid())
+	c.PrivateKeyFile = ""
+	assert.False(c.Valid())
+}
+
+func TestSAMLProvider_MetadataHandler(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	env := setupSAMLTest(t, SAMLProviderConfig{})
+
+	rec := httptest.NewRecorder()
+	env.e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/saml/metadata", nil))
+	assert.Equal(http.StatusOK, rec.Code)
+	assert.Equal("application/samlmetadata+xml", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(rec.Body.String(), `entityID="`+samlTestSPOrigin+`/api/auth/saml/metadata"`)
+	assert.Contains(rec.Body.String(), `Location="`+samlTestSPOrigin+`/api/auth/saml/acs"`)
+}
+
+func TestSAMLProvider_LoginHandler(t *testing.T) {
+	t.Parallel()
+	env := setupSAMLTest(t, SAMLProviderConfig{})
+
+	t.Run("redirect to idp", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		rec := httptest.NewRecorder()
+		env.e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/saml", nil))
+		if assert.Equal(http.StatusFound, rec.Code) {
+			loc, err := url.Parse(rec.Header().Get(echo.HeaderLocation))
+			if assert.NoError(err) {
+				assert.Equal(samlTestIDPOrigin+"/sso", loc.Scheme+"://"+loc.Host+loc.Path)
+				assert.NotEmpty(loc.Query().Get("SAMLRequest"))
+			}
+			cookies := rec.Result().Cookies()
+			if assert.Len(cookies, 1) {
+				assert.Equal(samlCookieName, cookies[0].Name)
+				assert.Equal("/api/auth/saml/acs", cookies[0].Path)
+				assert.True(cookies[0].Secure)
+				assert.Equal(http.SameSiteNoneMode, cookies[0].SameSite)
+			}
+		}
+	})
+
+	t.Run("account linking", func(t *testing.T) {
+		t.Parallel()
+		rec := httptest.NewRecorder()
+		env.e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/saml?link=true", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestSAMLProvider_ACSHandler(t *testing.T) {
+	t.Parallel()
+
+	newUser := func(status model.UserAccountStatus) *model.User {
+		return &model.User{
+			ID:     uuid.Must(uuid.NewV4()),
+			Name:   "alice",
+			Status: status,
+			Role:   "user",
+		}
+	}
+
+	t.Run("sp-initiated", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		env := setupSAMLTest(t, SAMLProviderConfig{})
+		env.users["alice@example.com"] = newUser(model.UserAccountStatusActive)
+
+		res, cookie := env.startSPInitiated(t)
+		rec := env.postACS(res, cookie)
+		if assert.Equal(http.StatusFound, rec.Code, rec.Body.String()) {
+			assert.Equal("/", rec.Header().Get(echo.HeaderLocation))
+			assert.True(hasSessionCookie(rec))
+		}
+	})
+
+	t.Run("sp-initiated without request cookie", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAMLTest(t, SAMLProviderConfig{})
+		env.users["alice@example.com"] = newUser(model.UserAccountStatusActive)
+
+		res, _ := env.startSPInitiated(t)
+		rec := env.postACS(res, nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		env := setupSAMLTest(t, SAMLProviderConfig{})
+		env.users["alice@example.com"] = newUser(model.UserAccountStatusActive)
+
+		res, cookie := env.startSPInitiated(t)
+		assert.Equal(http.StatusFound, env.postACS(res, cookie).Code)
+		assert.Equal(http.StatusForbidden, env.postACS(res, cookie).Code)
+	})
+
+	t.Run("idp-initiated (disallowed)", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAMLTest(t, SAMLProviderConfig{})
+		env.users["alice@example.com"] = newUser(model.UserAccountStatusActive)
+
+		rec := env.postACS(env.startIDPInitiated(t), nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("idp-initiated (allowed)", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		env := setupSAMLTest(t, SAMLProviderConfig{AllowIDPInitiated: true})
+		env.users["alice@example.com"] = newUser(model.UserAccountStatusActive)
+
+		rec := env.postACS(env.startIDPInitiated(t), nil)
+		if assert.Equal(http.StatusFound, rec.Code, rec.Body.String()) {
+			assert.True(hasSessionCookie(rec))
+		}
+	})
+
+	t.Run("id attribute", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAMLTest(t, SAMLProviderConfig{AllowIDPInitiated: true, IDAttribute: "uid"})
+		env.users["alice"] = newUser(model.UserAccountStatusActive)
+
+		rec := env.postACS(env.startIDPInitiated(t), nil)
+		assert.Equal(t, http.StatusFound, rec.Code, rec.Body.String())
+	})
+
+	t.Run("tampered response", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAMLTest(t, SAMLProviderConfig{AllowIDPInitiated: true})
+		env.users["alice@example.com"] = newUser(model.UserAccountStatusActive)
+
+		// 別のIdPの鍵で署名されたレスポンス
+		other := setupSAMLTest(t, SAMLProviderConfig{AllowIDPInitiated: true})
+		other.idp.ServiceProviderProvider = &samlTestServiceProviderProvider{sp: env.sp}
+		rec := env.postACS(other.startIDPInitiated(t), nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAMLTest(t, SAMLProviderConfig{AllowIDPInitiated: true})
+
+		rec := env.postACS(env.startIDPInitiated(t), nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("suspended user", func(t *testing.T) {
+		t.Parallel()
+		env := setupSAMLTest(t, SAMLProviderConfig{AllowIDPInitiated: true})
+		env.users["alice@example.com"] = newUser(model.UserAccountStatusDeactivated)
+
+		rec := env.postACS(env.startIDPInitiated(t), nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestSAMLProvider_userInfoFromAssertion(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	p := &SAMLProvider{config: SAMLProviderConfig{
+		NameAttribute:        "uid",
+		DisplayNameAttribute: "displayName",
+		EmailAttribute:       "mail",
+	}}
+	assertion := &saml.Assertion{
+		Subject: &saml.Subject{NameID: &saml.NameID{Value: "name-id"}},
+		AttributeStatements: []saml.AttributeStatement{{
+			Attributes: []saml.Attribute{
+				{FriendlyName: "uid", Name: "urn:oid:0.9.2342.19200300.100.1.1", Values: []saml.AttributeValue{{Value: "alice.liddell"}}},
+				{Name: "mail", Values: []saml.AttributeValue{{Value: "alice@example.com"}}},
+				{Name: "empty"},
+			},
+		}},
+	}
+
+	u := p.userInfoFromAssertion(assertion)
+	assert.Equal(SAMLProviderName, u.GetProviderName())
+	assert.Equal("name-id", u.GetID())
+	assert.Equal("alice.liddell", u.GetRawName())
+	assert.Equal("alice_liddell", u.GetName())
+	assert.Equal("alice.liddell", u.GetDisplayName())
+	assert.Equal("alice@example.com", u.email)
+
+	p.config.IDAttribute = "urn:oid:0.9.2342.19200300.100.1.1"
+	assert.Equal("alice.liddell", p.userInfoFromAssertion(assertion).GetID())
+}
@@ -1,15 +1,21 @@
 package router
 
 import (
+	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/auth"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	v3 "github.com/traPtitech/traQ/router/v3"
+	"github.com/traPtitech/traQ/service/file"
+	"go.uber.org/zap"
 )
 
 // Config APIサーバー設定
 type Config struct {
 	// 開発モードかどうか
 	Development bool
+	// Origin サーバーオリジン (例: https://q.trap.jp)
+	Origin string
 	// Version サーバーバージョン
 	Version string
 	// Revision サーバーリビジョン
@@ -22,10 +28,16 @@ type Config struct {
 	AccessTokenExp int
 	// IsRefreshEnabled リフレッシュトークンを発行するかどうか
 	IsRefreshEnabled bool
+	// RefreshTokenAbsoluteExp リフレッシュトークンの最初の発行からの有効時間(秒)
+	RefreshTokenAbsoluteExp int
+	// RefreshTokenIdleExp リフレッシュトークンを使用せずにいられる有効時間(秒)
+	RefreshTokenIdleExp int
 	// SkyWaySecretKey SkyWayクレデンシャル用シークレットキー
 	SkyWaySecretKey string
 	// ExternalAuth 外部認証設定
 	ExternalAuth ExternalAuthConfig
+	// SCIM SCIMプロビジョニング設定
+	SCIM SCIMConfig
 }
 
 // ExternalAuth 外部認証設定
@@ -38,6 +50,20 @@ type ExternalAuthConfig struct {
 	TraQ auth.TraQProviderConfig
 	// OIDC OpenID Connect
 	OIDC auth.OIDCProviderConfig
+	// LDAP LDAP
+	LDAP auth.LDAPProviderConfig
+	// SAML SAML 2.0
+	SAML auth.SAMLProviderConfig
+}
+
+// SCIMConfig SCIMプロビジョニング設定
+type SCIMConfig struct {
+	// Token SCIMクライアントの認証に使用するBearerトークン 空の場合はSCIMを無効にします
+	Token string
+	// ExternalProviderName externalIdを紐付ける外部認証プロバイダ名
+	ExternalProviderName string
+	// GroupType SCIMで作成したユーザーグループの種類
+	GroupType string
 }
 
 func (c ExternalAuthConfig) ValidProviders() map[string]bool {
@@ -54,13 +80,22 @@ func (c ExternalAuthConfig) ValidProviders() map[string]bool {
 	if c.OIDC.Valid() {
 		res[auth.OIDCProviderName] = true
 	}
+	if c.SAML.Valid() {
+		res[auth.SAMLProviderName] = true
+	}
+	if c.LDAP.Valid() {
+		res[auth.LDAPProviderName] = true
+	}
 	return res
 }
 
 func provideOAuth2Config(c *Config) oauth2.Config {
 	return oauth2.Config{
-		AccessTokenExp:   c.AccessTokenExp,
-		IsRefreshEnabled: c.IsRefreshEnabled,
+		AccessTokenExp:          c.AccessTokenExp,
+		IsRefreshEnabled:        c.IsRefreshEnabled,
+		RefreshTokenAbsoluteExp: c.RefreshTokenAbsoluteExp,
+		RefreshTokenIdleExp:     c.RefreshTokenIdleExp,
+		Origin:                  c.Origin,
 	}
 }
 
@@ -72,3 +107,19 @@ func provideV3Config(c *Config) v3.Config {
 		EnabledExternalAccountProviders: c.ExternalAuth.ValidProviders(),
 	}
 }
+
+func provideSCIMConfig(c *Config) scim.Config {
+	return scim.Config{
+		Token:                c.SCIM.Token,
+		ExternalProviderName: c.SCIM.ExternalProviderName,
+		GroupType:            c.SCIM.GroupType,
+		Origin:               c.Origin,
+	}
+}
+
+func provideLDAPProvider(repo repository.Repository, fm file.Manager, logger *zap.Logger, c *Config) *auth.LDAPProvider {
+	if !c.ExternalAuth.LDAP.Valid() {
+		return nil
+	}
+	return auth.NewLDAPProvider(repo, fm, logger.Named("ext_auth"), c.ExternalAuth.LDAP)
+}
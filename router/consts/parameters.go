@@ -16,5 +16,7 @@ const (
 	ParamBotID          = "botID"
 	ParamClientID       = "clientID"
 	ParamClipFolderID   = "folderID"
+	ParamNotificationID = "notificationID"
+	ParamKeywordAlertID = "keywordAlertID"
 	ParamURL            = "url"
 )
@@ -1,6 +1,7 @@
 package extension
 
 import (
+	"context"
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
 	jsoniter "github.com/json-iterator/go"
@@ -10,6 +11,7 @@ import (
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/router/utils"
 	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac/permission"
 )
 
 // CtxKey context.Context用のキータイプ
@@ -18,8 +20,20 @@ type CtxKey int
 const (
 	// CtxUserIDKey ユーザーUUIDキー
 	CtxUserIDKey CtxKey = iota
+	// CtxPermissionsKey 付与されている権限キー
+	CtxPermissionsKey
+	// CtxOAuth2TokenIDKey 認証に使用したOAuth2トークン(パーソナルアクセストークンを含む)のUUIDキー
+	CtxOAuth2TokenIDKey
 )
 
+// IsPermissionGranted リクエストのcontextに指定した権限が付与されているかどうかを返します
+//
+// middlewares.PermissionsToContextで格納された権限を参照します。
+func IsPermissionGranted(ctx context.Context, p permission.Permission) bool {
+	perms, ok := ctx.Value(CtxPermissionsKey).(map[permission.Permission]bool)
+	return ok && perms[p]
+}
+
 // Context echo.Contextのカスタム
 type Context struct {
 	echo.Context
@@ -1,11 +1,13 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/file"
@@ -21,20 +23,8 @@ func AccessControlMiddlewareGenerator(r rbac.RBAC) func(p ...permission.Permissi
 	return func(p ...permission.Permission) echo.MiddlewareFunc {
 		return func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(c echo.Context) error {
-				// OAuth2スコープ権限検証
-				if scopes, ok := c.Get(consts.KeyOAuth2AccessScopes).(model.AccessScopes); ok {
-					for _, v := range p {
-						if !r.IsAnyGranted(scopes.StringArray(), v) {
-							// NG
-							return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("you are not permitted to request to '%s'", c.Request().URL.Path))
-						}
-					}
-				}
-
-				// ユーザー権限検証
-				user := c.Get(consts.KeyUser).(model.UserInfo)
 				for _, v := range p {
-					if !r.IsGranted(user.GetRole(), v) {
+					if !isGranted(r, c, v) {
 						// NG
 						return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("you are not permitted to request to '%s'", c.Request().URL.Path))
 					}
@@ -46,6 +36,35 @@ func AccessControlMiddlewareGenerator(r rbac.RBAC) func(p ...permission.Permissi
 	}
 }
 
+// PermissionsToContext 指定した権限が付与されているかどうかをリクエストのcontextに格納するミドルウェア
+//
+// echo.Contextを参照できないWSストリーマーなどで、extension.IsPermissionGrantedを用いて権限を検証するために使います。
+func PermissionsToContext(r rbac.RBAC, p ...permission.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			perms := make(map[permission.Permission]bool, len(p))
+			for _, v := range p {
+				perms[v] = isGranted(r, c, v)
+			}
+			c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), extension.CtxPermissionsKey, perms)))
+			return next(c)
+		}
+	}
+}
+
+func isGranted(r rbac.RBAC, c echo.Context, p permission.Permission) bool {
+	// OAuth2スコープ権限検証
+	if scopes, ok := c.Get(consts.KeyOAuth2AccessScopes).(model.AccessScopes); ok {
+		if !r.IsAnyGranted(scopes.StringArray(), p) {
+			return false
+		}
+	}
+
+	// ユーザー権限検証
+	user := c.Get(consts.KeyUser).(model.UserInfo)
+	return r.IsGranted(user.GetRole(), p)
+}
+
 // AdminOnly 管理者ユーザーのみを通すミドルウェア
 func AdminOnly(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -118,8 +137,8 @@ func CheckFileAccessPerm(rbac rbac.RBAC, fm file.Manager) echo.MiddlewareFunc {
 			file := c.Get(consts.KeyParamFile).(model.File)
 			userID := c.Get(consts.KeyUser).(model.UserInfo).GetID()
 
-			if t := file.GetFileType(); t == model.FileTypeIcon || t == model.FileTypeStamp {
-				// スタンプ・アイコン画像の場合はスキップ
+			if t := file.GetFileType(); t == model.FileTypeIcon || t == model.FileTypeStamp || t == model.FileTypeOgpImage {
+				// スタンプ・アイコン・OGPプレビュー画像の場合はスキップ
 				return next(c)
 			}
 
@@ -166,6 +185,15 @@ func CheckMessageAccessPerm(rbac rbac.RBAC, cm channel.Manager) echo.MiddlewareF
 				return herror.NotFound()
 			}
 
+			// DMチャンネルのメッセージはDMへのアクセス権限が必要
+			ch, err := cm.GetChannel(channelID)
+			if err != nil {
+				return herror.InternalServerError(err)
+			}
+			if ch.IsDMChannel() && !isGranted(rbac, c, permission.AccessDMChannel) {
+				return herror.NotFound()
+			}
+
 			return next(c)
 		}
 	}
@@ -184,6 +212,9 @@ func CheckChannelAccessPerm(rbac rbac.RBAC, cm channel.Manager) echo.MiddlewareF
 			} else if !ok {
 				return herror.NotFound()
 			}
+			if ch.IsDMChannel() && !isGranted(rbac, c, permission.AccessDMChannel) {
+				return herror.NotFound()
+			}
 
 			return next(c)
 		}
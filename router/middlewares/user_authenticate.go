@@ -11,9 +11,15 @@ import (
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/router/session"
 	"golang.org/x/sync/singleflight"
+	"time"
 )
 
-const authScheme = "Bearer"
+const (
+	authScheme = "Bearer"
+
+	// personalAccessTokenLastUsedInterval パーソナルアクセストークンの最終使用日時を更新する最小間隔
+	personalAccessTokenLastUsedInterval = time.Minute
+)
 
 // UserAuthenticate リクエスト認証ミドルウェア
 func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.MiddlewareFunc {
@@ -21,10 +27,13 @@ func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			var uid uuid.UUID
+			var (
+				uid     uuid.UUID
+				tokenID uuid.UUID
+			)
 
 			if ah := c.Request().Header.Get(echo.HeaderAuthorization); len(ah) > 0 {
-				// AuthorizationヘッダーがあるためOAuth2で検証
+				// Authorizationヘッダーがあるためトークンで検証
 
 				// Authorizationスキーム検証
 				l := len(authScheme)
@@ -32,24 +41,55 @@ func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.
 					return herror.Unauthorized("invalid authorization scheme")
 				}
 
-				// OAuth2 Token検証
-				token, err := repo.GetTokenByAccess(ah[l+1:])
-				if err != nil {
-					switch err {
-					case repository.ErrNotFound:
+				if model.IsPersonalAccessToken(ah[l+1:]) {
+					// Personal Access Token検証
+					token, err := repo.GetPersonalAccessTokenByToken(ah[l+1:])
+					if err != nil {
+						switch err {
+						case repository.ErrNotFound:
+							return herror.Unauthorized("invalid token")
+						default:
+							return herror.InternalServerError(err)
+						}
+					}
+
+					// tokenの有効期限の検証
+					if token.IsExpired() {
 						return herror.Unauthorized("invalid token")
-					default:
-						return herror.InternalServerError(err)
 					}
-				}
 
-				// tokenの有効期限の検証
-				if token.IsExpired() {
-					return herror.Unauthorized("invalid token")
-				}
+					// 最終使用日時・IPアドレスの記録 (書き込みを減らすため、IPアドレスが同じ場合は一定時間間引く)
+					now := time.Now()
+					if ip := c.RealIP(); token.LastUsedAt == nil || token.LastUsedAt.Add(personalAccessTokenLastUsedInterval).Before(now) || token.LastUsedIP != ip {
+						if err := repo.UpdatePersonalAccessTokenLastUsed(token.ID, ip, now); err != nil {
+							return herror.InternalServerError(err)
+						}
+					}
 
-				c.Set(consts.KeyOAuth2AccessScopes, token.Scopes)
-				uid = token.UserID
+					c.Set(consts.KeyOAuth2AccessScopes, token.Scopes)
+					uid = token.UserID
+					tokenID = token.ID
+				} else {
+					// OAuth2 Token検証
+					token, err := repo.GetTokenByAccess(ah[l+1:])
+					if err != nil {
+						switch err {
+						case repository.ErrNotFound:
+							return herror.Unauthorized("invalid token")
+						default:
+							return herror.InternalServerError(err)
+						}
+					}
+
+					// tokenの有効期限の検証
+					if token.IsExpired() {
+						return herror.Unauthorized("invalid token")
+					}
+
+					c.Set(consts.KeyOAuth2AccessScopes, token.Scopes)
+					uid = token.UserID
+					tokenID = token.ID
+				}
 			} else {
 				// Authorizationヘッダーがないためセッションを確認する
 				sess, err := sessStore.GetSession(c, false)
@@ -78,6 +118,9 @@ func UserAuthenticate(repo repository.Repository, sessStore session.Store) echo.
 			c.Set(consts.KeyUser, user)
 			c.Set(consts.KeyUserID, user.GetID())
 			c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), extension.CtxUserIDKey, user.GetID()))) // SSEストリーマーで使う
+			if tokenID != uuid.Nil {
+				c.SetRequest(c.Request().WithContext(context.WithValue(c.Request().Context(), extension.CtxOAuth2TokenIDKey, tokenID))) // WSストリーマーで使う
+			}
 			return next(c)
 		}
 	}
@@ -0,0 +1,275 @@
+package oauth2
+
+import (
+	crand "crypto/rand"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/random"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// userCodeLetters ユーザーコードに使用する文字 (読み間違えやすい母音・数字を除く)
+	userCodeLetters = "BCDFGHJKLMNPQRSTVWXZ"
+	// deviceVerifySession 承認画面の表示時に発行したCSRFトークンのセッションキー
+	deviceVerifySession = "oauth2_device_verify"
+)
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceAuthorizationEndpointHandler デバイス認可エンドポイントのハンドラ (RFC 8628)
+func (h *Handler) DeviceAuthorizationEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req struct {
+		Scope        string `form:"scope"`
+		ClientID     string `form:"client_id"`
+		ClientSecret string `form:"client_secret"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		if len(req.ClientID) == 0 {
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		}
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+
+	// クライアント確認
+	client, err := h.Repo.GetClient(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	if client.Confidential && client.Secret != pw {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	// 要求スコープ確認
+	reqScopes, err := h.splitAndValidateScope(req.Scope)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidScope})
+	}
+	validScopes := client.GetAvailableScopes(reqScopes)
+	if len(reqScopes) == 0 {
+		validScopes = client.Scopes
+	} else if len(validScopes) == 0 {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidScope})
+	}
+
+	data := &model.OAuth2DeviceAuthorization{
+		DeviceCode:     random.SecureAlphaNumeric(36),
+		UserCode:       generateUserCode(),
+		ClientID:       client.ID,
+		Status:         model.DeviceAuthorizationPending,
+		Scopes:         validScopes,
+		OriginalScopes: reqScopes,
+		ExpiresIn:      deviceCodeExp,
+		Interval:       deviceCodeInterval,
+		CreatedAt:      time.Now(),
+	}
+	if err := h.Repo.SaveDeviceAuthorization(data); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+
+	userCode := formatUserCode(data.UserCode)
+	verificationURI := h.Origin + "/device"
+	return c.JSON(http.StatusOK, &deviceAuthorizationResponse{
+		DeviceCode:              data.DeviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {userCode}}.Encode(),
+		ExpiresIn:               data.ExpiresIn,
+		Interval:                data.Interval,
+	})
+}
+
+type deviceVerificationResponse struct {
+	ClientID          string             `json:"clientId"`
+	ClientName        string             `json:"clientName"`
+	ClientDescription string             `json:"clientDescription"`
+	Scopes            model.AccessScopes `json:"scopes"`
+	CSRFToken         string             `json:"csrfToken"`
+}
+
+// DeviceVerificationHandler デバイス認可のユーザーコード確認ハンドラ
+//
+// ユーザーが承認画面を表示するために、ユーザーコードに対応するクライアントと要求スコープを返します。
+// 承認フォームの送信に必要なCSRFトークンを発行し、セッションに保存します。
+func (h *Handler) DeviceVerificationHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	se, err := h.SessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if se == nil {
+		return herror.Forbidden("bad session")
+	}
+
+	da, err := h.getPendingDeviceAuthorization(c.QueryParam("user_code"))
+	if err != nil {
+		return err
+	}
+
+	client, err := h.Repo.GetClient(da.ClientID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.BadRequest("unknown client")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	// トークンは承認画面を表示したユーザーコードに対してのみ有効
+	token := random.SecureAlphaNumeric(32)
+	if err := se.Set(deviceVerifySession, da.UserCode+":"+token); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.JSON(http.StatusOK, &deviceVerificationResponse{
+		ClientID:          client.ID,
+		ClientName:        client.Name,
+		ClientDescription: client.Description,
+		Scopes:            da.Scopes,
+		CSRFToken:         token,
+	})
+}
+
+type deviceVerificationDecideHandlerRequest struct {
+	UserCode  string `form:"user_code"`
+	Submit    string `form:"submit"`
+	CSRFToken string `form:"csrf_token"`
+}
+
+func (r deviceVerificationDecideHandlerRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.UserCode, vd.Required),
+		vd.Field(&r.Submit, vd.Required),
+	)
+}
+
+// DeviceVerificationDecideHandler デバイス認可の承認フォームのハンドラ
+//
+// 第三者のサイトから送信されたフォームで承認されないように、GET /device/verifyで発行したCSRFトークンを要求します。
+func (h *Handler) DeviceVerificationDecideHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req deviceVerificationDecideHandlerRequest
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	// セッション確認 (承認はブラウザでログインしているユーザーのみ可能)
+	se, err := h.SessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if se == nil {
+		return herror.Forbidden("bad session")
+	}
+	ok, err := consumeSessionToken(se, deviceVerifySession, normalizeUserCode(req.UserCode)+":"+req.CSRFToken)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if !ok {
+		return herror.Forbidden("invalid csrf token")
+	}
+
+	da, err := h.getPendingDeviceAuthorization(req.UserCode)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Repo.DecideDeviceAuthorization(da.DeviceCode, se.UserID(), req.Submit == "approve"); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound("invalid user code")
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getPendingDeviceAuthorization 指定したユーザーコードの承認待ちのデバイス認可データを取得します
+func (h *Handler) getPendingDeviceAuthorization(userCode string) (*model.OAuth2DeviceAuthorization, error) {
+	da, err := h.Repo.GetDeviceAuthorizationByUserCode(normalizeUserCode(userCode))
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return nil, herror.NotFound("invalid user code")
+		default:
+			return nil, herror.InternalServerError(err)
+		}
+	}
+	if da.Status != model.DeviceAuthorizationPending || da.IsExpired() {
+		return nil, herror.NotFound("invalid user code")
+	}
+	return da, nil
+}
+
+// generateUserCode 8文字のランダムなユーザーコードを生成します
+func generateUserCode() string {
+	b := make([]byte, 8)
+	if _, err := crand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := 0; i < len(b); {
+		// 偏りが出ないよう、文字数の倍数に収まらない値は引き直す
+		if int(b[i]) < 256-256%len(userCodeLetters) {
+			b[i] = userCodeLetters[int(b[i])%len(userCodeLetters)]
+			i++
+		} else if _, err := crand.Read(b[i : i+1]); err != nil {
+			panic(err)
+		}
+	}
+	return string(b)
+}
+
+// formatUserCode ユーザーコードを入力しやすいようにXXXX-XXXXの形式にします
+func formatUserCode(code string) string {
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+// normalizeUserCode ユーザーが入力したユーザーコードから区切り文字を取り除き、大文字に揃えます
+func normalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
@@ -0,0 +1,249 @@
+package oauth2
+
+import (
+	"github.com/gavv/httpexpect/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func TestHandlers_DeviceAuthorizationEndpointHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read", "write")
+	client := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "cli client",
+		Confidential: false,
+		CreatorID:    user.GetID(),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		res := e.POST("/oauth2/device_authorization").
+			WithFormField("client_id", client.ID).
+			WithFormField("scope", "read").
+			Expect()
+
+		res.Status(http.StatusOK)
+		res.Header("Cache-Control").Equal("no-store")
+		obj := res.JSON().Object()
+		obj.Value("device_code").String().NotEmpty()
+		obj.Value("user_code").String().Match("^[A-Z]{4}-[A-Z]{4}$")
+		userCode := obj.Value("user_code").String().Raw()
+		obj.Value("verification_uri").String().Equal(testOrigin + "/device")
+		obj.Value("verification_uri_complete").String().Equal(testOrigin + "/device?user_code=" + userCode)
+		obj.Value("expires_in").Number().Equal(deviceCodeExp)
+		obj.Value("interval").Number().Equal(deviceCodeInterval)
+
+		da, err := env.Repository.GetDeviceAuthorizationByUserCode(normalizeUserCode(userCode))
+		require.NoError(t, err)
+		assert.Equal(t, model.DeviceAuthorizationPending, da.Status)
+		assert.ElementsMatch(t, []string{"read"}, da.Scopes.StringArray())
+	})
+
+	t.Run("Invalid Client (Unknown client)", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/device_authorization").
+			WithFormField("client_id", "unknown").
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("error").String().Equal(errInvalidClient)
+	})
+
+	t.Run("Invalid Scope", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/device_authorization").
+			WithFormField("client_id", client.ID).
+			WithFormField("scope", "manage_bot").
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("error").String().Equal(errInvalidScope)
+	})
+}
+
+func TestHandlers_DeviceVerificationHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	client := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "cli client",
+		Confidential: false,
+		CreatorID:    user.GetID(),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	issue := func(t *testing.T) string {
+		t.Helper()
+		return env.R(t).POST("/oauth2/device_authorization").
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("user_code").String().Raw()
+	}
+
+	// verify 承認画面を表示し、CSRFトークンを取得します
+	verify := func(t *testing.T, e *httpexpect.Expect, sess, userCode string) string {
+		t.Helper()
+		return e.GET("/oauth2/device/verify").
+			WithQuery("user_code", userCode).
+			WithCookie(session.CookieName, sess).
+			Expect().
+			Status(http.StatusOK).
+			JSON().Object().Value("csrfToken").String().NotEmpty().Raw()
+	}
+
+	t.Run("Get", func(t *testing.T) {
+		t.Parallel()
+		userCode := issue(t)
+		e := env.R(t)
+		obj := e.GET("/oauth2/device/verify").
+			WithQuery("user_code", userCode).
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+		obj.Value("clientId").String().Equal(client.ID)
+		obj.Value("clientName").String().Equal(client.Name)
+		obj.Value("scopes").Array().ContainsOnly("read")
+		obj.Value("csrfToken").String().NotEmpty()
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		t.Parallel()
+		userCode := issue(t)
+		e := env.R(t)
+		sess := env.S(t, user.GetID())
+		token := verify(t, e, sess, userCode)
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", userCode).
+			WithFormField("submit", "approve").
+			WithFormField("csrf_token", token).
+			WithCookie(session.CookieName, sess).
+			Expect().
+			Status(http.StatusNoContent)
+
+		da, err := env.Repository.GetDeviceAuthorizationByUserCode(normalizeUserCode(userCode))
+		require.NoError(t, err)
+		assert.Equal(t, model.DeviceAuthorizationApproved, da.Status)
+		assert.Equal(t, user.GetID(), da.UserID)
+
+		// 既に承認済みのコードの承認画面は表示できない
+		e.GET("/oauth2/device/verify").
+			WithQuery("user_code", userCode).
+			WithCookie(session.CookieName, sess).
+			Expect().
+			Status(http.StatusNotFound)
+		// CSRFトークンは使い捨て
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", userCode).
+			WithFormField("submit", "approve").
+			WithFormField("csrf_token", token).
+			WithCookie(session.CookieName, sess).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		t.Parallel()
+		userCode := issue(t)
+		e := env.R(t)
+		sess := env.S(t, user.GetID())
+		token := verify(t, e, sess, userCode)
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", userCode).
+			WithFormField("submit", "deny").
+			WithFormField("csrf_token", token).
+			WithCookie(session.CookieName, sess).
+			Expect().
+			Status(http.StatusNoContent)
+
+		da, err := env.Repository.GetDeviceAuthorizationByUserCode(normalizeUserCode(userCode))
+		require.NoError(t, err)
+		assert.Equal(t, model.DeviceAuthorizationDenied, da.Status)
+	})
+
+	t.Run("Missing CSRF token", func(t *testing.T) {
+		t.Parallel()
+		userCode := issue(t)
+		e := env.R(t)
+		sess := env.S(t, user.GetID())
+		verify(t, e, sess, userCode)
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", userCode).
+			WithFormField("submit", "approve").
+			WithCookie(session.CookieName, sess).
+			Expect().
+			Status(http.StatusForbidden)
+
+		da, err := env.Repository.GetDeviceAuthorizationByUserCode(normalizeUserCode(userCode))
+		require.NoError(t, err)
+		assert.Equal(t, model.DeviceAuthorizationPending, da.Status)
+	})
+
+	t.Run("CSRF token for other user code", func(t *testing.T) {
+		t.Parallel()
+		userCode := issue(t)
+		e := env.R(t)
+		sess := env.S(t, user.GetID())
+		token := verify(t, e, sess, issue(t))
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", userCode).
+			WithFormField("submit", "approve").
+			WithFormField("csrf_token", token).
+			WithCookie(session.CookieName, sess).
+			Expect().
+			Status(http.StatusForbidden)
+
+		da, err := env.Repository.GetDeviceAuthorizationByUserCode(normalizeUserCode(userCode))
+		require.NoError(t, err)
+		assert.Equal(t, model.DeviceAuthorizationPending, da.Status)
+	})
+
+	t.Run("Unknown user code", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/device/verify").
+			WithQuery("user_code", "AAAA-AAAA").
+			WithCookie(session.CookieName, env.S(t, user.GetID())).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/device/verify").
+			WithFormField("user_code", "AAAA-AAAA").
+			WithFormField("submit", "approve").
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "BCDFGHJK", normalizeUserCode("bcdf-ghjk"))
+	assert.Equal(t, "BCDFGHJK", normalizeUserCode("BCDF GHJK"))
+	assert.Equal(t, "BCDF-GHJK", formatUserCode("BCDFGHJK"))
+}
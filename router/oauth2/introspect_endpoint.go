@@ -0,0 +1,155 @@
+package oauth2
+
+import (
+	"crypto/subtle"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const tokenTypeHintRefreshToken = "refresh_token"
+
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+}
+
+// IntrospectTokenEndpointHandler トークンイントロスペクションエンドポイントのハンドラ (RFC 7662)
+//
+// クライアントは自身に発行されたトークンのみを確認できます。
+// リソースサーバーとして登録されたクライアントは、他のクライアントに発行されたトークンも確認できます。
+func (h *Handler) IntrospectTokenEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req struct {
+		Token         string `form:"token"`
+		TokenTypeHint string `form:"token_type_hint"`
+		ClientID      string `form:"client_id"`
+		ClientSecret  string `form:"client_secret"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		if len(req.ClientID) == 0 {
+			return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		}
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+
+	// クライアント確認 (リソースサーバーはConfidentialなクライアントとして認証する)
+	client, err := h.Repo.GetClient(id)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	if !client.Confidential || subtle.ConstantTimeCompare([]byte(client.Secret), []byte(pw)) != 1 {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	if len(req.Token) == 0 {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	token, isRefresh, err := h.findToken(req.Token, req.TokenTypeHint)
+	if err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+	}
+	if token == nil {
+		return c.JSON(http.StatusOK, &introspectionResponse{Active: false})
+	}
+	// 他のクライアントに発行されたトークンの存在を明かさないように、無効なトークンとして扱う
+	if token.ClientID != client.ID && !client.ResourceServer {
+		return c.JSON(http.StatusOK, &introspectionResponse{Active: false})
+	}
+
+	// 有効期限確認
+	var (
+		exp    time.Time
+		hasExp bool
+	)
+	if isRefresh {
+		exp, hasExp = token.RefreshTokenExpiresAt(h.RefreshTokenAbsoluteExp, h.RefreshTokenIdleExp)
+	} else {
+		exp, hasExp = token.CreatedAt.Add(time.Duration(token.ExpiresIn)*time.Second), true
+	}
+	if hasExp && exp.Before(time.Now()) {
+		return c.JSON(http.StatusOK, &introspectionResponse{Active: false})
+	}
+
+	res := &introspectionResponse{
+		Active:    true,
+		Scope:     token.Scopes.String(),
+		ClientID:  token.ClientID,
+		TokenType: authScheme,
+		Iat:       token.CreatedAt.Unix(),
+		Iss:       h.issuer(),
+	}
+	if hasExp {
+		res.Exp = exp.Unix()
+	}
+	if token.UserID != uuid.Nil {
+		user, err := h.Repo.GetUser(token.UserID, false)
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				return c.JSON(http.StatusOK, &introspectionResponse{Active: false})
+			default:
+				h.L(c).Error(err.Error(), zap.Error(err))
+				return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+			}
+		}
+		if !user.IsActive() {
+			return c.JSON(http.StatusOK, &introspectionResponse{Active: false})
+		}
+		res.Sub = user.GetID().String()
+		res.Username = user.GetName()
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// findToken アクセストークンまたはリフレッシュトークンからトークンを取得します
+//
+// token_type_hintで指定された種類から先に検索します。見つからなかった場合はnilを返します。
+func (h *Handler) findToken(token string, hint string) (t *model.OAuth2Token, isRefresh bool, err error) {
+	refreshFirst := hint == tokenTypeHintRefreshToken
+	for i := 0; i < 2; i++ {
+		if refreshFirst == (i == 0) {
+			t, err = h.Repo.GetTokenByRefresh(token)
+			isRefresh = true
+		} else {
+			t, err = h.Repo.GetTokenByAccess(token)
+			isRefresh = false
+		}
+		switch err {
+		case nil:
+			return t, isRefresh, nil
+		case repository.ErrNotFound:
+			continue
+		default:
+			return nil, false, err
+		}
+	}
+	return nil, false, nil
+}
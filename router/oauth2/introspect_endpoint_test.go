@@ -0,0 +1,269 @@
+package oauth2
+
+import (
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandlers_IntrospectTokenEndpointHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db2)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	resourceServer := &model.OAuth2Client{
+		ID:             random.AlphaNumeric(36),
+		Name:           "resource server",
+		Confidential:   true,
+		ResourceServer: true,
+		CreatorID:      user.GetID(),
+		Secret:         random.AlphaNumeric(36),
+		RedirectURI:    "http://example.com",
+		Scopes:         scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(resourceServer))
+	confidentialClient := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "confidential client",
+		Confidential: true,
+		CreatorID:    user.GetID(),
+		Secret:       random.AlphaNumeric(36),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(confidentialClient))
+	publicClient := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "public client",
+		Confidential: false,
+		CreatorID:    user.GetID(),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(publicClient))
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", "token").
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("Public client", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", "token").
+			WithFormField("client_id", publicClient.ID).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", "token").
+			WithBasicAuth(resourceServer.ID, "wrong").
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", random.AlphaNumeric(36)).
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("active").Boolean().False()
+		obj.NotContainsKey("sub")
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		t.Parallel()
+		token, err := env.Repository.IssueToken(publicClient, user.GetID(), "", scopes, -1, false)
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("active").Boolean().False()
+	})
+
+	t.Run("Access token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, publicClient, user.GetID(), true)
+
+		e := env.R(t)
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithFormField("client_id", resourceServer.ID).
+			WithFormField("client_secret", resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("active").Boolean().True()
+		obj.Value("scope").String().Equal("read")
+		obj.Value("client_id").String().Equal(publicClient.ID)
+		obj.Value("sub").String().Equal(user.GetID().String())
+		obj.Value("username").String().Equal(user.GetName())
+		obj.Value("exp").Number().Equal(token.CreatedAt.Unix() + int64(token.ExpiresIn))
+	})
+
+	t.Run("Refresh token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, publicClient, user.GetID(), true)
+
+		e := env.R(t)
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", token.RefreshToken).
+			WithFormField("token_type_hint", "refresh_token").
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		exp, ok := token.RefreshTokenExpiresAt(10000, 1000)
+		require.True(t, ok)
+		obj.Value("active").Boolean().True()
+		obj.Value("sub").String().Equal(user.GetID().String())
+		obj.Value("exp").Number().Equal(exp.Unix())
+	})
+
+	t.Run("Expired refresh token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, publicClient, user.GetID(), true)
+		require.NoError(t, env.DB.Model(token).UpdateColumn("created_at", time.Now().Add(-2000*time.Second)).Error)
+
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", token.RefreshToken).
+			WithFormField("token_type_hint", "refresh_token").
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("active").Boolean().False()
+	})
+
+	t.Run("Own token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, confidentialClient, user.GetID(), false)
+
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithBasicAuth(confidentialClient.ID, confidentialClient.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("active").Boolean().True()
+	})
+
+	t.Run("Other client's token", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, publicClient, user.GetID(), false)
+
+		// リソースサーバーでないクライアントは他のクライアントのトークンを確認できない
+		e := env.R(t)
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithBasicAuth(confidentialClient.ID, confidentialClient.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("active").Boolean().False()
+		obj.NotContainsKey("sub")
+	})
+
+	t.Run("Other client's token by resource server", func(t *testing.T) {
+		t.Parallel()
+		client := &model.OAuth2Client{
+			ID:           random.AlphaNumeric(36),
+			Name:         "to be resource server",
+			Confidential: true,
+			CreatorID:    user.GetID(),
+			Secret:       random.AlphaNumeric(36),
+			RedirectURI:  "http://example.com",
+			Scopes:       scopes,
+		}
+		require.NoError(t, env.Repository.SaveClient(client))
+		token := env.IssueToken(t, publicClient, user.GetID(), false)
+
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithBasicAuth(client.ID, client.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("active").Boolean().False()
+
+		// リソースサーバーに指定されると他のクライアントのトークンを確認できる
+		require.NoError(t, env.Repository.UpdateClient(client.ID, repository.UpdateClientArgs{ResourceServer: optional.BoolFrom(true)}))
+
+		obj := e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithBasicAuth(client.ID, client.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("active").Boolean().True()
+		obj.Value("client_id").String().Equal(publicClient.ID)
+		obj.Value("sub").String().Equal(user.GetID().String())
+	})
+
+	t.Run("Revoked token", func(t *testing.T) {
+		t.Parallel()
+		client := &model.OAuth2Client{
+			ID:           random.AlphaNumeric(36),
+			Name:         "revoked client",
+			Confidential: false,
+			CreatorID:    user.GetID(),
+			RedirectURI:  "http://example.com",
+			Scopes:       scopes,
+		}
+		require.NoError(t, env.Repository.SaveClient(client))
+		token := env.IssueToken(t, client, user.GetID(), false)
+		require.NoError(t, env.Repository.DeleteTokenByClient(client.ID))
+
+		e := env.R(t)
+		e.POST("/oauth2/introspect").
+			WithFormField("token", token.AccessToken).
+			WithBasicAuth(resourceServer.ID, resourceServer.Secret).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("active").Boolean().False()
+	})
+}
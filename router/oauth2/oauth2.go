@@ -17,6 +17,7 @@ const (
 	grantTypePassword          = "password"
 	grantTypeClientCredentials = "client_credentials"
 	grantTypeRefreshToken      = "refresh_token"
+	grantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
 
 	errInvalidRequest          = "invalid_request"
 	errUnauthorizedClient      = "unauthorized_client"
@@ -29,11 +30,16 @@ const (
 	errUnsupportedGrantType    = "unsupported_grant_type"
 	errLoginRequired           = "login_required"
 	errConsentRequired         = "consent_required"
+	errAuthorizationPending    = "authorization_pending"
+	errSlowDown                = "slow_down"
+	errExpiredToken            = "expired_token"
 
 	oauth2ContextSession = "oauth2_context"
 	authScheme           = "Bearer"
 
 	authorizationCodeExp = 60 * 5
+	deviceCodeExp        = 60 * 10
+	deviceCodeInterval   = 5
 )
 
 type Handler struct {
@@ -49,6 +55,12 @@ type Config struct {
 	AccessTokenExp int
 	// IsRefreshEnabled リフレッシュトークンを発行するかどうか
 	IsRefreshEnabled bool
+	// RefreshTokenAbsoluteExp リフレッシュトークンの最初の発行からの有効時間(秒) 0以下の場合は無期限
+	RefreshTokenAbsoluteExp int
+	// RefreshTokenIdleExp リフレッシュトークンを使用せずにいられる有効時間(秒) 0以下の場合は無期限
+	RefreshTokenIdleExp int
+	// Origin サーバーオリジン (例: https://q.trap.jp)
+	Origin string
 }
 
 func (h *Handler) Setup(e *echo.Group) {
@@ -57,6 +69,17 @@ func (h *Handler) Setup(e *echo.Group) {
 	e.POST("/authorize", h.AuthorizationEndpointHandler)
 	e.POST("/token", h.TokenEndpointHandler)
 	e.POST("/revoke", h.RevokeTokenEndpointHandler)
+	e.POST("/introspect", h.IntrospectTokenEndpointHandler)
+	e.POST("/device_authorization", h.DeviceAuthorizationEndpointHandler)
+	e.GET("/scopes", h.ScopesHandler)
+	e.GET("/device/verify", h.DeviceVerificationHandler, middlewares.UserAuthenticate(h.Repo, h.SessStore), middlewares.BlockBot(h.Repo))
+	e.POST("/device/verify", h.DeviceVerificationDecideHandler, middlewares.UserAuthenticate(h.Repo, h.SessStore), middlewares.BlockBot(h.Repo))
+	e.GET("/.well-known/openid-configuration", h.DiscoveryHandler)
+	e.GET("/jwks", h.JWKSHandler)
+	e.GET("/userinfo", h.UserInfoEndpointHandler)
+	e.POST("/userinfo", h.UserInfoEndpointHandler)
+	e.GET("/logout", h.LogoutEndpointHandler)
+	e.POST("/logout", h.LogoutEndpointHandler)
 }
 
 // splitAndValidateScope スペース区切りのスコープ文字列を分解し、検証します
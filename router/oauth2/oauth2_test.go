@@ -16,6 +16,7 @@ import (
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/jwt"
 	"github.com/traPtitech/traQ/utils/random"
 	"go.uber.org/zap"
 	"net/http"
@@ -30,6 +31,8 @@ const (
 	db1      = "db1"
 	db2      = "db2"
 	rand     = "random"
+
+	testOrigin = "http://traq.example.com"
 )
 
 var envs = map[string]*Env{}
@@ -47,6 +50,11 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
+	privRaw, _ := random.GenerateECDSAKey()
+	if err := jwt.SetupSigner(privRaw); err != nil {
+		panic(err)
+	}
+
 	for _, key := range dbs {
 		env := &Env{}
 
@@ -87,8 +95,11 @@ func TestMain(m *testing.M) {
 			SessStore: env.SessStore,
 			Logger:    zap.NewNop(),
 			Config: Config{
-				AccessTokenExp:   1000,
-				IsRefreshEnabled: true,
+				AccessTokenExp:          1000,
+				IsRefreshEnabled:        true,
+				RefreshTokenAbsoluteExp: 10000,
+				RefreshTokenIdleExp:     1000,
+				Origin:                  testOrigin,
 			},
 		}
 		config.Setup(e.Group("/oauth2"))
@@ -0,0 +1,390 @@
+package oauth2
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/session"
+	jwt2 "github.com/traPtitech/traQ/utils/jwt"
+	"github.com/traPtitech/traQ/utils/random"
+	"go.uber.org/zap"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// oidcBasePath OpenID Connectのエンドポイントのベースパス (Issuer)
+	oidcBasePath = "/api/v3/oauth2"
+	// logoutConfirmSession ログアウト確認ページで発行した確認トークンのセッションキー
+	logoutConfirmSession = "oidc_logout_confirm"
+
+	idTokenExp = 60 * 60
+)
+
+// issuer OpenID ConnectのIssuer Identifierを返します
+func (h *Handler) issuer() string {
+	return h.Origin + oidcBasePath
+}
+
+type discoveryResponse struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// DiscoveryHandler OpenID Provider Configurationのハンドラ
+func (h *Handler) DiscoveryHandler(c echo.Context) error {
+	issuer := h.issuer()
+	return c.JSON(http.StatusOK, &discoveryResponse{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/authorize",
+		TokenEndpoint:                     issuer + "/token",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/jwks",
+		RevocationEndpoint:                issuer + "/revoke",
+		IntrospectionEndpoint:             issuer + "/introspect",
+		DeviceAuthorizationEndpoint:       issuer + "/device_authorization",
+		EndSessionEndpoint:                issuer + "/logout",
+		ScopesSupported:                   supportedScopeNames(),
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{grantTypeAuthorizationCode, grantTypePassword, grantTypeClientCredentials, grantTypeRefreshToken, grantTypeDeviceCode},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{jwt.SigningMethodES256.Alg()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"plain", "S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "at_hash", "name", "preferred_username", "picture", "groups", "tags"},
+	})
+}
+
+// JWKSHandler ID Token検証用の公開鍵(JWK Set)のハンドラ
+func (h *Handler) JWKSHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, jwt2.JWKS())
+}
+
+type idTokenClaims struct {
+	jwt.StandardClaims
+	Nonce             string `json:"nonce,omitempty"`
+	AtHash            string `json:"at_hash,omitempty"`
+	Name              string `json:"name,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Picture           string `json:"picture,omitempty"`
+}
+
+// issueIDToken ID Tokenを発行します
+func (h *Handler) issueIDToken(token *model.OAuth2Token, nonce string) (string, error) {
+	now := time.Now()
+	claims := &idTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    h.issuer(),
+			Subject:   token.UserID.String(),
+			Audience:  token.ClientID,
+			ExpiresAt: now.Add(idTokenExp * time.Second).Unix(),
+			IssuedAt:  now.Unix(),
+		},
+		Nonce:  nonce,
+		AtHash: accessTokenHash(token.AccessToken),
+	}
+	if token.Scopes.Contains(model.ScopeProfile) {
+		user, err := h.Repo.GetUser(token.UserID, false)
+		if err != nil {
+			return "", err
+		}
+		claims.Name = user.GetDisplayName()
+		claims.PreferredUsername = user.GetName()
+		claims.Picture = h.iconURL(user)
+	}
+	return jwt2.Sign(claims)
+}
+
+// accessTokenHash ID Tokenのat_hashの値を計算します
+func accessTokenHash(accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(hash[:len(hash)/2])
+}
+
+// iconURL ユーザーアイコンの公開URLを返します
+func (h *Handler) iconURL(user model.UserInfo) string {
+	return fmt.Sprintf("%s/api/v3/public/icon/%s", h.Origin, url.PathEscape(user.GetName()))
+}
+
+type userInfoResponse struct {
+	Sub               string   `json:"sub"`
+	Name              string   `json:"name,omitempty"`
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Picture           string   `json:"picture,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+}
+
+// UserInfoEndpointHandler UserInfoエンドポイントのハンドラ
+func (h *Handler) UserInfoEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	ah := c.Request().Header.Get(echo.HeaderAuthorization)
+	l := len(authScheme)
+	if !(len(ah) > l+1 && ah[:l] == authScheme) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme)
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	token, err := h.Repo.GetTokenByAccess(ah[l+1:])
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(`%s error="invalid_token"`, authScheme))
+			return c.NoContent(http.StatusUnauthorized)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	if token.IsExpired() || token.UserID == uuid.Nil {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(`%s error="invalid_token"`, authScheme))
+		return c.NoContent(http.StatusUnauthorized)
+	}
+	if !token.Scopes.Contains(model.ScopeOpenID) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(`%s error="insufficient_scope"`, authScheme))
+		return c.NoContent(http.StatusForbidden)
+	}
+
+	user, err := h.Repo.GetUser(token.UserID, false)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(`%s error="invalid_token"`, authScheme))
+			return c.NoContent(http.StatusUnauthorized)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	if !user.IsActive() {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, fmt.Sprintf(`%s error="invalid_token"`, authScheme))
+		return c.NoContent(http.StatusUnauthorized)
+	}
+
+	res := &userInfoResponse{Sub: user.GetID().String()}
+	if token.Scopes.Contains(model.ScopeProfile) {
+		res.Name = user.GetDisplayName()
+		res.PreferredUsername = user.GetName()
+		res.Picture = h.iconURL(user)
+
+		groupIDs, err := h.Repo.GetUserBelongingGroupIDs(user.GetID())
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		res.Groups = make([]string, 0, len(groupIDs))
+		for _, id := range groupIDs {
+			g, err := h.Repo.GetUserGroup(id)
+			if err != nil {
+				if err == repository.ErrNotFound {
+					continue
+				}
+				return herror.InternalServerError(err)
+			}
+			res.Groups = append(res.Groups, g.Name)
+		}
+
+		tags, err := h.Repo.GetUserTagsByUserID(user.GetID())
+		if err != nil {
+			return herror.InternalServerError(err)
+		}
+		res.Tags = make([]string, len(tags))
+		for i, t := range tags {
+			res.Tags[i] = t.GetTag()
+		}
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// idTokenHintClaims ログアウトエンドポイントに渡されたID Tokenのクレーム
+type idTokenHintClaims struct {
+	jwt.StandardClaims
+}
+
+// Valid implements jwt.Claims interface.
+//
+// 有効期限切れのID Tokenもid_token_hintとして受け付けるため、検証を行いません。
+func (c *idTokenHintClaims) Valid() error {
+	return nil
+}
+
+type logoutRequest struct {
+	IDTokenHint           string `query:"id_token_hint"            form:"id_token_hint"`
+	ClientID              string `query:"client_id"                form:"client_id"`
+	PostLogoutRedirectURI string `query:"post_logout_redirect_uri" form:"post_logout_redirect_uri"`
+	State                 string `query:"state"                    form:"state"`
+	// Confirm ログアウト確認ページで発行した確認トークン
+	Confirm string `form:"confirm"`
+}
+
+// logoutConfirmPage ログアウト確認ページ
+//
+// id_token_hintの無いリクエストは第三者のサイトから送られた可能性があるため、ユーザーの確認を経てからログアウトします。
+const logoutConfirmPage = `<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="utf-8"><title>traQ ログアウト</title></head>
+<body>
+<form method="post" action="%s">
+<input type="hidden" name="confirm" value="%s">
+<input type="hidden" name="client_id" value="%s">
+<input type="hidden" name="post_logout_redirect_uri" value="%s">
+<input type="hidden" name="state" value="%s">
+<p>traQからログアウトしますか？</p>
+<button type="submit">ログアウト</button>
+</form>
+</body>
+</html>
+`
+
+// LogoutEndpointHandler RP-Initiated Logoutのハンドラ
+func (h *Handler) LogoutEndpointHandler(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req logoutRequest
+	if err := c.Bind(&req); err != nil {
+		return herror.BadRequest(err)
+	}
+
+	var hint *idTokenHintClaims
+	if len(req.IDTokenHint) > 0 {
+		hint = &idTokenHintClaims{}
+		if err := jwt2.Verify(req.IDTokenHint, hint); err != nil || hint.Issuer != h.issuer() {
+			return herror.BadRequest("invalid id_token_hint")
+		}
+		if len(req.ClientID) == 0 {
+			req.ClientID = hint.Audience
+		} else if req.ClientID != hint.Audience {
+			return herror.BadRequest("client_id does not match id_token_hint")
+		}
+	}
+
+	// リダイレクト先確認
+	var redirectURI *url.URL
+	if len(req.PostLogoutRedirectURI) > 0 {
+		if len(req.ClientID) == 0 {
+			return herror.BadRequest("client_id or id_token_hint is required")
+		}
+		client, err := h.Repo.GetClient(req.ClientID)
+		if err != nil {
+			switch err {
+			case repository.ErrNotFound:
+				return herror.BadRequest("unknown client")
+			default:
+				return herror.InternalServerError(err)
+			}
+		}
+		u, err := url.Parse(req.PostLogoutRedirectURI)
+		if err != nil || !isSameOrigin(u, client.RedirectURI) {
+			return herror.BadRequest("invalid post_logout_redirect_uri")
+		}
+		redirectURI = u
+	}
+
+	// セッション破棄
+	se, err := h.SessStore.GetSession(c, false)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if se != nil && se.LoggedIn() {
+		var revoke bool
+		switch {
+		case hint != nil:
+			// 別のユーザーのID Tokenが渡された場合はログアウトしない
+			revoke = hint.Subject == se.UserID().String()
+		case c.Request().Method == http.MethodPost && len(req.Confirm) > 0:
+			ok, err := consumeSessionToken(se, logoutConfirmSession, req.Confirm)
+			if err != nil {
+				return herror.InternalServerError(err)
+			}
+			if !ok {
+				return herror.BadRequest("invalid confirm")
+			}
+			revoke = true
+		default:
+			return h.renderLogoutConfirmation(c, se, &req)
+		}
+
+		if revoke {
+			if err := h.SessStore.RevokeSession(c); err != nil {
+				h.L(c).Error(err.Error(), zap.Error(err))
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+
+	if redirectURI == nil {
+		return c.String(http.StatusOK, "ログアウトしました")
+	}
+	if len(req.State) > 0 {
+		q := redirectURI.Query()
+		q.Set("state", req.State)
+		redirectURI.RawQuery = q.Encode()
+	}
+	return c.Redirect(http.StatusFound, redirectURI.String())
+}
+
+// renderLogoutConfirmation 確認トークンを発行し、ログアウト確認ページを返します
+func (h *Handler) renderLogoutConfirmation(c echo.Context, se session.Session, req *logoutRequest) error {
+	token := random.SecureAlphaNumeric(32)
+	if err := se.Set(logoutConfirmSession, token); err != nil {
+		h.L(c).Error(err.Error(), zap.Error(err))
+		return herror.InternalServerError(err)
+	}
+	return c.HTML(http.StatusOK, fmt.Sprintf(logoutConfirmPage,
+		oidcBasePath+"/logout",
+		token,
+		html.EscapeString(req.ClientID),
+		html.EscapeString(req.PostLogoutRedirectURI),
+		html.EscapeString(req.State),
+	))
+}
+
+// consumeSessionToken セッションのkeyに保存した使い捨てのトークンを消費し、tokenと一致するかどうかを返します
+func consumeSessionToken(se session.Session, key, token string) (bool, error) {
+	v, err := se.Get(key)
+	if err != nil {
+		return false, err
+	}
+	expected, ok := v.(string)
+	if !ok || len(expected) == 0 {
+		return false, nil
+	}
+	if err := se.Delete(key); err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1, nil
+}
+
+// isSameOrigin uがクライアントのリダイレクトURIと同じオリジンかどうか
+func isSameOrigin(u *url.URL, clientRedirectURI string) bool {
+	r, err := url.Parse(clientRedirectURI)
+	if err != nil || len(r.Host) == 0 {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, r.Scheme) && strings.EqualFold(u.Host, r.Host)
+}
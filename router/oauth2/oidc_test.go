@@ -0,0 +1,333 @@
+package oauth2
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/utils/jwt"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandler_DiscoveryHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	e := env.R(t)
+	obj := e.GET("/oauth2/.well-known/openid-configuration").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	obj.Value("issuer").String().Equal(testOrigin + oidcBasePath)
+	obj.Value("jwks_uri").String().Equal(testOrigin + oidcBasePath + "/jwks")
+	obj.Value("userinfo_endpoint").String().Equal(testOrigin + oidcBasePath + "/userinfo")
+	obj.Value("end_session_endpoint").String().Equal(testOrigin + oidcBasePath + "/logout")
+	obj.Value("scopes_supported").Array().Contains("openid", "profile", "read", "messages:read", "dms")
+	obj.Value("id_token_signing_alg_values_supported").Array().ContainsOnly("ES256")
+}
+
+func TestHandler_JWKSHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	e := env.R(t)
+	keys := e.GET("/oauth2/jwks").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		Value("keys").
+		Array()
+
+	keys.Length().Equal(1)
+	key := keys.First().Object()
+	key.Value("kty").String().Equal("EC")
+	key.Value("crv").String().Equal("P-256")
+	key.Value("alg").String().Equal("ES256")
+	key.Value("kid").String().Equal(jwt.KeyID())
+}
+
+func TestHandler_IssueIDToken(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read", "openid", "profile")
+	client := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    user.GetID(),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	authorize := &model.OAuth2Authorize{
+		Code:           random.AlphaNumeric(36),
+		ClientID:       client.ID,
+		UserID:         user.GetID(),
+		CreatedAt:      time.Now(),
+		ExpiresIn:      1000,
+		RedirectURI:    "http://example.com",
+		Scopes:         scopes,
+		OriginalScopes: scopes,
+		Nonce:          "nonce",
+	}
+	require.NoError(t, env.Repository.SaveAuthorize(authorize))
+
+	e := env.R(t)
+	obj := e.POST("/oauth2/token").
+		WithFormField("grant_type", grantTypeAuthorizationCode).
+		WithFormField("client_id", client.ID).
+		WithFormField("code", authorize.Code).
+		WithFormField("redirect_uri", "http://example.com").
+		Expect().
+		Status(http.StatusOK).
+		JSON().
+		Object()
+
+	accessToken := obj.Value("access_token").String().Raw()
+	idToken := obj.Value("id_token").String().NotEmpty().Raw()
+
+	var claims idTokenClaims
+	if assert.NoError(t, jwt.Verify(idToken, &claims)) {
+		assert.Equal(t, testOrigin+oidcBasePath, claims.Issuer)
+		assert.Equal(t, user.GetID().String(), claims.Subject)
+		assert.Equal(t, client.ID, claims.Audience)
+		assert.Equal(t, "nonce", claims.Nonce)
+		assert.Equal(t, accessTokenHash(accessToken), claims.AtHash)
+		assert.Equal(t, user.GetName(), claims.PreferredUsername)
+	}
+}
+
+func TestHandler_UserInfoEndpointHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	newClient := func(t *testing.T, scopes ...model.AccessScope) *model.OAuth2Client {
+		t.Helper()
+		s := model.AccessScopes{}
+		s.Add(scopes...)
+		client := &model.OAuth2Client{
+			ID:           random.AlphaNumeric(36),
+			Name:         "test client",
+			Confidential: false,
+			CreatorID:    user.GetID(),
+			RedirectURI:  "http://example.com",
+			Scopes:       s,
+		}
+		require.NoError(t, env.Repository.SaveClient(client))
+		return client
+	}
+
+	t.Run("No Token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+random.AlphaNumeric(36)).
+			Expect().
+			Status(http.StatusUnauthorized)
+	})
+
+	t.Run("Insufficient Scope", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, newClient(t, "read"), user.GetID(), false)
+		e := env.R(t)
+		e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusForbidden)
+	})
+
+	t.Run("Success (openid)", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, newClient(t, "openid"), user.GetID(), false)
+		e := env.R(t)
+		obj := e.GET("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("sub").String().Equal(user.GetID().String())
+		obj.NotContainsKey("preferred_username")
+	})
+
+	t.Run("Success (openid profile)", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, newClient(t, "openid", "profile"), user.GetID(), false)
+		e := env.R(t)
+		obj := e.POST("/oauth2/userinfo").
+			WithHeader("Authorization", authScheme+" "+token.AccessToken).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object()
+
+		obj.Value("sub").String().Equal(user.GetID().String())
+		obj.Value("preferred_username").String().Equal(user.GetName())
+		obj.Value("picture").String().Equal(testOrigin + "/api/v3/public/icon/" + user.GetName())
+	})
+}
+
+func TestHandler_LogoutEndpointHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	scopes := model.AccessScopes{}
+	scopes.Add("openid")
+	client := &model.OAuth2Client{
+		ID:           random.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    user.GetID(),
+		RedirectURI:  "http://example.com/callback",
+		Scopes:       scopes,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	t.Run("Success with id_token_hint", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), false)
+		hint, err := (&Handler{Config: Config{Origin: testOrigin}}).issueIDToken(token, "")
+		require.NoError(t, err)
+
+		s := env.S(t, user.GetID())
+		e := env.R(t)
+		e.GET("/oauth2/logout").
+			WithCookie(session.CookieName, s).
+			WithQuery("id_token_hint", hint).
+			Expect().
+			Status(http.StatusOK)
+
+		_, err = env.SessStore.GetSessionByToken(s)
+		assert.Equal(t, session.ErrSessionNotFound, err)
+	})
+
+	t.Run("Confirmation required", func(t *testing.T) {
+		t.Parallel()
+		s := env.S(t, user.GetID())
+		e := env.R(t)
+		e.GET("/oauth2/logout").
+			WithCookie(session.CookieName, s).
+			WithQuery("state", `"><script>`).
+			Expect().
+			Status(http.StatusOK).
+			ContentType("text/html").
+			Body().
+			Contains(`name="confirm"`).
+			NotContains("<script>")
+
+		// 確認するまでログアウトしない
+		se, err := env.SessStore.GetSessionByToken(s)
+		require.NoError(t, err)
+		assert.True(t, se.LoggedIn())
+		v, err := se.Get(logoutConfirmSession)
+		require.NoError(t, err)
+		confirm := v.(string)
+
+		e.POST("/oauth2/logout").
+			WithCookie(session.CookieName, s).
+			WithFormField("confirm", "invalid").
+			Expect().
+			Status(http.StatusBadRequest)
+		se, err = env.SessStore.GetSessionByToken(s)
+		require.NoError(t, err)
+		require.NoError(t, se.Set(logoutConfirmSession, confirm))
+
+		e.POST("/oauth2/logout").
+			WithCookie(session.CookieName, s).
+			WithFormField("confirm", confirm).
+			Expect().
+			Status(http.StatusOK)
+
+		_, err = env.SessStore.GetSessionByToken(s)
+		assert.Equal(t, session.ErrSessionNotFound, err)
+	})
+
+	t.Run("POST without confirmation", func(t *testing.T) {
+		t.Parallel()
+		s := env.S(t, user.GetID())
+		e := env.R(t)
+		e.POST("/oauth2/logout").
+			WithCookie(session.CookieName, s).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusOK).
+			ContentType("text/html")
+
+		se, err := env.SessStore.GetSessionByToken(s)
+		require.NoError(t, err)
+		assert.True(t, se.LoggedIn())
+	})
+
+	t.Run("Success with redirect", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/logout").
+			WithQuery("client_id", client.ID).
+			WithQuery("post_logout_redirect_uri", "http://example.com/logged_out").
+			WithQuery("state", "state").
+			Expect().
+			Status(http.StatusFound).
+			Header("Location").Equal("http://example.com/logged_out?state=state")
+	})
+
+	t.Run("Invalid redirect uri", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/logout").
+			WithQuery("client_id", client.ID).
+			WithQuery("post_logout_redirect_uri", "http://evil.example.com/").
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("Invalid id_token_hint", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/oauth2/logout").
+			WithQuery("id_token_hint", "invalid").
+			Expect().
+			Status(http.StatusBadRequest)
+	})
+
+	t.Run("Other user's id_token_hint", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, uuid.Must(uuid.NewV4()), false)
+		hint, err := (&Handler{Config: Config{Origin: testOrigin}}).issueIDToken(token, "")
+		require.NoError(t, err)
+
+		s := env.S(t, user.GetID())
+		e := env.R(t)
+		e.GET("/oauth2/logout").
+			WithCookie(session.CookieName, s).
+			WithQuery("id_token_hint", hint).
+			WithQuery("post_logout_redirect_uri", "http://example.com/").
+			Expect().
+			Status(http.StatusFound)
+
+		se, err := env.SessStore.GetSessionByToken(s)
+		require.NoError(t, err)
+		assert.NotNil(t, se)
+	})
+}
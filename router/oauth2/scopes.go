@@ -0,0 +1,80 @@
+package oauth2
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"net/http"
+	"sort"
+)
+
+// scopeDefinition 同意画面に表示するスコープの定義
+type scopeDefinition struct {
+	name        string
+	description string
+}
+
+// supportedScopes 使用可能なスコープの一覧
+var supportedScopes = []scopeDefinition{
+	{string(model.ScopeOpenID), "OpenID Connectでの認証"},
+	{string(model.ScopeProfile), "プロフィール情報(名前・アイコン・所属グループ・タグ)の取得"},
+	{role.Read, "メッセージ・チャンネル・ユーザー・スタンプ・ファイルなどの読み取り (DMを含む)"},
+	{role.Write, "メッセージ・チャンネル・ユーザー・スタンプ・ファイルなどの書き込み (DMを含む)"},
+	{role.ManageBot, "BOT・Webhook・OAuth2クライアントの管理"},
+	{role.ChannelsRead, "チャンネル情報・通知設定・スターの読み取り"},
+	{role.ChannelsWrite, "チャンネルの作成・トピック・通知設定・スターの変更"},
+	{role.MessagesRead, "メッセージ・未読情報の読み取り (DMを除く)"},
+	{role.MessagesWrite, "メッセージの投稿・編集・削除・ピン留め・既読化 (DMを除く)"},
+	{role.DMs, "ダイレクトメッセージへのアクセス"},
+	{role.StampsRead, "スタンプ・スタンプ履歴・スタンプパレットの読み取り"},
+	{role.StampsWrite, "スタンプの作成・編集・メッセージへの追加、スタンプパレットの編集"},
+	{role.UsersRead, "ユーザー情報・タグ・グループの読み取り"},
+	{role.UsersWrite, "自分のユーザー情報・アイコン・タグ・グループの変更"},
+	{role.FilesRead, "ファイルのダウンロード"},
+	{role.FilesWrite, "ファイルのアップロード・削除"},
+}
+
+type scopeResponse struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// ScopesHandler スコープ一覧のハンドラ
+//
+// 同意画面でクライアントが要求するスコープの詳細を表示するために使用します。
+// scopeパラメータ(スペース区切り)を指定した場合は、そのスコープのみを返します。
+func (h *Handler) ScopesHandler(c echo.Context) error {
+	var filter model.AccessScopes
+	if s := c.QueryParam("scope"); len(s) > 0 {
+		filter = model.AccessScopes{}
+		filter.FromString(s)
+	}
+
+	res := make([]*scopeResponse, 0, len(supportedScopes))
+	for _, s := range supportedScopes {
+		if filter != nil && !filter.Contains(model.AccessScope(s.name)) {
+			continue
+		}
+		perms := make([]string, 0)
+		for _, p := range h.RBAC.GetGrantedPermissions(s.name) {
+			perms = append(perms, p.Name())
+		}
+		sort.Strings(perms)
+		res = append(res, &scopeResponse{
+			Name:        s.name,
+			Description: s.description,
+			Permissions: perms,
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// supportedScopeNames 使用可能なスコープ名の一覧を返します
+func supportedScopeNames() []string {
+	names := make([]string, len(supportedScopes))
+	for i, s := range supportedScopes {
+		names[i] = s.name
+	}
+	return names
+}
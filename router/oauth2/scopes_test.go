@@ -0,0 +1,45 @@
+package oauth2
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHandler_ScopesHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	t.Run("All", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		arr := e.GET("/oauth2/scopes").
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array()
+
+		arr.Length().Equal(len(supportedScopes))
+	})
+
+	t.Run("Filtered", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		arr := e.GET("/oauth2/scopes").
+			WithQuery("scope", "messages:read dms").
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Array()
+
+		arr.Length().Equal(2)
+		messagesRead := arr.Element(0).Object()
+		messagesRead.Value("name").String().Equal("messages:read")
+		messagesRead.Value("description").String().NotEmpty()
+		messagesRead.Value("permissions").Array().Contains("get_message", "get_channel")
+		messagesRead.Value("permissions").Array().NotContains("access_dm_channel")
+
+		dms := arr.Element(1).Object()
+		dms.Value("name").String().Equal("dms")
+		dms.Value("permissions").Array().ContainsOnly("access_dm_channel")
+	})
+}
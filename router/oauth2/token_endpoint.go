@@ -4,10 +4,12 @@ import (
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/extension"
 	"go.uber.org/zap"
 	"net/http"
+	"time"
 )
 
 type oauth2ErrorResponse struct {
@@ -22,6 +24,7 @@ type tokenResponse struct {
 	ExpiresIn    int    `json:"expires_in,omitempty"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
 }
 
 // TokenEndpointHandler トークンエンドポイントのハンドラ
@@ -38,6 +41,8 @@ func (h *Handler) TokenEndpointHandler(c echo.Context) error {
 		return h.tokenEndpointClientCredentialsHandler(c)
 	case grantTypeRefreshToken:
 		return h.tokenEndpointRefreshTokenHandler(c)
+	case grantTypeDeviceCode:
+		return h.tokenEndpointDeviceCodeHandler(c)
 	default:
 		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errUnsupportedGrantType})
 	}
@@ -134,6 +139,14 @@ func (h *Handler) tokenEndpointAuthorizationCodeHandler(c echo.Context) error {
 	if newToken.IsRefreshEnabled() {
 		res.RefreshToken = newToken.RefreshToken
 	}
+	if newToken.Scopes.Contains(model.ScopeOpenID) {
+		idToken, err := h.issueIDToken(newToken, code.Nonce)
+		if err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		res.IDToken = idToken
+	}
 	return c.JSON(http.StatusOK, res)
 }
 
@@ -321,7 +334,7 @@ func (h *Handler) tokenEndpointRefreshTokenHandler(c echo.Context) error {
 	if err != nil {
 		switch err {
 		case repository.ErrNotFound:
-			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+			return h.detectRefreshTokenReuse(c, req.RefreshToken)
 		default:
 			h.L(c).Error(err.Error(), zap.Error(err))
 			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
@@ -353,6 +366,11 @@ func (h *Handler) tokenEndpointRefreshTokenHandler(c echo.Context) error {
 		}
 	}
 
+	// リフレッシュトークンの有効期限確認
+	if token.IsRefreshTokenExpired(h.RefreshTokenAbsoluteExp, h.RefreshTokenIdleExp) {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+	}
+
 	// 要求スコープ確認
 	reqScopes, err := h.splitAndValidateScope(req.Scope)
 	if err != nil {
@@ -365,13 +383,169 @@ func (h *Handler) tokenEndpointRefreshTokenHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidScope})
 	}
 
-	// トークン発行
-	newToken, err := h.Repo.IssueToken(client, token.UserID, token.RedirectURI, newScopes, h.AccessTokenExp, h.IsRefreshEnabled)
+	// トークンをローテーションして発行
+	newToken, err := h.Repo.RotateToken(token, newScopes, h.AccessTokenExp, h.IsRefreshEnabled)
 	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			// 同じリフレッシュトークンが同時に使用された
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+
+	res := &tokenResponse{
+		TokenType:   authScheme,
+		AccessToken: newToken.AccessToken,
+		ExpiresIn:   newToken.ExpiresIn,
+	}
+	if len(token.Scopes) != len(newToken.Scopes) {
+		res.Scope = newToken.Scopes.String()
+	}
+	if newToken.IsRefreshEnabled() {
+		res.RefreshToken = newToken.RefreshToken
+	}
+	if newToken.Scopes.Contains(model.ScopeOpenID) {
+		idToken, err := h.issueIDToken(newToken, "")
+		if err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		res.IDToken = idToken
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// detectRefreshTokenReuse 無効なリフレッシュトークンが使用済みのものかどうかを確認します
+//
+// 使用済みのリフレッシュトークンが再度使用された場合、トークンが漏洩した可能性があるため、
+// 同じファミリーのトークンを全て無効化します。
+func (h *Handler) detectRefreshTokenReuse(c echo.Context, refresh string) error {
+	rotated, err := h.Repo.GetRotatedTokenByRefresh(refresh)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+
+	h.L(c).Warn("refresh token reuse detected. revoking token family",
+		zap.Stringer("tokenId", rotated.ID),
+		zap.Stringer("familyId", rotated.FamilyID),
+		zap.String("clientId", rotated.ClientID),
+		zap.Stringer("userId", rotated.UserID),
+		zap.String("ip", c.RealIP()),
+	)
+	if err := h.Repo.DeleteTokenFamily(rotated.FamilyID); err != nil {
 		h.L(c).Error(err.Error(), zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 	}
-	if err := h.Repo.DeleteTokenByRefresh(req.RefreshToken); err != nil {
+	return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+}
+
+type tokenEndpointDeviceCodeHandlerRequest struct {
+	DeviceCode   string `form:"device_code"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+func (r tokenEndpointDeviceCodeHandlerRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.DeviceCode, vd.Required),
+	)
+}
+
+func (h *Handler) tokenEndpointDeviceCodeHandler(c echo.Context) error {
+	var req tokenEndpointDeviceCodeHandlerRequest
+	if err := extension.BindAndValidate(c, &req); err != nil {
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidRequest})
+	}
+
+	// デバイスコード確認
+	da, err := h.Repo.GetDeviceAuthorizationByDeviceCode(req.DeviceCode)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+
+	// クライアント確認
+	client, err := h.Repo.GetClient(da.ClientID)
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		default:
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+	}
+	id, pw, ok := c.Request().BasicAuth()
+	if !ok { // Request Body
+		if len(req.ClientID) == 0 {
+			return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidClient})
+		}
+		id = req.ClientID
+		pw = req.ClientSecret
+	}
+	if client.ID != id || (client.Confidential && client.Secret != pw) {
+		return c.JSON(http.StatusUnauthorized, oauth2ErrorResponse{ErrorType: errInvalidClient})
+	}
+
+	if da.IsExpired() {
+		if err := h.Repo.DeleteDeviceAuthorization(da.DeviceCode); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errExpiredToken})
+	}
+
+	switch da.Status {
+	case model.DeviceAuthorizationPending:
+		// ポーリング間隔確認 (短すぎる場合は間隔を5秒延ばす)
+		now := time.Now()
+		errType := errAuthorizationPending
+		interval := da.Interval
+		if da.IsPollingTooFast(now) {
+			errType = errSlowDown
+			interval += deviceCodeInterval
+		}
+		if err := h.Repo.UpdateDeviceAuthorizationPolling(da.DeviceCode, interval, now); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errType})
+
+	case model.DeviceAuthorizationApproved:
+		// デバイスコードは２回使えない (同時にポーリングされた場合も、消費できた1つのリクエストにのみ発行する)
+		if err := h.Repo.ConsumeApprovedDeviceAuthorization(da.DeviceCode); err != nil {
+			if err == repository.ErrNotFound {
+				return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errInvalidGrant})
+			}
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+
+	default: // 拒否された
+		if err := h.Repo.DeleteDeviceAuthorization(da.DeviceCode); err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		return c.JSON(http.StatusBadRequest, oauth2ErrorResponse{ErrorType: errAccessDenied})
+	}
+
+	// トークン発行
+	newToken, err := h.Repo.IssueToken(client, da.UserID, client.RedirectURI, da.Scopes, h.AccessTokenExp, h.IsRefreshEnabled)
+	if err != nil {
 		h.L(c).Error(err.Error(), zap.Error(err))
 		return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
 	}
@@ -381,11 +555,19 @@ func (h *Handler) tokenEndpointRefreshTokenHandler(c echo.Context) error {
 		AccessToken: newToken.AccessToken,
 		ExpiresIn:   newToken.ExpiresIn,
 	}
-	if len(token.Scopes) != len(newToken.Scopes) {
+	if len(da.OriginalScopes) != len(newToken.Scopes) {
 		res.Scope = newToken.Scopes.String()
 	}
 	if newToken.IsRefreshEnabled() {
 		res.RefreshToken = newToken.RefreshToken
 	}
+	if newToken.Scopes.Contains(model.ScopeOpenID) {
+		idToken, err := h.issueIDToken(newToken, "")
+		if err != nil {
+			h.L(c).Error(err.Error(), zap.Error(err))
+			return c.JSON(http.StatusInternalServerError, oauth2ErrorResponse{ErrorType: errServerError})
+		}
+		res.IDToken = idToken
+	}
 	return c.JSON(http.StatusOK, res)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	random2 "github.com/traPtitech/traQ/utils/random"
 	"net/http"
+	"sync"
 	"testing"
 	"time"
 )
@@ -629,6 +630,84 @@ func TestHandlers_TokenEndpointRefreshTokenHandler(t *testing.T) {
 		assert.EqualError(t, err, repository.ErrNotFound.Error())
 	})
 
+	t.Run("Success with rotation", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), true)
+		e := env.R(t)
+		refresh := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeRefreshToken).
+			WithFormField("refresh_token", token.RefreshToken).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("refresh_token").
+			String().
+			Raw()
+
+		newToken, err := env.Repository.GetTokenByRefresh(refresh)
+		require.NoError(t, err)
+		assert.Equal(t, token.FamilyID, newToken.FamilyID)
+		assert.Equal(t, token.ID, newToken.ParentID.UUID)
+		assert.WithinDuration(t, token.FamilyCreatedAt, newToken.FamilyCreatedAt, time.Second)
+	})
+
+	t.Run("Invalid Grant (Reused refresh token)", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), true)
+		e := env.R(t)
+		refresh := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeRefreshToken).
+			WithFormField("refresh_token", token.RefreshToken).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("refresh_token").
+			String().
+			Raw()
+
+		res := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeRefreshToken).
+			WithFormField("refresh_token", token.RefreshToken).
+			Expect()
+
+		res.Status(http.StatusBadRequest)
+		res.JSON().Object().Value("error").String().Equal(errInvalidGrant)
+
+		// 同じファミリーのトークンが全て無効化される
+		_, err := env.Repository.GetTokenByRefresh(refresh)
+		assert.EqualError(t, err, repository.ErrNotFound.Error())
+	})
+
+	t.Run("Invalid Grant (Idle expired refresh token)", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), true)
+		require.NoError(t, env.DB.Model(token).UpdateColumn("created_at", time.Now().Add(-2000*time.Second)).Error)
+		e := env.R(t)
+		res := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeRefreshToken).
+			WithFormField("refresh_token", token.RefreshToken).
+			Expect()
+
+		res.Status(http.StatusBadRequest)
+		res.JSON().Object().Value("error").String().Equal(errInvalidGrant)
+	})
+
+	t.Run("Invalid Grant (Absolute expired refresh token)", func(t *testing.T) {
+		t.Parallel()
+		token := env.IssueToken(t, client, user.GetID(), true)
+		require.NoError(t, env.DB.Model(token).UpdateColumn("family_created_at", time.Now().Add(-20000*time.Second)).Error)
+		e := env.R(t)
+		res := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeRefreshToken).
+			WithFormField("refresh_token", token.RefreshToken).
+			Expect()
+
+		res.Status(http.StatusBadRequest)
+		res.JSON().Object().Value("error").String().Equal(errInvalidGrant)
+	})
+
 	t.Run("Invalid Request (No refresh token)", func(t *testing.T) {
 		t.Parallel()
 		e := env.R(t)
@@ -1238,3 +1317,177 @@ func TestHandlers_TokenEndpointAuthorizationCodeHandler(t *testing.T) {
 		assert.EqualError(t, err, repository.ErrNotFound.Error())
 	})
 }
+
+func TestHandlers_TokenEndpointDeviceCodeHandler(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db2)
+	user := env.CreateUser(t, rand)
+
+	scopesRead := model.AccessScopes{}
+	scopesRead.Add("read")
+	client := &model.OAuth2Client{
+		ID:           random2.AlphaNumeric(36),
+		Name:         "test client",
+		Confidential: false,
+		CreatorID:    user.GetID(),
+		RedirectURI:  "http://example.com",
+		Scopes:       scopesRead,
+	}
+	require.NoError(t, env.Repository.SaveClient(client))
+
+	makeDeviceAuthorization := func(t *testing.T, status model.OAuth2DeviceAuthorizationStatus, createdAt time.Time) *model.OAuth2DeviceAuthorization {
+		t.Helper()
+		da := &model.OAuth2DeviceAuthorization{
+			DeviceCode:     random2.AlphaNumeric(36),
+			UserCode:       generateUserCode(),
+			ClientID:       client.ID,
+			UserID:         user.GetID(),
+			Status:         status,
+			Scopes:         scopesRead,
+			OriginalScopes: scopesRead,
+			ExpiresIn:      deviceCodeExp,
+			Interval:       deviceCodeInterval,
+			CreatedAt:      createdAt,
+		}
+		require.NoError(t, env.Repository.SaveDeviceAuthorization(da))
+		return da
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		da := makeDeviceAuthorization(t, model.DeviceAuthorizationApproved, time.Now())
+		e := env.R(t)
+		res := e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", da.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect()
+
+		res.Status(http.StatusOK)
+		res.Header("Cache-Control").Equal("no-store")
+		res.Header("Pragma").Equal("no-cache")
+		obj := res.JSON().Object()
+		obj.Value("access_token").String().NotEmpty()
+		obj.Value("token_type").String().Equal(authScheme)
+		obj.Value("expires_in").Number().Equal(1000)
+		obj.Value("refresh_token").String().NotEmpty()
+		obj.NotContainsKey("scope")
+
+		_, err := env.Repository.GetDeviceAuthorizationByDeviceCode(da.DeviceCode)
+		assert.EqualError(t, err, repository.ErrNotFound.Error())
+	})
+
+	t.Run("Concurrent polling", func(t *testing.T) {
+		t.Parallel()
+		da := makeDeviceAuthorization(t, model.DeviceAuthorizationApproved, time.Now())
+		e := env.R(t)
+
+		const n = 5
+		statuses := make(chan int, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				statuses <- e.POST("/oauth2/token").
+					WithFormField("grant_type", grantTypeDeviceCode).
+					WithFormField("device_code", da.DeviceCode).
+					WithFormField("client_id", client.ID).
+					Expect().
+					Raw().StatusCode
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		// 同じデバイスコードに対してトークンは1度しか発行されない
+		succeeded := 0
+		for status := range statuses {
+			if status == http.StatusOK {
+				succeeded++
+			} else {
+				assert.Equal(t, http.StatusBadRequest, status)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	t.Run("Authorization Pending and Slow Down", func(t *testing.T) {
+		t.Parallel()
+		da := makeDeviceAuthorization(t, model.DeviceAuthorizationPending, time.Now())
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", da.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("error").String().Equal(errAuthorizationPending)
+
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", da.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("error").String().Equal(errSlowDown)
+
+		actual, err := env.Repository.GetDeviceAuthorizationByDeviceCode(da.DeviceCode)
+		require.NoError(t, err)
+		assert.Equal(t, deviceCodeInterval*2, actual.Interval)
+	})
+
+	t.Run("Access Denied", func(t *testing.T) {
+		t.Parallel()
+		da := makeDeviceAuthorization(t, model.DeviceAuthorizationDenied, time.Now())
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", da.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("error").String().Equal(errAccessDenied)
+
+		_, err := env.Repository.GetDeviceAuthorizationByDeviceCode(da.DeviceCode)
+		assert.EqualError(t, err, repository.ErrNotFound.Error())
+	})
+
+	t.Run("Expired Token", func(t *testing.T) {
+		t.Parallel()
+		da := makeDeviceAuthorization(t, model.DeviceAuthorizationApproved, time.Now().Add(-time.Hour))
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", da.DeviceCode).
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("error").String().Equal(errExpiredToken)
+	})
+
+	t.Run("Invalid Grant (Unknown device code)", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", "unknown").
+			WithFormField("client_id", client.ID).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().Object().Value("error").String().Equal(errInvalidGrant)
+	})
+
+	t.Run("Invalid Client (Different client)", func(t *testing.T) {
+		t.Parallel()
+		da := makeDeviceAuthorization(t, model.DeviceAuthorizationApproved, time.Now())
+		e := env.R(t)
+		e.POST("/oauth2/token").
+			WithFormField("grant_type", grantTypeDeviceCode).
+			WithFormField("device_code", da.DeviceCode).
+			WithFormField("client_id", "other").
+			Expect().
+			Status(http.StatusUnauthorized).
+			JSON().Object().Value("error").String().Equal(errInvalidClient)
+	})
+}
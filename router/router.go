@@ -12,6 +12,7 @@ import (
 	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/middlewares"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/router/v1"
 	"github.com/traPtitech/traQ/router/v3"
@@ -27,6 +28,7 @@ type Router struct {
 	v1        *v1.Handlers
 	v3        *v3.Handlers
 	oauth2    *oauth2.Handler
+	scim      *scim.Handler
 }
 
 func Setup(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Services, logger *zap.Logger, config *Config) *echo.Echo {
@@ -40,6 +42,9 @@ func Setup(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Se
 	r.oauth2.Setup(api.Group("/oauth2"))
 	r.oauth2.Setup(api.Group("/1.0/oauth2"))
 	r.oauth2.Setup(api.Group("/v3/oauth2"))
+	if r.scim.Valid() {
+		r.scim.Setup(api.Group("/scim/v2"))
+	}
 
 	// 外部authハンドラ
 	extAuth := api.Group("/auth")
@@ -66,6 +71,18 @@ func Setup(hub *hub.Hub, db *gorm.DB, repo repository.Repository, ss *service.Se
 		extAuth.GET("/oidc", p.LoginHandler)
 		extAuth.GET("/oidc/callback", p.CallbackHandler)
 	}
+	if config.ExternalAuth.SAML.Valid() {
+		p, err := auth.NewSAMLProvider(repo, ss.FileManager, logger.Named("ext_auth"), r.sessStore, config.ExternalAuth.SAML)
+		if err != nil {
+			// IdPのメタデータを取得できない場合などは、SAML認証のみを無効にして起動を続ける
+			logger.Error("failed to initialize saml provider. saml login is disabled", zap.Error(err))
+			delete(r.v3.EnabledExternalAccountProviders, auth.SAMLProviderName)
+		} else {
+			extAuth.GET("/saml", p.LoginHandler)
+			extAuth.POST("/saml/acs", p.ACSHandler)
+			extAuth.GET("/saml/metadata", p.MetadataHandler)
+		}
+	}
 
 	return r.e
 }
@@ -0,0 +1,369 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// filterExpr SCIMのフィルタ式 (RFC 7644 3.4.2.2)
+//
+// リソースをJSONに変換したmap[string]interface{}に対して評価します。
+type filterExpr interface {
+	match(res map[string]interface{}) bool
+}
+
+type logicalExpr struct {
+	and         bool
+	left, right filterExpr
+}
+
+func (e *logicalExpr) match(res map[string]interface{}) bool {
+	if e.and {
+		return e.left.match(res) && e.right.match(res)
+	}
+	return e.left.match(res) || e.right.match(res)
+}
+
+type notExpr struct {
+	expr filterExpr
+}
+
+func (e *notExpr) match(res map[string]interface{}) bool {
+	return !e.expr.match(res)
+}
+
+type attrExpr struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (e *attrExpr) match(res map[string]interface{}) bool {
+	values := lookupValues(res, e.path)
+	if e.op == "pr" {
+		for _, v := range values {
+			if !isEmptyValue(v) {
+				return true
+			}
+		}
+		return false
+	}
+	if e.op == "ne" {
+		for _, v := range values {
+			if compareValue(v, "eq", e.value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range values {
+		if compareValue(v, e.op, e.value) {
+			return true
+		}
+	}
+	return false
+}
+
+type valuePathExpr struct {
+	path   []string
+	filter filterExpr
+}
+
+func (e *valuePathExpr) match(res map[string]interface{}) bool {
+	for _, v := range lookupValues(res, e.path) {
+		if m, ok := v.(map[string]interface{}); ok && e.filter.match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFilter SCIMのフィルタ文字列を解析します
+func parseFilter(s string) (filterExpr, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token: %s", p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) expect(t string) error {
+	if p.next() != t {
+		return fmt.Errorf("%s is expected", t)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (filterExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpr{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterExpr, error) {
+	switch t := p.peek(); {
+	case strings.EqualFold(t, "not"):
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &notExpr{expr: expr}, nil
+	case t == "(":
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case len(t) == 0:
+		return nil, errors.New("unexpected end of filter")
+	}
+
+	path := splitAttrPath(p.next())
+	if p.peek() == "[" {
+		p.next()
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &valuePathExpr{path: path, filter: filter}, nil
+	}
+
+	op := strings.ToLower(p.next())
+	switch op {
+	case "pr":
+		return &attrExpr{path: path, op: op}, nil
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+		raw := p.next()
+		if len(raw) == 0 {
+			return nil, errors.New("comparison value is expected")
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return nil, fmt.Errorf("invalid comparison value: %s", raw)
+		}
+		return &attrExpr{path: path, op: op, value: v}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator: %s", op)
+	}
+}
+
+// tokenizeFilter フィルタ文字列をトークンに分割します
+func tokenizeFilter(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, s[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(s) && !strings.ContainsRune(" ()[]\"", rune(s[j])); j++ {
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// splitAttrPath 属性パスをスキーマURNを除いて分割します
+func splitAttrPath(s string) []string {
+	for _, schema := range []string{schemaUser, schemaGroup} {
+		if len(s) > len(schema) && strings.EqualFold(s[:len(schema)+1], schema+":") {
+			s = s[len(schema)+1:]
+			break
+		}
+	}
+	if strings.EqualFold(s, schemaGroupExtension) {
+		return []string{schemaGroupExtension}
+	}
+	if len(s) > len(schemaGroupExtension) && strings.EqualFold(s[:len(schemaGroupExtension)+1], schemaGroupExtension+":") {
+		return append([]string{schemaGroupExtension}, strings.Split(s[len(schemaGroupExtension)+1:], ".")...)
+	}
+	return strings.Split(s, ".")
+}
+
+// lookupValues リソースから属性パスの値を全て取り出します 複数値属性は展開されます
+func lookupValues(v interface{}, path []string) []interface{} {
+	if arr, ok := v.([]interface{}); ok {
+		var res []interface{}
+		for _, e := range arr {
+			res = append(res, lookupValues(e, path)...)
+		}
+		return res
+	}
+	if len(path) == 0 {
+		return []interface{}{v}
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	k, ok := findKey(m, path[0])
+	if !ok {
+		return nil
+	}
+	return lookupValues(m[k], path[1:])
+}
+
+// findKey mapから大文字小文字を区別せずにキーを探します
+func findKey(m map[string]interface{}, name string) (string, bool) {
+	if _, ok := m[name]; ok {
+		return name, true
+	}
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// compareValue 属性値とフィルタの値を比較します 文字列は大文字小文字を区別しません
+func compareValue(v interface{}, op string, target interface{}) bool {
+	switch t := target.(type) {
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return false
+		}
+		s, t = strings.ToLower(s), strings.ToLower(t)
+		switch op {
+		case "eq":
+			return s == t
+		case "co":
+			return strings.Contains(s, t)
+		case "sw":
+			return strings.HasPrefix(s, t)
+		case "ew":
+			return strings.HasSuffix(s, t)
+		case "gt":
+			return s > t
+		case "ge":
+			return s >= t
+		case "lt":
+			return s < t
+		case "le":
+			return s <= t
+		}
+	case float64:
+		n, ok := v.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return n == t
+		case "gt":
+			return n > t
+		case "ge":
+			return n >= t
+		case "lt":
+			return n < t
+		case "le":
+			return n <= t
+		}
+	case bool:
+		b, ok := v.(bool)
+		return ok && op == "eq" && b == t
+	case nil:
+		return op == "eq" && v == nil
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package scim
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func testResource(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "takashi",
+		"displayName": "Takashi Trap",
+		"active": true,
+		"emails": [
+			{"value": "takashi@example.com", "type": "work", "primary": true},
+			{"value": "takashi@home.example.com", "type": "home"}
+		],
+		"urn:traptitech:params:scim:schemas:extension:traq:2.0:Group": {"type": "grade"}
+	}`), &m))
+	return m
+}
+
+func TestParseFilter(t *testing.T) {
+	t.Parallel()
+	res := testResource(t)
+
+	cases := []struct {
+		filter string
+		match  bool
+	}{
+		{`userName eq "takashi"`, true},
+		{`USERNAME EQ "TAKASHI"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "takashi"`, true},
+		{`userName ne "takashi"`, false},
+		{`userName co "kas"`, true},
+		{`userName sw "tak"`, true},
+		{`userName ew "shi"`, true},
+		{`userName gt "a"`, true},
+		{`userName lt "a"`, false},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`externalId pr`, false},
+		{`displayName pr`, true},
+		{`emails.value eq "takashi@home.example.com"`, true},
+		{`emails[type eq "work" and value co "example.com"]`, true},
+		{`emails[type eq "other"]`, false},
+		{`userName eq "x" or displayName sw "Takashi"`, true},
+		{`userName eq "takashi" and not (active eq true)`, false},
+		{`(userName eq "x" or userName eq "takashi") and active eq true`, true},
+		{`urn:traptitech:params:scim:schemas:extension:traq:2.0:Group:type eq "grade"`, true},
+		{`displayName eq "Takashi \"Trap\""`, false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.filter, func(t *testing.T) {
+			t.Parallel()
+			f, err := parseFilter(c.filter)
+			require.NoError(t, err)
+			assert.Equal(t, c.match, f.match(res))
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	t.Parallel()
+
+	cases := []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName xx "a"`,
+		`userName eq "a`,
+		`userName eq invalid`,
+		`(userName eq "a"`,
+		`emails[type eq "work"`,
+		`userName eq "a" extra`,
+		`not userName eq "a"`,
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c, func(t *testing.T) {
+			t.Parallel()
+			_, err := parseFilter(c)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package scim
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// GetGroups GET /Groups
+func (h *Handler) GetGroups(c echo.Context) error {
+	filter, err := parseFilterParam(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	groups, err := h.findGroups(filter)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	resources := make([]interface{}, len(groups))
+	for i, g := range groups {
+		resources[i] = h.formatGroup(g)
+	}
+	resources, err = filterResources(filter, resources)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	return paginate(c, resources)
+}
+
+// findGroups フィルタの候補となるグループを取得します
+//
+// displayNameの完全一致のフィルタはDBで検索します。
+func (h *Handler) findGroups(filter filterExpr) ([]*model.UserGroup, error) {
+	if f, ok := filter.(*attrExpr); ok && f.op == "eq" && len(f.path) == 1 && strings.EqualFold(f.path[0], "displayName") {
+		if v, ok := f.value.(string); ok {
+			g, err := h.Repo.GetUserGroupByName(v)
+			switch {
+			case err == repository.ErrNotFound:
+				return []*model.UserGroup{}, nil
+			case err != nil:
+				return nil, err
+			}
+			return []*model.UserGroup{g}, nil
+		}
+	}
+	return h.Repo.GetAllUserGroups()
+}
+
+// GetGroup GET /Groups/:id
+func (h *Handler) GetGroup(c echo.Context) error {
+	g, err := h.getGroup(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return h.respondGroup(c, http.StatusOK, g.ID)
+}
+
+// CreateGroup POST /Groups
+func (h *Handler) CreateGroup(c echo.Context) error {
+	var req groupResource
+	if err := bindJSON(c, &req); err != nil {
+		return h.handleError(c, err)
+	}
+
+	gType := h.GroupType
+	if req.Extension != nil {
+		gType = req.Extension.Type
+	}
+	members, err := h.validateMembers(req.Members)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	g, err := h.Repo.CreateUserGroup(req.DisplayName, "", gType, uuid.Nil)
+	if err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return scimError(c, http.StatusBadRequest, errInvalidValue, err.Error())
+		case err == repository.ErrAlreadyExists:
+			return scimError(c, http.StatusConflict, errUniqueness, "displayName conflicts")
+		default:
+			return h.internalError(c, err)
+		}
+	}
+	if err := h.updateMembers(g, members); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return h.respondGroup(c, http.StatusCreated, g.ID)
+}
+
+// ReplaceGroup PUT /Groups/:id
+func (h *Handler) ReplaceGroup(c echo.Context) error {
+	g, err := h.getGroup(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	var req groupResource
+	if err := bindJSON(c, &req); err != nil {
+		return h.handleError(c, err)
+	}
+	if err := h.updateGroup(g, &req); err != nil {
+		return h.handleError(c, err)
+	}
+	return h.respondGroup(c, http.StatusOK, g.ID)
+}
+
+// PatchGroup PATCH /Groups/:id
+func (h *Handler) PatchGroup(c echo.Context) error {
+	g, err := h.getGroup(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	var req patchRequest
+	if err := bindJSON(c, &req); err != nil {
+		return h.handleError(c, err)
+	}
+
+	m, err := toMap(h.formatGroup(g))
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	if err := applyPatch(m, &req); err != nil {
+		return h.handleError(c, err)
+	}
+	if _, ok := findKey(m, "members"); !ok {
+		// membersが全て削除された
+		m["members"] = []interface{}{}
+	}
+	var patched groupResource
+	if err := fromMap(m, &patched); err != nil {
+		return h.handleError(c, err)
+	}
+	if err := h.updateGroup(g, &patched); err != nil {
+		return h.handleError(c, err)
+	}
+	return h.respondGroup(c, http.StatusOK, g.ID)
+}
+
+// DeleteGroup DELETE /Groups/:id
+func (h *Handler) DeleteGroup(c echo.Context) error {
+	g, err := h.getGroup(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	if err := h.Repo.DeleteUserGroup(g.ID); err != nil {
+		if err == repository.ErrNotFound {
+			return h.handleError(c, errNotFound)
+		}
+		return h.internalError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getGroup パスパラメータidのグループを取得します
+//
+// グループが存在しない場合はerrNotFoundを返します。
+func (h *Handler) getGroup(c echo.Context) (*model.UserGroup, error) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		return nil, errNotFound
+	}
+	g, err := h.Repo.GetUserGroup(id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, errNotFound
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+// updateGroup リソースの内容をグループに反映します
+//
+// membersが指定されていない場合はメンバーを変更しません。
+func (h *Handler) updateGroup(g *model.UserGroup, req *groupResource) error {
+	members, err := h.validateMembers(req.Members)
+	if err != nil {
+		return err
+	}
+
+	var (
+		args    repository.UpdateUserGroupNameArgs
+		changed bool
+	)
+	if len(req.DisplayName) > 0 && req.DisplayName != g.Name {
+		args.Name = optional.StringFrom(req.DisplayName)
+		changed = true
+	}
+	if req.Extension != nil && req.Extension.Type != g.Type {
+		args.Type = optional.StringFrom(req.Extension.Type)
+		changed = true
+	}
+	if changed {
+		if err := h.Repo.UpdateUserGroup(g.ID, args); err != nil {
+			switch {
+			case repository.IsArgError(err):
+				return badRequest(errInvalidValue, err.Error())
+			case err == repository.ErrAlreadyExists:
+				return conflict("displayName conflicts")
+			default:
+				return err
+			}
+		}
+	}
+
+	if req.Members != nil {
+		return h.updateMembers(g, members)
+	}
+	return nil
+}
+
+// validateMembers membersのユーザーが存在するか確認し、そのIDを返します
+func (h *Handler) validateMembers(members []groupMember) (set.UUID, error) {
+	ids := set.UUID{}
+	for _, m := range members {
+		id, err := uuid.FromString(m.Value)
+		if err != nil {
+			return nil, badRequest(errInvalidValue, "invalid member: "+m.Value)
+		}
+		if ids.Contains(id) {
+			continue
+		}
+		if ok, err := h.Repo.UserExists(id); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, badRequest(errInvalidValue, "member not found: "+m.Value)
+		}
+		ids.Add(id)
+	}
+	return ids, nil
+}
+
+// updateMembers グループのメンバーをmembersに置き換えます
+func (h *Handler) updateMembers(g *model.UserGroup, members set.UUID) error {
+	for id := range members {
+		if g.IsMember(id) {
+			continue
+		}
+		if err := h.Repo.AddUserToGroup(id, g.ID, ""); err != nil {
+			return err
+		}
+	}
+	for _, m := range g.Members {
+		if members.Contains(m.UserID) {
+			continue
+		}
+		if err := h.Repo.RemoveUserFromGroup(m.UserID, g.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatGroup グループをSCIMのGroupリソースに変換します
+func (h *Handler) formatGroup(g *model.UserGroup) *groupResource {
+	id := g.ID.String()
+	members := make([]groupMember, len(g.Members))
+	for i, m := range g.Members {
+		uid := m.UserID.String()
+		members[i] = groupMember{Value: uid, Ref: h.location("Users", uid)}
+	}
+	return &groupResource{
+		Schemas:     []string{schemaGroup, schemaGroupExtension},
+		ID:          id,
+		DisplayName: g.Name,
+		Members:     members,
+		Extension:   &groupExtension{Type: g.Type},
+		Meta: &meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+			Location:     h.location("Groups", id),
+		},
+	}
+}
+
+// respondGroup グループを再取得してレスポンスとして返します
+func (h *Handler) respondGroup(c echo.Context, status int, id uuid.UUID) error {
+	g, err := h.Repo.GetUserGroup(id)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	res := h.formatGroup(g)
+	c.Response().Header().Set(echo.HeaderLocation, res.Meta.Location)
+	return scimJSON(c, status, res)
+}
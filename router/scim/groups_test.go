@@ -0,0 +1,159 @@
+package scim
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func TestHandler_CreateGroup(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		name := random.AlphaNumeric(20)
+		e := env.R(t)
+		obj := e.POST("/scim/v2/Groups").
+			WithJSON(map[string]interface{}{
+				"schemas":     []string{schemaGroup},
+				"displayName": name,
+				"members":     []map[string]interface{}{{"value": user.GetID()}},
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("displayName").String().Equal(name)
+		obj.Value("members").Array().Length().Equal(1)
+		obj.Value(schemaGroupExtension).Object().ValueEqual("type", testGroupType)
+
+		g, err := env.Repository.GetUserGroupByName(name)
+		require.NoError(t, err)
+		assert.True(t, g.IsMember(user.GetID()))
+		assert.Empty(t, g.Admins)
+	})
+
+	t.Run("WithType", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/scim/v2/Groups").
+			WithJSON(map[string]interface{}{
+				"schemas":            []string{schemaGroup, schemaGroupExtension},
+				"displayName":        random.AlphaNumeric(20),
+				schemaGroupExtension: map[string]interface{}{"type": "grade"},
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON(scimJSONOpts).
+			Object().
+			Value(schemaGroupExtension).Object().ValueEqual("type", "grade")
+	})
+
+	t.Run("UnknownMember", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/scim/v2/Groups").
+			WithJSON(map[string]interface{}{
+				"schemas":     []string{schemaGroup},
+				"displayName": random.AlphaNumeric(20),
+				"members":     []map[string]interface{}{{"value": "unknown"}},
+			}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("scimType", errInvalidValue)
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		t.Parallel()
+		g := env.CreateUserGroup(t, rand)
+		e := env.R(t)
+		e.POST("/scim/v2/Groups").
+			WithJSON(map[string]interface{}{
+				"schemas":     []string{schemaGroup},
+				"displayName": g.Name,
+			}).
+			Expect().
+			Status(http.StatusConflict)
+	})
+}
+
+func TestHandler_PatchGroup(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	t.Run("Members", func(t *testing.T) {
+		t.Parallel()
+		user1 := env.CreateUser(t, rand)
+		user2 := env.CreateUser(t, rand)
+		g := env.CreateUserGroup(t, rand)
+		require.NoError(t, env.Repository.AddUserToGroup(user1.GetID(), g.ID, ""))
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Groups/{id}", g.ID).
+			WithJSON(map[string]interface{}{
+				"schemas": []string{schemaPatchOp},
+				"Operations": []map[string]interface{}{
+					{"op": "add", "path": "members", "value": []map[string]interface{}{{"value": user2.GetID()}}},
+					{"op": "remove", "path": `members[value eq "` + user1.GetID().String() + `"]`},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK)
+
+		g, err := env.Repository.GetUserGroup(g.ID)
+		require.NoError(t, err)
+		assert.False(t, g.IsMember(user1.GetID()))
+		assert.True(t, g.IsMember(user2.GetID()))
+	})
+
+	t.Run("Rename", func(t *testing.T) {
+		t.Parallel()
+		g := env.CreateUserGroup(t, rand)
+		name := random.AlphaNumeric(20)
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Groups/{id}", g.ID).
+			WithJSON(map[string]interface{}{
+				"schemas": []string{schemaPatchOp},
+				"Operations": []map[string]interface{}{
+					{"op": "replace", "path": "displayName", "value": name},
+					{"op": "replace", "path": schemaGroupExtension + ":type", "value": "grade"},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("displayName", name)
+
+		g, err := env.Repository.GetUserGroup(g.ID)
+		require.NoError(t, err)
+		assert.Equal(t, name, g.Name)
+		assert.Equal(t, "grade", g.Type)
+	})
+}
+
+func TestHandler_DeleteGroup(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	g := env.CreateUserGroup(t, rand)
+
+	e := env.R(t)
+	e.DELETE("/scim/v2/Groups/{id}", g.ID).
+		Expect().
+		Status(http.StatusNoContent)
+	e.GET("/scim/v2/Groups/{id}", g.ID).
+		Expect().
+		Status(http.StatusNotFound)
+
+	_, err := env.Repository.GetUserGroup(g.ID)
+	assert.Equal(t, repository.ErrNotFound, err)
+}
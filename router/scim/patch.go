@@ -0,0 +1,296 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// patchPath PATCH操作の対象パス
+//
+// attrPath[valFilter].subAttr の形式 (RFC 7644 3.5.2)
+type patchPath struct {
+	attr   []string
+	filter filterExpr
+	sub    string
+}
+
+// parsePatchPath PATCH操作のパスを解析します
+func parsePatchPath(s string) (*patchPath, error) {
+	i := strings.IndexByte(s, '[')
+	if i < 0 {
+		return &patchPath{attr: splitAttrPath(s)}, nil
+	}
+
+	j := strings.LastIndexByte(s, ']')
+	if j < i {
+		return nil, fmt.Errorf("invalid path: %s", s)
+	}
+	filter, err := parseFilter(s[i+1 : j])
+	if err != nil {
+		return nil, err
+	}
+	p := &patchPath{attr: splitAttrPath(s[:i]), filter: filter}
+	if rest := s[j+1:]; len(rest) > 0 {
+		if rest[0] != '.' || len(rest) == 1 {
+			return nil, fmt.Errorf("invalid path: %s", s)
+		}
+		p.sub = rest[1:]
+	}
+	return p, nil
+}
+
+// applyPatch リソースのmapにPATCH操作を順に適用します
+func applyPatch(res map[string]interface{}, req *patchRequest) error {
+	if len(req.Schemas) != 1 || req.Schemas[0] != schemaPatchOp {
+		return badRequest(errInvalidSyntax, "schemas must be "+schemaPatchOp)
+	}
+	for _, op := range req.Operations {
+		if err := applyOperation(res, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOperation(res map[string]interface{}, op patchOperation) error {
+	kind := strings.ToLower(op.Op)
+	switch kind {
+	case "add", "replace", "remove":
+	default:
+		return badRequest(errInvalidSyntax, fmt.Sprintf("unknown op: %s", op.Op))
+	}
+
+	var value interface{}
+	if len(op.Value) > 0 {
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return badRequest(errInvalidSyntax, fmt.Sprintf("invalid value: %s", err))
+		}
+	}
+
+	if len(op.Path) == 0 {
+		// パスが無い場合、valueは対象リソースに適用する属性のmap
+		if kind == "remove" {
+			return badRequest(errNoTarget, "path is required for remove operation")
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return badRequest(errInvalidValue, "value must be an object when path is not specified")
+		}
+		for k, v := range m {
+			if err := applyValue(res, &patchPath{attr: splitAttrPath(k)}, kind, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, err := parsePatchPath(op.Path)
+	if err != nil {
+		return badRequest(errInvalidPath, err.Error())
+	}
+	if kind != "remove" && value == nil {
+		return badRequest(errInvalidValue, "value is required")
+	}
+	return applyValue(res, path, kind, value)
+}
+
+func applyValue(res map[string]interface{}, path *patchPath, kind string, value interface{}) error {
+	// 対象属性を持つmapまで辿る
+	parent := res
+	for _, name := range path.attr[:len(path.attr)-1] {
+		k, ok := findKey(parent, name)
+		if !ok {
+			if kind == "remove" {
+				return nil
+			}
+			k = name
+			parent[k] = map[string]interface{}{}
+		}
+		child, ok := parent[k].(map[string]interface{})
+		if !ok {
+			return badRequest(errInvalidPath, fmt.Sprintf("%s is not a complex attribute", name))
+		}
+		parent = child
+	}
+
+	name := path.attr[len(path.attr)-1]
+	key, exists := findKey(parent, name)
+	if !exists {
+		key = name
+	}
+
+	if path.filter != nil {
+		arr, _ := parent[key].([]interface{})
+		arr, err := applyFiltered(arr, path, kind, value)
+		if err != nil {
+			return err
+		}
+		parent[key] = arr
+		return nil
+	}
+
+	switch kind {
+	case "add":
+		switch current := parent[key].(type) {
+		case []interface{}:
+			if values, ok := value.([]interface{}); ok {
+				parent[key] = append(current, values...)
+			} else {
+				parent[key] = append(current, value)
+			}
+		case map[string]interface{}:
+			values, ok := value.(map[string]interface{})
+			if !ok {
+				return badRequest(errInvalidValue, fmt.Sprintf("%s must be an object", name))
+			}
+			for k, v := range values {
+				current[k] = v
+			}
+		default:
+			parent[key] = value
+		}
+	case "replace":
+		parent[key] = value
+	case "remove":
+		current, ok := parent[key].([]interface{})
+		if !ok || value == nil {
+			delete(parent, key)
+			return nil
+		}
+		// 複数値属性からvalueで指定した要素のみを削除する
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		parent[key] = removeElements(current, values)
+	}
+	return nil
+}
+
+// applyFiltered フィルタに一致する複数値属性の要素に操作を適用します
+func applyFiltered(arr []interface{}, path *patchPath, kind string, value interface{}) ([]interface{}, error) {
+	matched := make([]bool, len(arr))
+	found := false
+	for i, e := range arr {
+		if m, ok := e.(map[string]interface{}); ok && path.filter.match(m) {
+			matched[i] = true
+			found = true
+		}
+	}
+
+	if kind == "remove" {
+		result := make([]interface{}, 0, len(arr))
+		for i, e := range arr {
+			if !matched[i] {
+				result = append(result, e)
+			} else if len(path.sub) > 0 {
+				m := e.(map[string]interface{})
+				if k, ok := findKey(m, path.sub); ok {
+					delete(m, k)
+				}
+				result = append(result, m)
+			}
+		}
+		return result, nil
+	}
+
+	if !found {
+		if kind == "replace" {
+			return nil, badRequest(errNoTarget, "no element matched the filter")
+		}
+		// 一致する要素が無い場合は、フィルタの条件を満たす要素を追加する
+		e := equalityConstraints(path.filter)
+		if e == nil {
+			return nil, badRequest(errNoTarget, "no element matched the filter")
+		}
+		arr = append(arr, e)
+		matched = append(matched, true)
+	}
+
+	for i, e := range arr {
+		if !matched[i] {
+			continue
+		}
+		m := e.(map[string]interface{})
+		if len(path.sub) > 0 {
+			k, ok := findKey(m, path.sub)
+			if !ok {
+				k = path.sub
+			}
+			m[k] = value
+			continue
+		}
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, badRequest(errInvalidValue, "value must be an object")
+		}
+		if kind == "replace" {
+			arr[i] = values
+			continue
+		}
+		for k, v := range values {
+			m[k] = v
+		}
+	}
+	return arr, nil
+}
+
+// equalityConstraints フィルタに含まれる等価条件からmapを作成します
+//
+// andとeqのみで構成されていないフィルタの場合はnilを返します。
+func equalityConstraints(f filterExpr) map[string]interface{} {
+	switch f := f.(type) {
+	case *attrExpr:
+		if f.op != "eq" || len(f.path) != 1 {
+			return nil
+		}
+		return map[string]interface{}{f.path[0]: f.value}
+	case *logicalExpr:
+		if !f.and {
+			return nil
+		}
+		l, r := equalityConstraints(f.left), equalityConstraints(f.right)
+		if l == nil || r == nil {
+			return nil
+		}
+		for k, v := range r {
+			l[k] = v
+		}
+		return l
+	}
+	return nil
+}
+
+// removeElements 複数値属性からvalueが一致する要素を削除します
+func removeElements(arr []interface{}, values []interface{}) []interface{} {
+	targets := make(map[string]bool, len(values))
+	for _, v := range values {
+		if m, ok := v.(map[string]interface{}); ok {
+			if s, ok := m["value"].(string); ok {
+				targets[strings.ToLower(s)] = true
+			}
+		}
+	}
+	result := make([]interface{}, 0, len(arr))
+	for _, e := range arr {
+		if m, ok := e.(map[string]interface{}); ok {
+			if s, ok := m["value"].(string); ok && targets[strings.ToLower(s)] {
+				continue
+			}
+		}
+		result = append(result, e)
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package scim
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	t.Parallel()
+
+	patch := func(t *testing.T, ops string) (map[string]interface{}, error) {
+		t.Helper()
+		var req patchRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"schemas":["`+schemaPatchOp+`"],"Operations":`+ops+`}`), &req))
+		res := testResource(t)
+		return res, applyPatch(res, &req)
+	}
+
+	t.Run("replace attribute", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"Replace","path":"displayName","value":"Trap"}]`)
+		require.NoError(t, err)
+		assert.Equal(t, "Trap", res["displayName"])
+	})
+
+	t.Run("replace without path", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"replace","value":{"active":false,"displayName":"Trap"}}]`)
+		require.NoError(t, err)
+		assert.Equal(t, false, res["active"])
+		assert.Equal(t, "Trap", res["displayName"])
+	})
+
+	t.Run("replace extension attribute", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"replace","path":"`+schemaGroupExtension+`:type","value":"club"}]`)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"type": "club"}, res[schemaGroupExtension])
+	})
+
+	t.Run("replace extension without path", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"add","value":{"`+schemaGroupExtension+`":{"type":"club"}}}]`)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"type": "club"}, res[schemaGroupExtension])
+	})
+
+	t.Run("add to multi-valued attribute", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"add","path":"emails","value":[{"value":"new@example.com"}]}]`)
+		require.NoError(t, err)
+		assert.Len(t, res["emails"], 3)
+	})
+
+	t.Run("add with filter creates element", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"add","path":"emails[type eq \"other\"].value","value":"other@example.com"}]`)
+		require.NoError(t, err)
+		emails := res["emails"].([]interface{})
+		require.Len(t, emails, 3)
+		assert.Equal(t, map[string]interface{}{"type": "other", "value": "other@example.com"}, emails[2])
+	})
+
+	t.Run("replace with filter", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"replace","path":"emails[type eq \"work\"].value","value":"changed@example.com"}]`)
+		require.NoError(t, err)
+		emails := res["emails"].([]interface{})
+		assert.Equal(t, "changed@example.com", emails[0].(map[string]interface{})["value"])
+		assert.Equal(t, "takashi@home.example.com", emails[1].(map[string]interface{})["value"])
+	})
+
+	t.Run("replace with filter no target", func(t *testing.T) {
+		t.Parallel()
+		_, err := patch(t, `[{"op":"replace","path":"emails[type eq \"other\"].value","value":"x@example.com"}]`)
+		var re *requestError
+		require.ErrorAs(t, err, &re)
+		assert.Equal(t, errNoTarget, re.scimType)
+	})
+
+	t.Run("remove attribute", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"remove","path":"displayName"}]`)
+		require.NoError(t, err)
+		assert.NotContains(t, res, "displayName")
+	})
+
+	t.Run("remove with filter", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"remove","path":"emails[type eq \"home\"]"}]`)
+		require.NoError(t, err)
+		assert.Len(t, res["emails"], 1)
+	})
+
+	t.Run("remove with value", func(t *testing.T) {
+		t.Parallel()
+		res, err := patch(t, `[{"op":"remove","path":"emails","value":[{"value":"TAKASHI@example.com"}]}]`)
+		require.NoError(t, err)
+		emails := res["emails"].([]interface{})
+		require.Len(t, emails, 1)
+		assert.Equal(t, "takashi@home.example.com", emails[0].(map[string]interface{})["value"])
+	})
+
+	t.Run("remove without path", func(t *testing.T) {
+		t.Parallel()
+		_, err := patch(t, `[{"op":"remove"}]`)
+		var re *requestError
+		require.ErrorAs(t, err, &re)
+		assert.Equal(t, errNoTarget, re.scimType)
+	})
+
+	t.Run("unknown op", func(t *testing.T) {
+		t.Parallel()
+		_, err := patch(t, `[{"op":"move","path":"displayName","value":"x"}]`)
+		var re *requestError
+		require.ErrorAs(t, err, &re)
+		assert.Equal(t, errInvalidSyntax, re.scimType)
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		t.Parallel()
+		_, err := patch(t, `[{"op":"replace","path":"emails[type eq]","value":"x"}]`)
+		var re *requestError
+		require.ErrorAs(t, err, &re)
+		assert.Equal(t, errInvalidPath, re.scimType)
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		t.Parallel()
+		err := applyPatch(map[string]interface{}{}, &patchRequest{Schemas: []string{schemaUser}})
+		var re *requestError
+		require.ErrorAs(t, err, &re)
+		assert.Equal(t, errInvalidSyntax, re.scimType)
+	})
+}
@@ -0,0 +1,271 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scimBool SCIMのboolean値
+//
+// 一部のIdPは"True"や"False"のような文字列でbooleanを送信するため、それも受け付けます。
+type scimBool bool
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (b *scimBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = scimBool(v)
+	case string:
+		p, err := strconv.ParseBool(strings.ToLower(v))
+		if err != nil {
+			return fmt.Errorf("invalid boolean: %s", v)
+		}
+		*b = scimBool(p)
+	default:
+		return fmt.Errorf("invalid boolean: %s", data)
+	}
+	return nil
+}
+
+func boolPtr(b bool) *scimBool {
+	v := scimBool(b)
+	return &v
+}
+
+type meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+type userEmail struct {
+	Value   string    `json:"value"`
+	Type    string    `json:"type,omitempty"`
+	Primary *scimBool `json:"primary,omitempty"`
+}
+
+// userResource SCIMのUserリソース
+type userResource struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Active      *scimBool   `json:"active,omitempty"`
+	Emails      []userEmail `json:"emails,omitempty"`
+	// Password 書き込み専用
+	Password string `json:"password,omitempty"`
+	Meta     *meta  `json:"meta,omitempty"`
+}
+
+// primaryEmail 主メールアドレスを返します
+func (u *userResource) primaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary != nil && bool(*e.Primary) {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+type groupMember struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type groupExtension struct {
+	Type string `json:"type"`
+}
+
+// groupResource SCIMのGroupリソース
+type groupResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []groupMember   `json:"members"`
+	Extension   *groupExtension `json:"urn:traptitech:params:scim:schemas:extension:traq:2.0:Group,omitempty"`
+	Meta        *meta           `json:"meta,omitempty"`
+}
+
+type listResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	StartIndex   int           `json:"startIndex"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// toMap リソースをフィルタやPATCHで扱うmapに変換します
+func toMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// fromMap mapをリソースに変換します
+func fromMap(m map[string]interface{}, v interface{}) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return badRequest(errInvalidValue, err.Error())
+	}
+	return nil
+}
+
+// filterResources filterに一致するリソースのみを返します
+func filterResources(filter filterExpr, resources []interface{}) ([]interface{}, error) {
+	if filter == nil {
+		return resources, nil
+	}
+	result := make([]interface{}, 0, len(resources))
+	for _, r := range resources {
+		m, err := toMap(r)
+		if err != nil {
+			return nil, err
+		}
+		if filter.match(m) {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+// parseFilterParam クエリパラメータfilterを解析します
+func parseFilterParam(c echo.Context) (filterExpr, error) {
+	s := c.QueryParam("filter")
+	if len(s) == 0 {
+		return nil, nil
+	}
+	f, err := parseFilter(s)
+	if err != nil {
+		return nil, badRequest(errInvalidFilter, err.Error())
+	}
+	return f, nil
+}
+
+// paginate クエリパラメータstartIndex, countに従ってリソース一覧のレスポンスを返します
+func paginate(c echo.Context, resources []interface{}) error {
+	startIndex, count := parsePagination(c)
+	page := make([]interface{}, 0)
+	if startIndex <= len(resources) {
+		end := startIndex - 1 + count
+		if end > len(resources) {
+			end = len(resources)
+		}
+		page = resources[startIndex-1 : end]
+	}
+	return respondList(c, len(resources), startIndex, page)
+}
+
+// parsePagination クエリパラメータstartIndex, countを解析します
+func parsePagination(c echo.Context) (startIndex, count int) {
+	startIndex, err := strconv.Atoi(c.QueryParam("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(c.QueryParam("count"))
+	if err != nil || count > maxResults {
+		count = maxResults
+	}
+	if count < 0 {
+		count = 0
+	}
+	return startIndex, count
+}
+
+// respondList リソース一覧のレスポンスを返します
+func respondList(c echo.Context, totalResults, startIndex int, page []interface{}) error {
+	return scimJSON(c, http.StatusOK, &listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: totalResults,
+		ItemsPerPage: len(page),
+		StartIndex:   startIndex,
+		Resources:    page,
+	})
+}
+
+// GetServiceProviderConfig GET /ServiceProviderConfig
+func (h *Handler) GetServiceProviderConfig(c echo.Context) error {
+	supported := func(b bool) echo.Map { return echo.Map{"supported": b} }
+	return scimJSON(c, http.StatusOK, echo.Map{
+		"schemas": []string{schemaServiceProviderConfig},
+		"patch":   supported(true),
+		"bulk": echo.Map{
+			"supported":      false,
+			"maxOperations":  0,
+			"maxPayloadSize": 0,
+		},
+		"filter": echo.Map{
+			"supported":  true,
+			"maxResults": maxResults,
+		},
+		"changePassword": supported(true),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []echo.Map{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication scheme using the OAuth Bearer Token Standard",
+			"primary":     true,
+		}},
+		"meta": echo.Map{
+			"resourceType": "ServiceProviderConfig",
+			"location":     h.Origin + "/api/scim/v2/ServiceProviderConfig",
+		},
+	})
+}
+
+// GetResourceTypes GET /ResourceTypes
+func (h *Handler) GetResourceTypes(c echo.Context) error {
+	resourceType := func(name, endpoint, schema string, extensions ...string) echo.Map {
+		exts := make([]echo.Map, len(extensions))
+		for i, ext := range extensions {
+			exts[i] = echo.Map{"schema": ext, "required": false}
+		}
+		return echo.Map{
+			"schemas":          []string{schemaResourceType},
+			"id":               name,
+			"name":             name,
+			"endpoint":         endpoint,
+			"schema":           schema,
+			"schemaExtensions": exts,
+			"meta": echo.Map{
+				"resourceType": "ResourceType",
+				"location":     h.location("ResourceTypes", name),
+			},
+		}
+	}
+	resources := []interface{}{
+		resourceType("User", "/Users", schemaUser),
+		resourceType("Group", "/Groups", schemaGroup, schemaGroupExtension),
+	}
+	return scimJSON(c, http.StatusOK, &listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: len(resources),
+		ItemsPerPage: len(resources),
+		StartIndex:   1,
+		Resources:    resources,
+	})
+}
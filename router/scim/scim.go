@@ -0,0 +1,186 @@
+package scim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/file"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	schemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaGroupExtension        = "urn:traptitech:params:scim:schemas:extension:traq:2.0:Group"
+	schemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	schemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	schemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	errInvalidFilter = "invalidFilter"
+	errInvalidSyntax = "invalidSyntax"
+	errInvalidPath   = "invalidPath"
+	errInvalidValue  = "invalidValue"
+	errNoTarget      = "noTarget"
+	errMutability    = "mutability"
+	errUniqueness    = "uniqueness"
+
+	mimeApplicationSCIM = "application/scim+json"
+	authScheme          = "Bearer"
+
+	// maxResults 一度に返すリソースの最大数
+	maxResults = 1000
+)
+
+// Handler SCIM 2.0 (RFC 7643, RFC 7644) プロビジョニングAPIのハンドラ
+type Handler struct {
+	Repo        repository.Repository
+	FileManager file.Manager
+	SessStore   session.Store
+	Logger      *zap.Logger
+	Config
+}
+
+// Config SCIM設定
+type Config struct {
+	// Token SCIMクライアントの認証に使用するBearerトークン 空の場合はSCIMを無効にします
+	Token string
+	// ExternalProviderName externalIdを紐付ける外部認証プロバイダ名
+	//
+	// SAMLやOIDCなどのプロバイダ名を指定すると、IdPから作成したユーザーがそのプロバイダでログインできます。
+	ExternalProviderName string
+	// GroupType SCIMで作成したユーザーグループの種類
+	GroupType string
+	// Origin サーバーオリジン (例: https://q.trap.jp)
+	Origin string
+}
+
+// Valid SCIMが有効かどうか
+func (c Config) Valid() bool {
+	return len(c.Token) > 0
+}
+
+// Setup ルーティングを行います
+func (h *Handler) Setup(e *echo.Group) {
+	e.Use(h.authenticate)
+	e.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+	e.GET("/ResourceTypes", h.GetResourceTypes)
+	e.GET("/Users", h.GetUsers)
+	e.POST("/Users", h.CreateUser)
+	e.GET("/Users/:id", h.GetUser)
+	e.PUT("/Users/:id", h.ReplaceUser)
+	e.PATCH("/Users/:id", h.PatchUser)
+	e.DELETE("/Users/:id", h.DeleteUser)
+	e.GET("/Groups", h.GetGroups)
+	e.POST("/Groups", h.CreateGroup)
+	e.GET("/Groups/:id", h.GetGroup)
+	e.PUT("/Groups/:id", h.ReplaceGroup)
+	e.PATCH("/Groups/:id", h.PatchGroup)
+	e.DELETE("/Groups/:id", h.DeleteGroup)
+}
+
+// authenticate SCIM用のBearerトークンを検証するミドルウェア
+func (h *Handler) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ah := c.Request().Header.Get(echo.HeaderAuthorization)
+		token := strings.TrimSpace(strings.TrimPrefix(ah, authScheme))
+		if !strings.HasPrefix(ah, authScheme+" ") || subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, authScheme+` realm="traQ SCIM"`)
+			return scimError(c, http.StatusUnauthorized, "", "invalid token")
+		}
+		return next(c)
+	}
+}
+
+type errorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// scimError SCIMのエラーレスポンスを返します
+func scimError(c echo.Context, status int, scimType, detail string) error {
+	return scimJSON(c, status, &errorResponse{
+		Schemas:  []string{schemaError},
+		Status:   strconv.Itoa(status),
+		SCIMType: scimType,
+		Detail:   detail,
+	})
+}
+
+// requestError クライアントのリクエストに起因するエラー
+type requestError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+// Error detailを返します
+func (e *requestError) Error() string {
+	return e.detail
+}
+
+// errNotFound リソースが見つからないエラー
+var errNotFound = &requestError{status: http.StatusNotFound, detail: "resource not found"}
+
+func badRequest(scimType, detail string) *requestError {
+	return &requestError{status: http.StatusBadRequest, scimType: scimType, detail: detail}
+}
+
+func conflict(detail string) *requestError {
+	return &requestError{status: http.StatusConflict, scimType: errUniqueness, detail: detail}
+}
+
+// handleError エラーに応じたSCIMエラーレスポンスを返します
+func (h *Handler) handleError(c echo.Context, err error) error {
+	var re *requestError
+	if errors.As(err, &re) {
+		return scimError(c, re.status, re.scimType, re.detail)
+	}
+	return h.internalError(c, err)
+}
+
+// internalError エラーをログに記録し、500のSCIMエラーレスポンスを返します
+func (h *Handler) internalError(c echo.Context, err error) error {
+	h.L(c).Error(err.Error(), zap.Error(err))
+	return scimError(c, http.StatusInternalServerError, "", http.StatusText(http.StatusInternalServerError))
+}
+
+// scimJSON application/scim+jsonでレスポンスを返します
+func scimJSON(c echo.Context, status int, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Blob(status, mimeApplicationSCIM, b)
+}
+
+// bindJSON リクエストボディをJSONとしてデコードします
+//
+// SCIMクライアントはContent-Typeにapplication/scim+jsonを指定するため、echoのBinderは使用しません。
+func bindJSON(c echo.Context, v interface{}) error {
+	if err := json.NewDecoder(c.Request().Body).Decode(v); err != nil {
+		return badRequest(errInvalidSyntax, fmt.Sprintf("invalid json: %s", err))
+	}
+	return nil
+}
+
+// location リソースのURLを返します
+func (h *Handler) location(resourceType, id string) string {
+	return h.Origin + "/api/scim/v2/" + resourceType + "/" + id
+}
+
+// L ロガーを返します
+func (h *Handler) L(c echo.Context) *zap.Logger {
+	return h.Logger.With(zap.String("requestId", extension.GetRequestID(c)))
+}
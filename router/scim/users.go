@@ -0,0 +1,503 @@
+package scim
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"github.com/traPtitech/traQ/utils/validator"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// GetUsers GET /Users
+//
+// userName, externalId, activeの完全一致とその論理積のフィルタは、DBで検索・ページングします。
+// それ以外のフィルタは、候補のユーザーを全て取得してから評価します。
+func (h *Handler) GetUsers(c echo.Context) error {
+	filter, err := parseFilterParam(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	if query, ok := h.usersQuery(filter); ok {
+		startIndex, count := parsePagination(c)
+		total, err := h.Repo.CountUsers(query)
+		if err != nil {
+			return h.internalError(c, err)
+		}
+		users := make([]model.UserInfo, 0)
+		if count > 0 && startIndex <= total {
+			users, err = h.Repo.GetUsers(query.Paginate(startIndex-1, count))
+			if err != nil {
+				return h.internalError(c, err)
+			}
+		}
+		resources, err := h.formatUsers(users)
+		if err != nil {
+			return h.internalError(c, err)
+		}
+		return respondList(c, total, startIndex, resources)
+	}
+
+	users, err := h.Repo.GetUsers(repository.UsersQuery{}.NotBot())
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].GetName() < users[j].GetName() })
+
+	resources, err := h.formatUsers(users)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	resources, err = filterResources(filter, resources)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	return paginate(c, resources)
+}
+
+// usersQuery フィルタをユーザーの検索条件に変換します
+//
+// DBで評価できないフィルタの場合はfalseを返します。
+func (h *Handler) usersQuery(filter filterExpr) (repository.UsersQuery, bool) {
+	q := repository.UsersQuery{}.NotBot()
+	if filter == nil {
+		return q, true
+	}
+	return h.appendUsersQuery(q, filter)
+}
+
+func (h *Handler) appendUsersQuery(q repository.UsersQuery, filter filterExpr) (repository.UsersQuery, bool) {
+	switch f := filter.(type) {
+	case *logicalExpr:
+		if !f.and {
+			return q, false
+		}
+		q, ok := h.appendUsersQuery(q, f.left)
+		if !ok {
+			return q, false
+		}
+		return h.appendUsersQuery(q, f.right)
+	case *attrExpr:
+		if f.op != "eq" || len(f.path) != 1 {
+			return q, false
+		}
+		switch {
+		case strings.EqualFold(f.path[0], "userName"):
+			v, ok := f.value.(string)
+			if !ok || q.IsNamed.Valid {
+				return q, false
+			}
+			return q.Named(v), true
+		case strings.EqualFold(f.path[0], "externalId"):
+			v, ok := f.value.(string)
+			if !ok || q.IsExternalUserOf.Valid {
+				return q, false
+			}
+			return q.ExternalUserOf(h.ExternalProviderName, v), true
+		case strings.EqualFold(f.path[0], "active"):
+			v, ok := f.value.(bool)
+			if !ok || q.IsActive.Valid {
+				return q, false
+			}
+			q.IsActive = optional.BoolFrom(v)
+			return q, true
+		}
+	}
+	return q, false
+}
+
+// GetUser GET /Users/:id
+func (h *Handler) GetUser(c echo.Context) error {
+	user, err := h.getUser(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	return h.respondUser(c, http.StatusOK, user.GetID())
+}
+
+// CreateUser POST /Users
+func (h *Handler) CreateUser(c echo.Context) error {
+	var req userResource
+	if err := bindJSON(c, &req); err != nil {
+		return h.handleError(c, err)
+	}
+	if err := vd.Validate(req.UserName, validator.UserNameRuleRequired...); err != nil {
+		return scimError(c, http.StatusBadRequest, errInvalidValue, "userName: "+err.Error())
+	}
+	if err := validateUser(&req); err != nil {
+		return h.handleError(c, err)
+	}
+
+	args := repository.CreateUserArgs{
+		Name:        req.UserName,
+		DisplayName: req.DisplayName,
+		Role:        role.User,
+		Password:    req.Password,
+	}
+	if len(req.ExternalID) > 0 {
+		if _, err := h.Repo.GetUserByExternalID(h.ExternalProviderName, req.ExternalID, false); err == nil {
+			return scimError(c, http.StatusConflict, errUniqueness, "externalId conflicts")
+		} else if err != repository.ErrNotFound {
+			return h.internalError(c, err)
+		}
+		args.ExternalLogin = &model.ExternalProviderUser{
+			ProviderName: h.ExternalProviderName,
+			ExternalID:   req.ExternalID,
+			Extra:        model.JSON{"externalName": req.UserName},
+		}
+	}
+
+	iconFileID, err := file.GenerateIconFile(h.FileManager, req.UserName)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	args.IconFileID = iconFileID
+
+	user, err := h.Repo.CreateUser(args)
+	if err != nil {
+		switch err {
+		case repository.ErrAlreadyExists:
+			return scimError(c, http.StatusConflict, errUniqueness, "userName conflicts")
+		default:
+			return h.internalError(c, err)
+		}
+	}
+
+	// 作成時に反映済みの属性は変更されない
+	req.Password = ""
+	if err := h.updateUser(user, &req, false); err != nil {
+		return h.handleError(c, err)
+	}
+
+	return h.respondUser(c, http.StatusCreated, user.GetID())
+}
+
+// ReplaceUser PUT /Users/:id
+func (h *Handler) ReplaceUser(c echo.Context) error {
+	user, err := h.getUser(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	var req userResource
+	if err := bindJSON(c, &req); err != nil {
+		return h.handleError(c, err)
+	}
+	if err := validateUser(&req); err != nil {
+		return h.handleError(c, err)
+	}
+	if err := h.updateUser(user, &req, true); err != nil {
+		return h.handleError(c, err)
+	}
+	return h.respondUser(c, http.StatusOK, user.GetID())
+}
+
+// PatchUser PATCH /Users/:id
+func (h *Handler) PatchUser(c echo.Context) error {
+	user, err := h.getUser(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+
+	var req patchRequest
+	if err := bindJSON(c, &req); err != nil {
+		return h.handleError(c, err)
+	}
+
+	current, err := h.formatUser(user)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	m, err := toMap(current)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	if err := applyPatch(m, &req); err != nil {
+		return h.handleError(c, err)
+	}
+	var patched userResource
+	if err := fromMap(m, &patched); err != nil {
+		return h.handleError(c, err)
+	}
+	if err := validateUser(&patched); err != nil {
+		return h.handleError(c, err)
+	}
+	if err := h.updateUser(user, &patched, true); err != nil {
+		return h.handleError(c, err)
+	}
+	return h.respondUser(c, http.StatusOK, user.GetID())
+}
+
+// DeleteUser DELETE /Users/:id
+//
+// traQではユーザーを削除できないため、アカウントを凍結します。
+func (h *Handler) DeleteUser(c echo.Context) error {
+	user, err := h.getUser(c)
+	if err != nil {
+		return h.handleError(c, err)
+	}
+	if err := h.updateUser(user, &userResource{Active: boolPtr(false)}, false); err != nil {
+		return h.handleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// getUser パスパラメータidのユーザーを取得します
+//
+// ユーザーが存在しない場合はerrNotFoundを返します。
+func (h *Handler) getUser(c echo.Context) (model.UserInfo, error) {
+	id, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		return nil, errNotFound
+	}
+	user, err := h.Repo.GetUser(id, false)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, errNotFound
+		}
+		return nil, err
+	}
+	if user.IsBot() {
+		return nil, errNotFound
+	}
+	return user, nil
+}
+
+func validateUser(u *userResource) error {
+	if err := vd.Validate(u.DisplayName, vd.RuneLength(0, 64)); err != nil {
+		return badRequest(errInvalidValue, "displayName: "+err.Error())
+	}
+	if len(u.Password) > 0 {
+		if err := vd.Validate(u.Password, validator.PasswordRule...); err != nil {
+			return badRequest(errInvalidValue, "password: "+err.Error())
+		}
+	}
+	if err := vd.Validate(u.primaryEmail(), vd.RuneLength(0, 254), is.EmailFormat); err != nil {
+		return badRequest(errInvalidValue, "emails: "+err.Error())
+	}
+	return nil
+}
+
+// updateUser リソースの内容をユーザーに反映します
+//
+// replaceがtrueの場合はリソース全体の置き換えとして扱い、省略されたdisplayNameとemailsを消去します (RFC 7644 3.5.1)。
+// activeは省略された場合、replaceに関わらず変更しません。
+// replaceがfalseの場合、値が指定されていない属性は変更しません。
+func (h *Handler) updateUser(user model.UserInfo, req *userResource, replace bool) error {
+	if len(req.UserName) > 0 && req.UserName != user.GetName() {
+		return badRequest(errMutability, "userName cannot be changed")
+	}
+
+	var (
+		args    repository.UpdateUserArgs
+		changed bool
+		revoke  bool
+	)
+	if (replace || len(req.DisplayName) > 0) && req.DisplayName != user.GetDisplayName() {
+		args.DisplayName = optional.StringFrom(req.DisplayName)
+		changed = true
+	}
+	if req.Active != nil {
+		state := model.UserAccountStatusDeactivated
+		if *req.Active {
+			state = model.UserAccountStatusActive
+		}
+		if state != user.GetState() {
+			args.UserState.Valid = true
+			args.UserState.State = state
+			changed = true
+			revoke = !bool(*req.Active)
+		}
+	}
+	if len(req.Password) > 0 {
+		args.Password = optional.StringFrom(req.Password)
+		changed = true
+		revoke = true
+	}
+	if changed {
+		if err := h.Repo.UpdateUser(user.GetID(), args); err != nil {
+			return err
+		}
+	}
+	if revoke {
+		// 凍結・パスワード変更時は全てのセッションとトークンを破棄(強制ログアウト)
+		if err := h.SessStore.RevokeSessionsByUserID(user.GetID()); err != nil {
+			return err
+		}
+		if err := h.Repo.DeleteTokenByUser(user.GetID()); err != nil {
+			return err
+		}
+		if err := h.Repo.DeletePersonalAccessTokensByUser(user.GetID()); err != nil {
+			return err
+		}
+	}
+
+	if len(req.ExternalID) > 0 {
+		if err := h.updateExternalID(user, req.ExternalID); err != nil {
+			return err
+		}
+	}
+
+	if req.Emails != nil || replace {
+		current, err := h.getEmail(user.GetID())
+		if err != nil {
+			return err
+		}
+		if address := req.primaryEmail(); address != current {
+			if err := h.Repo.UpdateUserEmailSetting(user.GetID(), repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom(address)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// updateExternalID ユーザーに紐付いたexternalIdを変更します
+func (h *Handler) updateExternalID(user model.UserInfo, externalID string) error {
+	current, err := h.getExternalID(user.GetID())
+	if err != nil {
+		return err
+	}
+	if current == externalID {
+		return nil
+	}
+
+	if other, err := h.Repo.GetUserByExternalID(h.ExternalProviderName, externalID, false); err == nil {
+		if other.GetID() != user.GetID() {
+			return conflict("externalId conflicts")
+		}
+	} else if err != repository.ErrNotFound {
+		return err
+	}
+
+	if len(current) > 0 {
+		if err := h.Repo.UnlinkExternalUserAccount(user.GetID(), h.ExternalProviderName); err != nil && err != repository.ErrNotFound {
+			return err
+		}
+	}
+	err = h.Repo.LinkExternalUserAccount(user.GetID(), repository.LinkExternalUserAccountArgs{
+		ProviderName: h.ExternalProviderName,
+		ExternalID:   externalID,
+		Extra:        model.JSON{"externalName": user.GetName()},
+	})
+	if err == repository.ErrAlreadyExists {
+		return conflict("externalId conflicts")
+	}
+	return err
+}
+
+func (h *Handler) getExternalID(userID uuid.UUID) (string, error) {
+	links, err := h.Repo.GetLinkedExternalUserAccounts(userID)
+	if err != nil {
+		return "", err
+	}
+	for _, link := range links {
+		if link.ProviderName == h.ExternalProviderName {
+			return link.ExternalID, nil
+		}
+	}
+	return "", nil
+}
+
+func (h *Handler) getEmail(userID uuid.UUID) (string, error) {
+	s, err := h.Repo.GetUserEmailSetting(userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	return s.Address, nil
+}
+
+// formatUser ユーザーをSCIMのUserリソースに変換します
+func (h *Handler) formatUser(user model.UserInfo) (*userResource, error) {
+	externalID, err := h.getExternalID(user.GetID())
+	if err != nil {
+		return nil, err
+	}
+	email, err := h.getEmail(user.GetID())
+	if err != nil {
+		return nil, err
+	}
+	return h.newUserResource(user, externalID, email), nil
+}
+
+// formatUsers 複数のユーザーをSCIMのUserリソースに変換します
+//
+// externalIdとメールアドレスはまとめて取得します。
+func (h *Handler) formatUsers(users []model.UserInfo) ([]interface{}, error) {
+	ids := make(set.UUID, len(users))
+	for _, user := range users {
+		ids.Add(user.GetID())
+	}
+
+	links, err := h.Repo.GetExternalUserAccounts(h.ExternalProviderName, ids)
+	if err != nil {
+		return nil, err
+	}
+	externalIDs := make(map[uuid.UUID]string, len(links))
+	for _, link := range links {
+		externalIDs[link.UserID] = link.ExternalID
+	}
+
+	settings, err := h.Repo.GetUserEmailSettings(ids)
+	if err != nil {
+		return nil, err
+	}
+	emails := make(map[uuid.UUID]string, len(settings))
+	for _, s := range settings {
+		emails[s.UserID] = s.Address
+	}
+
+	resources := make([]interface{}, len(users))
+	for i, user := range users {
+		resources[i] = h.newUserResource(user, externalIDs[user.GetID()], emails[user.GetID()])
+	}
+	return resources, nil
+}
+
+func (h *Handler) newUserResource(user model.UserInfo, externalID, email string) *userResource {
+	id := user.GetID().String()
+	res := &userResource{
+		Schemas:     []string{schemaUser},
+		ID:          id,
+		ExternalID:  externalID,
+		UserName:    user.GetName(),
+		DisplayName: user.GetDisplayName(),
+		Active:      boolPtr(user.IsActive()),
+		Meta: &meta{
+			ResourceType: "User",
+			Created:      user.GetCreatedAt(),
+			LastModified: user.GetUpdatedAt(),
+			Location:     h.location("Users", id),
+		},
+	}
+	if len(email) > 0 {
+		res.Emails = []userEmail{{Value: email, Type: "work", Primary: boolPtr(true)}}
+	}
+	return res
+}
+
+// respondUser ユーザーを再取得してレスポンスとして返します
+func (h *Handler) respondUser(c echo.Context, status int, id uuid.UUID) error {
+	user, err := h.Repo.GetUser(id, false)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	res, err := h.formatUser(user)
+	if err != nil {
+		return h.internalError(c, err)
+	}
+	c.Response().Header().Set(echo.HeaderLocation, res.Meta.Location)
+	return scimJSON(c, status, res)
+}
@@ -0,0 +1,404 @@
+package scim
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHandler_GetUsers(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	t.Run("ListAll", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET("/scim/v2/Users").
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("schemas").Array().Elements(schemaListResponse)
+		obj.Value("totalResults").Number().Ge(1)
+		obj.Value("startIndex").Number().Equal(1)
+	})
+
+	t.Run("FilterByUserName", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET("/scim/v2/Users").
+			WithQuery("filter", `userName eq "`+user.GetName()+`"`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("totalResults").Number().Equal(1)
+		res := obj.Value("Resources").Array().First().Object()
+		res.Value("id").String().Equal(user.GetID().String())
+		res.Value("userName").String().Equal(user.GetName())
+		res.Value("active").Boolean().True()
+	})
+
+	t.Run("FilterNotFound", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Users").
+			WithQuery("filter", `userName eq "`+random.AlphaNumeric(32)+`"`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("totalResults", 0)
+	})
+
+	t.Run("FilterByExternalIDAndActive", func(t *testing.T) {
+		t.Parallel()
+		externalID := random.AlphaNumeric(20)
+		require.NoError(t, env.Repository.LinkExternalUserAccount(user.GetID(), repository.LinkExternalUserAccountArgs{
+			ProviderName: testProviderName,
+			ExternalID:   externalID,
+			Extra:        model.JSON{},
+		}))
+
+		e := env.R(t)
+		obj := e.GET("/scim/v2/Users").
+			WithQuery("filter", `externalId eq "`+externalID+`" and active eq true`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("totalResults").Number().Equal(1)
+		res := obj.Value("Resources").Array().First().Object()
+		res.Value("id").String().Equal(user.GetID().String())
+		res.Value("externalId").String().Equal(externalID)
+
+		e.GET("/scim/v2/Users").
+			WithQuery("filter", `externalId eq "`+externalID+`" and active eq false`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("totalResults", 0)
+	})
+
+	t.Run("FilterInMemory", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.GET("/scim/v2/Users").
+			WithQuery("filter", `userName sw "`+user.GetName()[:31]+`"`).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("totalResults").Number().Ge(1)
+		obj.Value("Resources").Array().First().Object().ValueEqual("userName", user.GetName())
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		t.Parallel()
+		env.CreateUser(t, rand)
+		e := env.R(t)
+		obj := e.GET("/scim/v2/Users").
+			WithQuery("startIndex", 2).
+			WithQuery("count", 1).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("totalResults").Number().Ge(2)
+		obj.Value("itemsPerPage").Number().Equal(1)
+		obj.Value("startIndex").Number().Equal(2)
+		obj.Value("Resources").Array().Length().Equal(1)
+	})
+
+	t.Run("InvalidFilter", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.GET("/scim/v2/Users").
+			WithQuery("filter", `userName eq`).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("scimType", errInvalidFilter)
+	})
+}
+
+func TestHandler_CreateUser(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		name := random.AlphaNumeric(20)
+		externalID := random.AlphaNumeric(20)
+		e := env.R(t)
+		obj := e.POST("/scim/v2/Users").
+			WithJSON(map[string]interface{}{
+				"schemas":     []string{schemaUser},
+				"userName":    name,
+				"externalId":  externalID,
+				"displayName": "SCIM User",
+				"emails":      []map[string]interface{}{{"value": name + "@example.com", "primary": true}},
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("userName").String().Equal(name)
+		obj.Value("externalId").String().Equal(externalID)
+		obj.Value("displayName").String().Equal("SCIM User")
+		obj.Value("emails").Array().First().Object().ValueEqual("value", name+"@example.com")
+
+		u, err := env.Repository.GetUserByExternalID(testProviderName, externalID, false)
+		require.NoError(t, err)
+		assert.Equal(t, name, u.GetName())
+		assert.True(t, u.IsActive())
+	})
+
+	t.Run("Inactive", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		obj := e.POST("/scim/v2/Users").
+			WithJSON(map[string]interface{}{
+				"schemas":  []string{schemaUser},
+				"userName": random.AlphaNumeric(20),
+				"active":   "False",
+			}).
+			Expect().
+			Status(http.StatusCreated).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("active").Boolean().False()
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		e := env.R(t)
+		e.POST("/scim/v2/Users").
+			WithJSON(map[string]interface{}{
+				"schemas":  []string{schemaUser},
+				"userName": user.GetName(),
+			}).
+			Expect().
+			Status(http.StatusConflict).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("scimType", errUniqueness)
+	})
+
+	t.Run("InvalidUserName", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.POST("/scim/v2/Users").
+			WithJSON(map[string]interface{}{
+				"schemas":  []string{schemaUser},
+				"userName": "invalid name",
+			}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("scimType", errInvalidValue)
+	})
+}
+
+func TestHandler_ReplaceUser(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		e := env.R(t)
+		obj := e.PUT("/scim/v2/Users/{id}", user.GetID()).
+			WithJSON(map[string]interface{}{
+				"schemas":     []string{schemaUser},
+				"userName":    user.GetName(),
+				"displayName": "replaced",
+				"active":      false,
+				"emails":      []map[string]interface{}{{"value": "replaced@example.com"}},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("displayName").String().Equal("replaced")
+		obj.Value("active").Boolean().False()
+		obj.Value("emails").Array().First().Object().ValueEqual("value", "replaced@example.com")
+	})
+
+	t.Run("ClearOmittedAttributes", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		require.NoError(t, env.Repository.UpdateUser(user.GetID(), repository.UpdateUserArgs{DisplayName: optional.StringFrom("display")}))
+		require.NoError(t, env.Repository.UpdateUserEmailSetting(user.GetID(), repository.UpdateUserEmailSettingArgs{Address: optional.StringFrom("old@example.com")}))
+		e := env.R(t)
+		obj := e.PUT("/scim/v2/Users/{id}", user.GetID()).
+			WithJSON(map[string]interface{}{
+				"schemas":  []string{schemaUser},
+				"userName": user.GetName(),
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.NotContainsKey("displayName")
+		obj.NotContainsKey("emails")
+
+		u, err := env.Repository.GetUser(user.GetID(), false)
+		require.NoError(t, err)
+		assert.Empty(t, u.GetDisplayName())
+		s, err := env.Repository.GetUserEmailSetting(user.GetID())
+		require.NoError(t, err)
+		assert.Empty(t, s.Address)
+	})
+
+	t.Run("KeepActiveWhenOmitted", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		e := env.R(t)
+		e.DELETE("/scim/v2/Users/{id}", user.GetID()).
+			Expect().
+			Status(http.StatusNoContent)
+
+		e.PUT("/scim/v2/Users/{id}", user.GetID()).
+			WithJSON(map[string]interface{}{
+				"schemas":     []string{schemaUser},
+				"userName":    user.GetName(),
+				"displayName": "replaced",
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("active", false)
+
+		u, err := env.Repository.GetUser(user.GetID(), false)
+		require.NoError(t, err)
+		assert.Equal(t, model.UserAccountStatusDeactivated, u.GetState())
+		assert.Equal(t, "replaced", u.GetDisplayName())
+	})
+}
+
+func TestHandler_PatchUser(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+
+	t.Run("Deactivate", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		s, err := env.SessStore.IssueSession(user.GetID(), nil)
+		require.NoError(t, err)
+		scopes := model.AccessScopes{}
+		scopes.Add("read")
+		pat, _, err := env.Repository.CreatePersonalAccessToken(repository.CreatePersonalAccessTokenArgs{UserID: user.GetID(), Name: "cli", Scopes: scopes, ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+
+		e := env.R(t)
+		e.PATCH("/scim/v2/Users/{id}", user.GetID()).
+			WithJSON(map[string]interface{}{
+				"schemas": []string{schemaPatchOp},
+				"Operations": []map[string]interface{}{
+					{"op": "Replace", "path": "active", "value": "False"},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("active", false)
+
+		u, err := env.Repository.GetUser(user.GetID(), false)
+		require.NoError(t, err)
+		assert.Equal(t, model.UserAccountStatusDeactivated, u.GetState())
+		_, err = env.SessStore.GetSessionByToken(s.Token())
+		assert.Equal(t, session.ErrSessionNotFound, err)
+		_, err = env.Repository.GetPersonalAccessToken(pat.ID)
+		assert.Equal(t, repository.ErrNotFound, err)
+	})
+
+	t.Run("DisplayNameAndEmail", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		e := env.R(t)
+		obj := e.PATCH("/scim/v2/Users/{id}", user.GetID()).
+			WithJSON(map[string]interface{}{
+				"schemas": []string{schemaPatchOp},
+				"Operations": []map[string]interface{}{
+					{"op": "replace", "value": map[string]interface{}{"displayName": "patched"}},
+					{"op": "add", "path": `emails[type eq "work"].value`, "value": "patched@example.com"},
+				},
+			}).
+			Expect().
+			Status(http.StatusOK).
+			JSON(scimJSONOpts).
+			Object()
+
+		obj.Value("displayName").String().Equal("patched")
+		obj.Value("emails").Array().First().Object().ValueEqual("value", "patched@example.com")
+	})
+
+	t.Run("UserNameImmutable", func(t *testing.T) {
+		t.Parallel()
+		user := env.CreateUser(t, rand)
+		e := env.R(t)
+		e.PATCH("/scim/v2/Users/{id}", user.GetID()).
+			WithJSON(map[string]interface{}{
+				"schemas": []string{schemaPatchOp},
+				"Operations": []map[string]interface{}{
+					{"op": "replace", "path": "userName", "value": random.AlphaNumeric(20)},
+				},
+			}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON(scimJSONOpts).
+			Object().
+			ValueEqual("scimType", errMutability)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		t.Parallel()
+		e := env.R(t)
+		e.PATCH("/scim/v2/Users/{id}", "invalid").
+			WithJSON(map[string]interface{}{
+				"schemas":    []string{schemaPatchOp},
+				"Operations": []map[string]interface{}{},
+			}).
+			Expect().
+			Status(http.StatusNotFound)
+	})
+}
+
+func TestHandler_DeleteUser(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, db1)
+	user := env.CreateUser(t, rand)
+
+	e := env.R(t)
+	e.DELETE("/scim/v2/Users/{id}", user.GetID()).
+		Expect().
+		Status(http.StatusNoContent)
+
+	u, err := env.Repository.GetUser(user.GetID(), false)
+	require.NoError(t, err)
+	assert.False(t, u.IsActive())
+}
@@ -7,8 +7,11 @@ import (
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/set"
@@ -24,7 +27,8 @@ func (h *Handlers) GetChannels(c echo.Context) error {
 		"public": h.ChannelManager.PublicChannelTree(),
 	}
 
-	if isTrue(c.QueryParam("include-dm")) {
+	// DMへのアクセス権限が無い場合(細粒度OAuth2スコープなど)はDMチャンネルを含めない
+	if isTrue(c.QueryParam("include-dm")) && extension.IsPermissionGranted(c.Request().Context(), permission.AccessDMChannel) {
 		mapping, err := h.ChannelManager.GetDMChannelMapping(getRequestUserID(c))
 		if err != nil {
 			return herror.InternalServerError(err)
@@ -158,6 +162,22 @@ func (h *Handlers) GetChannelViewers(c echo.Context) error {
 	return c.JSON(http.StatusOK, viewer.ConvertToArray(cv))
 }
 
+// PostChannelTyping POST /channels/:channelID/typing
+func (h *Handlers) PostChannelTyping(c echo.Context) error {
+	userID := getRequestUserID(c)
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	if err := h.Typing.Typing(userID, channelID); err != nil {
+		switch err {
+		case typing.ErrChannelNotAccessible:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 // GetChannelStats GET /channels/:channelID/stats
 func (h *Handlers) GetChannelStats(c echo.Context) error {
 	channelID := getParamAsUUID(c, consts.ParamChannelID)
@@ -8,6 +8,7 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/random"
 	"github.com/traPtitech/traQ/utils/validator"
@@ -89,10 +90,11 @@ func (h *Handlers) GetClient(c echo.Context) error {
 
 // PatchClientRequest PATCH /clients/:clientID リクエストボディ
 type PatchClientRequest struct {
-	Name        optional.String `json:"name"`
-	Description optional.String `json:"description"`
-	CallbackURL optional.String `json:"callbackUrl"`
-	DeveloperID optional.UUID   `json:"developerId"`
+	Name           optional.String `json:"name"`
+	Description    optional.String `json:"description"`
+	CallbackURL    optional.String `json:"callbackUrl"`
+	DeveloperID    optional.UUID   `json:"developerId"`
+	ResourceServer optional.Bool   `json:"resourceServer"`
 }
 
 func (r PatchClientRequest) Validate() error {
@@ -113,11 +115,16 @@ func (h *Handlers) EditClient(c echo.Context) error {
 		return err
 	}
 
+	if req.ResourceServer.Valid && getRequestUser(c).GetRole() != role.Admin {
+		return herror.Forbidden("you are not permitted to set resource server flag to clients")
+	}
+
 	args := repository.UpdateClientArgs{
-		Name:        req.Name,
-		Description: req.Description,
-		DeveloperID: req.DeveloperID,
-		CallbackURL: req.CallbackURL,
+		Name:           req.Name,
+		Description:    req.Description,
+		DeveloperID:    req.DeveloperID,
+		CallbackURL:    req.CallbackURL,
+		ResourceServer: req.ResourceServer,
 	}
 	if err := h.Repo.UpdateClient(oc.ID, args); err != nil {
 		return herror.InternalServerError(err)
@@ -137,3 +144,24 @@ func (h *Handlers) DeleteClient(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// GetClientTokens GET /clients/:clientID/tokens
+func (h *Handlers) GetClientTokens(c echo.Context) error {
+	oc := getParamClient(c)
+
+	ts, err := h.Repo.GetTokensByClient(oc.ID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, formatOAuth2TokenInfos(ts))
+}
+
+// RevokeClientTokens DELETE /clients/:clientID/tokens
+func (h *Handlers) RevokeClientTokens(c echo.Context) error {
+	oc := getParamClient(c)
+
+	if err := h.Repo.DeleteTokenByClient(oc.ID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
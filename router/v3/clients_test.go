@@ -0,0 +1,78 @@
+package v3
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/session"
+	"github.com/traPtitech/traQ/service/rbac/role"
+	"github.com/traPtitech/traQ/utils/random"
+	"net/http"
+	"testing"
+)
+
+func TestHandlers_EditClient(t *testing.T) {
+	t.Parallel()
+	env := Setup(t, common)
+	user := env.CreateUser(t, rand)
+	admin, err := env.Repository.CreateUser(repository.CreateUserArgs{Name: random.AlphaNumeric(32), Password: "testtesttesttest", Role: role.Admin, IconFileID: uuid.Must(uuid.NewV4())})
+	require.NoError(t, err)
+	userSession := env.S(t, user.GetID())
+	adminSession := env.S(t, admin.GetID())
+
+	scopes := model.AccessScopes{}
+	scopes.Add("read")
+	createClient := func(t *testing.T) *model.OAuth2Client {
+		t.Helper()
+		client := &model.OAuth2Client{
+			ID:           random.AlphaNumeric(36),
+			Name:         "test client",
+			Confidential: true,
+			CreatorID:    user.GetID(),
+			Secret:       random.AlphaNumeric(36),
+			RedirectURI:  "http://example.com",
+			Scopes:       scopes,
+		}
+		require.NoError(t, env.Repository.SaveClient(client))
+		return client
+	}
+
+	t.Run("ResourceServer by developer", func(t *testing.T) {
+		t.Parallel()
+		client := createClient(t)
+
+		e := env.R(t)
+		e.PATCH("/api/v3/clients/{clientID}", client.ID).
+			WithCookie(session.CookieName, userSession).
+			WithJSON(echo.Map{"resourceServer": true}).
+			Expect().
+			Status(http.StatusForbidden)
+
+		oc, err := env.Repository.GetClient(client.ID)
+		require.NoError(t, err)
+		assert.False(t, oc.ResourceServer)
+	})
+
+	t.Run("ResourceServer by admin", func(t *testing.T) {
+		t.Parallel()
+		client := createClient(t)
+
+		e := env.R(t)
+		e.PATCH("/api/v3/clients/{clientID}", client.ID).
+			WithCookie(session.CookieName, adminSession).
+			WithJSON(echo.Map{"resourceServer": true}).
+			Expect().
+			Status(http.StatusNoContent)
+
+		e.GET("/api/v3/clients/{clientID}", client.ID).
+			WithCookie(session.CookieName, userSession).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			Value("resourceServer").Boolean().True()
+	})
+}
@@ -0,0 +1,95 @@
+package v3
+
+import (
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/utils/optional"
+	"net/http"
+	"time"
+)
+
+// maxSnoozeMinutes 手動おやすみモードの最大時間 (分)
+const maxSnoozeMinutes = 7 * 24 * 60
+
+type dndSettingResponse struct {
+	SnoozeUntil optional.Time     `json:"snoozeUntil"`
+	Timezone    string            `json:"timezone"`
+	Schedule    model.DNDSchedule `json:"schedule"`
+	// ActiveUntil 現在おやすみモード中の場合、その終了日時
+	ActiveUntil optional.Time `json:"activeUntil"`
+}
+
+// GetMyDNDSetting GET /users/me/settings/dnd
+func (h *Handlers) GetMyDNDSetting(c echo.Context) error {
+	s, err := h.Repo.GetUserDNDSetting(getRequestUserID(c))
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusOK, &dndSettingResponse{Timezone: model.DefaultDNDTimezone, Schedule: model.DNDSchedule{}})
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	res := &dndSettingResponse{
+		Timezone: s.Timezone,
+		Schedule: s.Schedule,
+	}
+	now := time.Now()
+	if s.SnoozeUntil.Valid && s.SnoozeUntil.Time.After(now) {
+		res.SnoozeUntil = s.SnoozeUntil
+	}
+	if until, ok := s.ActiveUntil(now); ok {
+		res.ActiveUntil = optional.TimeFrom(until)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// PatchMyDNDSettingRequest PATCH /users/me/settings/dnd リクエストボディ
+type PatchMyDNDSettingRequest struct {
+	// SnoozeMinutes 今から指定した分数の間おやすみモードにする (0で解除)
+	SnoozeMinutes optional.Int       `json:"snoozeMinutes"`
+	Timezone      optional.String    `json:"timezone"`
+	Schedule      *model.DNDSchedule `json:"schedule"`
+}
+
+func (r PatchMyDNDSettingRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.SnoozeMinutes, vd.Min(0), vd.Max(maxSnoozeMinutes)),
+		vd.Field(&r.Timezone, vd.RuneLength(1, 64)),
+		vd.Field(&r.Schedule),
+	)
+}
+
+// EditMyDNDSetting PATCH /users/me/settings/dnd
+func (h *Handlers) EditMyDNDSetting(c echo.Context) error {
+	var req PatchMyDNDSettingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	args := repository.UpdateUserDNDSettingArgs{Timezone: req.Timezone}
+	if req.SnoozeMinutes.Valid {
+		args.SnoozeUntil.Valid = true
+		if req.SnoozeMinutes.Int64 > 0 {
+			args.SnoozeUntil.Until = optional.TimeFrom(time.Now().Add(time.Duration(req.SnoozeMinutes.Int64) * time.Minute))
+		}
+	}
+	if req.Schedule != nil {
+		args.Schedule.Valid = true
+		args.Schedule.Schedule = *req.Schedule
+	}
+	if err := h.Repo.UpdateUserDNDSetting(getRequestUserID(c), args); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,129 @@
+package v3
+
+import (
+	"fmt"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/email"
+	"github.com/traPtitech/traQ/utils/optional"
+	"html"
+	"net/http"
+)
+
+type emailSettingResponse struct {
+	Address   string            `json:"address"`
+	Immediate bool              `json:"immediate"`
+	Digest    model.EmailDigest `json:"digest"`
+}
+
+// GetMyEmailSetting GET /users/me/settings/email
+func (h *Handlers) GetMyEmailSetting(c echo.Context) error {
+	s, err := h.Repo.GetUserEmailSetting(getRequestUserID(c))
+	if err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return c.JSON(http.StatusOK, &emailSettingResponse{Digest: model.EmailDigestNone})
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusOK, &emailSettingResponse{
+		Address:   s.Address,
+		Immediate: s.Immediate,
+		Digest:    s.Digest,
+	})
+}
+
+// PatchMyEmailSettingRequest PATCH /users/me/settings/email リクエストボディ
+type PatchMyEmailSettingRequest struct {
+	Address   optional.String `json:"address"`
+	Immediate optional.Bool   `json:"immediate"`
+	Digest    optional.String `json:"digest"`
+}
+
+func (r PatchMyEmailSettingRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Address, vd.RuneLength(0, 254), is.EmailFormat),
+		vd.Field(&r.Digest, vd.In(string(model.EmailDigestNone), string(model.EmailDigestDaily), string(model.EmailDigestWeekly))),
+	)
+}
+
+// EditMyEmailSetting PATCH /users/me/settings/email
+func (h *Handlers) EditMyEmailSetting(c echo.Context) error {
+	var req PatchMyEmailSettingRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	args := repository.UpdateUserEmailSettingArgs{
+		Address:   req.Address,
+		Immediate: req.Immediate,
+	}
+	if req.Digest.Valid {
+		args.Digest.Valid = true
+		args.Digest.Digest = model.EmailDigest(req.Digest.String)
+	}
+	if err := h.Repo.UpdateUserEmailSetting(getRequestUserID(c), args); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// unsubscribeConfirmPage 配信停止確認ページ
+//
+// メールクライアントやセキュリティスキャナーによるリンクの先読みで配信停止されないように、GETでは確認ページのみを返します。
+const unsubscribeConfirmPage = `<!DOCTYPE html>
+<html lang="ja">
+<head><meta charset="utf-8"><title>traQ メール配信停止</title></head>
+<body>
+<form method="post">
+<input type="hidden" name="token" value="%s">
+<p>traQからのメール通知の配信を停止しますか？</p>
+<button type="submit">配信停止</button>
+</form>
+</body>
+</html>
+`
+
+// GetEmailUnsubscribe GET /email/unsubscribe?token={token}
+func (h *Handlers) GetEmailUnsubscribe(c echo.Context) error {
+	token := c.QueryParam("token")
+	if len(token) == 0 {
+		return herror.BadRequest("token is required")
+	}
+	return c.HTML(http.StatusOK, fmt.Sprintf(unsubscribeConfirmPage, html.EscapeString(token)))
+}
+
+// PostEmailUnsubscribe POST /email/unsubscribe?token={token}
+//
+// RFC 8058のワンクリック配信停止にも対応しています。
+func (h *Handlers) PostEmailUnsubscribe(c echo.Context) error {
+	if h.Email == nil {
+		return herror.HTTPError(http.StatusServiceUnavailable, "email notification is not available")
+	}
+
+	token := c.QueryParam("token")
+	if len(token) == 0 {
+		token = c.FormValue("token")
+	}
+	if err := h.Email.Unsubscribe(token); err != nil {
+		switch err {
+		case email.ErrInvalidToken:
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.String(http.StatusOK, "配信を停止しました")
+}
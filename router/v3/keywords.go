@@ -0,0 +1,96 @@
+package v3
+
+import (
+	"context"
+	"fmt"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/router/utils"
+	"github.com/traPtitech/traQ/service/keyword"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/validator"
+	"net/http"
+)
+
+// GetMyKeywordAlerts GET /users/me/keyword-alerts
+func (h *Handlers) GetMyKeywordAlerts(c echo.Context) error {
+	alerts, err := h.Repo.GetKeywordAlerts(getRequestUserID(c))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, alerts)
+}
+
+// PostMyKeywordAlertRequest POST /users/me/keyword-alerts リクエストボディ
+type PostMyKeywordAlertRequest struct {
+	Keyword   string        `json:"keyword"`
+	IsRegex   bool          `json:"isRegex"`
+	ChannelID optional.UUID `json:"channelId"`
+}
+
+func (r PostMyKeywordAlertRequest) ValidateWithContext(ctx context.Context) error {
+	return vd.ValidateStructWithContext(ctx, &r,
+		vd.Field(&r.Keyword, vd.Required, vd.RuneLength(1, 100)),
+		vd.Field(&r.ChannelID, validator.NotNilUUID, utils.IsPublicChannelID),
+	)
+}
+
+// CreateMyKeywordAlert POST /users/me/keyword-alerts
+func (h *Handlers) CreateMyKeywordAlert(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req PostMyKeywordAlertRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+	if err := keyword.ValidatePattern(req.Keyword, req.IsRegex); err != nil {
+		return herror.BadRequest(err)
+	}
+
+	alerts, err := h.Repo.GetKeywordAlerts(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	if len(alerts) >= keyword.MaxAlertsPerUser {
+		return herror.BadRequest(fmt.Sprintf("you cannot register more than %d keyword alerts", keyword.MaxAlertsPerUser))
+	}
+	if req.IsRegex {
+		if err := keyword.CheckRegexLimit(alerts, req.Keyword); err != nil {
+			return herror.BadRequest(err)
+		}
+	}
+
+	a, err := h.Repo.CreateKeywordAlert(repository.CreateKeywordAlertArgs{
+		UserID:    userID,
+		Keyword:   req.Keyword,
+		IsRegex:   req.IsRegex,
+		ChannelID: req.ChannelID,
+	})
+	if err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.JSON(http.StatusCreated, a)
+}
+
+// DeleteMyKeywordAlert DELETE /users/me/keyword-alerts/:keywordAlertID
+func (h *Handlers) DeleteMyKeywordAlert(c echo.Context) error {
+	alertID := getParamAsUUID(c, consts.ParamKeywordAlertID)
+
+	if err := h.Repo.DeleteKeywordAlert(getRequestUserID(c), alertID); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
@@ -7,8 +7,10 @@ import (
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
+	"github.com/traPtitech/traQ/service/dnd"
 	"github.com/traPtitech/traQ/service/message"
 	"net/http"
+	"time"
 )
 
 // GetMyUnreadChannels GET /users/me/unread
@@ -44,6 +46,8 @@ func (h *Handlers) GetMessage(c echo.Context) error {
 type PostMessageRequest struct {
 	Content string `json:"content"`
 	Embed   bool   `json:"embed" query:"embed"`
+	// Urgent 相手がおやすみモード中でも通知するかどうか (DMのみ)
+	Urgent bool `json:"urgent"`
 }
 
 func (r PostMessageRequest) Validate() error {
@@ -328,8 +332,22 @@ func (h *Handlers) PostDirectMessage(c echo.Context) error {
 		req.Content = h.Replacer.Replace(req.Content)
 	}
 
+	if req.Urgent {
+		if err := h.DND.RequestUrgentOverride(myID, targetID, time.Now()); err != nil {
+			switch err {
+			case dnd.ErrRateLimited:
+				return herror.HTTPError(http.StatusTooManyRequests, err)
+			default:
+				return herror.InternalServerError(err)
+			}
+		}
+	}
+
 	m, err := h.MessageManager.CreateDM(myID, targetID, req.Content)
 	if err != nil {
+		if req.Urgent {
+			h.DND.CancelUrgentOverride(myID, targetID)
+		}
 		return herror.InternalServerError(err)
 	}
 	return c.JSON(http.StatusCreated, m)
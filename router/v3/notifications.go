@@ -0,0 +1,127 @@
+package v3
+
+import (
+	"encoding/base64"
+	"errors"
+	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// encodeNotificationCursor 通知一覧のカーソルを`base64url(<作成日時>_<通知ID>)`の形式にします
+func encodeNotificationCursor(n *model.Notification) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(n.CreatedAt.UTC().Format(time.RFC3339Nano) + "_" + n.ID.String()))
+}
+
+func decodeNotificationCursor(s string) (*repository.NotificationCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(b), "_", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.FromString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	return &repository.NotificationCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+type notificationsQuery struct {
+	Limit  int    `query:"limit"`
+	Cursor string `query:"cursor"`
+	Unread bool   `query:"unread"`
+}
+
+func (q *notificationsQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = 20
+	}
+	return vd.ValidateStruct(q,
+		vd.Field(&q.Limit, vd.Min(1), vd.Max(200)),
+	)
+}
+
+type notificationsResponse struct {
+	Notifications []*model.Notification `json:"notifications"`
+	NextCursor    *string               `json:"nextCursor"`
+	UnreadCount   int                   `json:"unreadCount"`
+}
+
+// GetMyNotifications GET /users/me/notifications
+func (h *Handlers) GetMyNotifications(c echo.Context) error {
+	userID := getRequestUserID(c)
+
+	var req notificationsQuery
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	query := repository.NotificationsQuery{
+		User:       userID,
+		UnreadOnly: req.Unread,
+		Limit:      req.Limit,
+	}
+	if len(req.Cursor) > 0 {
+		cursor, err := decodeNotificationCursor(req.Cursor)
+		if err != nil {
+			return herror.BadRequest("invalid cursor")
+		}
+		query.Before = cursor
+	}
+
+	notifications, more, err := h.Repo.GetNotifications(query)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	unread, err := h.Repo.GetUnreadNotificationCount(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	res := &notificationsResponse{
+		Notifications: notifications,
+		UnreadCount:   unread,
+	}
+	if more {
+		next := encodeNotificationCursor(notifications[len(notifications)-1])
+		res.NextCursor = &next
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// ReadAllMyNotifications POST /users/me/notifications/read
+func (h *Handlers) ReadAllMyNotifications(c echo.Context) error {
+	if err := h.Repo.ReadAllNotifications(getRequestUserID(c)); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ReadMyNotification POST /users/me/notifications/:notificationID/read
+func (h *Handlers) ReadMyNotification(c echo.Context) error {
+	notificationID := getParamAsUUID(c, consts.ParamNotificationID)
+
+	if err := h.Repo.ReadNotification(getRequestUserID(c), notificationID); err != nil {
+		switch err {
+		case repository.ErrNotFound, repository.ErrNilID:
+			return herror.NotFound()
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+	return c.NoContent(http.StatusNoContent)
+}
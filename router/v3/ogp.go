@@ -59,14 +59,16 @@ func (h *Handlers) GetOgp(c echo.Context) error {
 			}, nil
 		}
 
-		og, meta, err := ogp.ParseMetaForURL(u)
-		if err == ogp.ErrClient || err == ogp.ErrParse || err == ogp.ErrNetwork || err == ogp.ErrContentTypeNotSupported {
-			// 4xxエラー、パースエラー、名前解決などのネットワークエラーの場合はネガティブキャッシュを作成
+		content, err := h.OGP.Fetch(u)
+		if err == ogp.ErrClient || err == ogp.ErrParse || err == ogp.ErrNetwork || err == ogp.ErrContentTypeNotSupported ||
+			err == ogp.ErrForbidden || err == ogp.ErrContentTooLarge {
+			// 4xxエラー、パースエラー、名前解決などのネットワークエラー、アクセス禁止の場合はネガティブキャッシュを作成
 			if shouldUpdateCache {
 				updateErr := h.Repo.UpdateOgpCache(cacheURL, nil)
 				if updateErr != nil {
 					return nil, herror.InternalServerError(updateErr)
 				}
+				h.OGP.DeleteProxiedImages(&cache.Content)
 			} else if shouldCreateCache {
 				_, createErr := h.Repo.CreateOgpCache(cacheURL, nil)
 				if createErr != nil {
@@ -83,16 +85,18 @@ func (h *Handlers) GetOgp(c echo.Context) error {
 			return nil, herror.NotFound(err)
 		}
 
-		content := ogp.MergeDefaultPageMetaAndOpenGraph(og, meta)
-
 		if shouldUpdateCache {
 			err = h.Repo.UpdateOgpCache(cacheURL, content)
 			if err != nil {
+				h.OGP.DeleteProxiedImages(content)
 				return nil, herror.InternalServerError(err)
 			}
+			// プレビュー画像の寿命はキャッシュに合わせる
+			h.OGP.DeleteProxiedImages(&cache.Content)
 		} else if shouldCreateCache {
 			_, err = h.Repo.CreateOgpCache(cacheURL, content)
 			if err != nil {
+				h.OGP.DeleteProxiedImages(content)
 				return nil, herror.InternalServerError(err)
 			}
 		}
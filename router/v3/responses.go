@@ -105,9 +105,10 @@ type UserDetail struct {
 	Groups      []uuid.UUID   `json:"groups"`
 	Bio         string        `json:"bio"`
 	HomeChannel optional.UUID `json:"homeChannel"`
+	DNDUntil    optional.Time `json:"dndUntil"`
 }
 
-func formatUserDetail(user model.UserInfo, uts []model.UserTag, g []uuid.UUID) *UserDetail {
+func formatUserDetail(user model.UserInfo, uts []model.UserTag, g []uuid.UUID, dndUntil optional.Time) *UserDetail {
 	return &UserDetail{
 		ID:          user.GetID(),
 		State:       user.GetState().Int(),
@@ -122,6 +123,7 @@ func formatUserDetail(user model.UserInfo, uts []model.UserTag, g []uuid.UUID) *
 		Groups:      g,
 		Bio:         user.GetBio(),
 		HomeChannel: user.GetHomeChannel(),
+		DNDUntil:    dndUntil,
 	}
 }
 
@@ -416,20 +418,22 @@ func formatFileInfos(metas []model.File) []*FileInfo {
 }
 
 type OAuth2Client struct {
-	ID          string             `json:"id"`
-	Name        string             `json:"name"`
-	Description string             `json:"description"`
-	DeveloperID uuid.UUID          `json:"developerId"`
-	Scopes      model.AccessScopes `json:"scopes"`
+	ID             string             `json:"id"`
+	Name           string             `json:"name"`
+	Description    string             `json:"description"`
+	DeveloperID    uuid.UUID          `json:"developerId"`
+	Scopes         model.AccessScopes `json:"scopes"`
+	ResourceServer bool               `json:"resourceServer"`
 }
 
 func formatOAuth2Client(oc *model.OAuth2Client) *OAuth2Client {
 	return &OAuth2Client{
-		ID:          oc.ID,
-		Name:        oc.Name,
-		Description: oc.Description,
-		DeveloperID: oc.CreatorID,
-		Scopes:      oc.Scopes,
+		ID:             oc.ID,
+		Name:           oc.Name,
+		Description:    oc.Description,
+		DeveloperID:    oc.CreatorID,
+		Scopes:         oc.Scopes,
+		ResourceServer: oc.ResourceServer,
 	}
 }
 
@@ -442,25 +446,70 @@ func formatOAuth2Clients(ocs []*model.OAuth2Client) []*OAuth2Client {
 }
 
 type OAuth2ClientDetail struct {
-	ID          string             `json:"id"`
-	Name        string             `json:"name"`
-	Description string             `json:"description"`
-	DeveloperID uuid.UUID          `json:"developerId"`
-	Scopes      model.AccessScopes `json:"scopes"`
-	CallbackURL string             `json:"callbackUrl"`
-	Secret      string             `json:"secret"`
+	ID             string             `json:"id"`
+	Name           string             `json:"name"`
+	Description    string             `json:"description"`
+	DeveloperID    uuid.UUID          `json:"developerId"`
+	Scopes         model.AccessScopes `json:"scopes"`
+	CallbackURL    string             `json:"callbackUrl"`
+	Secret         string             `json:"secret"`
+	ResourceServer bool               `json:"resourceServer"`
 }
 
 func formatOAuth2ClientDetail(oc *model.OAuth2Client) *OAuth2ClientDetail {
 	return &OAuth2ClientDetail{
-		ID:          oc.ID,
-		Name:        oc.Name,
-		Description: oc.Description,
-		DeveloperID: oc.CreatorID,
-		Scopes:      oc.Scopes,
-		CallbackURL: oc.RedirectURI,
-		Secret:      oc.Secret,
+		ID:             oc.ID,
+		Name:           oc.Name,
+		Description:    oc.Description,
+		DeveloperID:    oc.CreatorID,
+		Scopes:         oc.Scopes,
+		CallbackURL:    oc.RedirectURI,
+		Secret:         oc.Secret,
+		ResourceServer: oc.ResourceServer,
+	}
+}
+
+type OAuth2TokenInfo struct {
+	ID        uuid.UUID          `json:"id"`
+	Type      string             `json:"type"`
+	ClientID  string             `json:"clientId"`
+	Name      string             `json:"name,omitempty"`
+	UserID    uuid.UUID          `json:"userId"`
+	Scopes    model.AccessScopes `json:"scopes"`
+	IssuedAt  time.Time          `json:"issuedAt"`
+	ExpiresAt time.Time          `json:"expiresAt"`
+}
+
+func formatOAuth2TokenInfos(ts []*model.OAuth2Token) []*OAuth2TokenInfo {
+	arr := make([]*OAuth2TokenInfo, len(ts))
+	for i, t := range ts {
+		arr[i] = &OAuth2TokenInfo{
+			ID:        t.ID,
+			Type:      "oauth2",
+			ClientID:  t.ClientID,
+			UserID:    t.UserID,
+			Scopes:    t.Scopes,
+			IssuedAt:  t.CreatedAt,
+			ExpiresAt: t.CreatedAt.Add(time.Duration(t.ExpiresIn) * time.Second),
+		}
 	}
+	return arr
+}
+
+func formatPersonalAccessTokenInfos(ts []*model.PersonalAccessToken) []*OAuth2TokenInfo {
+	arr := make([]*OAuth2TokenInfo, len(ts))
+	for i, t := range ts {
+		arr[i] = &OAuth2TokenInfo{
+			ID:        t.ID,
+			Type:      "personal",
+			Name:      t.Name,
+			UserID:    t.UserID,
+			Scopes:    t.Scopes,
+			IssuedAt:  t.CreatedAt,
+			ExpiresAt: t.ExpiresAt,
+		}
+	}
+	return arr
 }
 
 type ClipFolder struct {
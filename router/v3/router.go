@@ -4,17 +4,23 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/auth"
 	"github.com/traPtitech/traQ/router/extension"
 	"github.com/traPtitech/traQ/router/middlewares"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/dnd"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/service/message"
+	"github.com/traPtitech/traQ/service/ogp"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webpush"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
 	mutil "github.com/traPtitech/traQ/utils/message"
@@ -31,12 +37,18 @@ type Handlers struct {
 	OC             *counter.OnlineCounter
 	VM             *viewer.Manager
 	WebRTC         *webrtcv3.Manager
+	Typing         *typing.Manager
+	WebPush        *webpush.Client
+	Email          *email.Service
+	DND            *dnd.Manager
+	OGP            *ogp.Service
 	Imaging        imaging.Processor
 	SessStore      session.Store
 	ChannelManager channel.Manager
 	MessageManager message.Manager
 	FileManager    file.Manager
 	Replacer       *mutil.Replacer
+	LDAP           *auth.LDAPProvider
 	Config
 
 	SFGroup singleflight.Group `wire:"-"`
@@ -81,12 +93,14 @@ func (h *Handlers) Setup(e *echo.Group) {
 			{
 				apiUsersUID.GET("", h.GetUser, requires(permission.GetUser))
 				apiUsersUID.PATCH("", h.EditUser, requires(permission.EditOtherUsers))
-				apiUsersUID.GET("/dm-channel", h.GetUserDMChannel, requires(permission.GetChannel))
-				apiUsersUID.GET("/messages", h.GetDirectMessages, requires(permission.GetMessage))
-				apiUsersUID.POST("/messages", h.PostDirectMessage, bodyLimit(100), requires(permission.PostMessage))
+				apiUsersUID.GET("/dm-channel", h.GetUserDMChannel, requires(permission.GetChannel, permission.AccessDMChannel))
+				apiUsersUID.GET("/messages", h.GetDirectMessages, requires(permission.GetMessage, permission.AccessDMChannel))
+				apiUsersUID.POST("/messages", h.PostDirectMessage, bodyLimit(100), requires(permission.PostMessage, permission.AccessDMChannel))
 				apiUsersUID.GET("/icon", h.GetUserIcon, requires(permission.DownloadFile))
 				apiUsersUID.PUT("/icon", h.ChangeUserIcon, requires(permission.EditOtherUsers))
 				apiUsersUID.PUT("/password", h.ChangeUserPassword, requires(permission.EditOtherUsers))
+				apiUsersUID.GET("/tokens", h.GetUserTokens, requires(permission.ManageOthersToken))
+				apiUsersUID.DELETE("/tokens", h.RevokeUserTokens, requires(permission.ManageOthersToken))
 				apiUsersUIDTags := apiUsersUID.Group("/tags")
 				{
 					apiUsersUIDTags.GET("", h.GetUserTags, requires(permission.GetUserTag))
@@ -108,6 +122,12 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiUsersMe.PUT("/icon", h.ChangeMyIcon, requires(permission.ChangeMyIcon))
 				apiUsersMe.PUT("/password", h.PutMyPassword, requires(permission.ChangeMyPassword), blockBot)
 				apiUsersMe.POST("/fcm-device", h.PostMyFCMDevice, requires(permission.RegisterFCMDevice), blockBot)
+				apiUsersMe.POST("/webpush-subscriptions", h.PostMyWebPushSubscription, requires(permission.RegisterFCMDevice), blockBot)
+				apiUsersMe.DELETE("/webpush-subscriptions", h.DeleteMyWebPushSubscription, requires(permission.RegisterFCMDevice), blockBot)
+				apiUsersMe.GET("/settings/email", h.GetMyEmailSetting, requires(permission.GetMe), blockBot)
+				apiUsersMe.PATCH("/settings/email", h.EditMyEmailSetting, requires(permission.EditMe), blockBot)
+				apiUsersMe.GET("/settings/dnd", h.GetMyDNDSetting, requires(permission.GetMe), blockBot)
+				apiUsersMe.PATCH("/settings/dnd", h.EditMyDNDSetting, requires(permission.EditMe), blockBot)
 				apiUsersMeTags := apiUsersMe.Group("/tags")
 				{
 					apiUsersMeTags.GET("", h.GetMyUserTags, requires(permission.GetUserTag))
@@ -129,6 +149,18 @@ func (h *Handlers) Setup(e *echo.Group) {
 					apiUsersMeUnread.GET("", h.GetMyUnreadChannels, requires(permission.GetUnread))
 					apiUsersMeUnread.DELETE("/:channelID", h.ReadChannel, requires(permission.DeleteUnread))
 				}
+				apiUsersMeNotifications := apiUsersMe.Group("/notifications", blockBot)
+				{
+					apiUsersMeNotifications.GET("", h.GetMyNotifications, requires(permission.GetUnread))
+					apiUsersMeNotifications.POST("/read", h.ReadAllMyNotifications, requires(permission.DeleteUnread))
+					apiUsersMeNotifications.POST("/:notificationID/read", h.ReadMyNotification, requires(permission.DeleteUnread))
+				}
+				apiUsersMeKeywordAlerts := apiUsersMe.Group("/keyword-alerts", blockBot)
+				{
+					apiUsersMeKeywordAlerts.GET("", h.GetMyKeywordAlerts, requires(permission.GetMe))
+					apiUsersMeKeywordAlerts.POST("", h.CreateMyKeywordAlert, requires(permission.EditMe))
+					apiUsersMeKeywordAlerts.DELETE("/:keywordAlertID", h.DeleteMyKeywordAlert, requires(permission.EditMe))
+				}
 				apiUsersMeSubscriptions := apiUsersMe.Group("/subscriptions", blockBot)
 				{
 					apiUsersMeSubscriptions.GET("", h.GetMyChannelSubscriptions, requires(permission.GetChannelSubscription))
@@ -142,6 +174,7 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiUsersMeTokens := apiUsersMe.Group("/tokens", blockBot)
 				{
 					apiUsersMeTokens.GET("", h.GetMyTokens, requires(permission.GetMyTokens))
+					apiUsersMeTokens.POST("", h.CreateMyToken, requires(permission.CreateMyToken))
 					apiUsersMeTokens.DELETE("/:tokenID", h.RevokeMyToken, requires(permission.RevokeMyToken))
 				}
 				apiUsersMeExAccounts := apiUsersMe.Group("/ex-accounts", blockBot)
@@ -154,7 +187,7 @@ func (h *Handlers) Setup(e *echo.Group) {
 		}
 		apiChannels := api.Group("/channels")
 		{
-			apiChannels.GET("", h.GetChannels, requires(permission.GetChannel))
+			apiChannels.GET("", h.GetChannels, requires(permission.GetChannel), middlewares.PermissionsToContext(h.RBAC, permission.AccessDMChannel))
 			apiChannels.POST("", h.CreateChannels, requires(permission.CreateChannel))
 			apiChannelsCID := apiChannels.Group("/:channelID", retrieve.ChannelID(), requiresChannelAccessPerm)
 			{
@@ -166,12 +199,14 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiChannelsCID.GET("/topic", h.GetChannelTopic, requires(permission.GetChannel))
 				apiChannelsCID.PUT("/topic", h.EditChannelTopic, requires(permission.EditChannelTopic))
 				apiChannelsCID.GET("/viewers", h.GetChannelViewers, requires(permission.GetChannel))
+				apiChannelsCID.POST("/typing", h.PostChannelTyping, requires(permission.PostMessage))
 				apiChannelsCID.GET("/pins", h.GetChannelPins, requires(permission.GetMessage))
 				apiChannelsCID.GET("/subscribers", h.GetChannelSubscribers, requires(permission.GetChannelSubscription))
 				apiChannelsCID.PUT("/subscribers", h.SetChannelSubscribers, requires(permission.EditChannelSubscription))
 				apiChannelsCID.PATCH("/subscribers", h.EditChannelSubscribers, requires(permission.EditChannelSubscription))
 				apiChannelsCID.GET("/bots", h.GetChannelBots, requires(permission.GetChannel))
 				apiChannelsCID.GET("/events", h.GetChannelEvents, requires(permission.GetChannel))
+				apiChannelsCID.GET("/calls", h.GetChannelCalls, requires(permission.GetChannel))
 			}
 		}
 		apiMessages := api.Group("/messages")
@@ -297,6 +332,8 @@ func (h *Handlers) Setup(e *echo.Group) {
 				apiClientsCID.GET("", h.GetClient, requires(permission.GetClients))
 				apiClientsCID.PATCH("", h.EditClient, requiresClientAccessPerm, requires(permission.EditMyClient))
 				apiClientsCID.DELETE("", h.DeleteClient, requiresClientAccessPerm, requires(permission.DeleteMyClient))
+				apiClientsCID.GET("/tokens", h.GetClientTokens, requiresClientAccessPerm, requires(permission.EditMyClient))
+				apiClientsCID.DELETE("/tokens", h.RevokeClientTokens, requiresClientAccessPerm, requires(permission.EditMyClient))
 			}
 		}
 		apiBots := api.Group("/bots")
@@ -321,6 +358,7 @@ func (h *Handlers) Setup(e *echo.Group) {
 				}
 			}
 		}
+		api.GET("/webpush/public-key", h.GetWebPushPublicKey, requires(permission.RegisterFCMDevice), blockBot)
 		apiWebRTC := api.Group("/webrtc", requires(permission.WebRTC))
 		{
 			apiWebRTC.GET("/state", h.GetWebRTCState)
@@ -343,7 +381,7 @@ func (h *Handlers) Setup(e *echo.Group) {
 				}
 			}
 		}
-		api.GET("/ws", echo.WrapHandler(h.WS), requires(permission.ConnectNotificationStream), blockBot)
+		api.GET("/ws", echo.WrapHandler(h.WS), requires(permission.ConnectNotificationStream), blockBot, middlewares.PermissionsToContext(h.RBAC, permission.WebRTC))
 		api.GET("/ogp", h.GetOgp, blockBot)
 	}
 
@@ -353,6 +391,8 @@ func (h *Handlers) Setup(e *echo.Group) {
 		apiNoAuth.POST("/login", h.Login, nologin)
 		apiNoAuth.POST("/logout", h.Logout)
 		apiNoAuth.POST("/webhooks/:webhookID", h.PostWebhook, retrieve.WebhookID())
+		apiNoAuth.GET("/email/unsubscribe", h.GetEmailUnsubscribe)
+		apiNoAuth.POST("/email/unsubscribe", h.PostEmailUnsubscribe)
 		apiNoAuthPublic := apiNoAuth.Group("/public")
 		{
 			apiNoAuthPublic.GET("/icon/:username", h.GetPublicUserIcon)
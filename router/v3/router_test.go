@@ -15,6 +15,7 @@ import (
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/ogp"
 	"github.com/traPtitech/traQ/service/rbac"
 	"github.com/traPtitech/traQ/service/rbac/role"
 	"github.com/traPtitech/traQ/utils/random"
@@ -101,6 +102,7 @@ func TestMain(m *testing.M) {
 				ThumbnailMaxSize: image.Pt(360, 480),
 				ImageMagickPath:  "",
 			}),
+			OGP: ogp.NewService(ogp.Config{}, nil, nil, nil, "", zap.NewNop()),
 			Config: Config{
 				Version:  "version",
 				Revision: "revision",
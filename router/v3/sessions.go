@@ -6,6 +6,7 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/auth"
 	"github.com/traPtitech/traQ/router/consts"
 	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/utils/validator"
@@ -36,6 +37,33 @@ func (h *Handlers) Login(c echo.Context) error {
 		return err
 	}
 
+	// LDAP認証
+	if h.LDAP != nil {
+		user, err := h.LDAP.Login(req.Name, req.Password)
+		if err == nil {
+			// ユーザーのアカウント状態の確認
+			if !user.IsActive() {
+				h.L(c).Info("an api login attempt failed: suspended user", zap.String("username", req.Name))
+				return herror.Forbidden("this account is currently suspended")
+			}
+			h.L(c).Info("an api login attempt succeeded by ldap", zap.String("username", req.Name), zap.Stringer("id", user.GetID()))
+			return h.renewLoginSession(c, user.GetID())
+		}
+
+		switch err {
+		case auth.ErrLDAPInvalidCredentials:
+			// LDAPに存在しないユーザー(BOT管理者など)のためにtraQのパスワードでの認証を続ける
+		case auth.ErrLDAPSignUpNotAllowed:
+			h.L(c).Info("an api login attempt failed: ldap user is not a member", zap.String("username", req.Name))
+			return herror.Unauthorized("You are not a member of traQ")
+		case repository.ErrAlreadyExists:
+			return herror.Conflict("name conflicts")
+		default:
+			// LDAPサーバーに接続できない場合などもtraQのパスワードでログインできるように、認証を続ける
+			h.L(c).Error("ldap authentication failed", zap.Error(err), zap.String("username", req.Name))
+		}
+	}
+
 	user, err := h.Repo.GetUserByName(req.Name, false)
 	if err != nil {
 		switch err {
@@ -60,7 +88,12 @@ func (h *Handlers) Login(c echo.Context) error {
 	}
 	h.L(c).Info("an api login attempt succeeded", zap.String("username", req.Name))
 
-	if _, err := h.SessStore.RenewSession(c, user.GetID()); err != nil {
+	return h.renewLoginSession(c, user.GetID())
+}
+
+// renewLoginSession ログインしたユーザーのセッションを発行します
+func (h *Handlers) renewLoginSession(c echo.Context, userID uuid.UUID) error {
+	if _, err := h.SessStore.RenewSession(c, userID); err != nil {
 		return herror.InternalServerError(err)
 	}
 
@@ -136,27 +169,97 @@ func (h *Handlers) GetMyTokens(c echo.Context) error {
 	if err != nil {
 		return herror.InternalServerError(err)
 	}
+	pt, err := h.Repo.GetPersonalAccessTokensByUser(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
 
 	type response struct {
-		ID       uuid.UUID          `json:"id"`
-		ClientID string             `json:"clientId"`
-		Scopes   model.AccessScopes `json:"scopes"`
-		IssuedAt time.Time          `json:"issuedAt"`
+		ID         uuid.UUID          `json:"id"`
+		Type       string             `json:"type"`
+		ClientID   string             `json:"clientId"`
+		Name       string             `json:"name,omitempty"`
+		Scopes     model.AccessScopes `json:"scopes"`
+		IssuedAt   time.Time          `json:"issuedAt"`
+		ExpiresAt  *time.Time         `json:"expiresAt,omitempty"`
+		LastUsedAt *time.Time         `json:"lastUsedAt,omitempty"`
+		LastUsedIP string             `json:"lastUsedIp,omitempty"`
 	}
 
-	res := make([]response, len(ot))
-	for i, v := range ot {
-		res[i] = response{
+	res := make([]response, 0, len(ot)+len(pt))
+	for _, v := range ot {
+		res = append(res, response{
 			ID:       v.ID,
+			Type:     "oauth2",
 			ClientID: v.ClientID,
 			Scopes:   v.Scopes,
 			IssuedAt: v.CreatedAt,
-		}
+		})
+	}
+	for _, v := range pt {
+		expiresAt := v.ExpiresAt
+		res = append(res, response{
+			ID:         v.ID,
+			Type:       "personal",
+			Name:       v.Name,
+			Scopes:     v.Scopes,
+			IssuedAt:   v.CreatedAt,
+			ExpiresAt:  &expiresAt,
+			LastUsedAt: v.LastUsedAt,
+			LastUsedIP: v.LastUsedIP,
+		})
 	}
 
 	return c.JSON(http.StatusOK, res)
 }
 
+// PostMyTokenRequest POST /users/me/tokens リクエストボディ
+type PostMyTokenRequest struct {
+	Name      string             `json:"name"`
+	Scopes    model.AccessScopes `json:"scopes"`
+	ExpiresAt time.Time          `json:"expiresAt"`
+}
+
+func (r PostMyTokenRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Name, vd.Required, vd.RuneLength(1, 32)),
+		vd.Field(&r.Scopes, vd.Required),
+		vd.Field(&r.ExpiresAt, vd.Required, vd.Min(time.Now()).Error("must be in the future")),
+	)
+}
+
+// CreateMyToken POST /users/me/tokens
+func (h *Handlers) CreateMyToken(c echo.Context) error {
+	var req PostMyTokenRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	t, token, err := h.Repo.CreatePersonalAccessToken(repository.CreatePersonalAccessTokenArgs{
+		UserID:    getRequestUserID(c),
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.JSON(http.StatusCreated, echo.Map{
+		"id":        t.ID,
+		"name":      t.Name,
+		"scopes":    t.Scopes,
+		"issuedAt":  t.CreatedAt,
+		"expiresAt": t.ExpiresAt,
+		"token":     token,
+	})
+}
+
 // RevokeMyToken DELETE /users/me/tokens/:tokenID
 func (h *Handlers) RevokeMyToken(c echo.Context) error {
 	tokenID := getParamAsUUID(c, consts.ParamTokenID)
@@ -166,7 +269,16 @@ func (h *Handlers) RevokeMyToken(c echo.Context) error {
 	if err != nil {
 		switch err {
 		case repository.ErrNotFound:
-			return herror.NotFound()
+			// OAuth2トークンが無い場合はパーソナルアクセストークンを探す
+			if err := h.Repo.DeletePersonalAccessToken(userID, tokenID); err != nil {
+				switch err {
+				case repository.ErrNotFound:
+					return herror.NotFound()
+				default:
+					return herror.InternalServerError(err)
+				}
+			}
+			return c.NoContent(http.StatusNoContent)
 		default:
 			return herror.InternalServerError(err)
 		}
@@ -182,6 +294,34 @@ func (h *Handlers) RevokeMyToken(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// GetUserTokens GET /users/:userID/tokens
+func (h *Handlers) GetUserTokens(c echo.Context) error {
+	userID := getParamAsUUID(c, consts.ParamUserID)
+
+	ts, err := h.Repo.GetTokensByUser(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	pt, err := h.Repo.GetPersonalAccessTokensByUser(userID)
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.JSON(http.StatusOK, append(formatOAuth2TokenInfos(ts), formatPersonalAccessTokenInfos(pt)...))
+}
+
+// RevokeUserTokens DELETE /users/:userID/tokens
+func (h *Handlers) RevokeUserTokens(c echo.Context) error {
+	userID := getParamAsUUID(c, consts.ParamUserID)
+
+	if err := h.Repo.DeleteTokenByUser(userID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	if err := h.Repo.DeletePersonalAccessTokensByUser(userID); err != nil {
+		return herror.InternalServerError(err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
 // GetMyExternalAccounts GET /users/me/ex-accounts
 func (h *Handlers) GetMyExternalAccounts(c echo.Context) error {
 	links, err := h.Repo.GetLinkedExternalUserAccounts(getRequestUserID(c))
@@ -231,6 +371,10 @@ func (h *Handlers) LinkExternalAccount(c echo.Context) error {
 	if !h.EnabledExternalAccountProviders[req.ProviderName] {
 		return herror.BadRequest("invalid provider name")
 	}
+	if req.ProviderName == auth.SAMLProviderName || req.ProviderName == auth.LDAPProviderName {
+		// SAML・LDAPは既存のアカウントへの関連付けに対応していない
+		return herror.BadRequest("account linking is not supported by this provider")
+	}
 
 	links, err := h.Repo.GetLinkedExternalUserAccounts(getRequestUserID(c))
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/dgrijalva/jwt-go"
 	vd "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/skip2/go-qrcode"
@@ -19,6 +20,7 @@ import (
 	"github.com/traPtitech/traQ/utils/optional"
 	"github.com/traPtitech/traQ/utils/validator"
 	"net/http"
+	"regexp"
 	"time"
 )
 
@@ -72,7 +74,7 @@ func (h *Handlers) CreateUser(c echo.Context) error {
 		}
 	}
 
-	return c.JSON(http.StatusCreated, formatUserDetail(user, []model.UserTag{}, []uuid.UUID{}))
+	return c.JSON(http.StatusCreated, formatUserDetail(user, []model.UserTag{}, []uuid.UUID{}, optional.Time{}))
 }
 
 // GetMe GET /users/me
@@ -296,6 +298,81 @@ func (h *Handlers) PostMyFCMDevice(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// PostMyWebPushSubscriptionRequest POST /users/me/webpush-subscriptions リクエストボディ
+type PostMyWebPushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+func (r PostMyWebPushSubscriptionRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Endpoint, vd.Required, is.URL, vd.Match(regexp.MustCompile(`^https://`)), vd.RuneLength(1, 2048)),
+		vd.Field(&r.Keys.P256dh, vd.Required, vd.RuneLength(1, 100)),
+		vd.Field(&r.Keys.Auth, vd.Required, vd.RuneLength(1, 30)),
+	)
+}
+
+// PostMyWebPushSubscription POST /users/me/webpush-subscriptions
+func (h *Handlers) PostMyWebPushSubscription(c echo.Context) error {
+	if h.WebPush == nil {
+		return herror.HTTPError(http.StatusServiceUnavailable, "Web Push is not available")
+	}
+
+	var req PostMyWebPushSubscriptionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	userID := getRequestUserID(c)
+	if err := h.Repo.RegisterWebPushSubscription(userID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth); err != nil {
+		switch {
+		case repository.IsArgError(err):
+			return herror.BadRequest(err)
+		default:
+			return herror.InternalServerError(err)
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// DeleteMyWebPushSubscriptionRequest DELETE /users/me/webpush-subscriptions リクエストボディ
+type DeleteMyWebPushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint"`
+}
+
+func (r DeleteMyWebPushSubscriptionRequest) Validate() error {
+	return vd.ValidateStruct(&r,
+		vd.Field(&r.Endpoint, vd.Required),
+	)
+}
+
+// DeleteMyWebPushSubscription DELETE /users/me/webpush-subscriptions
+func (h *Handlers) DeleteMyWebPushSubscription(c echo.Context) error {
+	var req DeleteMyWebPushSubscriptionRequest
+	if err := bindAndValidate(c, &req); err != nil {
+		return err
+	}
+
+	userID := getRequestUserID(c)
+	if err := h.Repo.DeleteWebPushSubscription(userID, req.Endpoint); err != nil {
+		return herror.InternalServerError(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetWebPushPublicKey GET /webpush/public-key
+func (h *Handlers) GetWebPushPublicKey(c echo.Context) error {
+	if h.WebPush == nil {
+		return herror.HTTPError(http.StatusServiceUnavailable, "Web Push is not available")
+	}
+	return c.JSON(http.StatusOK, echo.Map{"publicKey": h.WebPush.PublicKey()})
+}
+
 // PutUserPasswordRequest PUT /users/:userID/password リクエストボディ
 type PutUserPasswordRequest struct {
 	NewPassword string `json:"newPassword"`
@@ -330,7 +407,18 @@ func (h *Handlers) GetUser(c echo.Context) error {
 		return herror.InternalServerError(err)
 	}
 
-	return c.JSON(http.StatusOK, formatUserDetail(user, tags, groups))
+	var dndUntil optional.Time
+	s, err := h.Repo.GetUserDNDSetting(user.GetID())
+	if err != nil && err != repository.ErrNotFound {
+		return herror.InternalServerError(err)
+	}
+	if s != nil {
+		if until, ok := s.ActiveUntil(time.Now()); ok {
+			dndUntil = optional.TimeFrom(until)
+		}
+	}
+
+	return c.JSON(http.StatusOK, formatUserDetail(user, tags, groups, dndUntil))
 }
 
 // PatchUserRequest PATCH /users/:userID リクエストボディ
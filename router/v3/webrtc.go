@@ -6,9 +6,15 @@ import (
 	vd "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/router/consts"
+	"github.com/traPtitech/traQ/router/extension/herror"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/utils/hmac"
+	"github.com/traPtitech/traQ/utils/optional"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -74,3 +80,55 @@ func (h *Handlers) GetWebRTCState(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, res)
 }
+
+type channelCallsQuery struct {
+	Limit     int           `query:"limit"`
+	Offset    int           `query:"offset"`
+	Since     optional.Time `query:"since"`
+	Until     optional.Time `query:"until"`
+	Inclusive bool          `query:"inclusive"`
+	Order     string        `query:"order"`
+}
+
+func (q *channelCallsQuery) bind(c echo.Context) error {
+	return bindAndValidate(c, q)
+}
+
+func (q *channelCallsQuery) Validate() error {
+	if q.Limit == 0 {
+		q.Limit = 20
+	}
+	return vd.ValidateStruct(q,
+		vd.Field(&q.Limit, vd.Min(1), vd.Max(200)),
+		vd.Field(&q.Offset, vd.Min(0)),
+	)
+}
+
+func (q *channelCallsQuery) convert(cid uuid.UUID) repository.CallSessionsQuery {
+	return repository.CallSessionsQuery{
+		Since:     q.Since,
+		Until:     q.Until,
+		Inclusive: q.Inclusive,
+		Limit:     q.Limit,
+		Offset:    q.Offset,
+		Asc:       strings.ToLower(q.Order) == "asc",
+		Channel:   cid,
+	}
+}
+
+// GetChannelCalls GET /channels/:channelID/calls
+func (h *Handlers) GetChannelCalls(c echo.Context) error {
+	channelID := getParamAsUUID(c, consts.ParamChannelID)
+
+	var req channelCallsQuery
+	if err := req.bind(c); err != nil {
+		return err
+	}
+
+	sessions, more, err := h.Repo.GetCallSessions(req.convert(channelID))
+	if err != nil {
+		return herror.InternalServerError(err)
+	}
+	c.Response().Header().Set(consts.HeaderMore, strconv.FormatBool(more))
+	return c.JSON(http.StatusOK, sessions)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/router/oauth2"
+	"github.com/traPtitech/traQ/router/scim"
 	"github.com/traPtitech/traQ/router/session"
 	"github.com/traPtitech/traQ/router/utils"
 	"github.com/traPtitech/traQ/router/v1"
@@ -49,7 +50,13 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 	}
 	streamer := ss.WS
 	webrtcv3Manager := ss.WebRTCv3
+	typingManager := ss.Typing
+	webpushClient := ss.WebPush
+	emailService := ss.Email
+	dndManager := ss.DND
+	ogpService := ss.OGP
 	v3Config := provideV3Config(config)
+	ldapProvider := provideLDAPProvider(repo, fileManager, logger, config)
 	v3Handlers := &v3.Handlers{
 		RBAC:           rbac,
 		Repo:           repo,
@@ -59,12 +66,18 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		OC:             onlineCounter,
 		VM:             viewerManager,
 		WebRTC:         webrtcv3Manager,
+		Typing:         typingManager,
+		WebPush:        webpushClient,
+		Email:          emailService,
+		DND:            dndManager,
+		OGP:            ogpService,
 		Imaging:        processor,
 		SessStore:      store,
 		ChannelManager: manager,
 		MessageManager: messageManager,
 		FileManager:    fileManager,
 		Replacer:       replacer,
+		LDAP:           ldapProvider,
 		Config:         v3Config,
 	}
 	oauth2Config := provideOAuth2Config(config)
@@ -75,12 +88,21 @@ func newRouter(hub2 *hub.Hub, db *gorm.DB, repo repository.Repository, ss *servi
 		SessStore: store,
 		Config:    oauth2Config,
 	}
+	scimConfig := provideSCIMConfig(config)
+	scimHandler := &scim.Handler{
+		Repo:        repo,
+		FileManager: fileManager,
+		SessStore:   store,
+		Logger:      logger,
+		Config:      scimConfig,
+	}
 	router := &Router{
 		e:         echo,
 		sessStore: store,
 		v1:        handlers,
 		v3:        v3Handlers,
 		oauth2:    handler,
+		scim:      scimHandler,
 	}
 	return router
 }
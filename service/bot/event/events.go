@@ -37,6 +37,10 @@ const (
 	TagAdded model.BotEventType = "TAG_ADDED"
 	// TagRemoved タグ削除イベント
 	TagRemoved model.BotEventType = "TAG_REMOVED"
+	// CallStarted 通話開始イベント
+	CallStarted model.BotEventType = "CALL_STARTED"
+	// CallEnded 通話終了イベント
+	CallEnded model.BotEventType = "CALL_ENDED"
 )
 
 var Types model.BotEventTypes
@@ -62,6 +66,8 @@ func init() {
 		StampCreated,
 		TagAdded,
 		TagRemoved,
+		CallStarted,
+		CallEnded,
 	} {
 		Types[t] = struct{}{}
 	}
@@ -0,0 +1,32 @@
+package payload
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
+
+// CallEnded CALL_ENDEDイベントペイロード
+type CallEnded struct {
+	Base
+	CallID       uuid.UUID `json:"callId"`
+	Channel      Channel   `json:"channel"`
+	StartedAt    time.Time `json:"startedAt"`
+	EndedAt      time.Time `json:"endedAt"`
+	Participants []User    `json:"participants"`
+}
+
+func MakeCallEnded(et time.Time, callID uuid.UUID, ch *model.Channel, chPath string, chCreator model.UserInfo, startedAt, endedAt time.Time, participants []model.UserInfo) *CallEnded {
+	users := make([]User, len(participants))
+	for i, user := range participants {
+		users[i] = MakeUser(user)
+	}
+	return &CallEnded{
+		Base:         MakeBase(et),
+		CallID:       callID,
+		Channel:      MakeChannel(ch, chPath, chCreator),
+		StartedAt:    startedAt,
+		EndedAt:      endedAt,
+		Participants: users,
+	}
+}
@@ -0,0 +1,22 @@
+package payload
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
+
+// CallStarted CALL_STARTEDイベントペイロード
+type CallStarted struct {
+	Base
+	CallID  uuid.UUID `json:"callId"`
+	Channel Channel   `json:"channel"`
+}
+
+func MakeCallStarted(et time.Time, callID uuid.UUID, ch *model.Channel, chPath string, chCreator model.UserInfo) *CallStarted {
+	return &CallStarted{
+		Base:    MakeBase(et),
+		CallID:  callID,
+		Channel: MakeChannel(ch, chPath, chCreator),
+	}
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/bot/event"
+	"github.com/traPtitech/traQ/service/bot/event/payload"
+	"time"
+)
+
+func CallEnded(ctx Context, datetime time.Time, _ string, fields hub.Fields) error {
+	callID := fields["call_id"].(uuid.UUID)
+	chID := fields["channel_id"].(uuid.UUID)
+	startedAt := fields["started_at"].(time.Time)
+	endedAt := fields["ended_at"].(time.Time)
+	participantIDs := fields["participants"].([]uuid.UUID)
+
+	bots, err := ctx.GetChannelBots(chID, event.CallEnded)
+	if err != nil {
+		return fmt.Errorf("failed to GetChannelBots: %w", err)
+	}
+	if len(bots) == 0 {
+		return nil
+	}
+
+	ch, err := ctx.CM().GetChannel(chID)
+	if err != nil {
+		return fmt.Errorf("failed to GetChannel: %w", err)
+	}
+
+	chCreator, err := ctx.R().GetUser(ch.CreatorID, false)
+	if err != nil && err != repository.ErrNotFound {
+		return fmt.Errorf("failed to GetUser: %w", err)
+	}
+
+	participants := make([]model.UserInfo, 0, len(participantIDs))
+	for _, id := range participantIDs {
+		user, err := ctx.R().GetUser(id, false)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				continue
+			}
+			return fmt.Errorf("failed to GetUser: %w", err)
+		}
+		participants = append(participants, user)
+	}
+
+	if err := ctx.Multicast(
+		event.CallEnded,
+		payload.MakeCallEnded(datetime, callID, ch, ctx.CM().PublicChannelTree().GetChannelPath(ch.ID), chCreator, startedAt, endedAt, participants),
+		bots,
+	); err != nil {
+		return fmt.Errorf("failed to multicast: %w", err)
+	}
+	return nil
+}
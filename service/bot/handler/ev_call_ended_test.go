@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	intevent "github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/bot/event"
+	"github.com/traPtitech/traQ/service/bot/event/payload"
+	"github.com/traPtitech/traQ/service/channel/mock_channel"
+	"testing"
+	"time"
+)
+
+func TestCallEnded(t *testing.T) {
+	t.Parallel()
+
+	b := &model.Bot{
+		ID:              uuid.NewV3(uuid.Nil, "b"),
+		BotUserID:       uuid.NewV3(uuid.Nil, "bu"),
+		SubscribeEvents: model.BotEventTypesFromArray([]string{event.CallEnded.String()}),
+		State:           model.BotActive,
+	}
+	u := &model.User{
+		ID:   uuid.NewV3(uuid.Nil, "u"),
+		Name: "testman",
+	}
+	u2 := &model.User{
+		ID:   uuid.NewV3(uuid.Nil, "u2"),
+		Name: "testman2",
+	}
+	ch := &model.Channel{
+		ID:        uuid.NewV3(uuid.Nil, "c"),
+		Name:      "test",
+		IsPublic:  true,
+		CreatorID: u.ID,
+	}
+	callID := uuid.NewV3(uuid.Nil, "call")
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		handlerCtx, cm, repo := setup(t, ctrl)
+
+		tree := mock_channel.NewMockTree(ctrl)
+		cm.EXPECT().PublicChannelTree().Return(tree).AnyTimes()
+		tree.EXPECT().GetChannelPath(ch.ID).Return(ch.Name).AnyTimes()
+
+		registerBot(t, handlerCtx, b)
+		registerChannel(cm, ch)
+		registerUser(repo, u)
+		registerUser(repo, u2)
+
+		handlerCtx.EXPECT().
+			GetChannelBots(ch.ID, event.CallEnded).
+			Return([]*model.Bot{b}, nil).
+			AnyTimes()
+
+		et := time.Now()
+		startedAt := et.Add(-time.Hour)
+
+		expectMulticast(handlerCtx, event.CallEnded, payload.MakeCallEnded(et, callID, ch, ch.Name, u, startedAt, et, []model.UserInfo{u, u2}), []*model.Bot{b})
+		assert.NoError(t, CallEnded(handlerCtx, et, intevent.WebRTCCallEnded, hub.Fields{
+			"call_id":      callID,
+			"channel_id":   ch.ID,
+			"started_at":   startedAt,
+			"ended_at":     et,
+			"participants": []uuid.UUID{u.ID, u2.ID},
+		}))
+	})
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/bot/event"
+	"github.com/traPtitech/traQ/service/bot/event/payload"
+	"time"
+)
+
+func CallStarted(ctx Context, datetime time.Time, _ string, fields hub.Fields) error {
+	callID := fields["call_id"].(uuid.UUID)
+	chID := fields["channel_id"].(uuid.UUID)
+
+	bots, err := ctx.GetChannelBots(chID, event.CallStarted)
+	if err != nil {
+		return fmt.Errorf("failed to GetChannelBots: %w", err)
+	}
+	if len(bots) == 0 {
+		return nil
+	}
+
+	ch, err := ctx.CM().GetChannel(chID)
+	if err != nil {
+		return fmt.Errorf("failed to GetChannel: %w", err)
+	}
+
+	chCreator, err := ctx.R().GetUser(ch.CreatorID, false)
+	if err != nil && err != repository.ErrNotFound {
+		return fmt.Errorf("failed to GetUser: %w", err)
+	}
+
+	if err := ctx.Multicast(
+		event.CallStarted,
+		payload.MakeCallStarted(datetime, callID, ch, ctx.CM().PublicChannelTree().GetChannelPath(ch.ID), chCreator),
+		bots,
+	); err != nil {
+		return fmt.Errorf("failed to multicast: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	intevent "github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/bot/event"
+	"github.com/traPtitech/traQ/service/bot/event/payload"
+	"github.com/traPtitech/traQ/service/channel/mock_channel"
+	"testing"
+	"time"
+)
+
+func TestCallStarted(t *testing.T) {
+	t.Parallel()
+
+	b := &model.Bot{
+		ID:              uuid.NewV3(uuid.Nil, "b"),
+		BotUserID:       uuid.NewV3(uuid.Nil, "bu"),
+		SubscribeEvents: model.BotEventTypesFromArray([]string{event.CallStarted.String()}),
+		State:           model.BotActive,
+	}
+	u := &model.User{
+		ID:   uuid.NewV3(uuid.Nil, "u"),
+		Name: "testman",
+	}
+	ch := &model.Channel{
+		ID:        uuid.NewV3(uuid.Nil, "c"),
+		Name:      "test",
+		IsPublic:  true,
+		CreatorID: u.ID,
+	}
+	callID := uuid.NewV3(uuid.Nil, "call")
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		handlerCtx, cm, repo := setup(t, ctrl)
+
+		tree := mock_channel.NewMockTree(ctrl)
+		cm.EXPECT().PublicChannelTree().Return(tree).AnyTimes()
+		tree.EXPECT().GetChannelPath(ch.ID).Return(ch.Name).AnyTimes()
+
+		registerBot(t, handlerCtx, b)
+		registerChannel(cm, ch)
+		registerUser(repo, u)
+
+		handlerCtx.EXPECT().
+			GetChannelBots(ch.ID, event.CallStarted).
+			Return([]*model.Bot{b}, nil).
+			AnyTimes()
+
+		et := time.Now()
+
+		expectMulticast(handlerCtx, event.CallStarted, payload.MakeCallStarted(et, callID, ch, ch.Name, u), []*model.Bot{b})
+		assert.NoError(t, CallStarted(handlerCtx, et, intevent.WebRTCCallStarted, hub.Fields{
+			"call_id":    callID,
+			"channel_id": ch.ID,
+			"started_at": et,
+		}))
+	})
+
+	t.Run("no bots", func(t *testing.T) {
+		t.Parallel()
+		ctrl := gomock.NewController(t)
+		handlerCtx, _, _ := setup(t, ctrl)
+
+		handlerCtx.EXPECT().
+			GetChannelBots(ch.ID, event.CallStarted).
+			Return([]*model.Bot{}, nil).
+			AnyTimes()
+
+		et := time.Now()
+		assert.NoError(t, CallStarted(handlerCtx, et, intevent.WebRTCCallStarted, hub.Fields{
+			"call_id":    callID,
+			"channel_id": ch.ID,
+			"started_at": et,
+		}))
+	})
+}
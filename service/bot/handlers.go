@@ -23,4 +23,6 @@ var eventHandlerSet = map[string]eventHandler{
 	intevent.UserTagAdded:         handler.UserTagAdded,
 	intevent.UserTagRemoved:       handler.UserTagRemoved,
 	intevent.MessageStampsUpdated: handler.MessageStampsUpdated,
+	intevent.WebRTCCallStarted:    handler.CallStarted,
+	intevent.WebRTCCallEnded:      handler.CallEnded,
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/gofrs/uuid"
 	"github.com/leandro-lugaresi/hub"
+	intevent "github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/service/bot/event"
@@ -51,6 +52,10 @@ func (p *serviceImpl) Start() {
 
 	go func() {
 		for ev := range p.sub.Receiver {
+			if intevent.IsRemote(ev) {
+				// 他のインスタンスで処理済み
+				continue
+			}
 			p.wg.Add(1)
 			go func(ev hub.Message) {
 				defer p.wg.Done()
@@ -0,0 +1,27 @@
+package bridge
+
+import (
+	"github.com/leandro-lugaresi/hub"
+)
+
+// Bridge 複数のtraQインスタンス間でイベントを中継するメッセージバス
+type Bridge interface {
+	// Publish メッセージを全てのインスタンスに送信します
+	//
+	// 実装によっては送信元のインスタンス自身もメッセージを受信します。
+	Publish(m *Message) error
+	// Receive 受信したメッセージのチャネルを返します
+	Receive() <-chan *Message
+	// Close ブリッジを閉じます
+	Close() error
+}
+
+// Message インスタンス間で中継されるメッセージ
+type Message struct {
+	// Origin 送信元インスタンスのID
+	Origin string
+	// Topic hubのトピック名
+	Topic string
+	// Fields hubのメッセージフィールド
+	Fields hub.Fields
+}
@@ -0,0 +1,55 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"github.com/traPtitech/traQ/utils/message"
+	"time"
+)
+
+func init() {
+	// 中継されるイベントのフィールドに含まれる型
+	gob.Register(uuid.UUID{})
+	gob.Register([]uuid.UUID{})
+	gob.Register(time.Time{})
+	gob.Register(map[string]string{})
+	gob.Register(map[uuid.UUID]int{})
+	gob.Register(map[uuid.UUID]viewer.StateWithTime{})
+	gob.Register(map[uuid.UUID]map[uuid.UUID]viewer.StateWithTime{})
+	gob.Register(webrtcv3.UserStateSnapshot{})
+	gob.Register(map[uuid.UUID]webrtcv3.UserStateSnapshot{})
+	gob.Register(&message.ParseResult{})
+	gob.Register(&model.Message{})
+	gob.Register([]*model.Unread{})
+	gob.Register(&model.Channel{})
+	gob.Register(&model.User{})
+	gob.Register(&model.UserGroup{})
+	gob.Register(&model.Stamp{})
+	gob.Register(&model.StampPalette{})
+	gob.Register(&model.ClipFolder{})
+	gob.Register(&model.ClipFolderMessage{})
+	gob.Register(&model.KeywordAlert{})
+	gob.Register(&model.Notification{})
+}
+
+// Encode メッセージをバイト列にエンコードします
+func Encode(m *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode バイト列からメッセージをデコードします
+func Decode(b []byte) (*Message, error) {
+	var m Message
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -0,0 +1,83 @@
+package bridge
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrClosed ブリッジが既に閉じられています
+var ErrClosed = errors.New("bridge is closed")
+
+// LoopbackNetwork プロセス内で複数のブリッジを接続するネットワーク
+//
+// 外部のメッセージブローカーを用いずに複数インスタンス構成をテストするために使用します。
+type LoopbackNetwork struct {
+	nodes map[*loopbackBridge]struct{}
+	mu    sync.RWMutex
+}
+
+// NewLoopbackNetwork LoopbackNetworkを生成します
+func NewLoopbackNetwork() *LoopbackNetwork {
+	return &LoopbackNetwork{
+		nodes: map[*loopbackBridge]struct{}{},
+	}
+}
+
+// Join ネットワークに接続されたブリッジを生成します
+func (n *LoopbackNetwork) Join() Bridge {
+	b := &loopbackBridge{
+		network: n,
+		recv:    make(chan *Message, 100),
+	}
+	n.mu.Lock()
+	n.nodes[b] = struct{}{}
+	n.mu.Unlock()
+	return b
+}
+
+type loopbackBridge struct {
+	network *LoopbackNetwork
+	recv    chan *Message
+	closed  bool
+}
+
+func (b *loopbackBridge) Publish(m *Message) error {
+	// 実際のブローカーと同様に、シリアライズしたものを配送する
+	data, err := Encode(m)
+	if err != nil {
+		return err
+	}
+
+	b.network.mu.RLock()
+	defer b.network.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+	for node := range b.network.nodes {
+		if node == b {
+			continue
+		}
+		m, err := Decode(data)
+		if err != nil {
+			return err
+		}
+		node.recv <- m
+	}
+	return nil
+}
+
+func (b *loopbackBridge) Receive() <-chan *Message {
+	return b.recv
+}
+
+func (b *loopbackBridge) Close() error {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	b.closed = true
+	delete(b.network.nodes, b)
+	close(b.recv)
+	return nil
+}
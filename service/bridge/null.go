@@ -0,0 +1,22 @@
+package bridge
+
+type nullBridge struct{}
+
+// NewNullBridge 何も中継しないブリッジを返します
+//
+// 単一インスタンスで動作させる場合に使用します。
+func NewNullBridge() Bridge {
+	return &nullBridge{}
+}
+
+func (b *nullBridge) Publish(*Message) error {
+	return nil
+}
+
+func (b *nullBridge) Receive() <-chan *Message {
+	return nil
+}
+
+func (b *nullBridge) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// RedisConfig Redis Pub/Subブリッジの設定
+type RedisConfig struct {
+	// Addr Redisサーバーのアドレス (host:port)
+	Addr string
+	// Password パスワード
+	Password string
+	// DB データベース番号
+	DB int
+	// Channel 使用するPub/Subチャンネル名
+	Channel string
+}
+
+type redisBridge struct {
+	client  *redis.Client
+	pubsub  *redis.PubSub
+	channel string
+	recv    chan *Message
+	logger  *zap.Logger
+}
+
+// NewRedisBridge Redis Pub/Subを用いたブリッジを生成します
+func NewRedisBridge(config RedisConfig, logger *zap.Logger) (Bridge, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+
+	ctx := context.Background()
+	pubsub := client.Subscribe(ctx, config.Channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to subscribe redis channel: %w", err)
+	}
+
+	b := &redisBridge{
+		client:  client,
+		pubsub:  pubsub,
+		channel: config.Channel,
+		recv:    make(chan *Message, 100),
+		logger:  logger.Named("bridge.redis"),
+	}
+	go b.loop()
+	return b, nil
+}
+
+func (b *redisBridge) loop() {
+	defer close(b.recv)
+	for msg := range b.pubsub.Channel() {
+		m, err := Decode([]byte(msg.Payload))
+		if err != nil {
+			b.logger.Warn("failed to decode message", zap.Error(err))
+			continue
+		}
+		b.recv <- m
+	}
+}
+
+func (b *redisBridge) Publish(m *Message) error {
+	data, err := Encode(m)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(context.Background(), b.channel, data).Err()
+}
+
+func (b *redisBridge) Receive() <-chan *Message {
+	return b.recv
+}
+
+func (b *redisBridge) Close() error {
+	if err := b.pubsub.Close(); err != nil {
+		return err
+	}
+	return b.client.Close()
+}
@@ -0,0 +1,372 @@
+package bridge
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"github.com/traPtitech/traQ/utils/random"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	// topicHeartbeat インスタンスの生存通知
+	topicHeartbeat = "bridge.heartbeat"
+	// topicLeave インスタンスの停止通知
+	topicLeave = "bridge.leave"
+	// topicPresence インスタンスでのユーザーの接続数の変化
+	// 	Fields:
+	// 		user_id: uuid.UUID
+	// 		count: int
+	topicPresence = "bridge.presence"
+	// topicSnapshot インスタンスのオンライン状態・閲覧状態・WebRTC状態の全体
+	// 	Fields:
+	// 		counts: map[uuid.UUID]int
+	// 		viewers: map[uuid.UUID]map[uuid.UUID]viewer.StateWithTime
+	// 		webrtc: map[uuid.UUID]webrtcv3.UserStateSnapshot
+	topicSnapshot = "bridge.snapshot"
+
+	heartbeatInterval = 5 * time.Second
+	nodeTimeout       = 4 * heartbeatInterval
+)
+
+// relayedTopics 他のインスタンスにそのまま中継するトピック
+var relayedTopics = []string{
+	event.UserCreated,
+	event.UserUpdated,
+	event.UserIconUpdated,
+	event.UserTagAdded,
+	event.UserTagUpdated,
+	event.UserTagRemoved,
+	event.UserGroupCreated,
+	event.UserGroupDeleted,
+	event.UserGroupMemberAdded,
+	event.UserGroupMemberRemoved,
+	event.MessageCreated,
+	event.MessageUpdated,
+	event.MessageDeleted,
+	event.MessageUnread,
+	event.MessageStamped,
+	event.MessageUnstamped,
+	event.MessagePinned,
+	event.MessageUnpinned,
+	event.ChannelCreated,
+	event.ChannelUpdated,
+	event.ChannelDeleted,
+	event.ChannelRead,
+	event.ChannelStared,
+	event.ChannelUnstared,
+	event.ChannelSubscribersChanged,
+	event.StampCreated,
+	event.StampUpdated,
+	event.StampDeleted,
+	event.StampPaletteCreated,
+	event.StampPaletteUpdated,
+	event.StampPaletteDeleted,
+	event.UserTyping,
+	event.ClipFolderCreated,
+	event.ClipFolderUpdated,
+	event.ClipFolderDeleted,
+	event.ClipFolderMessageDeleted,
+	event.ClipFolderMessageAdded,
+	event.NotificationCreated,
+	event.KeywordAlertCreated,
+	event.KeywordAlertDeleted,
+	event.OAuth2TokensRevoked,
+}
+
+// stateTopics 受信側でオンライン状態・閲覧状態・WebRTC状態に統合するトピック
+var stateTopics = []string{
+	event.WSConnected,
+	event.WSDisconnected,
+	event.SSEConnected,
+	event.SSEDisconnected,
+	event.ChannelViewersChanged,
+	event.UserWebRTCv3StateChanged,
+}
+
+// Relay hubのイベントをBridgeを介して他のインスタンスと中継します
+//
+// このインスタンスで発生したイベントを他のインスタンスに送信し、
+// 他のインスタンスから受信したイベントをevent.FieldOriginを付与してこのインスタンスのhubに発行します。
+// オンライン状態とチャンネル閲覧状態、WebRTC状態は、それぞれcounter.OnlineCounterとviewer.Manager、webrtcv3.Managerに統合されます。
+//
+// これらの状態は差分ではなく現在値として送信されるため、
+// 新しいインスタンスに送るスナップショットと個々の変化が前後しても整合性が保たれます。
+type Relay struct {
+	nodeID string
+	hub    *hub.Hub
+	bridge Bridge
+	vm     *viewer.Manager
+	oc     *counter.OnlineCounter
+	wm     *webrtcv3.Manager
+	logger *zap.Logger
+
+	sub hub.Subscription
+	// counts このインスタンスでの各ユーザーの接続数 sendLoopからのみアクセスされます
+	counts      map[uuid.UUID]int
+	snapshotReq chan struct{}
+	nodes       map[string]time.Time
+	nodesMu     sync.Mutex
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewRelay Relayを生成し、中継を開始します
+func NewRelay(hub *hub.Hub, bridge Bridge, vm *viewer.Manager, oc *counter.OnlineCounter, wm *webrtcv3.Manager, logger *zap.Logger) *Relay {
+	r := &Relay{
+		nodeID:      random.AlphaNumeric(16),
+		hub:         hub,
+		bridge:      bridge,
+		vm:          vm,
+		oc:          oc,
+		wm:          wm,
+		logger:      logger.Named("bridge"),
+		counts:      map[uuid.UUID]int{},
+		snapshotReq: make(chan struct{}, 1),
+		nodes:       map[string]time.Time{},
+		stop:        make(chan struct{}),
+	}
+	r.sub = hub.Subscribe(200, append(append([]string{}, relayedTopics...), stateTopics...)...)
+
+	// 受信したイベントのhubへの発行が送信をブロックしないように、送信と受信は別のgoroutineで処理する
+	r.wg.Add(2)
+	go r.sendLoop()
+	go r.receiveLoop()
+	return r
+}
+
+// NodeID このインスタンスのIDを返します
+func (r *Relay) NodeID() string {
+	return r.nodeID
+}
+
+// Close 中継を停止します
+func (r *Relay) Close() error {
+	close(r.stop)
+
+	// receiveLoopがhubへの発行でブロックしないように、停止するまでsubscriptionを読み捨てる
+	drained := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-r.sub.Receiver:
+			case <-drained:
+				return
+			}
+		}
+	}()
+	r.wg.Wait()
+	close(drained)
+	r.hub.Unsubscribe(r.sub)
+
+	r.publish(topicLeave, hub.Fields{})
+	return r.bridge.Close()
+}
+
+func (r *Relay) sendLoop() {
+	defer r.wg.Done()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	r.publish(topicHeartbeat, hub.Fields{})
+
+	for {
+		select {
+		case <-r.stop:
+			return
+
+		case m, ok := <-r.sub.Receiver:
+			if !ok {
+				return
+			}
+			r.forward(m)
+
+		case <-r.snapshotReq:
+			r.publish(topicSnapshot, hub.Fields{
+				"counts":  r.copyCounts(),
+				"viewers": r.vm.GetLocalSnapshot(),
+				"webrtc":  r.wm.GetLocalSnapshot(),
+			})
+
+		case <-heartbeat.C:
+			r.publish(topicHeartbeat, hub.Fields{})
+		}
+	}
+}
+
+func (r *Relay) receiveLoop() {
+	defer r.wg.Done()
+
+	expire := time.NewTicker(heartbeatInterval)
+	defer expire.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+
+		case now := <-expire.C:
+			r.expireNodes(now)
+
+		case m, ok := <-r.bridge.Receive():
+			if !ok {
+				return
+			}
+			r.receive(m)
+		}
+	}
+}
+
+// forward このインスタンスで発生したイベントを他のインスタンスに送信します
+func (r *Relay) forward(m hub.Message) {
+	if event.IsRemote(m) {
+		// 中継されたイベントは再送しない
+		return
+	}
+
+	switch m.Topic() {
+	case event.WSConnected, event.WSDisconnected, event.SSEConnected, event.SSEDisconnected:
+		userID := m.Fields["user_id"].(uuid.UUID)
+		if m.Topic() == event.WSConnected || m.Topic() == event.SSEConnected {
+			r.counts[userID]++
+		} else if r.counts[userID] > 0 {
+			r.counts[userID]--
+		}
+		n := r.counts[userID]
+		if n == 0 {
+			delete(r.counts, userID)
+		}
+		r.publish(topicPresence, hub.Fields{
+			"user_id": userID,
+			"count":   n,
+		})
+
+	case event.ChannelViewersChanged:
+		cid := m.Fields["channel_id"].(uuid.UUID)
+		r.publish(m.Topic(), hub.Fields{
+			"channel_id": cid,
+			"viewers":    r.vm.GetLocalChannelViewers(cid),
+		})
+
+	case event.UserWebRTCv3StateChanged:
+		userID := m.Fields["user_id"].(uuid.UUID)
+		r.publish(m.Topic(), hub.Fields{
+			"user_id": userID,
+			"state":   r.wm.GetLocalUserState(userID),
+		})
+
+	default:
+		r.publish(m.Topic(), m.Fields)
+	}
+}
+
+// receive 他のインスタンスから受信したイベントを処理します
+func (r *Relay) receive(m *Message) {
+	if m.Origin == r.nodeID {
+		return
+	}
+	if r.touchNode(m.Origin) && m.Topic != topicLeave {
+		// 新しいインスタンスに現在の状態を送る
+		select {
+		case r.snapshotReq <- struct{}{}:
+		default:
+		}
+	}
+
+	switch m.Topic {
+	case topicHeartbeat:
+		// 何もしない
+
+	case topicLeave:
+		r.removeNode(m.Origin)
+
+	case topicSnapshot:
+		counts, _ := m.Fields["counts"].(map[uuid.UUID]int)
+		viewers, _ := m.Fields["viewers"].(map[uuid.UUID]map[uuid.UUID]viewer.StateWithTime)
+		webrtc, _ := m.Fields["webrtc"].(map[uuid.UUID]webrtcv3.UserStateSnapshot)
+		r.oc.ReplaceRemote(m.Origin, counts)
+		r.vm.ReplaceRemote(m.Origin, viewers)
+		r.wm.ReplaceRemote(m.Origin, webrtc)
+
+	case topicPresence:
+		r.oc.SetRemote(m.Origin, m.Fields["user_id"].(uuid.UUID), m.Fields["count"].(int))
+
+	case event.ChannelViewersChanged:
+		viewers, _ := m.Fields["viewers"].(map[uuid.UUID]viewer.StateWithTime)
+		r.vm.SetRemoteChannelViewers(m.Origin, m.Fields["channel_id"].(uuid.UUID), viewers)
+
+	case event.UserWebRTCv3StateChanged:
+		state, _ := m.Fields["state"].(webrtcv3.UserStateSnapshot)
+		r.wm.SetRemote(m.Origin, m.Fields["user_id"].(uuid.UUID), state)
+
+	default:
+		fields := make(hub.Fields, len(m.Fields)+1)
+		for k, v := range m.Fields {
+			fields[k] = v
+		}
+		fields[event.FieldOrigin] = m.Origin
+		r.hub.Publish(hub.Message{
+			Name:   m.Topic,
+			Fields: fields,
+		})
+	}
+}
+
+func (r *Relay) publish(topic string, fields hub.Fields) {
+	err := r.bridge.Publish(&Message{
+		Origin: r.nodeID,
+		Topic:  topic,
+		Fields: fields,
+	})
+	if err != nil {
+		r.logger.Error("failed to publish message", zap.Error(err), zap.String("topic", topic))
+	}
+}
+
+func (r *Relay) copyCounts() map[uuid.UUID]int {
+	counts := make(map[uuid.UUID]int, len(r.counts))
+	for k, v := range r.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// touchNode インスタンスの最終受信日時を更新し、新しいインスタンスかどうかを返します
+func (r *Relay) touchNode(node string) bool {
+	r.nodesMu.Lock()
+	defer r.nodesMu.Unlock()
+	_, ok := r.nodes[node]
+	r.nodes[node] = time.Now()
+	return !ok
+}
+
+func (r *Relay) removeNode(node string) {
+	r.nodesMu.Lock()
+	delete(r.nodes, node)
+	r.nodesMu.Unlock()
+
+	r.oc.RemoveNode(node)
+	r.vm.RemoveNode(node)
+	r.wm.RemoveNode(node)
+}
+
+func (r *Relay) expireNodes(now time.Time) {
+	r.nodesMu.Lock()
+	expired := make([]string, 0)
+	for node, last := range r.nodes {
+		if now.Sub(last) > nodeTimeout {
+			expired = append(expired, node)
+		}
+	}
+	r.nodesMu.Unlock()
+
+	for _, node := range expired {
+		r.logger.Warn("node timed out", zap.String("node", node))
+		r.removeNode(node)
+	}
+}
@@ -0,0 +1,305 @@
+package bridge
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"github.com/traPtitech/traQ/utils/message"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type testNode struct {
+	hub   *hub.Hub
+	vm    *viewer.Manager
+	oc    *counter.OnlineCounter
+	wm    *webrtcv3.Manager
+	relay *Relay
+}
+
+func newTestNode(network *LoopbackNetwork) *testNode {
+	h := hub.New()
+	n := &testNode{
+		hub: h,
+		vm:  viewer.NewManager(h),
+		oc:  counter.NewOnlineCounter(h),
+		wm:  webrtcv3.NewManager(h),
+	}
+	n.relay = NewRelay(h, network.Join(), n.vm, n.oc, n.wm, zap.NewNop())
+	return n
+}
+
+// webrtcUsers 他のインスタンスを含めたWebRTC状態を ユーザーID -> チャンネルID で返します
+func (n *testNode) webrtcUsers() map[uuid.UUID]uuid.UUID {
+	result := map[uuid.UUID]uuid.UUID{}
+	n.wm.IterateStates(func(state webrtcv3.ChannelState) {
+		for _, us := range state.Users() {
+			result[us.UserID()] = us.ChannelID()
+		}
+	})
+	return result
+}
+
+func TestEncodeDecode(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	mid := uuid.Must(uuid.NewV4())
+	m := &Message{
+		Origin: "node",
+		Topic:  event.MessageCreated,
+		Fields: hub.Fields{
+			"message_id": mid,
+			"message": &model.Message{
+				ID:        mid,
+				Text:      "test",
+				CreatedAt: time.Now().Truncate(time.Second),
+			},
+			"parse_result": &message.ParseResult{
+				PlainText: "test",
+				Mentions:  []uuid.UUID{uuid.Must(uuid.NewV4())},
+			},
+		},
+	}
+
+	b, err := Encode(m)
+	require.NoError(err)
+	decoded, err := Decode(b)
+	require.NoError(err)
+
+	assert.Equal(m.Origin, decoded.Origin)
+	assert.Equal(m.Topic, decoded.Topic)
+	assert.Equal(mid, decoded.Fields["message_id"])
+	if dm, ok := decoded.Fields["message"].(*model.Message); assert.True(ok) {
+		assert.Equal("test", dm.Text)
+		assert.True(m.Fields["message"].(*model.Message).CreatedAt.Equal(dm.CreatedAt))
+	}
+	assert.Equal(m.Fields["parse_result"], decoded.Fields["parse_result"])
+}
+
+func TestRelay_Events(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	network := NewLoopbackNetwork()
+	a := newTestNode(network)
+	b := newTestNode(network)
+	defer a.relay.Close()
+	defer b.relay.Close()
+
+	sub := b.hub.Subscribe(10, event.StampCreated)
+	defer b.hub.Unsubscribe(sub)
+
+	sid := uuid.Must(uuid.NewV4())
+	a.hub.Publish(hub.Message{
+		Name: event.StampCreated,
+		Fields: hub.Fields{
+			"stamp_id": sid,
+		},
+	})
+
+	select {
+	case m := <-sub.Receiver:
+		assert.Equal(sid, m.Fields["stamp_id"])
+		assert.True(event.IsRemote(m))
+		assert.Equal(a.relay.NodeID(), m.Fields[event.FieldOrigin])
+	case <-time.After(3 * time.Second):
+		assert.Fail("event was not relayed")
+	}
+}
+
+func TestRelay_Notification(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	network := NewLoopbackNetwork()
+	a := newTestNode(network)
+	b := newTestNode(network)
+	defer a.relay.Close()
+	defer b.relay.Close()
+
+	sub := b.hub.Subscribe(10, event.NotificationCreated)
+	defer b.hub.Unsubscribe(sub)
+
+	n := &model.Notification{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    uuid.Must(uuid.NewV4()),
+		Type:      model.NotificationTypeMention,
+		MessageID: uuid.Must(uuid.NewV4()),
+		ChannelID: uuid.Must(uuid.NewV4()),
+		ActorID:   uuid.Must(uuid.NewV4()),
+		CreatedAt: time.Now().Truncate(time.Second),
+	}
+	a.hub.Publish(hub.Message{
+		Name: event.NotificationCreated,
+		Fields: hub.Fields{
+			"user_id":      n.UserID,
+			"notification": n,
+		},
+	})
+
+	select {
+	case m := <-sub.Receiver:
+		assert.True(event.IsRemote(m))
+		assert.Equal(n.UserID, m.Fields["user_id"])
+		if rn, ok := m.Fields["notification"].(*model.Notification); assert.True(ok) {
+			assert.Equal(n.ID, rn.ID)
+			assert.Equal(n.Type, rn.Type)
+			assert.Equal(n.MessageID, rn.MessageID)
+			assert.True(n.CreatedAt.Equal(rn.CreatedAt))
+		}
+	case <-time.After(3 * time.Second):
+		assert.Fail("event was not relayed")
+	}
+}
+
+func TestRelay_Presence(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	network := NewLoopbackNetwork()
+	a := newTestNode(network)
+	b := newTestNode(network)
+	defer b.relay.Close()
+
+	uid := uuid.Must(uuid.NewV4())
+	a.hub.Publish(hub.Message{
+		Name: event.WSConnected,
+		Fields: hub.Fields{
+			"user_id": uid,
+		},
+	})
+	assert.Eventually(func() bool { return b.oc.IsOnline(uid) }, 3*time.Second, 10*time.Millisecond)
+
+	// 後から参加したインスタンスにも反映される
+	c := newTestNode(network)
+	defer c.relay.Close()
+	assert.Eventually(func() bool { return c.oc.IsOnline(uid) }, 3*time.Second, 10*time.Millisecond)
+
+	// インスタンスが停止すると、そのインスタンスでの接続は削除される
+	assert.NoError(a.relay.Close())
+	assert.Eventually(func() bool { return !b.oc.IsOnline(uid) }, 3*time.Second, 10*time.Millisecond)
+	assert.Eventually(func() bool { return !c.oc.IsOnline(uid) }, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestRelay_Viewers(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	network := NewLoopbackNetwork()
+	a := newTestNode(network)
+	b := newTestNode(network)
+	defer a.relay.Close()
+	defer b.relay.Close()
+
+	cid := uuid.Must(uuid.NewV4())
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+
+	a.vm.SetViewer("a", u1, cid, viewer.StateMonitoring)
+	b.vm.SetViewer("b", u2, cid, viewer.StateEditing)
+
+	for _, n := range []*testNode{a, b} {
+		n := n
+		assert.Eventually(func() bool {
+			vs := n.vm.GetChannelViewers(cid)
+			return len(vs) == 2 && vs[u1].State == viewer.StateMonitoring && vs[u2].State == viewer.StateEditing
+		}, 3*time.Second, 10*time.Millisecond)
+	}
+	assert.Len(a.vm.GetLocalChannelViewers(cid), 1)
+
+	a.vm.RemoveViewer("a")
+	assert.Eventually(func() bool {
+		_, ok := b.vm.GetChannelViewers(cid)[u1]
+		return !ok
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestRelay_WebRTC(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	network := NewLoopbackNetwork()
+	a := newTestNode(network)
+	b := newTestNode(network)
+	defer b.relay.Close()
+
+	subA := a.hub.Subscribe(100, event.WebRTCCallStarted, event.WebRTCCallEnded, event.WebRTCCallParticipantJoined)
+	defer a.hub.Unsubscribe(subA)
+	subB := b.hub.Subscribe(100, event.WebRTCCallStarted, event.WebRTCCallEnded, event.WebRTCCallParticipantJoined)
+	defer b.hub.Unsubscribe(subB)
+	receive := func(sub hub.Subscription, topic string) hub.Message {
+		t.Helper()
+		select {
+		case ev := <-sub.Receiver:
+			require.Equal(topic, ev.Topic())
+			return ev
+		case <-time.After(3 * time.Second):
+			require.FailNow("event was not published", topic)
+			return hub.Message{}
+		}
+	}
+
+	cid := uuid.Must(uuid.NewV4())
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+	sessions := map[string]string{"s": "joined"}
+
+	// aで通話が開始される
+	require.NoError(a.wm.SetState("a", u1, cid, sessions))
+	callID := receive(subA, event.WebRTCCallStarted).Fields["call_id"].(uuid.UUID)
+	receive(subA, event.WebRTCCallParticipantJoined)
+	assert.Eventually(func() bool { return b.webrtcUsers()[u1] == cid }, 3*time.Second, 10*time.Millisecond)
+
+	// 他のインスタンスが保持している状態は変更できない
+	assert.Equal(webrtcv3.ErrOccupied, b.wm.SetState("b", u1, cid, sessions))
+
+	// bでの参加は、aで開始された通話への参加として扱われる
+	require.NoError(b.wm.SetState("b", u2, cid, sessions))
+	ev := receive(subB, event.WebRTCCallParticipantJoined)
+	assert.Equal(callID, ev.Fields["call_id"])
+	assert.Equal(u2, ev.Fields["user_id"])
+	for _, n := range []*testNode{a, b} {
+		n := n
+		assert.Eventually(func() bool {
+			users := n.webrtcUsers()
+			return len(users) == 2 && users[u1] == cid && users[u2] == cid
+		}, 3*time.Second, 10*time.Millisecond)
+	}
+
+	// 後から参加したインスタンスにも反映される
+	c := newTestNode(network)
+	defer c.relay.Close()
+	assert.Eventually(func() bool { return len(c.webrtcUsers()) == 2 }, 3*time.Second, 10*time.Millisecond)
+
+	// 他のインスタンスに参加者が残っている間は通話は終了しない
+	require.NoError(a.wm.ResetState("a", u1))
+	assert.Eventually(func() bool {
+		_, ok := b.webrtcUsers()[u1]
+		return !ok
+	}, 3*time.Second, 10*time.Millisecond)
+	require.NoError(b.wm.ResetState("b", u2))
+	ev = receive(subB, event.WebRTCCallEnded)
+	assert.Equal(callID, ev.Fields["call_id"])
+	assert.ElementsMatch([]uuid.UUID{u1, u2}, ev.Fields["participants"])
+	assert.Eventually(func() bool { return len(a.webrtcUsers()) == 0 }, 3*time.Second, 10*time.Millisecond)
+
+	// aでは通話の終了は発行されず、次の参加で新しい通話が開始される
+	require.NoError(a.wm.SetState("a", u1, cid, sessions))
+	assert.NotEqual(callID, receive(subA, event.WebRTCCallStarted).Fields["call_id"])
+
+	// インスタンスが停止すると、そのインスタンスでの状態は削除される
+	assert.Eventually(func() bool { return b.webrtcUsers()[u1] == cid }, 3*time.Second, 10*time.Millisecond)
+	assert.NoError(a.relay.Close())
+	assert.Eventually(func() bool { return len(b.webrtcUsers()) == 0 }, 3*time.Second, 10*time.Millisecond)
+	assert.NoError(b.wm.SetState("b", u1, cid, sessions))
+}
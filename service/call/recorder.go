@@ -0,0 +1,135 @@
+package call
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/message"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"time"
+)
+
+// systemUserName システムメッセージを投稿するユーザーの名前
+const systemUserName = "traq"
+
+// Config 通話履歴設定
+type Config struct {
+	// SystemMessage 通話の開始・終了時にチャンネルにメッセージを投稿するかどうか
+	SystemMessage bool
+}
+
+// Recorder webrtcv3.Managerの通話の開始・終了を通話履歴として記録します
+type Recorder struct {
+	repo   repository.Repository
+	mm     message.Manager
+	config Config
+	logger *zap.Logger
+}
+
+// NewRecorder Recorderを生成します
+func NewRecorder(hub *hub.Hub, repo repository.Repository, mm message.Manager, config Config, logger *zap.Logger) *Recorder {
+	r := &Recorder{
+		repo:   repo,
+		mm:     mm,
+		config: config,
+		logger: logger.Named("call_recorder"),
+	}
+	go func() {
+		// 参加・退出を通話の開始・終了と同じ順序で処理する必要があるため、1つの購読で受信する
+		for ev := range hub.Subscribe(100,
+			event.WebRTCCallStarted,
+			event.WebRTCCallEnded,
+			event.WebRTCCallParticipantJoined,
+			event.WebRTCCallParticipantLeft,
+		).Receiver {
+			r.handle(ev)
+		}
+	}()
+	return r
+}
+
+func (r *Recorder) handle(ev hub.Message) {
+	callID := ev.Fields["call_id"].(uuid.UUID)
+	channelID := ev.Fields["channel_id"].(uuid.UUID)
+
+	switch ev.Topic() {
+	case event.WebRTCCallStarted:
+		if _, err := r.repo.CreateCallSession(callID, channelID, ev.Fields["started_at"].(time.Time)); err != nil {
+			r.logger.Error("failed to CreateCallSession", zap.Error(err), zap.Stringer("callID", callID))
+			return
+		}
+		if r.config.SystemMessage {
+			r.postSystemMessage(channelID, "通話が開始されました")
+		}
+
+	case event.WebRTCCallEnded:
+		startedAt := ev.Fields["started_at"].(time.Time)
+		endedAt := ev.Fields["ended_at"].(time.Time)
+		if err := r.repo.EndCallSession(callID, endedAt); err != nil {
+			r.logger.Error("failed to EndCallSession", zap.Error(err), zap.Stringer("callID", callID))
+			return
+		}
+		if r.config.SystemMessage {
+			names := r.userNames(ev.Fields["participants"].([]uuid.UUID))
+			r.postSystemMessage(channelID, fmt.Sprintf("通話が終了しました\n通話時間: %s\n参加者: %s", FormatDuration(endedAt.Sub(startedAt)), strings.Join(names, ", ")))
+		}
+
+	case event.WebRTCCallParticipantJoined:
+		if err := r.repo.AddCallParticipant(callID, ev.Fields["user_id"].(uuid.UUID), ev.Fields["datetime"].(time.Time)); err != nil {
+			r.logger.Error("failed to AddCallParticipant", zap.Error(err), zap.Stringer("callID", callID))
+		}
+
+	case event.WebRTCCallParticipantLeft:
+		if err := r.repo.RemoveCallParticipant(callID, ev.Fields["user_id"].(uuid.UUID), ev.Fields["datetime"].(time.Time)); err != nil {
+			r.logger.Error("failed to RemoveCallParticipant", zap.Error(err), zap.Stringer("callID", callID))
+		}
+	}
+}
+
+func (r *Recorder) postSystemMessage(channelID uuid.UUID, content string) {
+	user, err := r.repo.GetUserByName(systemUserName, false)
+	if err != nil {
+		r.logger.Error("failed to GetUserByName", zap.Error(err))
+		return
+	}
+	if _, err := r.mm.Create(channelID, user.GetID(), content); err != nil {
+		r.logger.Warn("failed to post system message", zap.Error(err), zap.Stringer("channelID", channelID))
+	}
+}
+
+// userNames 指定したユーザーの名前を昇順で返します
+func (r *Recorder) userNames(ids []uuid.UUID) []string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		user, err := r.repo.GetUser(id, false)
+		if err != nil {
+			r.logger.Warn("failed to GetUser", zap.Error(err), zap.Stringer("userID", id))
+			continue
+		}
+		names = append(names, user.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FormatDuration 通話時間を`1時間2分3秒`の形式で返します
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+
+	var b strings.Builder
+	if h > 0 {
+		fmt.Fprintf(&b, "%d時間", h)
+	}
+	if h > 0 || m > 0 {
+		fmt.Fprintf(&b, "%d分", m)
+	}
+	fmt.Fprintf(&b, "%d秒", s)
+	return b.String()
+}
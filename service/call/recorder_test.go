@@ -0,0 +1,136 @@
+package call
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/service/message"
+	"github.com/traPtitech/traQ/testutils"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type Repo struct {
+	*mock_repository.MockCallRepository
+	*mock_repository.MockUserRepository
+	testutils.EmptyTestRepository
+}
+
+type postedMessage struct {
+	channelID uuid.UUID
+	userID    uuid.UUID
+	content   string
+}
+
+type testMessageManager struct {
+	message.Manager
+	posted []postedMessage
+}
+
+func (m *testMessageManager) Create(channelID, userID uuid.UUID, content string) (message.Message, error) {
+	m.posted = append(m.posted, postedMessage{channelID: channelID, userID: userID, content: content})
+	return nil, nil
+}
+
+func setup(t *testing.T, config Config) (*Recorder, *Repo, *testMessageManager) {
+	ctrl := gomock.NewController(t)
+	repo := &Repo{
+		MockCallRepository: mock_repository.NewMockCallRepository(ctrl),
+		MockUserRepository: mock_repository.NewMockUserRepository(ctrl),
+	}
+	mm := &testMessageManager{}
+	return &Recorder{repo: repo, mm: mm, config: config, logger: zap.NewNop()}, repo, mm
+}
+
+func TestRecorder_handle(t *testing.T) {
+	t.Parallel()
+
+	callID := uuid.NewV3(uuid.Nil, "call")
+	channelID := uuid.NewV3(uuid.Nil, "c")
+	traq := &model.User{ID: uuid.NewV3(uuid.Nil, "traq"), Name: "traq"}
+	u1 := &model.User{ID: uuid.NewV3(uuid.Nil, "u1"), Name: "user1"}
+	u2 := &model.User{ID: uuid.NewV3(uuid.Nil, "u2"), Name: "user2"}
+	startedAt := time.Now()
+	endedAt := startedAt.Add(time.Hour + 2*time.Minute + 3*time.Second)
+
+	started := hub.Message{Name: event.WebRTCCallStarted, Fields: hub.Fields{
+		"call_id":    callID,
+		"channel_id": channelID,
+		"started_at": startedAt,
+	}}
+	ended := hub.Message{Name: event.WebRTCCallEnded, Fields: hub.Fields{
+		"call_id":      callID,
+		"channel_id":   channelID,
+		"started_at":   startedAt,
+		"ended_at":     endedAt,
+		"participants": []uuid.UUID{u2.ID, u1.ID},
+	}}
+
+	t.Run("record", func(t *testing.T) {
+		t.Parallel()
+		r, repo, mm := setup(t, Config{})
+
+		repo.MockCallRepository.EXPECT().CreateCallSession(callID, channelID, startedAt).Return(&model.CallSession{}, nil).Times(1)
+		repo.MockCallRepository.EXPECT().AddCallParticipant(callID, u1.ID, startedAt).Return(nil).Times(1)
+		repo.MockCallRepository.EXPECT().RemoveCallParticipant(callID, u1.ID, endedAt).Return(nil).Times(1)
+		repo.MockCallRepository.EXPECT().EndCallSession(callID, endedAt).Return(nil).Times(1)
+
+		r.handle(started)
+		r.handle(hub.Message{Name: event.WebRTCCallParticipantJoined, Fields: hub.Fields{
+			"call_id":    callID,
+			"channel_id": channelID,
+			"user_id":    u1.ID,
+			"datetime":   startedAt,
+		}})
+		r.handle(hub.Message{Name: event.WebRTCCallParticipantLeft, Fields: hub.Fields{
+			"call_id":    callID,
+			"channel_id": channelID,
+			"user_id":    u1.ID,
+			"datetime":   endedAt,
+		}})
+		r.handle(ended)
+		assert.Empty(t, mm.posted)
+	})
+
+	t.Run("system message", func(t *testing.T) {
+		t.Parallel()
+		r, repo, mm := setup(t, Config{SystemMessage: true})
+
+		repo.MockCallRepository.EXPECT().CreateCallSession(callID, channelID, startedAt).Return(&model.CallSession{}, nil).Times(1)
+		repo.MockCallRepository.EXPECT().EndCallSession(callID, endedAt).Return(nil).Times(1)
+		repo.MockUserRepository.EXPECT().GetUserByName("traq", false).Return(traq, nil).AnyTimes()
+		repo.MockUserRepository.EXPECT().GetUser(u1.ID, false).Return(u1, nil).AnyTimes()
+		repo.MockUserRepository.EXPECT().GetUser(u2.ID, false).Return(u2, nil).AnyTimes()
+
+		r.handle(started)
+		r.handle(ended)
+		if assert.Len(t, mm.posted, 2) {
+			assert.Equal(t, postedMessage{channelID: channelID, userID: traq.ID, content: "通話が開始されました"}, mm.posted[0])
+			assert.Equal(t, postedMessage{channelID: channelID, userID: traq.ID, content: "通話が終了しました\n通話時間: 1時間2分3秒\n参加者: user1, user2"}, mm.posted[1])
+		}
+	})
+}
+
+func TestFormatDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0秒"},
+		{1500 * time.Millisecond, "2秒"},
+		{59 * time.Second, "59秒"},
+		{time.Minute, "1分0秒"},
+		{time.Hour + 3*time.Second, "1時間0分3秒"},
+		{25*time.Hour + 30*time.Minute, "25時間30分0秒"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, FormatDuration(tt.d))
+	}
+}
@@ -43,18 +43,67 @@ func NewOnlineCounter(hub *hub.Hub) *OnlineCounter {
 
 // inc 指定したユーザーのカウンタをインクリメントします
 func (oc *OnlineCounter) inc(userID uuid.UUID) (toOnline bool) {
+	toOnline, _ = oc.update(userID, func(c *counter) (bool, bool) {
+		return c.add(localNode, 1)
+	})
+	return
+}
+
+// dec 指定したユーザーのカウンタをデクリメントします
+func (oc *OnlineCounter) dec(userID uuid.UUID) (toOffline bool) {
+	_, toOffline = oc.update(userID, func(c *counter) (bool, bool) {
+		return c.add(localNode, -1)
+	})
+	return
+}
+
+// SetRemote 他のインスタンスでの指定したユーザーの接続数を設定します
+func (oc *OnlineCounter) SetRemote(node string, userID uuid.UUID, n int) {
+	oc.update(userID, func(c *counter) (bool, bool) {
+		return c.set(node, n)
+	})
+}
+
+// ReplaceRemote 他のインスタンスでの全ユーザーの接続数を置き換えます
+func (oc *OnlineCounter) ReplaceRemote(node string, counts map[uuid.UUID]int) {
+	oc.countersLock.Lock()
+	users := make([]uuid.UUID, 0, len(oc.counters)+len(counts))
+	for u := range oc.counters {
+		users = append(users, u)
+	}
+	oc.countersLock.Unlock()
+	for u := range counts {
+		users = append(users, u)
+	}
+
+	for _, u := range users {
+		n := counts[u]
+		oc.update(u, func(c *counter) (bool, bool) {
+			return c.set(node, n)
+		})
+	}
+}
+
+// RemoveNode 他のインスタンスでの接続を全て削除します
+func (oc *OnlineCounter) RemoveNode(node string) {
+	oc.ReplaceRemote(node, nil)
+}
+
+func (oc *OnlineCounter) update(userID uuid.UUID, f func(c *counter) (toOnline bool, toOffline bool)) (toOnline bool, toOffline bool) {
 	oc.countersLock.Lock()
 	c, ok := oc.counters[userID]
 	if !ok {
 		c = &counter{
 			userID: userID,
+			counts: map[string]int{},
 		}
 		oc.counters[userID] = c
 	}
 	oc.countersLock.Unlock()
 
-	toOnline = c.inc()
-	if toOnline {
+	toOnline, toOffline = f(c)
+	switch {
+	case toOnline:
 		onlineUsersCounter.Inc()
 		oc.hub.Publish(hub.Message{
 			Name: event.UserOnline,
@@ -63,22 +112,7 @@ func (oc *OnlineCounter) inc(userID uuid.UUID) (toOnline bool) {
 				"datetime": c.getLastUpdated(),
 			},
 		})
-	}
-	return
-}
-
-// dec 指定したユーザーのカウンタをデクリメントします
-func (oc *OnlineCounter) dec(userID uuid.UUID) (toOffline bool) {
-	oc.countersLock.Lock()
-	c, ok := oc.counters[userID]
-	if !ok {
-		oc.countersLock.Unlock()
-		return
-	}
-	oc.countersLock.Unlock()
-
-	toOffline = c.dec()
-	if toOffline {
+	case toOffline:
 		onlineUsersCounter.Dec()
 		oc.hub.Publish(hub.Message{
 			Name: event.UserOffline,
@@ -117,42 +151,55 @@ func (oc *OnlineCounter) GetOnlineUserIDs() []uuid.UUID {
 	return users
 }
 
+// localNode このインスタンスを表すノード名
+const localNode = ""
+
 type counter struct {
 	sync.RWMutex
-	userID      uuid.UUID
-	count       int
+	userID uuid.UUID
+	// counts インスタンス毎の接続数
+	counts      map[string]int
+	total       int
 	lastUpdated time.Time
 }
 
 func (s *counter) isOnline() (r bool) {
 	s.RLock()
-	r = s.count > 0
+	r = s.total > 0
 	s.RUnlock()
 	return
 }
 
-func (s *counter) inc() (toOnline bool) {
+func (s *counter) add(node string, n int) (toOnline bool, toOffline bool) {
 	s.Lock()
-	s.count++
-	s.lastUpdated = time.Now()
-	if s.count == 1 {
-		toOnline = true
+	defer s.Unlock()
+	if s.counts[node]+n < 0 {
+		n = -s.counts[node]
 	}
-	s.Unlock()
-	return
+	return s.apply(node, s.counts[node]+n)
 }
 
-func (s *counter) dec() (toOffline bool) {
+func (s *counter) set(node string, n int) (toOnline bool, toOffline bool) {
 	s.Lock()
-	if s.count > 0 {
-		s.count--
-		s.lastUpdated = time.Now()
-		if s.count == 0 {
-			toOffline = true
-		}
+	defer s.Unlock()
+	return s.apply(node, n)
+}
+
+func (s *counter) apply(node string, n int) (toOnline bool, toOffline bool) {
+	old := s.counts[node]
+	if old == n {
+		return
 	}
-	s.Unlock()
-	return
+	if n == 0 {
+		delete(s.counts, node)
+	} else {
+		s.counts[node] = n
+	}
+
+	prev := s.total
+	s.total += n - old
+	s.lastUpdated = time.Now()
+	return prev == 0 && s.total > 0, prev > 0 && s.total == 0
 }
 
 func (s *counter) getLastUpdated() (t time.Time) {
@@ -0,0 +1,126 @@
+// Package dnd おやすみモード
+package dnd
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/set"
+	"sync"
+	"time"
+)
+
+const (
+	// UrgentOverrideLimit 同じ相手に送信できる緊急DMの期間あたりの最大数
+	UrgentOverrideLimit = 3
+	// UrgentOverrideWindow 緊急DMの回数制限の期間
+	UrgentOverrideWindow = time.Hour
+	// urgentPassTTL 緊急DMの通知許可の有効期間
+	urgentPassTTL = time.Minute
+)
+
+// ErrRateLimited 緊急DMの回数制限を超えています
+var ErrRateLimited = errors.New("too many urgent messages")
+
+// Manager おやすみモードマネージャー
+//
+// おやすみモード中のユーザーにはプッシュ通知が送信されません。
+// ただし、緊急DMとして送信されたDMは、回数制限の範囲内でおやすみモードを無視して通知されます。
+// 回数制限には実際におやすみモードを無視して通知した緊急DMのみを数え、全てのインスタンスで共有するためDBに記録します。
+// 緊急DMの通知許可はDMを作成したインスタンスで消費されるため、インスタンスのメモリ上で管理します。
+type Manager struct {
+	repo repository.UserDNDSettingRepository
+	// passes 送信者・受信者ごとの未使用の緊急DMの通知許可の期限
+	passes map[pair]time.Time
+	mu     sync.Mutex
+}
+
+type pair struct {
+	from uuid.UUID
+	to   uuid.UUID
+}
+
+// NewManager おやすみモードマネージャーを生成します
+func NewManager(repo repository.Repository) *Manager {
+	return &Manager{
+		repo:   repo,
+		passes: map[pair]time.Time{},
+	}
+}
+
+// FilterActive 指定したユーザーのうち、nowの時点でおやすみモード中のユーザーを返します
+func (m *Manager) FilterActive(userIDs set.UUID, now time.Time) (set.UUID, error) {
+	settings, err := m.repo.GetUserDNDSettings(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	active := set.UUID{}
+	for _, s := range settings {
+		if s.IsActive(now) {
+			active.Add(s.UserID)
+		}
+	}
+	return active, nil
+}
+
+// RequestUrgentOverride fromからtoへの次のDMを緊急DMとして通知するように要求します
+//
+// toがおやすみモード中でない場合は、通常のDMとして通知されるため何もしません。
+// 同じ相手への緊急DMの通知がUrgentOverrideWindowの間に既にUrgentOverrideLimit回ある場合、ErrRateLimitedを返します。
+// 要求はurgentPassTTLの間有効です。
+func (m *Manager) RequestUrgentOverride(from, to uuid.UUID, now time.Time) error {
+	active, err := m.FilterActive(set.UUIDSetFromArray([]uuid.UUID{to}), now)
+	if err != nil {
+		return err
+	}
+	if !active.Contains(to) {
+		return nil
+	}
+	count, err := m.repo.GetUrgentDNDOverrideCount(from, to, now.Add(-UrgentOverrideWindow))
+	if err != nil {
+		return err
+	}
+	if count >= UrgentOverrideLimit {
+		return ErrRateLimited
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gc(now)
+	m.passes[pair{from: from, to: to}] = now.Add(urgentPassTTL)
+	return nil
+}
+
+// CancelUrgentOverride 未使用の緊急DMの要求を取り消します
+//
+// DMの送信に失敗した場合に使用します。
+func (m *Manager) CancelUrgentOverride(from, to uuid.UUID) {
+	m.mu.Lock()
+	delete(m.passes, pair{from: from, to: to})
+	m.mu.Unlock()
+}
+
+// ConsumeUrgentOverride fromからtoへの有効な緊急DMの要求があれば消費し、回数制限の範囲内であればtrueを返します
+//
+// おやすみモード中のtoへの通知時に使用します。trueを返した場合、回数制限のカウントに記録されます。
+func (m *Manager) ConsumeUrgentOverride(from, to uuid.UUID, now time.Time) (bool, error) {
+	k := pair{from: from, to: to}
+	m.mu.Lock()
+	expires, ok := m.passes[k]
+	delete(m.passes, k)
+	m.mu.Unlock()
+
+	if !ok || !now.Before(expires) {
+		return false, nil
+	}
+	return m.repo.RecordUrgentDNDOverride(from, to, now.Add(-UrgentOverrideWindow), UrgentOverrideLimit)
+}
+
+// gc 期限切れの通知許可を削除します
+func (m *Manager) gc(now time.Time) {
+	for k, expires := range m.passes {
+		if !now.Before(expires) {
+			delete(m.passes, k)
+		}
+	}
+}
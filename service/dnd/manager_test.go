@@ -0,0 +1,135 @@
+package dnd
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"testing"
+	"time"
+)
+
+type Repo struct {
+	*mock_repository.MockUserDNDSettingRepository
+	testutils.EmptyTestRepository
+}
+
+func TestManager_FilterActive(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	now := time.Now()
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+	u3 := uuid.Must(uuid.NewV4())
+	users := set.UUIDSetFromArray([]uuid.UUID{u1, u2, u3})
+
+	ctrl := gomock.NewController(t)
+	repo := &Repo{MockUserDNDSettingRepository: mock_repository.NewMockUserDNDSettingRepository(ctrl)}
+	repo.MockUserDNDSettingRepository.EXPECT().GetUserDNDSettings(users).Return([]*model.UserDNDSetting{
+		{UserID: u1, SnoozeUntil: optional.TimeFrom(now.Add(time.Hour))},
+		{UserID: u2, SnoozeUntil: optional.TimeFrom(now.Add(-time.Hour))},
+	}, nil)
+
+	active, err := NewManager(repo).FilterActive(users, now)
+	if assert.NoError(err) {
+		assert.ElementsMatch([]uuid.UUID{u1}, active.Array())
+	}
+}
+
+func TestManager_UrgentOverride(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	since := now.Add(-UrgentOverrideWindow)
+	from := uuid.Must(uuid.NewV4())
+	to := uuid.Must(uuid.NewV4())
+	sleeping := []*model.UserDNDSetting{{UserID: to, SnoozeUntil: optional.TimeFrom(now.Add(time.Hour))}}
+
+	t.Run("not sleeping", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		ctrl := gomock.NewController(t)
+		repo := &Repo{MockUserDNDSettingRepository: mock_repository.NewMockUserDNDSettingRepository(ctrl)}
+		repo.MockUserDNDSettingRepository.EXPECT().GetUserDNDSettings(gomock.Any()).Return([]*model.UserDNDSetting{}, nil)
+		m := NewManager(repo)
+
+		// おやすみモード中でない相手には通常のDMとして通知され、回数に数えない
+		assert.NoError(m.RequestUrgentOverride(from, to, now))
+		urgent, err := m.ConsumeUrgentOverride(from, to, now)
+		assert.NoError(err)
+		assert.False(urgent)
+	})
+
+	t.Run("sleeping", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		ctrl := gomock.NewController(t)
+		repo := &Repo{MockUserDNDSettingRepository: mock_repository.NewMockUserDNDSettingRepository(ctrl)}
+		repo.MockUserDNDSettingRepository.EXPECT().GetUserDNDSettings(gomock.Any()).Return(sleeping, nil).AnyTimes()
+		repo.MockUserDNDSettingRepository.EXPECT().GetUrgentDNDOverrideCount(from, to, since).Return(0, nil).AnyTimes()
+		repo.MockUserDNDSettingRepository.EXPECT().RecordUrgentDNDOverride(from, to, since, UrgentOverrideLimit).Return(true, nil).Times(1)
+		m := NewManager(repo)
+
+		urgent, err := m.ConsumeUrgentOverride(from, to, now)
+		assert.NoError(err)
+		assert.False(urgent)
+
+		assert.NoError(m.RequestUrgentOverride(from, to, now))
+		urgent, err = m.ConsumeUrgentOverride(from, to, now)
+		assert.NoError(err)
+		assert.True(urgent)
+		urgent, err = m.ConsumeUrgentOverride(from, to, now)
+		assert.NoError(err)
+		assert.False(urgent)
+
+		// 期限切れ
+		assert.NoError(m.RequestUrgentOverride(from, to, now))
+		urgent, err = m.ConsumeUrgentOverride(from, to, now.Add(urgentPassTTL))
+		assert.NoError(err)
+		assert.False(urgent)
+
+		// 取り消し
+		assert.NoError(m.RequestUrgentOverride(from, to, now))
+		m.CancelUrgentOverride(from, to)
+		urgent, err = m.ConsumeUrgentOverride(from, to, now)
+		assert.NoError(err)
+		assert.False(urgent)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		ctrl := gomock.NewController(t)
+		repo := &Repo{MockUserDNDSettingRepository: mock_repository.NewMockUserDNDSettingRepository(ctrl)}
+		repo.MockUserDNDSettingRepository.EXPECT().GetUserDNDSettings(gomock.Any()).Return(sleeping, nil).AnyTimes()
+		repo.MockUserDNDSettingRepository.EXPECT().GetUrgentDNDOverrideCount(from, to, since).Return(UrgentOverrideLimit, nil)
+		m := NewManager(repo)
+
+		assert.EqualError(m.RequestUrgentOverride(from, to, now), ErrRateLimited.Error())
+		urgent, err := m.ConsumeUrgentOverride(from, to, now)
+		assert.NoError(err)
+		assert.False(urgent)
+	})
+
+	t.Run("rate limited on consume", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+		ctrl := gomock.NewController(t)
+		repo := &Repo{MockUserDNDSettingRepository: mock_repository.NewMockUserDNDSettingRepository(ctrl)}
+		repo.MockUserDNDSettingRepository.EXPECT().GetUserDNDSettings(gomock.Any()).Return(sleeping, nil)
+		repo.MockUserDNDSettingRepository.EXPECT().GetUrgentDNDOverrideCount(from, to, since).Return(UrgentOverrideLimit-1, nil)
+		// 他のインスタンスで先に上限に達した
+		repo.MockUserDNDSettingRepository.EXPECT().RecordUrgentDNDOverride(from, to, since, UrgentOverrideLimit).Return(false, nil)
+		m := NewManager(repo)
+
+		assert.NoError(m.RequestUrgentOverride(from, to, now))
+		urgent, err := m.ConsumeUrgentOverride(from, to, now)
+		assert.NoError(err)
+		assert.False(urgent)
+	})
+}
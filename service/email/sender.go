@@ -0,0 +1,96 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// Message 送信するメール
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// UnsubscribeURL 配信停止URL (List-Unsubscribeヘッダーに使用)
+	UnsubscribeURL string
+}
+
+// Sender メール送信者
+type Sender interface {
+	// Send メールを送信します
+	Send(m *Message) error
+}
+
+// SMTPConfig SMTP設定
+type SMTPConfig struct {
+	// Host SMTPサーバーのホスト
+	Host string
+	// Port SMTPサーバーのポート
+	Port int
+	// Username 認証ユーザー名 (空の場合は認証しない)
+	Username string
+	// Password 認証パスワード
+	Password string
+	// From 送信元アドレス
+	From string
+}
+
+type smtpSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender SMTPでメールを送信するSenderを生成します
+//
+// サーバーがSTARTTLSに対応している場合は自動的に使用します。
+func NewSMTPSender(config SMTPConfig) Sender {
+	return &smtpSender{config: config}
+}
+
+// Send implements Sender interface.
+func (s *smtpSender) Send(m *Message) error {
+	var auth smtp.Auth
+	if len(s.config.Username) > 0 {
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+	}
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	return smtp.SendMail(addr, auth, s.config.From, []string{m.To}, buildMessage(s.config.From, m, time.Now()))
+}
+
+// buildMessage RFC 5322形式のメールを組み立てます
+func buildMessage(from string, m *Message, now time.Time) []byte {
+	var b bytes.Buffer
+	writeHeader := func(k, v string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+	}
+	writeHeader("From", from)
+	writeHeader("To", m.To)
+	writeHeader("Subject", mime.BEncoding.Encode("UTF-8", m.Subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", fmt.Sprintf("<%s@traq>", uuid.Must(uuid.NewV4())))
+	writeHeader("MIME-Version", "1.0")
+	writeHeader("Content-Type", "text/plain; charset=UTF-8")
+	writeHeader("Content-Transfer-Encoding", "base64")
+	if len(m.UnsubscribeURL) > 0 {
+		// RFC 8058 ワンクリック配信停止
+		writeHeader("List-Unsubscribe", "<"+m.UnsubscribeURL+">")
+		writeHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	b.WriteString("\r\n")
+
+	// base64は1行76文字以内で折り返す
+	encoded := base64.StdEncoding.EncodeToString([]byte(m.Body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,248 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// digestCheckInterval ダイジェストメールの送信判定を行う間隔
+	digestCheckInterval = 10 * time.Minute
+	// dailyDigestMinInterval 同じユーザーに日次ダイジェストを再送しない最小間隔
+	dailyDigestMinInterval = 20 * time.Hour
+	// weeklyDigestMinInterval 同じユーザーに週次ダイジェストを再送しない最小間隔
+	weeklyDigestMinInterval = 6 * 24 * time.Hour
+)
+
+// Config メール通知設定
+type Config struct {
+	// Secret 配信停止トークンの署名鍵
+	Secret []byte
+	// Origin traQのオリジン (リンク生成に使用)
+	Origin string
+	// DigestHour ダイジェストメールを送信する時刻 (0-23, ローカルタイム)
+	DigestHour int
+}
+
+// MessageNotification 即時通知するメッセージ
+type MessageNotification struct {
+	// Title 通知タイトル (`#チャンネルパス`または`@ユーザー名`)
+	Title string
+	// Body 通知本文
+	Body string
+	// Path クライアントのパス
+	Path string
+	// IsDM DMかどうか
+	IsDM bool
+}
+
+// Service メール通知サービス
+type Service struct {
+	repo   repository.Repository
+	cm     channel.Manager
+	oc     *counter.OnlineCounter
+	sender Sender
+	signer *tokenSigner
+	config Config
+	logger *zap.Logger
+}
+
+// NewService メール通知サービスを生成し、ダイジェストメールの送信を開始します
+func NewService(repo repository.Repository, cm channel.Manager, oc *counter.OnlineCounter, sender Sender, config Config, logger *zap.Logger) (*Service, error) {
+	if len(config.Secret) == 0 {
+		return nil, errors.New("email: secret is required")
+	}
+	if config.DigestHour < 0 || config.DigestHour > 23 {
+		return nil, fmt.Errorf("email: invalid digest hour: %d", config.DigestHour)
+	}
+	s := &Service{
+		repo:   repo,
+		cm:     cm,
+		oc:     oc,
+		sender: sender,
+		signer: &tokenSigner{secret: config.Secret},
+		config: config,
+		logger: logger.Named("email"),
+	}
+	go func() {
+		ticker := time.NewTicker(digestCheckInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			s.sendDigests(now)
+		}
+	}()
+	return s, nil
+}
+
+// NotifyMessage 即時通知が有効でオフラインのユーザーにメッセージの通知メールを送信します
+func (s *Service) NotifyMessage(targets set.UUID, n *MessageNotification) {
+	offline := set.UUID{}
+	for id := range targets {
+		if !s.oc.IsOnline(id) {
+			offline.Add(id)
+		}
+	}
+	if len(offline) == 0 {
+		return
+	}
+
+	settings, err := s.repo.GetImmediateEmailRecipients(offline)
+	if err != nil {
+		s.logger.Error("failed to GetImmediateEmailRecipients", zap.Error(err))
+		return
+	}
+
+	var subject string
+	if n.IsDM {
+		subject = fmt.Sprintf("[traQ] %sからのダイレクトメッセージ", n.Title)
+	} else {
+		subject = fmt.Sprintf("[traQ] %sでメンションされました", n.Title)
+	}
+	for _, setting := range settings {
+		unsubscribeURL := s.unsubscribeURL(setting.UserID, UnsubscribeImmediate)
+		body := fmt.Sprintf("%s\n%s\n\ntraQで開く: %s%s\n%s", n.Title, n.Body, s.config.Origin, n.Path, footer(unsubscribeURL))
+		s.send(&Message{To: setting.Address, Subject: subject, Body: body, UnsubscribeURL: unsubscribeURL})
+	}
+}
+
+// Unsubscribe 配信停止トークンを検証し、対応するメール通知を無効にします
+//
+// トークンが不正な場合、ErrInvalidTokenを返します。
+func (s *Service) Unsubscribe(token string) error {
+	userID, kind, err := s.signer.verify(token)
+	if err != nil {
+		return err
+	}
+
+	var args repository.UpdateUserEmailSettingArgs
+	switch kind {
+	case UnsubscribeImmediate:
+		args.Immediate = optional.BoolFrom(false)
+	case UnsubscribeDigest:
+		args.Digest.Valid = true
+		args.Digest.Digest = model.EmailDigestNone
+	}
+	return s.repo.UpdateUserEmailSetting(userID, args)
+}
+
+// sendDigests 送信時刻であればダイジェストメールを送信します
+func (s *Service) sendDigests(now time.Time) {
+	if now.Hour() != s.config.DigestHour {
+		return
+	}
+	s.sendDigest(model.EmailDigestDaily, now, now.Add(-dailyDigestMinInterval))
+	if now.Weekday() == time.Monday {
+		s.sendDigest(model.EmailDigestWeekly, now, now.Add(-weeklyDigestMinInterval))
+	}
+}
+
+func (s *Service) sendDigest(digest model.EmailDigest, now, sentBefore time.Time) {
+	settings, err := s.repo.GetDigestEmailRecipients(digest, sentBefore)
+	if err != nil {
+		s.logger.Error("failed to GetDigestEmailRecipients", zap.Error(err), zap.String("digest", string(digest)))
+		return
+	}
+
+	for _, setting := range settings {
+		if err := s.repo.SetEmailDigestSentAt(setting.UserID, now); err != nil {
+			s.logger.Error("failed to SetEmailDigestSentAt", zap.Error(err), zap.Stringer("userId", setting.UserID))
+			continue
+		}
+
+		lines, err := s.digestLines(setting.UserID)
+		if err != nil {
+			s.logger.Error("failed to build digest", zap.Error(err), zap.Stringer("userId", setting.UserID))
+			continue
+		}
+		if len(lines) == 0 {
+			continue // 未読無し
+		}
+
+		unsubscribeURL := s.unsubscribeURL(setting.UserID, UnsubscribeDigest)
+		body := fmt.Sprintf("未読メッセージがあります\n\n%s\n\ntraQを開く: %s\n%s", strings.Join(lines, "\n"), s.config.Origin, footer(unsubscribeURL))
+		s.send(&Message{To: setting.Address, Subject: "[traQ] 未読メッセージのお知らせ", Body: body, UnsubscribeURL: unsubscribeURL})
+	}
+}
+
+// digestLines 未読チャンネルごとのダイジェストの行を作成します
+func (s *Service) digestLines(userID uuid.UUID) ([]string, error) {
+	unreads, err := s.repo.GetUserUnreadChannels(userID)
+	if err != nil {
+		return nil, err
+	}
+	// 新しい順
+	sort.Slice(unreads, func(i, j int) bool { return unreads[i].UpdatedAt.After(unreads[j].UpdatedAt) })
+
+	tree := s.cm.PublicChannelTree()
+	var dmPartners map[uuid.UUID]uuid.UUID
+	lines := make([]string, 0, len(unreads))
+	for _, unread := range unreads {
+		var name string
+		if tree.IsChannelPresent(unread.ChannelID) {
+			name = "#" + tree.GetChannelPath(unread.ChannelID)
+		} else {
+			if dmPartners == nil {
+				dmPartners, err = s.getDMPartners(userID)
+				if err != nil {
+					return nil, err
+				}
+			}
+			name = "ダイレクトメッセージ"
+			if partner, ok := dmPartners[unread.ChannelID]; ok {
+				if u, err := s.repo.GetUser(partner, false); err == nil {
+					name = "@" + u.GetName()
+				}
+			}
+		}
+
+		line := fmt.Sprintf("%s: %d件", name, unread.Count)
+		if unread.Noticeable {
+			line += " (メンションあり)"
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// getDMPartners DMチャンネルIDと相手のユーザーIDのマップを取得します
+func (s *Service) getDMPartners(userID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	mappings, err := s.repo.GetDirectMessageChannelMapping(userID)
+	if err != nil {
+		return nil, err
+	}
+	partners := make(map[uuid.UUID]uuid.UUID, len(mappings))
+	for _, m := range mappings {
+		if m.User1 == userID {
+			partners[m.ChannelID] = m.User2
+		} else {
+			partners[m.ChannelID] = m.User1
+		}
+	}
+	return partners, nil
+}
+
+func (s *Service) unsubscribeURL(userID uuid.UUID, kind UnsubscribeKind) string {
+	return s.config.Origin + "/api/v3/email/unsubscribe?token=" + url.QueryEscape(s.signer.sign(userID, kind))
+}
+
+func (s *Service) send(m *Message) {
+	if err := s.sender.Send(m); err != nil {
+		s.logger.Warn("failed to send email", zap.Error(err))
+	}
+}
+
+func footer(unsubscribeURL string) string {
+	return "\n--\nこのメールはtraQのメール通知設定に基づいて送信されています。\n配信停止: " + unsubscribeURL
+}
@@ -0,0 +1,314 @@
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/service/channel/mock_channel"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Repo struct {
+	*mock_repository.MockUserEmailSettingRepository
+	*mock_repository.MockMessageRepository
+	*mock_repository.MockChannelRepository
+	*mock_repository.MockUserRepository
+	testutils.EmptyTestRepository
+}
+
+type recordingSender struct {
+	mu   sync.Mutex
+	sent []*Message
+}
+
+func (s *recordingSender) Send(m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func setup(t *testing.T) (*Service, *Repo, *mock_channel.MockManager, *counter.OnlineCounter, *recordingSender) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	repo := &Repo{
+		MockUserEmailSettingRepository: mock_repository.NewMockUserEmailSettingRepository(ctrl),
+		MockMessageRepository:          mock_repository.NewMockMessageRepository(ctrl),
+		MockChannelRepository:          mock_repository.NewMockChannelRepository(ctrl),
+		MockUserRepository:             mock_repository.NewMockUserRepository(ctrl),
+	}
+	cm := mock_channel.NewMockManager(ctrl)
+	oc := counter.NewOnlineCounter(hub.New())
+	sender := &recordingSender{}
+	s := &Service{
+		repo:   repo,
+		cm:     cm,
+		oc:     oc,
+		sender: sender,
+		signer: &tokenSigner{secret: []byte("secret")},
+		config: Config{Origin: "https://example.com", DigestHour: 9},
+		logger: zap.NewNop(),
+	}
+	return s, repo, cm, oc, sender
+}
+
+func TestService_NotifyMessage(t *testing.T) {
+	t.Parallel()
+	s, repo, _, oc, sender := setup(t)
+
+	online := uuid.NewV3(uuid.Nil, "online")
+	offline := uuid.NewV3(uuid.Nil, "offline")
+	oc.SetRemote("node", online, 1)
+
+	repo.MockUserEmailSettingRepository.EXPECT().
+		GetImmediateEmailRecipients(set.UUIDSetFromArray([]uuid.UUID{offline})).
+		Return([]*model.UserEmailSetting{{UserID: offline, Address: "offline@example.com", Immediate: true}}, nil).
+		Times(1)
+
+	s.NotifyMessage(set.UUIDSetFromArray([]uuid.UUID{online, offline}), &MessageNotification{
+		Title: "#general",
+		Body:  "user: @offline hello",
+		Path:  "/channels/general",
+	})
+
+	if assert.Len(t, sender.sent, 1) {
+		m := sender.sent[0]
+		assert.Equal(t, "offline@example.com", m.To)
+		assert.Equal(t, "[traQ] #generalでメンションされました", m.Subject)
+		assert.Contains(t, m.Body, "user: @offline hello")
+		assert.Contains(t, m.Body, "https://example.com/channels/general")
+		assert.Contains(t, m.Body, m.UnsubscribeURL)
+		assert.True(t, strings.HasPrefix(m.UnsubscribeURL, "https://example.com/api/v3/email/unsubscribe?token="))
+	}
+}
+
+func TestService_sendDigests(t *testing.T) {
+	t.Parallel()
+
+	t.Run("not digest hour", func(t *testing.T) {
+		t.Parallel()
+		s, _, _, _, sender := setup(t)
+		s.sendDigests(time.Date(2021, 1, 5, 10, 0, 0, 0, time.Local))
+		assert.Len(t, sender.sent, 0)
+	})
+
+	t.Run("daily", func(t *testing.T) {
+		t.Parallel()
+		s, repo, cm, _, sender := setup(t)
+		tree := mock_channel.NewMockTree(gomock.NewController(t))
+
+		now := time.Date(2021, 1, 5, 9, 0, 0, 0, time.Local) // 火曜日
+		user := uuid.NewV3(uuid.Nil, "user")
+		noUnread := uuid.NewV3(uuid.Nil, "no unread")
+		partner := &model.User{ID: uuid.NewV3(uuid.Nil, "partner"), Name: "partner"}
+		general := uuid.NewV3(uuid.Nil, "general")
+		dm := uuid.NewV3(uuid.Nil, "dm")
+
+		repo.MockUserEmailSettingRepository.EXPECT().
+			GetDigestEmailRecipients(model.EmailDigestDaily, now.Add(-dailyDigestMinInterval)).
+			Return([]*model.UserEmailSetting{
+				{UserID: user, Address: "user@example.com", Digest: model.EmailDigestDaily},
+				{UserID: noUnread, Address: "no-unread@example.com", Digest: model.EmailDigestDaily},
+			}, nil).
+			Times(1)
+		repo.MockUserEmailSettingRepository.EXPECT().SetEmailDigestSentAt(user, now).Return(nil).Times(1)
+		repo.MockUserEmailSettingRepository.EXPECT().SetEmailDigestSentAt(noUnread, now).Return(nil).Times(1)
+		repo.MockMessageRepository.EXPECT().
+			GetUserUnreadChannels(user).
+			Return([]*repository.UserUnreadChannel{
+				{ChannelID: general, Count: 3, Noticeable: true, UpdatedAt: now.Add(-2 * time.Hour)},
+				{ChannelID: dm, Count: 1, UpdatedAt: now.Add(-time.Hour)},
+			}, nil).
+			Times(1)
+		repo.MockMessageRepository.EXPECT().
+			GetUserUnreadChannels(noUnread).
+			Return([]*repository.UserUnreadChannel{}, nil).
+			Times(1)
+		repo.MockChannelRepository.EXPECT().
+			GetDirectMessageChannelMapping(user).
+			Return([]*model.DMChannelMapping{{ChannelID: dm, User1: partner.ID, User2: user}}, nil).
+			Times(1)
+		repo.MockUserRepository.EXPECT().GetUser(partner.ID, false).Return(partner, nil).Times(1)
+		cm.EXPECT().PublicChannelTree().Return(tree).AnyTimes()
+		tree.EXPECT().IsChannelPresent(general).Return(true).AnyTimes()
+		tree.EXPECT().IsChannelPresent(dm).Return(false).AnyTimes()
+		tree.EXPECT().GetChannelPath(general).Return("general").AnyTimes()
+
+		s.sendDigests(now)
+
+		if assert.Len(t, sender.sent, 1) {
+			m := sender.sent[0]
+			assert.Equal(t, "user@example.com", m.To)
+			assert.Equal(t, "[traQ] 未読メッセージのお知らせ", m.Subject)
+			assert.Contains(t, m.Body, "@partner: 1件\n#general: 3件 (メンションあり)")
+		}
+	})
+}
+
+func TestService_Unsubscribe(t *testing.T) {
+	t.Parallel()
+	s, repo, _, _, _ := setup(t)
+	user := uuid.NewV3(uuid.Nil, "user")
+
+	immediate := repository.UpdateUserEmailSettingArgs{Immediate: optional.BoolFrom(false)}
+	repo.MockUserEmailSettingRepository.EXPECT().UpdateUserEmailSetting(user, immediate).Return(nil).Times(1)
+	assert.NoError(t, s.Unsubscribe(s.signer.sign(user, UnsubscribeImmediate)))
+
+	var digest repository.UpdateUserEmailSettingArgs
+	digest.Digest.Valid = true
+	digest.Digest.Digest = model.EmailDigestNone
+	repo.MockUserEmailSettingRepository.EXPECT().UpdateUserEmailSetting(user, digest).Return(nil).Times(1)
+	assert.NoError(t, s.Unsubscribe(s.signer.sign(user, UnsubscribeDigest)))
+
+	assert.Equal(t, ErrInvalidToken, s.Unsubscribe("invalid"))
+}
+
+func TestTokenSigner(t *testing.T) {
+	t.Parallel()
+	signer := &tokenSigner{secret: []byte("secret")}
+	user := uuid.NewV3(uuid.Nil, "user")
+
+	token := signer.sign(user, UnsubscribeDigest)
+	id, kind, err := signer.verify(token)
+	if assert.NoError(t, err) {
+		assert.Equal(t, user, id)
+		assert.Equal(t, UnsubscribeDigest, kind)
+	}
+
+	// 別の鍵で署名されたトークン
+	_, _, err = (&tokenSigner{secret: []byte("other")}).verify(token)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	// ペイロードの改ざん
+	parts := strings.SplitN(token, ".", 2)
+	forged := base64.RawURLEncoding.EncodeToString([]byte(uuid.NewV3(uuid.Nil, "other").String()+":digest")) + "." + parts[1]
+	_, _, err = signer.verify(forged)
+	assert.Equal(t, ErrInvalidToken, err)
+
+	for _, token := range []string{"", "a", "a.b", token + "x"} {
+		_, _, err = signer.verify(token)
+		assert.Equal(t, ErrInvalidToken, err, token)
+	}
+}
+
+// smtpServer テスト用のSMTPサーバー
+type smtpServer struct {
+	l    net.Listener
+	from string
+	to   []string
+	data string
+	done chan struct{}
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpServer{l: l, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+func (s *smtpServer) serve() {
+	defer close(s.done)
+	conn, err := s.l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = line
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			s.to = append(s.to, line)
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 Go ahead")
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data = string(b)
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	t.Parallel()
+	server := newSMTPServer(t)
+	defer server.l.Close()
+
+	port := server.l.Addr().(*net.TCPAddr).Port
+	sender := NewSMTPSender(SMTPConfig{Host: "127.0.0.1", Port: port, From: "traq@example.com"})
+	err := sender.Send(&Message{
+		To:             "user@example.com",
+		Subject:        "[traQ] 未読メッセージのお知らせ",
+		Body:           "未読メッセージがあります",
+		UnsubscribeURL: "https://example.com/api/v3/email/unsubscribe?token=abc",
+	})
+	require.NoError(t, err)
+	<-server.done
+
+	assert.Equal(t, "MAIL FROM:<traq@example.com>", strings.SplitN(server.from, " BODY", 2)[0])
+	assert.Equal(t, []string{"RCPT TO:<user@example.com>"}, server.to)
+
+	m, err := mail.ReadMessage(strings.NewReader(server.data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "[traQ] 未読メッセージのお知らせ", subject)
+	assert.Equal(t, "<https://example.com/api/v3/email/unsubscribe?token=abc>", m.Header.Get("List-Unsubscribe"))
+	assert.Equal(t, "List-Unsubscribe=One-Click", m.Header.Get("List-Unsubscribe-Post"))
+
+	body, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(readAll(t, m), "\n", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "未読メッセージがあります", string(body))
+}
+
+func readAll(t *testing.T, m *mail.Message) string {
+	t.Helper()
+	var b strings.Builder
+	sc := bufio.NewScanner(m.Body)
+	for sc.Scan() {
+		b.WriteString(sc.Text())
+		b.WriteString("\n")
+	}
+	require.NoError(t, sc.Err())
+	return b.String()
+}
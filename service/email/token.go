@@ -0,0 +1,72 @@
+package email
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/gofrs/uuid"
+	"strings"
+)
+
+// UnsubscribeKind 配信停止の対象
+type UnsubscribeKind string
+
+const (
+	// UnsubscribeImmediate 即時通知メールの配信停止
+	UnsubscribeImmediate UnsubscribeKind = "immediate"
+	// UnsubscribeDigest ダイジェストメールの配信停止
+	UnsubscribeDigest UnsubscribeKind = "digest"
+)
+
+// ErrInvalidToken 配信停止トークンが不正です
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// tokenSigner 配信停止トークンの署名・検証器
+//
+// トークンは`base64url(<ユーザーID>:<種類>).base64url(HMAC-SHA256)`の形式です。
+// ログインせずに配信停止できるように、有効期限は設けていません。
+type tokenSigner struct {
+	secret []byte
+}
+
+func (s *tokenSigner) sign(userID uuid.UUID, kind UnsubscribeKind) string {
+	payload := []byte(userID.String() + ":" + string(kind))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.mac(payload))
+}
+
+func (s *tokenSigner) verify(token string) (uuid.UUID, UnsubscribeKind, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, s.mac(payload)) {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+
+	fields := strings.SplitN(string(payload), ":", 2)
+	if len(fields) != 2 {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	userID, err := uuid.FromString(fields[0])
+	if err != nil {
+		return uuid.Nil, "", ErrInvalidToken
+	}
+	switch kind := UnsubscribeKind(fields[1]); kind {
+	case UnsubscribeImmediate, UnsubscribeDigest:
+		return userID, kind, nil
+	default:
+		return uuid.Nil, "", ErrInvalidToken
+	}
+}
+
+func (s *tokenSigner) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
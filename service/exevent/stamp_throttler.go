@@ -39,6 +39,10 @@ func (st *StampThrottler) loop() {
 	for {
 		select {
 		case msg := <-sub.Receiver:
+			if event.IsRemote(msg) {
+				// 他のインスタンスで処理済み
+				continue
+			}
 			mid := msg.Fields["message_id"].(uuid.UUID)
 			st.mLock.Lock()
 			ent, ok := st.m[mid]
@@ -0,0 +1,24 @@
+package fcm
+
+import "github.com/traPtitech/traQ/utils/set"
+
+type multiClient struct {
+	clients []Client
+}
+
+// NewMultiClient 複数のクライアントに同じペイロードを送信するクライアントを返します
+func NewMultiClient(clients ...Client) Client {
+	return &multiClient{clients: clients}
+}
+
+func (m *multiClient) Send(targetUserIDs set.UUID, payload *Payload, withUnreadCount bool) {
+	for _, c := range m.clients {
+		c.Send(targetUserIDs, payload, withUnreadCount)
+	}
+}
+
+func (m *multiClient) Close() {
+	for _, c := range m.clients {
+		c.Close()
+	}
+}
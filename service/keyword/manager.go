@@ -0,0 +1,238 @@
+// Package keyword キーワード通知
+package keyword
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/utils/ahocorasick"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// MaxAlertsPerUser 1ユーザーあたりのキーワード通知の最大数
+	MaxAlertsPerUser = 50
+	// MaxRegexAlertsPerUser 1ユーザーあたりの正規表現のキーワード通知の最大数
+	MaxRegexAlertsPerUser = 10
+	// MaxRegexLengthPerUser 1ユーザーあたりの正規表現のパターンの合計の最大バイト数
+	MaxRegexLengthPerUser = 500
+)
+
+var (
+	// ErrInvalidPattern 不正なパターンです
+	ErrInvalidPattern = errors.New("invalid pattern")
+	// ErrRegexLimitExceeded 正規表現のキーワード通知の上限を超えています
+	ErrRegexLimitExceeded = errors.New("regex keyword alert limit exceeded")
+)
+
+// Manager キーワード通知マネージャー
+//
+// 全ユーザーのキーワード通知をメモリ上に保持し、メッセージとのマッチングを行います。
+// 通常のキーワードはAho-Corasick法でまとめてマッチングするため、ルール数が増えても本文の長さに比例した時間で判定できます。
+// 正規表現のキーワードはユーザーごとに数と長さを制限した上で、通知対象ごとに1つの正規表現にまとめてマッチングします。
+type Manager struct {
+	repo    repository.KeywordAlertRepository
+	logger  *zap.Logger
+	rules   atomic.Value // *ruleSet
+	reloadC chan struct{}
+}
+
+type target struct {
+	userID    uuid.UUID
+	channelID uuid.UUID // uuid.Nilの場合は全ての公開チャンネル
+}
+
+type regexRule struct {
+	re *regexp.Regexp
+	target
+}
+
+// ruleSet ある時点での全キーワード通知のスナップショット (読み取り専用)
+type ruleSet struct {
+	literals *ahocorasick.Matcher
+	// literalTargets literalsのパターンのインデックスに対応する通知対象
+	literalTargets [][]target
+	// regexes 通知対象ごとに正規表現のパターンをまとめたもの
+	regexes []regexRule
+}
+
+// NewManager キーワード通知マネージャーを生成します
+func NewManager(repo repository.Repository, hub *hub.Hub, logger *zap.Logger) (*Manager, error) {
+	m := &Manager{
+		repo:    repo,
+		logger:  logger.Named("keyword"),
+		reloadC: make(chan struct{}, 1),
+	}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+
+	sub := hub.Subscribe(10, event.KeywordAlertCreated, event.KeywordAlertDeleted)
+	go func() {
+		for range sub.Receiver {
+			select {
+			case m.reloadC <- struct{}{}:
+			default: // 再構築待ち
+			}
+		}
+	}()
+	go func() {
+		for range m.reloadC {
+			if err := m.reload(); err != nil {
+				m.logger.Error("failed to reload keyword alerts", zap.Error(err))
+			}
+		}
+	}()
+	return m, nil
+}
+
+// Match 指定したチャンネルに投稿されたテキストにマッチするキーワード通知を持つユーザーを返します
+//
+// 大文字・小文字は区別しません。
+func (m *Manager) Match(text string, channelID uuid.UUID) set.UUID {
+	rs := m.rules.Load().(*ruleSet)
+	result := set.UUID{}
+	add := func(t target) {
+		if t.channelID == uuid.Nil || t.channelID == channelID {
+			result.Add(t.userID)
+		}
+	}
+
+	for _, idx := range rs.literals.Match(strings.ToLower(text)) {
+		for _, t := range rs.literalTargets[idx] {
+			add(t)
+		}
+	}
+	for _, r := range rs.regexes {
+		if result.Contains(r.userID) {
+			continue
+		}
+		if (r.channelID == uuid.Nil || r.channelID == channelID) && r.re.MatchString(text) {
+			result.Add(r.userID)
+		}
+	}
+	return result
+}
+
+// ValidatePattern キーワード通知のパターンとして使用可能かどうかを検証します
+//
+// 使用できない場合、ErrInvalidPatternをラップしたエラーを返します。
+func ValidatePattern(keyword string, isRegex bool) error {
+	if len(strings.TrimSpace(keyword)) == 0 {
+		return fmt.Errorf("%w: empty keyword", ErrInvalidPattern)
+	}
+	if isRegex {
+		if _, err := compile(keyword); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+		}
+	}
+	return nil
+}
+
+// CheckRegexLimit ユーザーの既存のキーワード通知に正規表現のパターンを追加できるかどうかを検証します
+//
+// 追加できない場合、ErrRegexLimitExceededをラップしたエラーを返します。
+func CheckRegexLimit(alerts []*model.KeywordAlert, pattern string) error {
+	count, length := 1, len(pattern)
+	for _, a := range alerts {
+		if a.IsRegex {
+			count++
+			length += len(a.Keyword)
+		}
+	}
+	if count > MaxRegexAlertsPerUser {
+		return fmt.Errorf("%w: you cannot register more than %d regex keyword alerts", ErrRegexLimitExceeded, MaxRegexAlertsPerUser)
+	}
+	if length > MaxRegexLengthPerUser {
+		return fmt.Errorf("%w: total length of regex patterns must be at most %d bytes", ErrRegexLimitExceeded, MaxRegexLengthPerUser)
+	}
+	return nil
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// compileAll 複数のパターンのいずれかにマッチする1つの正規表現をコンパイルします
+func compileAll(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 1 {
+		return compile(patterns[0])
+	}
+	groups := make([]string, len(patterns))
+	for i, p := range patterns {
+		groups[i] = "(?:" + p + ")"
+	}
+	return compile(strings.Join(groups, "|"))
+}
+
+func (m *Manager) reload() error {
+	alerts, err := m.repo.GetAllKeywordAlerts()
+	if err != nil {
+		return err
+	}
+	m.rules.Store(m.build(alerts))
+	return nil
+}
+
+func (m *Manager) build(alerts []*model.KeywordAlert) *ruleSet {
+	rs := &ruleSet{}
+	patterns := make([]string, 0, len(alerts))
+	index := map[string]int{}
+	regexTargets := make([]target, 0)
+	regexPatterns := map[target][]string{}
+	userRegexes := map[uuid.UUID][]*model.KeywordAlert{}
+	for _, a := range alerts {
+		t := target{userID: a.UserID}
+		if a.ChannelID.Valid {
+			t.channelID = a.ChannelID.UUID
+		}
+
+		if a.IsRegex {
+			if _, err := compile(a.Keyword); err != nil {
+				m.logger.Warn("invalid keyword alert pattern", zap.Error(err), zap.Stringer("keywordAlertId", a.ID))
+				continue
+			}
+			// 上限の導入前に登録されたものなど、上限を超える分は無視する
+			if err := CheckRegexLimit(userRegexes[a.UserID], a.Keyword); err != nil {
+				m.logger.Warn("ignored keyword alert pattern", zap.Error(err), zap.Stringer("keywordAlertId", a.ID))
+				continue
+			}
+			userRegexes[a.UserID] = append(userRegexes[a.UserID], a)
+			if _, ok := regexPatterns[t]; !ok {
+				regexTargets = append(regexTargets, t)
+			}
+			regexPatterns[t] = append(regexPatterns[t], a.Keyword)
+			continue
+		}
+
+		// 同じキーワードは1つのパターンにまとめる
+		p := strings.ToLower(a.Keyword)
+		i, ok := index[p]
+		if !ok {
+			i = len(patterns)
+			index[p] = i
+			patterns = append(patterns, p)
+			rs.literalTargets = append(rs.literalTargets, nil)
+		}
+		rs.literalTargets[i] = append(rs.literalTargets[i], t)
+	}
+	rs.literals = ahocorasick.New(patterns)
+
+	for _, t := range regexTargets {
+		re, err := compileAll(regexPatterns[t])
+		if err != nil {
+			m.logger.Warn("failed to combine keyword alert patterns", zap.Error(err), zap.Stringer("userId", t.userID))
+			continue
+		}
+		rs.regexes = append(rs.regexes, regexRule{re: re, target: t})
+	}
+	return rs
+}
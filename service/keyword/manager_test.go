@@ -0,0 +1,170 @@
+package keyword
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/optional"
+	"go.uber.org/zap"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Repo struct {
+	*mock_repository.MockKeywordAlertRepository
+	testutils.EmptyTestRepository
+}
+
+func alert(userID uuid.UUID, keyword string, isRegex bool, channelID uuid.UUID) *model.KeywordAlert {
+	a := &model.KeywordAlert{
+		ID:      uuid.Must(uuid.NewV4()),
+		UserID:  userID,
+		Keyword: keyword,
+		IsRegex: isRegex,
+	}
+	if channelID != uuid.Nil {
+		a.ChannelID = optional.UUIDFrom(channelID)
+	}
+	return a
+}
+
+func TestManager_Match(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+	u3 := uuid.Must(uuid.NewV4())
+	ch1 := uuid.Must(uuid.NewV4())
+	ch2 := uuid.Must(uuid.NewV4())
+
+	ctrl := gomock.NewController(t)
+	repo := &Repo{MockKeywordAlertRepository: mock_repository.NewMockKeywordAlertRepository(ctrl)}
+	repo.MockKeywordAlertRepository.EXPECT().GetAllKeywordAlerts().Return([]*model.KeywordAlert{
+		alert(u1, "障害", false, uuid.Nil),
+		alert(u1, "Deploy", false, uuid.Nil),
+		alert(u2, "deploy", false, ch1),
+		alert(u3, `v\d+\.\d+`, true, uuid.Nil),
+		alert(u3, "(", true, uuid.Nil), // 不正なパターンは無視される
+	}, nil)
+	m, err := NewManager(repo, hub.New(), zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Len(m.Match("平和です", ch1), 0)
+	assert.ElementsMatch([]uuid.UUID{u1}, m.Match("本番で障害発生", ch1).Array())
+	assert.ElementsMatch([]uuid.UUID{u1, u2}, m.Match("DEPLOY しました", ch1).Array())
+	assert.ElementsMatch([]uuid.UUID{u1}, m.Match("deploy しました", ch2).Array())
+	assert.ElementsMatch([]uuid.UUID{u1, u3}, m.Match("V1.2 を deploy", ch2).Array())
+}
+
+func TestManager_Reload(t *testing.T) {
+	t.Parallel()
+
+	u1 := uuid.Must(uuid.NewV4())
+	ctrl := gomock.NewController(t)
+	repo := &Repo{MockKeywordAlertRepository: mock_repository.NewMockKeywordAlertRepository(ctrl)}
+	gomock.InOrder(
+		repo.MockKeywordAlertRepository.EXPECT().GetAllKeywordAlerts().Return([]*model.KeywordAlert{}, nil),
+		repo.MockKeywordAlertRepository.EXPECT().GetAllKeywordAlerts().Return([]*model.KeywordAlert{alert(u1, "traQ", false, uuid.Nil)}, nil).MinTimes(1),
+	)
+	h := hub.New()
+	m, err := NewManager(repo, h, zap.NewNop())
+	require.NoError(t, err)
+	assert.Len(t, m.Match("traQ", uuid.Nil), 0)
+
+	h.Publish(hub.Message{Name: event.KeywordAlertCreated})
+	assert.Eventually(t, func() bool {
+		return m.Match("traq", uuid.Nil).Contains(u1)
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestManager_RegexLimit(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+	ch := uuid.Must(uuid.NewV4())
+	alerts := make([]*model.KeywordAlert, 0)
+	for i := 0; i < MaxRegexAlertsPerUser+1; i++ {
+		// 上限を超える最後のパターンは無視される
+		alerts = append(alerts, alert(u1, fmt.Sprintf(`^r%d\b`, i), true, uuid.Nil))
+	}
+	alerts = append(alerts, alert(u2, `^a+$`, true, uuid.Nil), alert(u2, `b\d`, true, ch))
+
+	m := &Manager{logger: zap.NewNop()}
+	rs := m.build(alerts)
+	assert.Len(rs.regexes, 3) // 通知対象ごとにまとめられる
+	m.rules.Store(rs)
+
+	assert.True(m.Match("r0 です", uuid.Nil).Contains(u1))
+	assert.True(m.Match(fmt.Sprintf("R%d です", MaxRegexAlertsPerUser-1), uuid.Nil).Contains(u1))
+	assert.False(m.Match(fmt.Sprintf("r%d です", MaxRegexAlertsPerUser), uuid.Nil).Contains(u1))
+	assert.True(m.Match("AAA", uuid.Nil).Contains(u2))
+	assert.False(m.Match("xaaa", uuid.Nil).Contains(u2))
+	assert.False(m.Match("b1", uuid.Nil).Contains(u2))
+	assert.True(m.Match("b1", ch).Contains(u2))
+}
+
+func TestCheckRegexLimit(t *testing.T) {
+	t.Parallel()
+
+	uid := uuid.Must(uuid.NewV4())
+	alerts := []*model.KeywordAlert{alert(uid, "literal", false, uuid.Nil)}
+	for i := 0; i < MaxRegexAlertsPerUser-1; i++ {
+		alerts = append(alerts, alert(uid, "a+", true, uuid.Nil))
+	}
+	assert.NoError(t, CheckRegexLimit(alerts, "b+"))
+	assert.True(t, errors.Is(CheckRegexLimit(append(alerts, alert(uid, "c+", true, uuid.Nil)), "b+"), ErrRegexLimitExceeded))
+	assert.True(t, errors.Is(CheckRegexLimit(nil, strings.Repeat("a", MaxRegexLengthPerUser+1)), ErrRegexLimitExceeded))
+}
+
+func TestValidatePattern(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidatePattern("traQ", false))
+	assert.NoError(t, ValidatePattern("(", false))
+	assert.NoError(t, ValidatePattern(`^v\d+$`, true))
+	assert.True(t, errors.Is(ValidatePattern("(", true), ErrInvalidPattern))
+	assert.True(t, errors.Is(ValidatePattern("  ", false), ErrInvalidPattern))
+}
+
+func BenchmarkManager_Match(b *testing.B) {
+	alerts := make([]*model.KeywordAlert, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		alerts = append(alerts, alert(uuid.Must(uuid.NewV4()), fmt.Sprintf("keyword%d", i), false, uuid.Nil))
+	}
+	m := &Manager{logger: zap.NewNop()}
+	m.rules.Store(m.build(alerts))
+	text := "今日の keyword4999 と keyword12 の進捗について共有します。"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(text, uuid.Nil)
+	}
+}
+
+func BenchmarkManager_MatchRegex(b *testing.B) {
+	alerts := make([]*model.KeywordAlert, 0, 500*MaxRegexAlertsPerUser)
+	for i := 0; i < 500; i++ {
+		uid := uuid.Must(uuid.NewV4())
+		for j := 0; j < MaxRegexAlertsPerUser; j++ {
+			alerts = append(alerts, alert(uid, fmt.Sprintf(`keyword%d-%d\b`, i, j), true, uuid.Nil))
+		}
+	}
+	m := &Manager{logger: zap.NewNop()}
+	m.rules.Store(m.build(alerts))
+	text := "今日の keyword499-9 と keyword12-0 の進捗について共有します。"
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(text, uuid.Nil)
+	}
+}
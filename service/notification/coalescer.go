@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/traPtitech/traQ/service/dnd"
+	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	// coalesceWindow チャンネル購読によるプッシュ通知をまとめる期間
+	coalesceWindow = 30 * time.Second
+	// coalesceFlushInterval まとめたプッシュ通知の送信判定を行う間隔
+	coalesceFlushInterval = time.Second
+)
+
+var pushCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "traq",
+	Name:      "notification_push_count_total",
+}, []string{"result"})
+
+// coalescer チャンネル購読によるプッシュ通知をユーザー・チャンネルごとにまとめます
+//
+// 期間外の最初の通知は即座に送信し、それからcoalesceWindowの間の通知は抑制します。
+// 期間の終了時に抑制した通知があれば「N件の新着メッセージ」として1つにまとめて送信し、新たな期間を開始します。
+// まとめた通知は、送信時におやすみモード中のユーザーには送信しません。
+type coalescer struct {
+	fcm     fcm.Client
+	dnd     *dnd.Manager
+	logger  *zap.Logger
+	windows map[coalesceKey]*coalesceWindowState
+	mu      sync.Mutex
+	stop    chan struct{}
+}
+
+type coalesceKey struct {
+	userID    uuid.UUID
+	channelID uuid.UUID
+}
+
+type coalesceWindowState struct {
+	expires time.Time
+	// suppressed 期間中に抑制した通知の数
+	suppressed int
+	// last 期間中に抑制した最後の通知
+	last *fcm.Payload
+}
+
+func newCoalescer(client fcm.Client, dnd *dnd.Manager, logger *zap.Logger) *coalescer {
+	c := &coalescer{
+		fcm:     client,
+		dnd:     dnd,
+		logger:  logger,
+		windows: map[coalesceKey]*coalesceWindowState{},
+		stop:    make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+func (c *coalescer) run() {
+	ticker := time.NewTicker(coalesceFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.flush(now)
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// close まとめたプッシュ通知の送信を停止します
+func (c *coalescer) close() {
+	close(c.stop)
+}
+
+// Coalesce 指定したチャンネルのメッセージのプッシュ通知をまとめながら送信します
+func (c *coalescer) Coalesce(targets set.UUID, channelID uuid.UUID, payload *fcm.Payload, now time.Time) {
+	immediate := set.UUID{}
+	c.mu.Lock()
+	for id := range targets {
+		k := coalesceKey{userID: id, channelID: channelID}
+		if w, ok := c.windows[k]; ok {
+			w.suppressed++
+			w.last = payload
+			continue
+		}
+		c.windows[k] = &coalesceWindowState{expires: now.Add(coalesceWindow)}
+		immediate.Add(id)
+	}
+	c.mu.Unlock()
+
+	pushCounter.WithLabelValues("suppressed").Add(float64(len(targets) - len(immediate)))
+	c.SendNow(immediate, payload)
+}
+
+// Discard 指定したユーザー・チャンネルの抑制中の通知を破棄します
+func (c *coalescer) Discard(userID, channelID uuid.UUID) {
+	c.mu.Lock()
+	delete(c.windows, coalesceKey{userID: userID, channelID: channelID})
+	c.mu.Unlock()
+}
+
+// flush 終了した期間の抑制した通知をまとめて送信します
+func (c *coalescer) flush(now time.Time) {
+	type summaryKey struct {
+		last       *fcm.Payload
+		suppressed int
+	}
+	summaries := map[summaryKey]set.UUID{}
+
+	c.mu.Lock()
+	for k, w := range c.windows {
+		if now.Before(w.expires) {
+			continue
+		}
+		if w.suppressed == 0 {
+			delete(c.windows, k)
+			continue
+		}
+		sk := summaryKey{last: w.last, suppressed: w.suppressed}
+		if summaries[sk] == nil {
+			summaries[sk] = set.UUID{}
+		}
+		summaries[sk].Add(k.userID)
+		c.windows[k] = &coalesceWindowState{expires: now.Add(coalesceWindow)}
+	}
+	c.mu.Unlock()
+	if len(summaries) == 0 {
+		return
+	}
+
+	// 期間中におやすみモードになったユーザーには送信しない
+	targets := set.UUID{}
+	for _, users := range summaries {
+		targets.Plus(users)
+	}
+	sleeping, err := c.dnd.FilterActive(targets, now)
+	if err != nil {
+		c.logger.Error("failed to FilterActive", zap.Error(err)) // 失敗
+		sleeping = set.UUID{}
+	}
+
+	for sk, users := range summaries {
+		users.Remove(sleeping.Array()...)
+		p := *sk.last
+		p.Body = fmt.Sprintf("%d件の新着メッセージ", sk.suppressed)
+		p.Image.Valid = false
+		c.SendNow(users, &p)
+	}
+}
+
+// SendNow プッシュ通知をまとめずに即座に送信します
+func (c *coalescer) SendNow(targets set.UUID, payload *fcm.Payload) {
+	if len(targets) == 0 {
+		return
+	}
+	pushCounter.WithLabelValues("sent").Add(float64(len(targets)))
+	c.fcm.Send(targets, payload, true)
+}
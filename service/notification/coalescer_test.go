@@ -0,0 +1,161 @@
+package notification
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/service/dnd"
+	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/optional"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+type dndRepo struct {
+	*mock_repository.MockUserDNDSettingRepository
+	testutils.EmptyTestRepository
+}
+
+// newTestCoalescer 指定したユーザーがおやすみモード中のcoalescerを生成します (送信判定のgoroutineは起動しません)
+func newTestCoalescer(t *testing.T, client fcm.Client, sleeping ...uuid.UUID) *coalescer {
+	t.Helper()
+	settings := make([]*model.UserDNDSetting, 0, len(sleeping))
+	for _, id := range sleeping {
+		settings = append(settings, &model.UserDNDSetting{UserID: id, SnoozeUntil: optional.TimeFrom(time.Now().Add(24 * time.Hour))})
+	}
+	repo := &dndRepo{MockUserDNDSettingRepository: mock_repository.NewMockUserDNDSettingRepository(gomock.NewController(t))}
+	repo.MockUserDNDSettingRepository.EXPECT().GetUserDNDSettings(gomock.Any()).Return(settings, nil).AnyTimes()
+	return &coalescer{
+		fcm:     client,
+		dnd:     dnd.NewManager(repo),
+		logger:  zap.NewNop(),
+		windows: map[coalesceKey]*coalesceWindowState{},
+		stop:    make(chan struct{}),
+	}
+}
+
+type sentPush struct {
+	targets []uuid.UUID
+	payload fcm.Payload
+}
+
+type fakeFCMClient struct {
+	sent []sentPush
+	mu   sync.Mutex
+}
+
+func (f *fakeFCMClient) Send(targetUserIDs set.UUID, payload *fcm.Payload, _ bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, sentPush{targets: targetUserIDs.Array(), payload: *payload})
+}
+
+func (f *fakeFCMClient) Close() {}
+
+func (f *fakeFCMClient) take() []sentPush {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sent := f.sent
+	f.sent = nil
+	return sent
+}
+
+func TestCoalescer(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+	ch := uuid.Must(uuid.NewV4())
+	client := &fakeFCMClient{}
+	c := newTestCoalescer(t, client)
+	now := time.Now()
+	payload := func(body string) *fcm.Payload {
+		return &fcm.Payload{Title: "#general", Body: body, Tag: "c:" + ch.String()}
+	}
+
+	// 最初の通知は即座に送信
+	c.Coalesce(set.UUIDSetFromArray([]uuid.UUID{u1}), ch, payload("1"), now)
+	if sent := client.take(); assert.Len(sent, 1) {
+		assert.Equal("1", sent[0].payload.Body)
+	}
+
+	// 期間中の通知は抑制される
+	for i := 0; i < 5; i++ {
+		c.Coalesce(set.UUIDSetFromArray([]uuid.UUID{u1, u2}), ch, payload("burst"), now.Add(time.Second))
+	}
+	if sent := client.take(); assert.Len(sent, 1) {
+		assert.Equal([]uuid.UUID{u2}, sent[0].targets)
+	}
+
+	// 期間中はまとめて送信しない
+	c.flush(now.Add(coalesceWindow - time.Second))
+	assert.Len(client.take(), 0)
+
+	// 期間終了時にまとめて送信
+	c.flush(now.Add(coalesceWindow))
+	if sent := client.take(); assert.Len(sent, 1) {
+		assert.Equal([]uuid.UUID{u1}, sent[0].targets)
+		assert.Equal("#general", sent[0].payload.Title)
+		assert.Equal("5件の新着メッセージ", sent[0].payload.Body)
+	}
+
+	// まとめて送信した後も新たな期間が始まっている
+	c.Coalesce(set.UUIDSetFromArray([]uuid.UUID{u1}), ch, payload("after"), now.Add(coalesceWindow+time.Second))
+	assert.Len(client.take(), 0)
+
+	// 既読にすると破棄される
+	c.Discard(u1, ch)
+	c.Discard(u2, ch)
+	c.flush(now.Add(3 * coalesceWindow))
+	assert.Len(client.take(), 0)
+	c.Coalesce(set.UUIDSetFromArray([]uuid.UUID{u1}), ch, payload("new"), now.Add(3*coalesceWindow))
+	assert.Len(client.take(), 1)
+}
+
+func TestCoalescer_DND(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+	ch := uuid.Must(uuid.NewV4())
+	client := &fakeFCMClient{}
+	// u2は期間中におやすみモードになった
+	c := newTestCoalescer(t, client, u2)
+	now := time.Now()
+	payload := &fcm.Payload{Title: "#general", Body: "body", Tag: "c:" + ch.String()}
+
+	c.Coalesce(set.UUIDSetFromArray([]uuid.UUID{u1, u2}), ch, payload, now)
+	c.Coalesce(set.UUIDSetFromArray([]uuid.UUID{u1, u2}), ch, payload, now.Add(time.Second))
+	client.take()
+
+	c.flush(now.Add(coalesceWindow))
+	if sent := client.take(); assert.Len(sent, 1) {
+		assert.Equal([]uuid.UUID{u1}, sent[0].targets)
+	}
+}
+
+func TestCoalescer_Close(t *testing.T) {
+	t.Parallel()
+
+	c := newTestCoalescer(t, &fakeFCMClient{})
+	done := make(chan struct{})
+	go func() {
+		c.run()
+		close(done)
+	}()
+	c.close()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		assert.Fail(t, "coalescer did not stop")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/model"
 	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/sse"
 	"github.com/traPtitech/traQ/service/viewer"
@@ -30,6 +31,7 @@ var handlerMap = map[string]eventHandler{
 	event.MessageUnpinned:           messageUnpinnedHandler,
 	event.MessageStamped:            messageStampedHandler,
 	event.MessageUnstamped:          messageUnstampedHandler,
+	event.MessageCited:              messageCitedHandler,
 	event.ChannelCreated:            channelCreatedHandler,
 	event.ChannelUpdated:            channelUpdatedHandler,
 	event.ChannelDeleted:            channelDeletedHandler,
@@ -57,17 +59,20 @@ var handlerMap = map[string]eventHandler{
 	event.StampPaletteUpdated:       stampPaletteUpdatedHandler,
 	event.StampPaletteDeleted:       stampPaletteDeletedHandler,
 	event.UserWebRTCv3StateChanged:  userWebRTCv3StateChangedHandler,
+	event.UserTyping:                userTypingHandler,
 	event.ClipFolderCreated:         clipFolderCreatedHandler,
 	event.ClipFolderUpdated:         clipFolderUpdatedHandler,
 	event.ClipFolderDeleted:         clipFolderDeletedHandler,
 	event.ClipFolderMessageDeleted:  clipFolderMessageDeletedHandler,
 	event.ClipFolderMessageAdded:    clipFolderMessageAddedHandler,
+	event.NotificationCreated:       notificationCreatedHandler,
 }
 
 func messageCreatedHandler(ns *Service, ev hub.Message) {
 	m := ev.Fields["message"].(*model.Message)
 	parsed := ev.Fields["parse_result"].(*message.ParseResult)
 	logger := ns.logger.With(zap.Stringer("messageId", m.ID))
+	remote := event.IsRemote(ev) // 他のインスタンスで未読追加・FCM送信済み
 
 	chTree := ns.cm.PublicChannelTree()
 	chID := m.ChannelID
@@ -97,6 +102,13 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 	notifiedUsers := set.UUID{} // チャンネル通知購読ユーザー
 	markedUsers := set.UUID{}   // チャンネル未読管理ユーザー
 	noticeable := set.UUID{}    // noticeableな未読追加対象のユーザー
+	emailTargets := set.UUID{}  // メール通知対象のユーザー (DM・メンション・キーワード)
+	inbox := map[model.NotificationType]set.UUID{
+		model.NotificationTypeDM:           {},
+		model.NotificationTypeMention:      {},
+		model.NotificationTypeGroupMention: {},
+		model.NotificationTypeKeyword:      {},
+	} // 通知受信箱への追加対象のユーザー
 
 	// メッセージボディ作成
 	if !isDM {
@@ -139,6 +151,8 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 		}
 		notifiedUsers.Add(users...)
 		markedUsers.Add(users...)
+		emailTargets.Add(users...)
+		inbox[model.NotificationTypeDM].Add(users...)
 
 	default: // 通常チャンネルメッセージ
 		// チャンネル通知購読者取得
@@ -171,6 +185,10 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 			notifiedUsers.Add(uid)
 			markedUsers.Add(uid)
 			noticeable.Add(uid)
+			emailTargets.Add(uid)
+			if !user.IsBot() {
+				inbox[model.NotificationTypeMention].Add(uid)
+			}
 		}
 		for _, gid := range parsed.GroupMentions {
 			gs, err := ns.repo.GetUserIDs(q.GMemberOf(gid))
@@ -181,6 +199,29 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 			notifiedUsers.Add(gs...)
 			markedUsers.Add(gs...)
 			noticeable.Add(gs...)
+			emailTargets.Add(gs...)
+			inbox[model.NotificationTypeGroupMention].Add(gs...)
+		}
+
+		// キーワード通知対象ユーザー取得
+		for uid := range ns.km.Match(parsed.PlainText, chID) {
+			if uid == m.UserID {
+				continue
+			}
+			user, err := ns.repo.GetUser(uid, false)
+			if err != nil {
+				logger.Error("failed to GetUser", zap.Error(err), zap.Stringer("userId", uid)) // 失敗
+				continue
+			}
+			// 凍結ユーザーの除外
+			if !user.IsActive() {
+				continue
+			}
+			notifiedUsers.Add(uid)
+			markedUsers.Add(uid)
+			noticeable.Add(uid)
+			emailTargets.Add(uid)
+			inbox[model.NotificationTypeKeyword].Add(uid)
 		}
 	}
 
@@ -194,10 +235,24 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 
 	// 未読追加
 	markedUsers.Remove(m.UserID)
-	for id := range markedUsers {
-		err := ns.repo.SetMessageUnread(id, m.ID, noticeable.Contains(id))
-		if err != nil {
-			logger.Error("failed to SetMessageUnread", zap.Error(err), zap.Stringer("user_id", id)) // 失敗
+	if !remote {
+		for id := range markedUsers {
+			err := ns.repo.SetMessageUnread(id, m.ID, noticeable.Contains(id))
+			if err != nil {
+				logger.Error("failed to SetMessageUnread", zap.Error(err), zap.Stringer("user_id", id)) // 失敗
+			}
+		}
+	}
+
+	// 通知受信箱に追加
+	// 同じメッセージの通知は1ユーザーにつき1つなので、優先度の高い種類から追加する
+	if !remote {
+		for _, typ := range []model.NotificationType{model.NotificationTypeDM, model.NotificationTypeMention, model.NotificationTypeGroupMention, model.NotificationTypeKeyword} {
+			users := inbox[typ]
+			users.Remove(m.UserID)
+			for id := range users {
+				addToInbox(ns, logger, id, typ, m)
+			}
 		}
 	}
 
@@ -214,9 +269,111 @@ func messageCreatedHandler(ns *Service, ev hub.Message) {
 	go ns.ws.WriteMessage(ssePayload.EventType, ssePayload.Payload, targetFunc)
 
 	// FCM送信
+	if remote {
+		return
+	}
 	targets := notifiedUsers.Clone()
 	targets.Remove(m.UserID)
-	ns.fcm.Send(targets, fcmPayload, true)
+
+	// おやすみモード中のユーザーにはプッシュ通知を送らない (緊急DMを除く)
+	now := time.Now()
+	sleeping, err := ns.dnd.FilterActive(targets, now)
+	if err != nil {
+		logger.Error("failed to FilterActive", zap.Error(err)) // 失敗
+		sleeping = set.UUID{}
+	}
+	for id := range sleeping {
+		if isDM {
+			urgent, err := ns.dnd.ConsumeUrgentOverride(m.UserID, id, now)
+			if err != nil {
+				logger.Error("failed to ConsumeUrgentOverride", zap.Error(err), zap.Stringer("userId", id)) // 失敗
+			}
+			if urgent {
+				continue
+			}
+		}
+		targets.Remove(id)
+		emailTargets.Remove(id)
+	}
+
+	if isDM {
+		ns.push.SendNow(targets, fcmPayload)
+	} else {
+		// メンション等を含まない、チャンネル通知購読のみによるプッシュ通知はまとめて送信する
+		direct := set.UUID{}
+		subscribed := set.UUID{}
+		for id := range targets {
+			if noticeable.Contains(id) {
+				direct.Add(id)
+			} else {
+				subscribed.Add(id)
+			}
+		}
+		ns.push.SendNow(direct, fcmPayload)
+		ns.push.Coalesce(subscribed, chID, fcmPayload, now)
+	}
+
+	// メール送信
+	if ns.email != nil {
+		emailTargets.Remove(m.UserID)
+		ns.email.NotifyMessage(emailTargets, &email.MessageNotification{
+			Title: fcmPayload.Title,
+			Body:  fcmPayload.Body,
+			Path:  fcmPayload.Path,
+			IsDM:  isDM,
+		})
+	}
+}
+
+func messageCitedHandler(ns *Service, ev hub.Message) {
+	if event.IsRemote(ev) {
+		return // 他のインスタンスで追加済み
+	}
+	m := ev.Fields["message"].(*model.Message)
+	logger := ns.logger.With(zap.Stringer("messageId", m.ID))
+
+	// 引用されたメッセージの投稿者が閲覧できない可能性があるので、公開チャンネルでの引用のみ
+	if !ns.cm.IsPublicChannel(m.ChannelID) {
+		return
+	}
+
+	authors := set.UUID{}
+	for _, id := range ev.Fields["cited_ids"].([]uuid.UUID) {
+		cited, err := ns.repo.GetMessageByID(id)
+		if err != nil {
+			if err != repository.ErrNotFound {
+				logger.Error("failed to GetMessageByID", zap.Error(err), zap.Stringer("citedId", id)) // 失敗
+			}
+			continue
+		}
+		authors.Add(cited.UserID)
+	}
+	authors.Remove(m.UserID)
+
+	for id := range authors {
+		user, err := ns.repo.GetUser(id, false)
+		if err != nil {
+			logger.Error("failed to GetUser", zap.Error(err), zap.Stringer("userId", id)) // 失敗
+			continue
+		}
+		if !user.IsActive() || user.IsBot() {
+			continue
+		}
+		addToInbox(ns, logger, id, model.NotificationTypeCitation, m)
+	}
+}
+
+func addToInbox(ns *Service, logger *zap.Logger, userID uuid.UUID, typ model.NotificationType, m *model.Message) {
+	_, err := ns.repo.CreateNotification(repository.CreateNotificationArgs{
+		UserID:    userID,
+		Type:      typ,
+		MessageID: m.ID,
+		ChannelID: m.ChannelID,
+		ActorID:   m.UserID,
+	})
+	if err != nil && err != repository.ErrAlreadyExists {
+		logger.Error("failed to CreateNotification", zap.Error(err), zap.Stringer("userId", userID)) // 失敗
+	}
 }
 
 func messageUpdatedHandler(ns *Service, ev hub.Message) {
@@ -357,6 +514,8 @@ func channelUnstaredHandler(ns *Service, ev hub.Message) {
 }
 
 func channelReadHandler(ns *Service, ev hub.Message) {
+	// 既読にしたチャンネルのまとめ待ちの通知は不要
+	ns.push.Discard(ev.Fields["user_id"].(uuid.UUID), ev.Fields["channel_id"].(uuid.UUID))
 	userMulticast(ns, ev.Fields["user_id"].(uuid.UUID), &sse.EventData{
 		EventType: "MESSAGE_READ",
 		Payload: map[string]interface{}{
@@ -538,6 +697,18 @@ func userWebRTCv3StateChangedHandler(ns *Service, ev hub.Message) {
 	}, ws.TargetAll())
 }
 
+func userTypingHandler(ns *Service, ev hub.Message) {
+	cid := ev.Fields["channel_id"].(uuid.UUID)
+	channelViewerMulticast(ns, cid, &sse.EventData{
+		EventType: "USER_TYPING",
+		Payload: map[string]interface{}{
+			"user_id":    ev.Fields["user_id"].(uuid.UUID),
+			"channel_id": cid,
+			"typing":     ev.Fields["typing"].(bool),
+		},
+	})
+}
+
 func clipFolderCreatedHandler(ns *Service, ev hub.Message) {
 	userMulticast(ns, ev.Fields["user_id"].(uuid.UUID), &sse.EventData{
 		EventType: "CLIP_FOLDER_CREATED",
@@ -585,6 +756,20 @@ func clipFolderMessageAddedHandler(ns *Service, ev hub.Message) {
 	})
 }
 
+func notificationCreatedHandler(ns *Service, ev hub.Message) {
+	n := ev.Fields["notification"].(*model.Notification)
+	userMulticast(ns, n.UserID, &sse.EventData{
+		EventType: "NOTIFICATION_CREATED",
+		Payload: map[string]interface{}{
+			"id":         n.ID,
+			"type":       n.Type,
+			"message_id": n.MessageID,
+			"channel_id": n.ChannelID,
+			"actor_id":   n.ActorID,
+		},
+	})
+}
+
 func channelHandler(ns *Service, ev hub.Message, ssePayload *sse.EventData) {
 	private := ev.Fields["private"].(bool)
 	if private {
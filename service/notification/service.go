@@ -4,8 +4,11 @@ import (
 	"github.com/leandro-lugaresi/hub"
 	"github.com/traPtitech/traQ/repository"
 	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/dnd"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/keyword"
 	"github.com/traPtitech/traQ/service/message"
 	"github.com/traPtitech/traQ/service/variable"
 	"github.com/traPtitech/traQ/service/viewer"
@@ -22,13 +25,17 @@ type Service struct {
 	hub    *hub.Hub
 	logger *zap.Logger
 	fcm    fcm.Client
+	push   *coalescer
+	email  *email.Service
+	km     *keyword.Manager
+	dnd    *dnd.Manager
 	ws     *ws.Streamer
 	vm     *viewer.Manager
 	origin string
 }
 
 // NewService 通知サービスを作成して起動します
-func NewService(repo repository.Repository, cm channel.Manager, mm message.Manager, fm file.Manager, hub *hub.Hub, logger *zap.Logger, fcm fcm.Client, ws *ws.Streamer, vm *viewer.Manager, origin variable.ServerOriginString) *Service {
+func NewService(repo repository.Repository, cm channel.Manager, mm message.Manager, fm file.Manager, hub *hub.Hub, logger *zap.Logger, fcm fcm.Client, email *email.Service, km *keyword.Manager, dnd *dnd.Manager, ws *ws.Streamer, vm *viewer.Manager, origin variable.ServerOriginString) *Service {
 	service := &Service{
 		repo:   repo,
 		cm:     cm,
@@ -37,10 +44,14 @@ func NewService(repo repository.Repository, cm channel.Manager, mm message.Manag
 		hub:    hub,
 		logger: logger.Named("notification"),
 		fcm:    fcm,
+		email:  email,
+		km:     km,
+		dnd:    dnd,
 		ws:     ws,
 		vm:     vm,
 		origin: string(origin),
 	}
+	service.push = newCoalescer(fcm, dnd, service.logger)
 	go func() {
 		topics := make([]string, 0, len(handlerMap))
 		for k := range handlerMap {
@@ -55,3 +66,8 @@ func NewService(repo repository.Repository, cm channel.Manager, mm message.Manag
 	}()
 	return service
 }
+
+// Close 通知サービスを停止します
+func (ns *Service) Close() {
+	ns.push.close()
+}
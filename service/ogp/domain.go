@@ -1,14 +1,40 @@
 package ogp
 
 import (
-	"github.com/dyatlov/go-opengraph/opengraph"
+	"github.com/traPtitech/traQ/model"
 	"net/url"
 )
 
-func FetchSpecialDomainInfo(url *url.URL) (og *opengraph.OpenGraph, meta *DefaultPageMeta, isSpecialDomain bool, err error) {
-	if url.Host == "twitter.com" {
-		og, meta, err = FetchTwitterInfo(url)
-		return og, meta, true, err
+// DomainHandler 特殊処理を行うドメインのハンドラ
+type DomainHandler interface {
+	// Match 指定したURLをこのハンドラで処理するかどうかを返します
+	Match(u *url.URL) bool
+	// Fetch 指定したURLのOGP情報を取得します
+	//
+	// エラーを返した場合、通常のページとしてOGP情報を取得します。
+	Fetch(u *url.URL) (*model.Ogp, error)
+}
+
+// RegisterDomainHandler 特殊処理を行うドメインのハンドラを登録します
+//
+// 先に登録されたハンドラが優先されます。
+func (s *Service) RegisterDomainHandler(h DomainHandler) {
+	s.handlersLock.Lock()
+	defer s.handlersLock.Unlock()
+	s.handlers = append(s.handlers, h)
+}
+
+// FetchSpecialDomainInfo 特殊処理を行うドメインのURLの場合、ハンドラで取得したOGP情報を返します
+func (s *Service) FetchSpecialDomainInfo(u *url.URL) (content *model.Ogp, isSpecialDomain bool, err error) {
+	s.handlersLock.RLock()
+	handlers := s.handlers
+	s.handlersLock.RUnlock()
+
+	for _, h := range handlers {
+		if h.Match(u) {
+			content, err = h.Fetch(u)
+			return content, true, err
+		}
 	}
-	return nil, nil, false, nil
+	return nil, false, nil
 }
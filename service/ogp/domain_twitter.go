@@ -4,10 +4,9 @@ import (
 	"fmt"
 	"github.com/dyatlov/go-opengraph/opengraph"
 	jsoniter "github.com/json-iterator/go"
-	"net/http"
+	"github.com/traPtitech/traQ/model"
 	"net/url"
 	"strings"
-	"time"
 )
 
 type TwitterSyndicationAPIResponse struct {
@@ -27,13 +26,32 @@ type TwitterSyndicationAPIResponse struct {
 	} `json:"video"`
 }
 
-func FetchTwitterInfo(url *url.URL) (*opengraph.OpenGraph, *DefaultPageMeta, error) {
+// twitterHandler twitter.comのハンドラ
+type twitterHandler struct {
+	s *Service
+}
+
+// Match implements DomainHandler interface.
+func (h *twitterHandler) Match(u *url.URL) bool {
+	return u.Host == "twitter.com"
+}
+
+// Fetch implements DomainHandler interface.
+func (h *twitterHandler) Fetch(u *url.URL) (*model.Ogp, error) {
+	og, meta, err := h.s.FetchTwitterInfo(u)
+	if err != nil {
+		return nil, err
+	}
+	return MergeDefaultPageMetaAndOpenGraph(og, meta), nil
+}
+
+func (s *Service) FetchTwitterInfo(url *url.URL) (*opengraph.OpenGraph, *DefaultPageMeta, error) {
 	splitPath := strings.Split(url.Path, "/")
 	if len(splitPath) < 4 || splitPath[2] != "status" {
 		return nil, nil, ErrDomainRequest
 	}
 	statusID := splitPath[3]
-	apiResponse, err := fetchTwitterSyndicationAPI(statusID)
+	apiResponse, err := s.fetchTwitterSyndicationAPI(statusID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -62,22 +80,13 @@ func FetchTwitterInfo(url *url.URL) (*opengraph.OpenGraph, *DefaultPageMeta, err
 	return &og, &result, nil
 }
 
-func fetchTwitterSyndicationAPI(statusID string) (*TwitterSyndicationAPIResponse, error) {
-	client := http.Client{
-		Timeout: 5 * time.Second,
-	}
-	requestURL := fmt.Sprintf("https://syndication.twitter.com/tweet?id=%s", statusID)
-	resp, err := client.Get(requestURL)
+func (s *Service) fetchTwitterSyndicationAPI(statusID string) (*TwitterSyndicationAPIResponse, error) {
+	requestURL := fmt.Sprintf("https://syndication.twitter.com/tweet?id=%s", url.QueryEscape(statusID))
+	resp, err := s.get(requestURL)
 	if err != nil {
-		return nil, ErrNetwork
+		return nil, err
 	}
-
 	defer resp.Body.Close()
-	if resp.StatusCode >= 500 {
-		return nil, ErrServer
-	} else if resp.StatusCode >= 400 {
-		return nil, ErrClient
-	}
 
 	data := TwitterSyndicationAPIResponse{}
 	if err = jsoniter.ConfigFastest.NewDecoder(resp.Body).Decode(&data); err != nil {
@@ -15,4 +15,8 @@ var (
 	ErrServer = errors.New("network error (server)")
 	// ErrDomainRequest 特殊処理を行うドメインのURLが期待した形式ではありませんでした
 	ErrDomainRequest = errors.New("bad request for special domain ")
+	// ErrForbidden 対象URLへのアクセスが禁止されていました
+	ErrForbidden = errors.New("forbidden url")
+	// ErrContentTooLarge 対象URLのコンテンツが大きすぎました
+	ErrContentTooLarge = errors.New("content too large")
 )
@@ -1,9 +1,12 @@
 package ogp
 
-import "time"
+import (
+	"github.com/traPtitech/traQ/model"
+	"time"
+)
 
-const CacheHours = 7 * 24
+const CacheHours = model.OgpCacheHours
 
 func GetCacheExpireDate() time.Time {
-	return time.Now().Add(time.Duration(CacheHours) * time.Hour)
+	return model.OgpCacheExpireDate()
 }
@@ -0,0 +1,170 @@
+package ogp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/utils/optional"
+	"go.uber.org/zap"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	imageMaxFileSize   = 5 << 20 // 5MB
+	imageMaxSize       = 1200
+	imageMaxCount      = 4
+	imageJPEGQuality   = 85
+	cacheSweepInterval = 1 * time.Hour
+	// cacheSweepGrace 有効期限切れのキャッシュを削除するまでの猶予
+	//
+	// 有効期限切れのキャッシュはリクエスト時に更新されるため、更新中に削除されないように猶予を設けています。
+	cacheSweepGrace = 24 * time.Hour
+	cacheSweepLimit = 500
+	// imagePathPrefix プロキシした画像のURLのパス
+	imagePathPrefix = "/api/v3/files/"
+)
+
+// proxyImages OGPのプレビュー画像をダウンロードしてtraQから配信する画像に置き換えます
+//
+// 取得できなかった画像は、外部サイトへのアクセスを防ぐため取り除きます。
+func (s *Service) proxyImages(content *model.Ogp) {
+	if s.fm == nil || len(content.Images) == 0 {
+		return
+	}
+
+	images := make([]model.OgpMedia, 0, len(content.Images))
+	for _, media := range content.Images {
+		if len(images) >= imageMaxCount {
+			break
+		}
+		src := media.URL
+		if media.SecureURL.Valid && len(media.SecureURL.String) > 0 {
+			src = media.SecureURL.String
+		}
+		proxied, err := s.proxyImage(src)
+		if err != nil {
+			s.logger.Debug("failed to proxy ogp image", zap.Error(err), zap.String("url", src))
+			continue
+		}
+		images = append(images, proxied)
+	}
+	if len(images) == 0 {
+		images = nil
+	}
+	content.Images = images
+}
+
+// proxyImage 指定したURLの画像をダウンロードして縮小し、ファイルとして保存します
+func (s *Service) proxyImage(src string) (model.OgpMedia, error) {
+	resp, err := s.get(src)
+	if err != nil {
+		return model.OgpMedia{}, err
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength > imageMaxFileSize {
+		return model.OgpMedia{}, ErrContentTooLarge
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, imageMaxFileSize+1))
+	if err != nil {
+		return model.OgpMedia{}, ErrNetwork
+	}
+	if len(b) > imageMaxFileSize {
+		return model.OgpMedia{}, ErrContentTooLarge
+	}
+
+	// Content-Typeヘッダーは信用せず、中身から判定する
+	mimeType := http.DetectContentType(b)
+	switch mimeType {
+	case "image/png", "image/jpeg", "image/gif":
+	default:
+		return model.OgpMedia{}, ErrContentTypeNotSupported
+	}
+
+	img, err := s.ip.Fit(bytes.NewReader(b), imageMaxSize, imageMaxSize)
+	if err != nil {
+		return model.OgpMedia{}, err
+	}
+
+	var buf bytes.Buffer
+	args := file.SaveArgs{
+		FileType:                model.FileTypeOgpImage,
+		SkipThumbnailGeneration: true,
+	}
+	if mimeType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: imageJPEGQuality}); err != nil {
+			return model.OgpMedia{}, err
+		}
+		args.FileName = "ogp.jpg"
+		args.MimeType = "image/jpeg"
+	} else {
+		// GIFは静止画に変換
+		if err := png.Encode(&buf, img); err != nil {
+			return model.OgpMedia{}, err
+		}
+		args.FileName = "ogp.png"
+		args.MimeType = "image/png"
+	}
+	args.FileSize = int64(buf.Len())
+	args.Src = &buf
+
+	f, err := s.fm.Save(args)
+	if err != nil {
+		return model.OgpMedia{}, err
+	}
+
+	bounds := img.Bounds()
+	return model.OgpMedia{
+		URL:    s.imageURL(f.GetID()),
+		Type:   optional.StringFrom(args.MimeType),
+		Width:  optional.IntFrom(int64(bounds.Dx())),
+		Height: optional.IntFrom(int64(bounds.Dy())),
+	}, nil
+}
+
+// imageURL プロキシした画像のURLを返します
+func (s *Service) imageURL(fileID uuid.UUID) string {
+	return fmt.Sprintf("%s%s%s", s.origin, imagePathPrefix, fileID)
+}
+
+// DeleteProxiedImages OGP情報に含まれるプロキシした画像を削除します
+//
+// OGPキャッシュを更新・削除した際に、古いOGP情報に対して呼び出してください。
+func (s *Service) DeleteProxiedImages(content *model.Ogp) {
+	if s.fm == nil || content == nil {
+		return
+	}
+	prefix := string(s.origin) + imagePathPrefix
+	for _, media := range content.Images {
+		if !strings.HasPrefix(media.URL, prefix) {
+			continue
+		}
+		id, err := uuid.FromString(strings.TrimPrefix(media.URL, prefix))
+		if err != nil {
+			continue
+		}
+		if err := s.fm.Delete(id); err != nil && err != file.ErrNotFound {
+			s.logger.Warn("failed to delete ogp image", zap.Error(err), zap.Stringer("fid", id))
+		}
+	}
+}
+
+// sweepExpiredCaches 有効期限切れのOGPキャッシュとそのプレビュー画像を削除します
+func (s *Service) sweepExpiredCaches(now time.Time) {
+	caches, err := s.repo.DeleteExpiredOgpCaches(now.Add(-cacheSweepGrace), cacheSweepLimit)
+	if err != nil {
+		s.logger.Error("failed to delete expired ogp caches", zap.Error(err))
+		return
+	}
+	for _, c := range caches {
+		s.DeleteProxiedImages(&c.Content)
+	}
+}
@@ -0,0 +1,238 @@
+package ogp
+
+import (
+	"fmt"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/optional"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// OEmbedProvider oEmbedプロバイダ
+type OEmbedProvider struct {
+	// Name プロバイダ名
+	Name string
+	// Endpoint oEmbed APIのエンドポイント
+	//
+	// `{format}`は`json`に置換されます。
+	Endpoint string
+	// Schemes 対象URLのパターン
+	//
+	// `*`は任意の文字列にマッチします。ただし、ホスト部分ではサブドメインにのみマッチします。
+	Schemes []string
+}
+
+// DefaultOEmbedProviders デフォルトのoEmbedプロバイダ
+var DefaultOEmbedProviders = []OEmbedProvider{
+	{
+		Name:     "YouTube",
+		Endpoint: "https://www.youtube.com/oembed",
+		Schemes: []string{
+			"https://youtube.com/watch*",
+			"https://*.youtube.com/watch*",
+			"https://*.youtube.com/v/*",
+			"https://*.youtube.com/shorts/*",
+			"https://*.youtube.com/playlist?list=*",
+			"https://youtu.be/*",
+		},
+	},
+	{
+		Name:     "Vimeo",
+		Endpoint: "https://vimeo.com/api/oembed.{format}",
+		Schemes: []string{
+			"https://vimeo.com/*",
+			"https://player.vimeo.com/video/*",
+		},
+	},
+	{
+		Name:     "SoundCloud",
+		Endpoint: "https://soundcloud.com/oembed",
+		Schemes: []string{
+			"https://soundcloud.com/*",
+			"https://on.soundcloud.com/*",
+		},
+	},
+	{
+		Name:     "Spotify",
+		Endpoint: "https://open.spotify.com/oembed",
+		Schemes: []string{
+			"https://open.spotify.com/*",
+		},
+	},
+	{
+		Name:     "Speaker Deck",
+		Endpoint: "https://speakerdeck.com/oembed.{format}",
+		Schemes: []string{
+			"https://speakerdeck.com/*/*",
+		},
+	},
+	{
+		Name:     "Flickr",
+		Endpoint: "https://www.flickr.com/services/oembed/",
+		Schemes: []string{
+			"https://*.flickr.com/photos/*",
+			"https://flic.kr/p/*",
+		},
+	},
+}
+
+// oEmbedResponse oEmbed APIのレスポンス
+type oEmbedResponse struct {
+	Type            string          `json:"type"`
+	Version         string          `json:"version"`
+	Title           string          `json:"title"`
+	AuthorName      string          `json:"author_name"`
+	ProviderName    string          `json:"provider_name"`
+	ThumbnailURL    string          `json:"thumbnail_url"`
+	ThumbnailWidth  oEmbedDimension `json:"thumbnail_width"`
+	ThumbnailHeight oEmbedDimension `json:"thumbnail_height"`
+	URL             string          `json:"url"`
+	Width           oEmbedDimension `json:"width"`
+	Height          oEmbedDimension `json:"height"`
+}
+
+// oEmbedDimension oEmbedの幅・高さ
+//
+// 数値ではなく文字列で返すプロバイダがあるため、どちらも受け付けます。
+// 数値として解釈できない場合は0になります。
+type oEmbedDimension int64
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (d *oEmbedDimension) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		*d = 0
+		return nil
+	}
+	*d = oEmbedDimension(v)
+	return nil
+}
+
+func (d oEmbedDimension) optional() optional.Int {
+	if d <= 0 {
+		return optional.Int{}
+	}
+	return optional.IntFrom(int64(d))
+}
+
+// oEmbedHandler oEmbedプロバイダのハンドラ
+type oEmbedHandler struct {
+	s        *Service
+	provider OEmbedProvider
+	schemes  []*regexp.Regexp
+}
+
+func newOEmbedHandler(s *Service, provider OEmbedProvider) *oEmbedHandler {
+	h := &oEmbedHandler{s: s, provider: provider}
+	for _, scheme := range provider.Schemes {
+		h.schemes = append(h.schemes, compileOEmbedScheme(scheme))
+	}
+	return h
+}
+
+// compileOEmbedScheme oEmbedのURLパターンを正規表現に変換します
+//
+// ホスト部分の`*`はサブドメインにのみマッチします。
+func compileOEmbedScheme(scheme string) *regexp.Regexp {
+	hostEnd := len(scheme)
+	if i := strings.Index(scheme, "://"); i >= 0 {
+		if j := strings.Index(scheme[i+3:], "/"); j >= 0 {
+			hostEnd = i + 3 + j
+		}
+	}
+	host := strings.ReplaceAll(regexp.QuoteMeta(scheme[:hostEnd]), `\*`, `[^/?#@]*`)
+	path := strings.ReplaceAll(regexp.QuoteMeta(scheme[hostEnd:]), `\*`, `.*`)
+	return regexp.MustCompile("^" + host + path + "$")
+}
+
+// Match implements DomainHandler interface.
+func (h *oEmbedHandler) Match(u *url.URL) bool {
+	target := u.String()
+	for _, r := range h.schemes {
+		if r.MatchString(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch implements DomainHandler interface.
+func (h *oEmbedHandler) Fetch(u *url.URL) (*model.Ogp, error) {
+	endpoint := strings.ReplaceAll(h.provider.Endpoint, "{format}", "json")
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	res, err := h.s.fetchOEmbed(fmt.Sprintf("%s%surl=%s&format=json", endpoint, sep, url.QueryEscape(u.String())))
+	if err != nil {
+		return nil, err
+	}
+	if len(res.ProviderName) == 0 {
+		res.ProviderName = h.provider.Name
+	}
+	return res.toOgp(u.String()), nil
+}
+
+// fetchOEmbed 指定したoEmbed APIのURLからレスポンスを取得します
+func (s *Service) fetchOEmbed(requestURL string) (*oEmbedResponse, error) {
+	resp, err := s.get(requestURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var res oEmbedResponse
+	if err := jsoniter.ConfigFastest.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, ErrParse
+	}
+	switch res.Type {
+	case "photo", "video", "link", "rich":
+	default:
+		return nil, ErrParse
+	}
+	return &res, nil
+}
+
+// toOgp oEmbedのレスポンスをOGP情報に変換します
+func (res *oEmbedResponse) toOgp(pageURL string) *model.Ogp {
+	result := &model.Ogp{
+		Type:  "website",
+		Title: res.Title,
+		URL:   pageURL,
+	}
+	if res.Type == "video" {
+		result.Type = "video.other"
+	}
+	if len(res.Title) == 0 {
+		result.Title = res.AuthorName
+	}
+	if res.Type == "photo" && len(res.URL) > 0 {
+		result.Images = []model.OgpMedia{{
+			URL:    res.URL,
+			Width:  res.Width.optional(),
+			Height: res.Height.optional(),
+		}}
+	} else if len(res.ThumbnailURL) > 0 {
+		result.Images = []model.OgpMedia{{
+			URL:    res.ThumbnailURL,
+			Width:  res.ThumbnailWidth.optional(),
+			Height: res.ThumbnailHeight.optional(),
+		}}
+	}
+	result.Embed = res.toEmbed()
+	return result
+}
+
+// toEmbed oEmbedのレスポンスを埋め込み情報に変換します
+func (res *oEmbedResponse) toEmbed() *model.OgpEmbed {
+	return &model.OgpEmbed{
+		Type:         res.Type,
+		ProviderName: res.ProviderName,
+		Width:        res.Width.optional(),
+		Height:       res.Height.optional(),
+	}
+}
@@ -2,49 +2,66 @@ package ogp
 
 import (
 	"context"
+	"errors"
 	"github.com/dyatlov/go-opengraph/opengraph"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/utils/safehttp"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/charset"
-	"golang.org/x/sync/semaphore"
-	"net/http"
 	"net/url"
 	"strings"
-	"time"
 )
 
-const concurrentRequestLimit = 10
-
-var requestLimiter = semaphore.NewWeighted(concurrentRequestLimit)
-
 type DefaultPageMeta struct {
 	Title, Description, URL, Image string
+	// OEmbedURL oEmbed Discoveryで見つかったoEmbed APIのURL
+	OEmbedURL string
 }
 
-// ParseMetaForURL 指定したURLのメタタグをパースした結果を返します。
-func ParseMetaForURL(url *url.URL) (*opengraph.OpenGraph, *DefaultPageMeta, error) {
-	_ = requestLimiter.Acquire(context.Background(), 1)
-	defer requestLimiter.Release(1)
-
-	og, meta, isSpecialDomain, err := FetchSpecialDomainInfo(url)
+// Fetch 指定したURLのOGP情報を取得します。
+//
+// 特殊処理を行うドメインのURLの場合はそのハンドラを用い、
+// それ以外の場合はページのメタタグをパースした結果を返します。
+// ページからoEmbed APIが見つかった場合は埋め込み情報も付与します。
+// プレビュー画像はtraQから配信する画像に置き換えられます。
+func (s *Service) Fetch(url *url.URL) (*model.Ogp, error) {
+	_ = s.limiter.Acquire(context.Background(), 1)
+	defer s.limiter.Release(1)
+
+	content, isSpecialDomain, err := s.FetchSpecialDomainInfo(url)
 	if isSpecialDomain && (err == nil) {
-		return og, meta, nil
+		s.proxyImages(content)
+		return content, nil
 	}
 
-	client := http.Client{
-		Timeout: 5 * time.Second,
-	}
-	resp, err := client.Get(url.String())
+	og, meta, err := s.ParseMetaForURL(url)
 	if err != nil {
-		return nil, nil, ErrNetwork
+		return nil, err
 	}
+	content = MergeDefaultPageMetaAndOpenGraph(og, meta)
 
-	defer resp.Body.Close()
+	if len(meta.OEmbedURL) > 0 {
+		if res, err := s.fetchOEmbed(meta.OEmbedURL); err == nil {
+			content.Embed = res.toEmbed()
+			if len(content.Title) == 0 {
+				content.Title = res.Title
+			}
+			if len(content.Images) == 0 {
+				content.Images = res.toOgp(content.URL).Images
+			}
+		}
+	}
+	s.proxyImages(content)
+	return content, nil
+}
 
-	if resp.StatusCode >= 500 {
-		return nil, nil, ErrServer
-	} else if resp.StatusCode >= 400 {
-		return nil, nil, ErrClient
+// ParseMetaForURL 指定したURLのメタタグをパースした結果を返します。
+func (s *Service) ParseMetaForURL(url *url.URL) (*opengraph.OpenGraph, *DefaultPageMeta, error) {
+	resp, err := s.get(url.String())
+	if err != nil {
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
 
 	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
 		return nil, nil, ErrContentTypeNotSupported
@@ -57,13 +74,24 @@ func ParseMetaForURL(url *url.URL) (*opengraph.OpenGraph, *DefaultPageMeta, erro
 	}
 	doc, err := html.Parse(decodedReader)
 	if err != nil {
+		if errors.Is(err, safehttp.ErrBodyTooLarge) {
+			return nil, nil, ErrContentTooLarge
+		}
 		return nil, nil, ErrParse
 	}
 
-	og, meta = parseDoc(doc)
+	og, meta := parseDoc(doc)
 	if len(meta.URL) == 0 {
 		meta.URL = url.String()
 	}
+	if len(meta.OEmbedURL) > 0 {
+		// 相対URLの場合はリダイレクト後のページのURLを基準に解決する
+		if u, err := resp.Request.URL.Parse(meta.OEmbedURL); err == nil {
+			meta.OEmbedURL = u.String()
+		} else {
+			meta.OEmbedURL = ""
+		}
+	}
 	return og, meta, nil
 }
 
@@ -103,6 +131,13 @@ func (m *DefaultPageMeta) processMeta(metaAttrs map[string]string) {
 	}
 }
 
+// processLink linkタグ内の情報をパースする
+func (m *DefaultPageMeta) processLink(linkAttrs map[string]string) {
+	if linkAttrs["rel"] == "alternate" && linkAttrs["type"] == "application/json+oembed" && len(m.OEmbedURL) == 0 {
+		m.OEmbedURL = linkAttrs["href"]
+	}
+}
+
 // parseMetaTags metaタグを直下の子に持つタグをパース
 func parseMetaTags(og *opengraph.OpenGraph, meta *DefaultPageMeta, node *html.Node) {
 	for c := node.FirstChild; c != nil; c = c.NextSibling {
@@ -113,6 +148,12 @@ func parseMetaTags(og *opengraph.OpenGraph, meta *DefaultPageMeta, node *html.No
 			}
 			og.ProcessMeta(m)
 			meta.processMeta(m)
+		} else if c.Type == html.ElementNode && c.Data == "link" {
+			m := make(map[string]string)
+			for _, a := range c.Attr {
+				m[a.Key] = a.Val
+			}
+			meta.processLink(m)
 		} else if title := extractTitleFromNode(c); len(title) > 0 {
 			meta.Title = title
 		}
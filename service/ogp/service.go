@@ -0,0 +1,106 @@
+package ogp
+
+import (
+	"context"
+	"errors"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/imaging"
+	"github.com/traPtitech/traQ/service/variable"
+	"github.com/traPtitech/traQ/utils/safehttp"
+	"go.uber.org/zap"
+	"golang.org/x/sync/semaphore"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const concurrentRequestLimit = 10
+
+// Config OGP取得サービス設定
+type Config struct {
+	// AllowHosts プライベートネットワーク空間のアドレスであってもアクセスを許可するホスト
+	AllowHosts []string
+	// DenyHosts アクセスを拒否するホスト
+	DenyHosts []string
+	// MaxRedirects リダイレクトの最大回数
+	MaxRedirects int
+	// MaxBodySize レスポンスボディの最大バイト数
+	MaxBodySize int64
+	// OEmbedProviders 追加のoEmbedプロバイダ (DefaultOEmbedProvidersより優先)
+	OEmbedProviders []OEmbedProvider
+}
+
+// Service OGP取得サービス
+type Service struct {
+	client       *safehttp.Client
+	limiter      *semaphore.Weighted
+	handlers     []DomainHandler
+	handlersLock sync.RWMutex
+	repo         repository.OgpCacheRepository
+	fm           file.Manager
+	ip           imaging.Processor
+	origin       variable.ServerOriginString
+	logger       *zap.Logger
+}
+
+// NewService OGP取得サービスを生成します
+//
+// fmがnilの場合、プレビュー画像のプロキシは行いません。
+func NewService(config Config, repo repository.Repository, fm file.Manager, ip imaging.Processor, origin variable.ServerOriginString, logger *zap.Logger) *Service {
+	s := &Service{
+		repo:   repo,
+		fm:     fm,
+		ip:     ip,
+		origin: origin,
+		logger: logger.Named("ogp"),
+		client: safehttp.NewClient(safehttp.Config{
+			MaxRedirects: config.MaxRedirects,
+			MaxBodySize:  config.MaxBodySize,
+			AllowHosts:   config.AllowHosts,
+			DenyHosts:    config.DenyHosts,
+		}),
+		limiter: semaphore.NewWeighted(concurrentRequestLimit),
+	}
+	s.RegisterDomainHandler(&twitterHandler{s: s})
+	for _, p := range config.OEmbedProviders {
+		s.RegisterDomainHandler(newOEmbedHandler(s, p))
+	}
+	for _, p := range DefaultOEmbedProviders {
+		s.RegisterDomainHandler(newOEmbedHandler(s, p))
+	}
+
+	if repo != nil {
+		go func() {
+			ticker := time.NewTicker(cacheSweepInterval)
+			defer ticker.Stop()
+			for now := range ticker.C {
+				s.sweepExpiredCaches(now)
+			}
+		}()
+	}
+	return s
+}
+
+// get 指定したURLにGETリクエストを送信します
+func (s *Service) get(rawURL string) (*http.Response, error) {
+	resp, err := s.client.Get(context.Background(), rawURL)
+	if err != nil {
+		switch {
+		case errors.Is(err, safehttp.ErrForbiddenHost), errors.Is(err, safehttp.ErrUnsupportedScheme):
+			return nil, ErrForbidden
+		case errors.Is(err, safehttp.ErrBodyTooLarge):
+			return nil, ErrContentTooLarge
+		default:
+			return nil, ErrNetwork
+		}
+	}
+	if resp.StatusCode >= 500 {
+		resp.Body.Close()
+		return nil, ErrServer
+	} else if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, ErrClient
+	}
+	return resp, nil
+}
@@ -0,0 +1,385 @@
+package ogp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/file"
+	"github.com/traPtitech/traQ/service/imaging"
+	"go.uber.org/zap"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newTestService(config Config) *Service {
+	return NewService(config, nil, nil, nil, "", zap.NewNop())
+}
+
+func TestService_ParseMetaForURL(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testHTML))
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	})
+	mux.HandleFunc("/notfound", http.NotFound)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mustParse := func(t *testing.T, s string) *url.URL {
+		t.Helper()
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+
+	t.Run("private address", func(t *testing.T) {
+		t.Parallel()
+		s := newTestService(Config{})
+		_, _, err := s.ParseMetaForURL(mustParse(t, server.URL))
+		assert.Equal(t, ErrForbidden, err)
+	})
+
+	t.Run("denied host", func(t *testing.T) {
+		t.Parallel()
+		s := newTestService(Config{DenyHosts: []string{".example.com"}})
+		_, _, err := s.ParseMetaForURL(mustParse(t, "https://www.example.com/"))
+		assert.Equal(t, ErrForbidden, err)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		s := newTestService(Config{AllowHosts: []string{"127.0.0.1"}})
+		og, meta, err := s.ParseMetaForURL(mustParse(t, server.URL))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "TITLE", og.Title)
+			assert.Empty(t, meta.OEmbedURL)
+		}
+	})
+
+	t.Run("content type not supported", func(t *testing.T) {
+		t.Parallel()
+		s := newTestService(Config{AllowHosts: []string{"127.0.0.1"}})
+		_, _, err := s.ParseMetaForURL(mustParse(t, server.URL+"/image.png"))
+		assert.Equal(t, ErrContentTypeNotSupported, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		s := newTestService(Config{AllowHosts: []string{"127.0.0.1"}})
+		_, _, err := s.ParseMetaForURL(mustParse(t, server.URL+"/notfound"))
+		assert.Equal(t, ErrClient, err)
+	})
+
+	t.Run("content too large", func(t *testing.T) {
+		t.Parallel()
+		s := newTestService(Config{AllowHosts: []string{"127.0.0.1"}, MaxBodySize: 16})
+		_, _, err := s.ParseMetaForURL(mustParse(t, server.URL))
+		assert.Equal(t, ErrContentTooLarge, err)
+	})
+}
+
+type testDomainHandler struct {
+	host    string
+	content *model.Ogp
+	err     error
+}
+
+func (h *testDomainHandler) Match(u *url.URL) bool {
+	return u.Hostname() == h.host
+}
+
+func (h *testDomainHandler) Fetch(_ *url.URL) (*model.Ogp, error) {
+	return h.content, h.err
+}
+
+func TestService_Fetch(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testHTML))
+	})
+	mux.HandleFunc("/discovery", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprintf(w, `<html><head>
+<title>DISCOVERY</title>
+<link rel="alternate" type="application/json+oembed" href="/oembed.json?url=%s">
+</head><body></body></html>`, url.QueryEscape(server.URL+"/discovery"))
+	})
+	mux.HandleFunc("/oembed.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "" && r.URL.Query().Get("format") != "json" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Query().Get("url"), "/video/broken"):
+			http.NotFound(w, r)
+		case strings.HasSuffix(r.URL.Query().Get("url"), "/discovery"):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"type":"rich","version":"1.0","provider_name":"Discovered","width":"100%","height":200}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"type":"video","version":"1.0","title":"VIDEO","thumbnail_url":"https://example.com/thumb.png","thumbnail_width":480,"thumbnail_height":360,"width":640,"height":"360"}`))
+		}
+	})
+	mux.HandleFunc("/video/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testHTML))
+	})
+
+	newService := func() *Service {
+		return newTestService(Config{
+			AllowHosts: []string{"127.0.0.1"},
+			OEmbedProviders: []OEmbedProvider{{
+				Name:     "Local",
+				Endpoint: server.URL + "/oembed.{format}",
+				Schemes:  []string{server.URL + "/video/*"},
+			}},
+		})
+	}
+	mustParse := func(t *testing.T, s string) *url.URL {
+		t.Helper()
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+
+	t.Run("oEmbed provider", func(t *testing.T) {
+		t.Parallel()
+		s := newService()
+		content, err := s.Fetch(mustParse(t, server.URL+"/video/1"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "video.other", content.Type)
+			assert.Equal(t, "VIDEO", content.Title)
+			assert.Equal(t, server.URL+"/video/1", content.URL)
+			if assert.Len(t, content.Images, 1) {
+				assert.Equal(t, "https://example.com/thumb.png", content.Images[0].URL)
+				assert.EqualValues(t, 480, content.Images[0].Width.Int64)
+			}
+			if assert.NotNil(t, content.Embed) {
+				assert.Equal(t, "video", content.Embed.Type)
+				assert.Equal(t, "Local", content.Embed.ProviderName)
+				assert.EqualValues(t, 640, content.Embed.Width.Int64)
+				assert.EqualValues(t, 360, content.Embed.Height.Int64)
+			}
+		}
+	})
+
+	t.Run("oEmbed provider failure falls back to page", func(t *testing.T) {
+		t.Parallel()
+		s := newService()
+		content, err := s.Fetch(mustParse(t, server.URL+"/video/broken"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "TITLE", content.Title)
+			assert.Nil(t, content.Embed)
+		}
+	})
+
+	t.Run("oEmbed discovery", func(t *testing.T) {
+		t.Parallel()
+		s := newService()
+		content, err := s.Fetch(mustParse(t, server.URL+"/discovery"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "DISCOVERY", content.Title)
+			if assert.NotNil(t, content.Embed) {
+				assert.Equal(t, "rich", content.Embed.Type)
+				assert.Equal(t, "Discovered", content.Embed.ProviderName)
+				assert.False(t, content.Embed.Width.Valid)
+				assert.EqualValues(t, 200, content.Embed.Height.Int64)
+			}
+		}
+	})
+
+	t.Run("custom domain handler", func(t *testing.T) {
+		t.Parallel()
+		s := newService()
+		s.RegisterDomainHandler(&testDomainHandler{host: "example.test", content: &model.Ogp{Title: "CUSTOM"}})
+		content, err := s.Fetch(mustParse(t, "https://example.test/"))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "CUSTOM", content.Title)
+		}
+	})
+
+	t.Run("domain handler failure falls back to page", func(t *testing.T) {
+		t.Parallel()
+		s := newService()
+		s.RegisterDomainHandler(&testDomainHandler{host: "127.0.0.1", err: ErrDomainRequest})
+		content, err := s.Fetch(mustParse(t, server.URL))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "TITLE", content.Title)
+		}
+	})
+}
+
+func TestOEmbedHandler_Match(t *testing.T) {
+	t.Parallel()
+
+	s := newTestService(Config{})
+	cases := []struct {
+		url      string
+		provider string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "YouTube"},
+		{"https://youtube.com/watch?v=dQw4w9WgXcQ", "YouTube"},
+		{"https://youtu.be/dQw4w9WgXcQ", "YouTube"},
+		{"https://vimeo.com/76979871", "Vimeo"},
+		{"https://soundcloud.com/user/track", "SoundCloud"},
+		{"https://open.spotify.com/track/xxxx", "Spotify"},
+		{"https://twitter.com/user/status/1", ""},
+		{"https://example.com/watch", ""},
+		{"https://www.youtube.com.example.com/watch", ""},
+		{"https://example.com/?.youtube.com/watch", ""},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.url, func(t *testing.T) {
+			t.Parallel()
+			u, err := url.Parse(c.url)
+			require.NoError(t, err)
+
+			name := ""
+			for _, h := range s.handlers {
+				if oh, ok := h.(*oEmbedHandler); ok && oh.Match(u) {
+					name = oh.provider.Name
+					break
+				}
+			}
+			assert.Equal(t, c.provider, name)
+		})
+	}
+}
+
+type fakeFile struct {
+	model.File
+	id uuid.UUID
+}
+
+func (f *fakeFile) GetID() uuid.UUID {
+	return f.id
+}
+
+type fakeFileManager struct {
+	file.Manager
+	sync.Mutex
+	saved   map[uuid.UUID]file.SaveArgs
+	deleted []uuid.UUID
+}
+
+func (m *fakeFileManager) Save(args file.SaveArgs) (model.File, error) {
+	m.Lock()
+	defer m.Unlock()
+	id := uuid.Must(uuid.NewV4())
+	m.saved[id] = args
+	return &fakeFile{id: id}, nil
+}
+
+func (m *fakeFileManager) Delete(id uuid.UUID) error {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.saved[id]; !ok {
+		return file.ErrNotFound
+	}
+	delete(m.saved, id)
+	m.deleted = append(m.deleted, id)
+	return nil
+}
+
+func TestService_ProxyImages(t *testing.T) {
+	t.Parallel()
+
+	var imgBuf bytes.Buffer
+	require.NoError(t, png.Encode(&imgBuf, image.NewRGBA(image.Rect(0, 0, 2400, 100))))
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		// Content-Typeヘッダーではなく中身で判定されることを確認する
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write(imgBuf.Bytes())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprintf(w, `<html><head>
+<meta property="og:title" content="TITLE" />
+<meta property="og:image" content="%s/image.png" />
+<meta property="og:image" content="%s/not-image" />
+</head><body></body></html>`, server.URL, server.URL)
+	})
+
+	const origin = "https://traq.example.com"
+	newService := func() (*Service, *fakeFileManager) {
+		fm := &fakeFileManager{saved: map[uuid.UUID]file.SaveArgs{}}
+		ip := imaging.NewProcessor(imaging.Config{
+			MaxPixels:        1000 * 1000,
+			Concurrency:      1,
+			ThumbnailMaxSize: image.Pt(360, 480),
+		})
+		return NewService(Config{AllowHosts: []string{"127.0.0.1"}}, nil, fm, ip, origin, zap.NewNop()), fm
+	}
+	mustParse := func(t *testing.T, s string) *url.URL {
+		t.Helper()
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+
+	t.Run("proxy", func(t *testing.T) {
+		t.Parallel()
+		s, fm := newService()
+		content, err := s.Fetch(mustParse(t, server.URL))
+		require.NoError(t, err)
+
+		if assert.Len(t, content.Images, 1) && assert.Len(t, fm.saved, 1) {
+			for id, args := range fm.saved {
+				assert.Equal(t, fmt.Sprintf("%s/api/v3/files/%s", origin, id), content.Images[0].URL)
+				assert.Equal(t, model.FileTypeOgpImage, args.FileType)
+				assert.Equal(t, "image/png", args.MimeType)
+			}
+			assert.EqualValues(t, 1200, content.Images[0].Width.Int64)
+			assert.EqualValues(t, 50, content.Images[0].Height.Int64)
+			assert.Equal(t, "image/png", content.Images[0].Type.String)
+		}
+	})
+
+	t.Run("delete proxied images", func(t *testing.T) {
+		t.Parallel()
+		s, fm := newService()
+		content, err := s.Fetch(mustParse(t, server.URL))
+		require.NoError(t, err)
+		require.Len(t, content.Images, 1)
+
+		content.Images = append(content.Images, model.OgpMedia{URL: "https://example.com/image.png"})
+		s.DeleteProxiedImages(content)
+		assert.Len(t, fm.saved, 0)
+		assert.Len(t, fm.deleted, 1)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		s := newTestService(Config{AllowHosts: []string{"127.0.0.1"}})
+		content, err := s.Fetch(mustParse(t, server.URL))
+		require.NoError(t, err)
+		if assert.Len(t, content.Images, 2) {
+			assert.Equal(t, server.URL+"/image.png", content.Images[0].URL)
+		}
+	})
+}
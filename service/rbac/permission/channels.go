@@ -17,4 +17,6 @@ const (
 	GetChannelStar = Permission("get_channel_star")
 	// EditChannelStar チャンネルスター編集権限
 	EditChannelStar = Permission("edit_channel_star")
+	// AccessDMChannel ダイレクトメッセージチャンネルへのアクセス権限
+	AccessDMChannel = Permission("access_dm_channel")
 )
@@ -5,6 +5,8 @@ const (
 	GetMyTokens = Permission("get_my_tokens")
 	// RevokeMyToken 自トークン削除権限
 	RevokeMyToken = Permission("revoke_my_token")
+	// CreateMyToken パーソナルアクセストークン作成権限
+	CreateMyToken = Permission("create_my_token")
 	// GetClients クライアント情報取得権限
 	GetClients = Permission("get_clients")
 	// CreateClient 新規クライアント登録権限
@@ -15,4 +17,6 @@ const (
 	DeleteMyClient = Permission("delete_my_client")
 	// ManageOthersClient 他人のClientの管理権限
 	ManageOthersClient = Permission("manage_others_client")
+	// ManageOthersToken 他人のトークンの管理権限
+	ManageOthersToken = Permission("manage_others_token")
 )
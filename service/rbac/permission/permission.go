@@ -69,11 +69,13 @@ var List = []Permission{
 
 	GetMyTokens,
 	RevokeMyToken,
+	CreateMyToken,
 	GetClients,
 	CreateClient,
 	EditMyClient,
 	DeleteMyClient,
 	ManageOthersClient,
+	ManageOthersToken,
 
 	UploadFile,
 	DownloadFile,
@@ -111,6 +113,7 @@ var List = []Permission{
 
 	GetChannelStar,
 	EditChannelStar,
+	AccessDMChannel,
 
 	GetUnread,
 	DeleteUnread,
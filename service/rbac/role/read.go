@@ -15,6 +15,7 @@ var readPerms = []permission.Permission{
 	permission.GetUser,
 	permission.GetMe,
 	permission.GetChannelStar,
+	permission.AccessDMChannel,
 	permission.GetUnread,
 	permission.GetUserTag,
 	permission.GetUserGroup,
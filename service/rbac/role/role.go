@@ -7,7 +7,7 @@ import (
 
 // GetSystemRoles システム定義ロールのRolesを返します
 func GetSystemRoles() Roles {
-	return Roles{
+	roles := Roles{
 		Admin: &systemRole{
 			name:        Admin,
 			oauth2Scope: false,
@@ -39,6 +39,14 @@ func GetSystemRoles() Roles {
 			permissions: permission.PermissionsFromArray(manageBotPerms),
 		},
 	}
+	for name, perms := range scopePerms {
+		roles.Add(&systemRole{
+			name:        name,
+			oauth2Scope: true,
+			permissions: permission.PermissionsFromArray(perms),
+		})
+	}
+	return roles
 }
 
 func SystemRoleModels() []*model.UserRole {
@@ -0,0 +1,101 @@
+package role
+
+import (
+	"github.com/traPtitech/traQ/service/rbac/permission"
+)
+
+// 細粒度OAuth2スコープロール
+//
+// read, write, manage_botよりも細かい単位でクライアントに権限を与えるためのスコープです。
+// DMチャンネルへのアクセスはDMスコープでのみ許可されます。
+const (
+	// ChannelsRead チャンネル情報読み取りスコープ
+	ChannelsRead = "channels:read"
+	// ChannelsWrite チャンネル情報書き込みスコープ
+	ChannelsWrite = "channels:write"
+	// MessagesRead メッセージ読み取りスコープ
+	MessagesRead = "messages:read"
+	// MessagesWrite メッセージ書き込みスコープ
+	MessagesWrite = "messages:write"
+	// DMs ダイレクトメッセージアクセススコープ
+	DMs = "dms"
+	// StampsRead スタンプ読み取りスコープ
+	StampsRead = "stamps:read"
+	// StampsWrite スタンプ書き込みスコープ
+	StampsWrite = "stamps:write"
+	// UsersRead ユーザー情報読み取りスコープ
+	UsersRead = "users:read"
+	// UsersWrite ユーザー情報書き込みスコープ
+	UsersWrite = "users:write"
+	// FilesRead ファイル読み取りスコープ
+	FilesRead = "files:read"
+	// FilesWrite ファイル書き込みスコープ
+	FilesWrite = "files:write"
+)
+
+var scopePerms = map[string][]permission.Permission{
+	ChannelsRead: {
+		permission.GetChannel,
+		permission.GetChannelSubscription,
+		permission.GetChannelStar,
+	},
+	ChannelsWrite: {
+		permission.CreateChannel,
+		permission.EditChannelTopic,
+		permission.EditChannelSubscription,
+		permission.EditChannelStar,
+	},
+	MessagesRead: {
+		permission.GetChannel,
+		permission.GetMessage,
+		permission.GetUnread,
+		permission.ConnectNotificationStream,
+	},
+	MessagesWrite: {
+		permission.PostMessage,
+		permission.EditMessage,
+		permission.DeleteMessage,
+		permission.ReportMessage,
+		permission.CreateMessagePin,
+		permission.DeleteMessagePin,
+		permission.DeleteUnread,
+	},
+	DMs: {
+		permission.AccessDMChannel,
+	},
+	StampsRead: {
+		permission.GetStamp,
+		permission.GetMyStampHistory,
+		permission.GetStampPalette,
+	},
+	StampsWrite: {
+		permission.CreateStamp,
+		permission.EditStamp,
+		permission.AddMessageStamp,
+		permission.RemoveMessageStamp,
+		permission.CreateStampPalette,
+		permission.EditStampPalette,
+		permission.DeleteStampPalette,
+	},
+	UsersRead: {
+		permission.GetUser,
+		permission.GetMe,
+		permission.GetUserTag,
+		permission.GetUserGroup,
+	},
+	UsersWrite: {
+		permission.EditMe,
+		permission.ChangeMyIcon,
+		permission.EditUserTag,
+		permission.CreateUserGroup,
+		permission.EditUserGroup,
+		permission.DeleteUserGroup,
+	},
+	FilesRead: {
+		permission.DownloadFile,
+	},
+	FilesWrite: {
+		permission.UploadFile,
+		permission.DeleteFile,
+	},
+}
@@ -15,6 +15,7 @@ var userPerms = []permission.Permission{
 	permission.DeleteMySessions,
 	permission.GetMyTokens,
 	permission.RevokeMyToken,
+	permission.CreateMyToken,
 	permission.GetMyExternalAccount,
 	permission.EditMyExternalAccount,
 	permission.GetClients,
@@ -21,6 +21,7 @@ var writePerms = []permission.Permission{
 	permission.EditMe,
 	permission.ChangeMyIcon,
 	permission.EditChannelStar,
+	permission.AccessDMChannel,
 	permission.DeleteUnread,
 	permission.EditUserTag,
 	permission.CreateUserGroup,
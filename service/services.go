@@ -2,35 +2,51 @@ package service
 
 import (
 	"github.com/traPtitech/traQ/service/bot"
+	"github.com/traPtitech/traQ/service/bridge"
+	"github.com/traPtitech/traQ/service/call"
 	"github.com/traPtitech/traQ/service/channel"
 	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/dnd"
+	"github.com/traPtitech/traQ/service/email"
 	"github.com/traPtitech/traQ/service/exevent"
 	"github.com/traPtitech/traQ/service/fcm"
 	"github.com/traPtitech/traQ/service/file"
 	"github.com/traPtitech/traQ/service/imaging"
 	"github.com/traPtitech/traQ/service/message"
 	"github.com/traPtitech/traQ/service/notification"
+	"github.com/traPtitech/traQ/service/ogp"
 	"github.com/traPtitech/traQ/service/rbac"
+	"github.com/traPtitech/traQ/service/sfu"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webpush"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/service/ws"
 )
 
 type Services struct {
 	BOT                  bot.Service
+	CallRecorder         *call.Recorder
 	ChannelManager       channel.Manager
 	OnlineCounter        *counter.OnlineCounter
 	UnreadMessageCounter counter.UnreadMessageCounter
 	MessageCounter       counter.MessageCounter
 	ChannelCounter       counter.ChannelCounter
+	DND                  *dnd.Manager
+	Email                *email.Service
 	StampThrottler       *exevent.StampThrottler
 	FCM                  fcm.Client
 	FileManager          file.Manager
 	Imaging              imaging.Processor
 	MessageManager       message.Manager
 	Notification         *notification.Service
+	OGP                  *ogp.Service
 	RBAC                 rbac.RBAC
+	Relay                *bridge.Relay
+	SFU                  *sfu.SFU
+	Typing               *typing.Manager
 	ViewerManager        *viewer.Manager
+	WebPush              *webpush.Client
 	WebRTCv3             *webrtcv3.Manager
 	WS                   *ws.Streamer
 }
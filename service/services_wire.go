@@ -8,19 +8,27 @@ import (
 
 var ProviderSet = wire.NewSet(wire.FieldsOf(new(*Services),
 	"BOT",
+	"CallRecorder",
 	"ChannelManager",
 	"OnlineCounter",
 	"UnreadMessageCounter",
 	"MessageCounter",
 	"ChannelCounter",
+	"DND",
+	"Email",
 	"StampThrottler",
 	"FCM",
 	"FileManager",
 	"Imaging",
 	"MessageManager",
 	"Notification",
+	"OGP",
 	"RBAC",
+	"Relay",
+	"SFU",
+	"Typing",
 	"ViewerManager",
+	"WebPush",
 	"WebRTCv3",
 	"WS",
 ))
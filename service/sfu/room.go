@@ -0,0 +1,188 @@
+package sfu
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"go.uber.org/zap"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// keyFrameInterval 映像の送信者にキーフレームを要求する間隔
+	keyFrameInterval = 3 * time.Second
+	// maxSyncAttempts シグナリングの同期を連続して試行する最大回数
+	maxSyncAttempts = 25
+	// syncRetryInterval シグナリングの同期に失敗し続けた場合に再試行するまでの時間
+	syncRetryInterval = 3 * time.Second
+)
+
+type peer struct {
+	Peer
+	room *room
+	pc   *webrtc.PeerConnection
+}
+
+// room 通話ルーム
+type room struct {
+	channelID uuid.UUID
+	logger    *zap.Logger
+	peers     map[*peer]struct{}
+	// tracks 参加者から受信し、他の参加者に中継するトラック
+	tracks map[string]*webrtc.TrackLocalStaticRTP
+	mu     sync.Mutex
+}
+
+func newRoom(channelID uuid.UUID, logger *zap.Logger) *room {
+	return &room{
+		channelID: channelID,
+		logger:    logger.With(zap.Stringer("channelID", channelID)),
+		peers:     map[*peer]struct{}{},
+		tracks:    map[string]*webrtc.TrackLocalStaticRTP{},
+	}
+}
+
+func (r *room) addPeer(p *peer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peers[p] = struct{}{}
+}
+
+// removePeer 参加者を削除し、ルームが空になったかどうかを返します
+func (r *room) removePeer(p *peer) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.peers, p)
+	return len(r.peers) == 0
+}
+
+// forward 参加者から受信したトラックを他の参加者に中継します
+//
+// トラックが終了するまでブロックします。
+func (r *room) forward(from *peer, remote *webrtc.TrackRemote) {
+	// 受信側が送信者を識別できるように、ストリームIDには送信者のユーザーIDを用いる
+	local, err := webrtc.NewTrackLocalStaticRTP(remote.Codec().RTPCodecCapability, remote.ID(), from.UserID().String())
+	if err != nil {
+		r.logger.Error("failed to create local track", zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	r.tracks[local.ID()] = local
+	r.mu.Unlock()
+	r.signalPeers()
+
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		r.mu.Lock()
+		delete(r.tracks, local.ID())
+		r.mu.Unlock()
+		r.signalPeers()
+	}()
+
+	if remote.Kind() == webrtc.RTPCodecTypeVideo {
+		// 途中から受信した参加者が映像を復号できるように、定期的にキーフレームを要求する
+		go func() {
+			ticker := time.NewTicker(keyFrameInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					_ = from.pc.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}})
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := remote.Read(buf)
+		if err != nil {
+			return
+		}
+		if _, err := local.Write(buf[:n]); err != nil && err != io.ErrClosedPipe {
+			return
+		}
+	}
+}
+
+// setAnswer 参加者から受信したanswerを設定します
+//
+// PeerConnectionのofferの生成とanswerの設定は並行して行えないため、ルームのロックを取得して行います。
+func (r *room) setAnswer(p *peer, sdp string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return p.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: sdp})
+}
+
+// signalPeers 各参加者の送信トラックをルームのトラックと同期し、offerを送信します
+func (r *room) signalPeers() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for attempt := 0; attempt < maxSyncAttempts; attempt++ {
+		if !r.attemptSync() {
+			return
+		}
+	}
+
+	// 参加者の状態が安定するまで待ってから再試行する
+	go func() {
+		time.Sleep(syncRetryInterval)
+		r.signalPeers()
+	}()
+}
+
+// attemptSync 同期を試行し、再試行が必要かどうかを返します
+func (r *room) attemptSync() (retry bool) {
+	for p := range r.peers {
+		if p.pc.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			continue
+		}
+
+		existing := map[string]bool{}
+		for _, sender := range p.pc.GetSenders() {
+			if sender.Track() == nil {
+				continue
+			}
+			existing[sender.Track().ID()] = true
+			// 終了したトラックの送信を停止する
+			if _, ok := r.tracks[sender.Track().ID()]; !ok {
+				if err := p.pc.RemoveTrack(sender); err != nil {
+					return true
+				}
+			}
+		}
+		// 自分が送信しているトラックは中継しない
+		for _, receiver := range p.pc.GetReceivers() {
+			if receiver.Track() == nil {
+				continue
+			}
+			existing[receiver.Track().ID()] = true
+		}
+		for id, track := range r.tracks {
+			if !existing[id] {
+				if _, err := p.pc.AddTrack(track); err != nil {
+					return true
+				}
+			}
+		}
+
+		offer, err := p.pc.CreateOffer(nil)
+		if err != nil {
+			return true
+		}
+		if err := p.pc.SetLocalDescription(offer); err != nil {
+			return true
+		}
+		if err := p.SendSignal(&Signal{Type: SignalOffer, ChannelID: r.channelID, SDP: offer.SDP}); err != nil {
+			r.logger.Warn("failed to send offer", zap.Error(err))
+		}
+	}
+	return false
+}
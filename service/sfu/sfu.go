@@ -0,0 +1,263 @@
+package sfu
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/pion/interceptor"
+	"github.com/pion/webrtc/v3"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/service/channel"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"go.uber.org/zap"
+	"sync"
+)
+
+const (
+	// sessionID webrtcv3.Managerに設定するSFUのセッションID
+	sessionID = "sfu"
+	// sessionState webrtcv3.Managerに設定するSFUのセッションの状態
+	sessionState = "joined"
+)
+
+var (
+	// ErrNotJoined 通話ルームに参加していません
+	ErrNotJoined = errors.New("not joined")
+	// ErrUnknownSignal 不明なシグナリングメッセージです
+	ErrUnknownSignal = errors.New("unknown signal")
+	// ErrChannelNotAccessible チャンネルにアクセスできません
+	ErrChannelNotAccessible = errors.New("channel is not accessible")
+)
+
+// Config SFU設定
+type Config struct {
+	// ICEServers サーバーが使用するSTUN/TURNサーバーのURL
+	ICEServers []string
+	// NAT1To1IPs NAT環境下でICE candidateとして通知するIPアドレス
+	NAT1To1IPs []string
+	// UDPPortMin メディア通信に使用するUDPポート範囲の最小値 (0の場合は制限しない)
+	UDPPortMin uint16
+	// UDPPortMax メディア通信に使用するUDPポート範囲の最大値 (0の場合は制限しない)
+	UDPPortMax uint16
+}
+
+// SFU WebRTCのメディアを中継するSelective Forwarding Unit
+//
+// チャンネル毎の通話ルームの参加者は、webrtcv3.Managerのユーザー状態として管理されます。
+// シグナリングはサーバーから各参加者にofferを送信し、参加者がanswerを返す形で行われます。
+type SFU struct {
+	webrtc *webrtcv3.Manager
+	cm     channel.Manager
+	api    *webrtc.API
+	config webrtc.Configuration
+	logger *zap.Logger
+
+	peers map[string]*peer
+	rooms map[uuid.UUID]*room
+	mu    sync.Mutex
+}
+
+// NewSFU SFUを生成します
+func NewSFU(hub *hub.Hub, manager *webrtcv3.Manager, cm channel.Manager, config Config, logger *zap.Logger) (*SFU, error) {
+	se := webrtc.SettingEngine{}
+	if config.UDPPortMin != 0 || config.UDPPortMax != 0 {
+		if err := se.SetEphemeralUDPPortRange(config.UDPPortMin, config.UDPPortMax); err != nil {
+			return nil, err
+		}
+	}
+	if len(config.NAT1To1IPs) > 0 {
+		se.SetNAT1To1IPs(config.NAT1To1IPs, webrtc.ICECandidateTypeHost)
+	}
+
+	me := &webrtc.MediaEngine{}
+	if err := me.RegisterDefaultCodecs(); err != nil {
+		return nil, err
+	}
+	ir := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(me, ir); err != nil {
+		return nil, err
+	}
+
+	s := &SFU{
+		webrtc: manager,
+		cm:     cm,
+		api:    webrtc.NewAPI(webrtc.WithMediaEngine(me), webrtc.WithInterceptorRegistry(ir), webrtc.WithSettingEngine(se)),
+		logger: logger.Named("sfu"),
+		peers:  map[string]*peer{},
+		rooms:  map[uuid.UUID]*room{},
+	}
+	if len(config.ICEServers) > 0 {
+		s.config.ICEServers = []webrtc.ICEServer{{URLs: config.ICEServers}}
+	}
+
+	go func() {
+		for ev := range hub.NonBlockingSubscribe(100, event.UserWebRTCv3StateChanged).Receiver {
+			s.onStateChanged(ev)
+		}
+	}()
+	return s, nil
+}
+
+// HandleSignal 接続から受信したシグナリングメッセージを処理します
+func (s *SFU) HandleSignal(p Peer, sig *Signal) error {
+	switch sig.Type {
+	case SignalJoin:
+		return s.Join(p, sig.ChannelID)
+	case SignalLeave:
+		s.Leave(p)
+		return nil
+	case SignalAnswer:
+		pr, ok := s.getPeer(p)
+		if !ok {
+			return ErrNotJoined
+		}
+		return pr.room.setAnswer(pr, sig.SDP)
+	case SignalCandidate:
+		pr, ok := s.getPeer(p)
+		if !ok {
+			return ErrNotJoined
+		}
+		if sig.Candidate == nil {
+			return nil
+		}
+		return pr.pc.AddICECandidate(*sig.Candidate)
+	default:
+		return ErrUnknownSignal
+	}
+}
+
+// Join 指定したチャンネルの通話ルームに参加します
+//
+// 既に別の通話ルームに参加している場合は、そのルームから退出します。
+// チャンネルにアクセスできない場合は、ErrChannelNotAccessibleを返します。
+func (s *SFU) Join(p Peer, channelID uuid.UUID) error {
+	ok, err := s.cm.IsChannelAccessibleToUser(p.UserID(), channelID)
+	if err != nil {
+		return fmt.Errorf("failed to IsChannelAccessibleToUser: %w", err)
+	}
+	if !ok {
+		return ErrChannelNotAccessible
+	}
+
+	s.Leave(p)
+
+	if err := s.webrtc.SetState(p.Key(), p.UserID(), channelID, map[string]string{sessionID: sessionState}); err != nil {
+		return err
+	}
+
+	pc, err := s.newPeerConnection()
+	if err != nil {
+		_ = s.webrtc.ResetState(p.Key(), p.UserID())
+		return fmt.Errorf("failed to create peer connection: %w", err)
+	}
+
+	s.mu.Lock()
+	r, ok := s.rooms[channelID]
+	if !ok {
+		r = newRoom(channelID, s.logger)
+		s.rooms[channelID] = r
+	}
+	pr := &peer{Peer: p, room: r, pc: pc}
+	s.peers[p.Key()] = pr
+	r.addPeer(pr)
+	s.mu.Unlock()
+
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c == nil {
+			return
+		}
+		candidate := c.ToJSON()
+		_ = p.SendSignal(&Signal{Type: SignalCandidate, ChannelID: channelID, Candidate: &candidate})
+	})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed {
+			go s.leave(pr)
+		}
+	})
+	pc.OnTrack(func(t *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		r.forward(pr, t)
+	})
+
+	r.signalPeers()
+	return nil
+}
+
+// Leave 参加している通話ルームから退出します
+func (s *SFU) Leave(p Peer) {
+	if pr, ok := s.getPeer(p); ok {
+		s.leave(pr)
+	}
+}
+
+func (s *SFU) leave(pr *peer) {
+	s.mu.Lock()
+	if s.peers[pr.Key()] != pr {
+		// 既に退出済み
+		s.mu.Unlock()
+		return
+	}
+	delete(s.peers, pr.Key())
+	empty := pr.room.removePeer(pr)
+	if empty {
+		delete(s.rooms, pr.room.channelID)
+	}
+	s.mu.Unlock()
+
+	if err := pr.pc.Close(); err != nil {
+		s.logger.Warn("failed to close peer connection", zap.Error(err))
+	}
+	_ = s.webrtc.ResetState(pr.Key(), pr.UserID())
+	if !empty {
+		pr.room.signalPeers()
+	}
+}
+
+func (s *SFU) getPeer(p Peer) (*peer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pr, ok := s.peers[p.Key()]
+	return pr, ok
+}
+
+func (s *SFU) newPeerConnection() (*webrtc.PeerConnection, error) {
+	pc, err := s.api.NewPeerConnection(s.config)
+	if err != nil {
+		return nil, err
+	}
+	// 参加者からのメディアを受信する
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeAudio, webrtc.RTPCodecTypeVideo} {
+		if _, err := pc.AddTransceiverFromKind(kind, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+			_ = pc.Close()
+			return nil, err
+		}
+	}
+	return pc, nil
+}
+
+// onStateChanged webrtcv3.Managerのユーザー状態が通話ルームから外れた場合に退出させます
+func (s *SFU) onStateChanged(ev hub.Message) {
+	if event.IsRemote(ev) {
+		// 他のインスタンスの状態はこのインスタンスのピアに関係しない
+		return
+	}
+	userID := ev.Fields["user_id"].(uuid.UUID)
+	channelID := ev.Fields["channel_id"].(uuid.UUID)
+	sessions := ev.Fields["sessions"].(map[string]string)
+
+	s.mu.Lock()
+	targets := make([]*peer, 0)
+	for _, pr := range s.peers {
+		if pr.UserID() != userID {
+			continue
+		}
+		if _, ok := sessions[sessionID]; !ok || pr.room.channelID != channelID {
+			targets = append(targets, pr)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, pr := range targets {
+		s.leave(pr)
+	}
+}
@@ -0,0 +1,200 @@
+package sfu
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/service/channel/mock_channel"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// testPeer SFUに接続するクライアント
+type testPeer struct {
+	key    string
+	userID uuid.UUID
+	sfu    *SFU
+	pc     *webrtc.PeerConnection
+	t      *testing.T
+	// signals サーバーから受信したシグナリングメッセージを順番に処理するためのキュー
+	signals chan *Signal
+}
+
+func newTestPeer(t *testing.T, s *SFU) *testPeer {
+	t.Helper()
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	require.NoError(t, err)
+	p := &testPeer{
+		key:     uuid.Must(uuid.NewV4()).String(),
+		userID:  uuid.Must(uuid.NewV4()),
+		sfu:     s,
+		pc:      pc,
+		t:       t,
+		signals: make(chan *Signal, 100),
+	}
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		if c == nil {
+			return
+		}
+		candidate := c.ToJSON()
+		_ = s.HandleSignal(p, &Signal{Type: SignalCandidate, Candidate: &candidate})
+	})
+	go p.handleSignals()
+	return p
+}
+
+func (p *testPeer) Key() string {
+	return p.key
+}
+
+func (p *testPeer) UserID() uuid.UUID {
+	return p.userID
+}
+
+func (p *testPeer) SendSignal(sig *Signal) error {
+	p.signals <- sig
+	return nil
+}
+
+func (p *testPeer) handleSignals() {
+	for sig := range p.signals {
+		switch sig.Type {
+		case SignalOffer:
+			if err := p.pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sig.SDP}); err != nil {
+				continue
+			}
+			answer, err := p.pc.CreateAnswer(nil)
+			if err != nil {
+				continue
+			}
+			if err := p.pc.SetLocalDescription(answer); err != nil {
+				continue
+			}
+			_ = p.sfu.HandleSignal(p, &Signal{Type: SignalAnswer, ChannelID: sig.ChannelID, SDP: answer.SDP})
+		case SignalCandidate:
+			_ = p.pc.AddICECandidate(*sig.Candidate)
+		}
+	}
+}
+
+// accessibleChannelManager 全てのチャンネルにアクセスできるチャンネルマネージャーのモックを作成します
+func accessibleChannelManager(t *testing.T) *mock_channel.MockManager {
+	t.Helper()
+	cm := mock_channel.NewMockManager(gomock.NewController(t))
+	cm.EXPECT().IsChannelAccessibleToUser(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	return cm
+}
+
+func TestSFU(t *testing.T) {
+	t.Parallel()
+	assert := assert.New(t)
+	require := require.New(t)
+
+	h := hub.New()
+	m := webrtcv3.NewManager(h)
+	s, err := NewSFU(h, m, accessibleChannelManager(t), Config{}, zap.NewNop())
+	require.NoError(err)
+
+	cid := uuid.Must(uuid.NewV4())
+	sender := newTestPeer(t, s)
+	receiver := newTestPeer(t, s)
+	defer sender.pc.Close()
+	defer receiver.pc.Close()
+
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus}, "audio", "test")
+	require.NoError(err)
+	_, err = sender.pc.AddTrack(track)
+	require.NoError(err)
+
+	received := make(chan *webrtc.TrackRemote, 1)
+	receiver.pc.OnTrack(func(t *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		received <- t
+	})
+
+	require.NoError(s.Join(sender, cid))
+	require.NoError(s.Join(receiver, cid))
+
+	// webrtcv3.Managerの状態に反映される
+	n := 0
+	m.IterateStates(func(state webrtcv3.ChannelState) {
+		if state.ChannelID() == cid {
+			n = len(state.Users())
+		}
+	})
+	assert.Equal(2, n)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				_ = track.WriteSample(media.Sample{Data: []byte{0xfc, 0xff, 0xfe}, Duration: 20 * time.Millisecond})
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	select {
+	case remote := <-received:
+		// ストリームIDは送信者のユーザーID
+		assert.Equal(sender.userID.String(), remote.StreamID())
+		assert.Equal(webrtc.RTPCodecTypeAudio, remote.Kind())
+	case <-time.After(10 * time.Second):
+		assert.Fail("track was not forwarded")
+	}
+
+	// 退出すると状態が削除される
+	s.Leave(sender)
+	s.Leave(receiver)
+	m.IterateStates(func(state webrtcv3.ChannelState) {
+		assert.NotEqual(cid, state.ChannelID())
+	})
+	_, ok := s.getPeer(sender)
+	assert.False(ok)
+}
+
+func TestSFU_HandleSignal(t *testing.T) {
+	t.Parallel()
+
+	h := hub.New()
+	s, err := NewSFU(h, webrtcv3.NewManager(h), accessibleChannelManager(t), Config{}, zap.NewNop())
+	require.NoError(t, err)
+	p := newTestPeer(t, s)
+	defer p.pc.Close()
+
+	assert.Equal(t, ErrNotJoined, s.HandleSignal(p, &Signal{Type: SignalAnswer, SDP: "v=0"}))
+	assert.Equal(t, ErrUnknownSignal, s.HandleSignal(p, &Signal{Type: "unknown"}))
+	assert.NoError(t, s.HandleSignal(p, &Signal{Type: SignalLeave}))
+}
+
+func TestSFU_JoinNotAccessible(t *testing.T) {
+	t.Parallel()
+
+	h := hub.New()
+	m := webrtcv3.NewManager(h)
+	cm := mock_channel.NewMockManager(gomock.NewController(t))
+	s, err := NewSFU(h, m, cm, Config{}, zap.NewNop())
+	require.NoError(t, err)
+	p := newTestPeer(t, s)
+	defer p.pc.Close()
+
+	dmID := uuid.Must(uuid.NewV4())
+	cm.EXPECT().IsChannelAccessibleToUser(p.UserID(), dmID).Return(false, nil)
+
+	assert.Equal(t, ErrChannelNotAccessible, s.Join(p, dmID))
+	_, ok := s.getPeer(p)
+	assert.False(t, ok)
+	m.IterateStates(func(state webrtcv3.ChannelState) {
+		assert.NotEqual(t, dmID, state.ChannelID())
+	})
+}
@@ -0,0 +1,37 @@
+package sfu
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// SignalJoin 通話ルームに参加する (クライアント -> サーバー)
+	SignalJoin = "join"
+	// SignalLeave 通話ルームから退出する (クライアント -> サーバー)
+	SignalLeave = "leave"
+	// SignalOffer SDP offer (サーバー -> クライアント)
+	SignalOffer = "offer"
+	// SignalAnswer SDP answer (クライアント -> サーバー)
+	SignalAnswer = "answer"
+	// SignalCandidate ICE candidate (双方向)
+	SignalCandidate = "candidate"
+)
+
+// Signal シグナリングメッセージ
+type Signal struct {
+	Type      string                   `json:"type"`
+	ChannelID uuid.UUID                `json:"channelId"`
+	SDP       string                   `json:"sdp,omitempty"`
+	Candidate *webrtc.ICECandidateInit `json:"candidate,omitempty"`
+}
+
+// Peer シグナリングを行う接続
+type Peer interface {
+	// Key 接続のキー
+	Key() string
+	// UserID 接続のユーザーID
+	UserID() uuid.UUID
+	// SendSignal 接続にシグナリングメッセージを送信します
+	SendSignal(sig *Signal) error
+}
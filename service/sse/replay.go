@@ -0,0 +1,116 @@
+package sse
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/utils/ringbuf"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// replayBufferSize ユーザー毎に保持する再送用イベントの最大数
+	replayBufferSize = 200
+	// replayRetention 全コネクション切断後に再送用イベントを保持する期間
+	replayRetention = 5 * time.Minute
+)
+
+// eventLog ユーザー毎の再送用イベントバッファ
+type eventLog struct {
+	buf        *ringbuf.Buffer
+	clients    int
+	detachedAt time.Time
+}
+
+// attach ユーザーの再送用バッファを用意します
+func (s *Streamer) attach(userID uuid.UUID) {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+
+	now := time.Now()
+	for id, l := range s.logs {
+		if l.clients == 0 && now.Sub(l.detachedAt) > replayRetention {
+			delete(s.logs, id)
+		}
+	}
+
+	l, ok := s.logs[userID]
+	if !ok {
+		l = &eventLog{buf: ringbuf.New(replayBufferSize, atomic.LoadUint64(&s.seq))}
+		s.logs[userID] = l
+	}
+	l.clients++
+}
+
+// detach ユーザーのコネクションが切断されたことを記録します
+func (s *Streamer) detach(userID uuid.UUID) {
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+
+	if l, ok := s.logs[userID]; ok {
+		l.clients--
+		if l.clients == 0 {
+			l.detachedAt = time.Now()
+		}
+	}
+}
+
+// record イベントにシーケンス番号を付与し、対象ユーザーの再送用バッファに追加します
+//
+// userIDsがnilの場合は全ユーザーが対象です。
+func (s *Streamer) record(data *EventData, userIDs ...uuid.UUID) *EventData {
+	d := *data
+	d.ID = atomic.AddUint64(&s.seq, 1)
+
+	s.logsMu.Lock()
+	defer s.logsMu.Unlock()
+	if userIDs == nil {
+		for _, l := range s.logs {
+			l.buf.Push(d.ID, &d)
+		}
+	} else {
+		for _, id := range userIDs {
+			if l, ok := s.logs[id]; ok {
+				l.buf.Push(d.ID, &d)
+			}
+		}
+	}
+	return &d
+}
+
+// replay Last-Event-IDより後のイベントを再送し、再送した最後のシーケンス番号を返します
+//
+// 欠落があり再送できない場合は、RESYNC_REQUIREDイベントを送信します。
+func (s *Streamer) replay(rw http.ResponseWriter, userID uuid.UUID, lastEventID string) uint64 {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	current := atomic.LoadUint64(&s.seq)
+	resync := &EventData{
+		EventType: "RESYNC_REQUIRED",
+		Payload: map[string]interface{}{
+			"seq": current,
+		},
+	}
+
+	lastSeq, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil || lastSeq > current {
+		resync.write(rw)
+		return current
+	}
+
+	s.logsMu.Lock()
+	l := s.logs[userID]
+	s.logsMu.Unlock()
+	entries, ok := l.buf.After(lastSeq)
+	if !ok {
+		resync.write(rw)
+		return current
+	}
+
+	for _, e := range entries {
+		e.Value.(*EventData).write(rw)
+	}
+	return current
+}
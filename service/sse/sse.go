@@ -8,6 +8,7 @@ import (
 	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/router/extension"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -23,6 +24,11 @@ type Streamer struct {
 	connect    chan *sseClient
 	disconnect chan *sseClient
 	stop       chan struct{}
+	logs       map[uuid.UUID]*eventLog
+	logsMu     sync.Mutex
+	// seq 最後に発行したイベントのシーケンス番号
+	seq   uint64
+	seqMu sync.Mutex
 }
 
 // NewStreamer SSEストリーマーを作成します
@@ -32,6 +38,7 @@ func NewStreamer(hub *hub.Hub) *Streamer {
 		connect:    make(chan *sseClient),
 		disconnect: make(chan *sseClient, 10),
 		stop:       make(chan struct{}),
+		logs:       make(map[uuid.UUID]*eventLog),
 	}
 	go func() {
 		for {
@@ -42,16 +49,24 @@ func NewStreamer(hub *hub.Hub) *Streamer {
 				return
 
 			case c := <-s.connect:
-				arr, ok := s.loadClients(c.userID)
-				if !ok {
-					arr = make(map[uuid.UUID]*sseClient)
-					s.storeClients(c.userID, arr)
+				// 配信中のマップを変更しないように、コネクションの増減時はマップを作り直して置き換える
+				arr, _ := s.loadClients(c.userID)
+				next := make(map[uuid.UUID]*sseClient, len(arr)+1)
+				for id, v := range arr {
+					next[id] = v
 				}
-				arr[c.connectionID] = c
+				next[c.connectionID] = c
+				s.storeClients(c.userID, next)
 
 			case c := <-s.disconnect:
 				arr, _ := s.loadClients(c.userID)
-				delete(arr, c.connectionID)
+				next := make(map[uuid.UUID]*sseClient, len(arr))
+				for id, v := range arr {
+					if id != c.connectionID {
+						next[id] = v
+					}
+				}
+				s.storeClients(c.userID, next)
 			}
 		}
 	}()
@@ -65,12 +80,16 @@ func (s *Streamer) Dispose() {
 
 // Broadcast イベントデータを全コネクションに配信します
 func (s *Streamer) Broadcast(data *EventData) {
-	s.broadcast(data)
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+	s.broadcast(s.record(data))
 }
 
 // Multicast イベントデータを指定ユーザーの全コネクションに配信します
 func (s *Streamer) Multicast(userID uuid.UUID, data *EventData) {
-	s.multicast(userID, data)
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+	s.multicast(userID, s.record(data, userID))
 }
 
 // ServeHTTP http.Handlerインターフェイスの実装
@@ -87,6 +106,8 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		connectionID: uuid.Must(uuid.NewV4()),
 		send:         make(chan *EventData, 100),
 	}
+	s.attach(client.userID)
+	defer s.detach(client.userID)
 	s.connect <- client
 
 	sseConnectionsCounter.Inc()
@@ -111,6 +132,12 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	defer t.Stop()
 
 	fl := rw.(http.Flusher)
+
+	// 切断中に配信されたイベントを再送
+	var replayed uint64
+	if lastEventID := r.Header.Get("Last-Event-ID"); len(lastEventID) > 0 {
+		replayed = s.replay(rw, client.userID, lastEventID)
+	}
 	fl.Flush()
 StreamFor:
 	for {
@@ -125,6 +152,10 @@ StreamFor:
 			break StreamFor
 
 		case m := <-client.send: // イベントを送信
+			if m.ID <= replayed {
+				// 再送済み
+				continue
+			}
 			m.write(rw)
 			fl.Flush()
 
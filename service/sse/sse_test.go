@@ -0,0 +1,161 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/router/extension"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	id        string
+	eventType string
+	data      string
+}
+
+// readEvent コメント行を読み飛ばして次のイベントを読み込みます
+func readEvent(t *testing.T, r *bufio.Reader) testEvent {
+	t.Helper()
+	var ev testEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case len(line) == 0:
+			if len(ev.eventType) > 0 {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamer_LastEventID(t *testing.T) {
+	t.Parallel()
+
+	s := NewStreamer(hub.New())
+	defer s.Dispose()
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		uid := uuid.FromStringOrNil(r.URL.Query().Get("user"))
+		s.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), extension.CtxUserIDKey, uid)))
+	}))
+	defer server.Close()
+
+	// waitLoop ストリーマーのループが保留中の接続・切断を全て処理するまで待つ
+	waitLoop := func() {
+		for {
+			// ループが次の接続を受け取った時点で、それより前に受け取った接続・切断は処理済み
+			s.connect <- &sseClient{connectionID: uuid.Must(uuid.NewV4()), disconnected: true}
+			if len(s.disconnect) == 0 {
+				s.connect <- &sseClient{connectionID: uuid.Must(uuid.NewV4()), disconnected: true}
+				return
+			}
+		}
+	}
+	attached := func(uid uuid.UUID) bool {
+		s.logsMu.Lock()
+		defer s.logsMu.Unlock()
+		l, ok := s.logs[uid]
+		return ok && l.clients > 0
+	}
+	connect := func(t *testing.T, uid uuid.UUID, lastEventID string) (*bufio.Reader, func()) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?user="+uid.String(), nil)
+		require.NoError(t, err)
+		if len(lastEventID) > 0 {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		waitLoop()
+		return bufio.NewReader(res.Body), func() {
+			cancel()
+			_ = res.Body.Close()
+			require.Eventually(t, func() bool { return !attached(uid) }, time.Second, 10*time.Millisecond)
+			waitLoop()
+		}
+	}
+	type payload struct {
+		N int `json:"n"`
+	}
+	readTest := func(t *testing.T, r *bufio.Reader, n int) uint64 {
+		t.Helper()
+		ev := readEvent(t, r)
+		require.Equal(t, "TEST", ev.eventType)
+		require.JSONEq(t, `{"n":`+strconv.Itoa(n)+`}`, ev.data)
+		id, err := strconv.ParseUint(ev.id, 10, 64)
+		require.NoError(t, err, "id line is missing")
+		return id
+	}
+
+	t.Run("replay missed events", func(t *testing.T) {
+		uid := uuid.Must(uuid.NewV4())
+
+		r, disconnect := connect(t, uid, "")
+		s.Multicast(uid, &EventData{EventType: "TEST", Payload: payload{N: 1}})
+		s.Multicast(uid, &EventData{EventType: "TEST", Payload: payload{N: 2}})
+		last := readTest(t, r, 1)
+		id2 := readTest(t, r, 2)
+		disconnect()
+
+		// 切断中のイベント
+		s.Multicast(uid, &EventData{EventType: "TEST", Payload: payload{N: 3}})
+		s.Broadcast(&EventData{EventType: "TEST", Payload: payload{N: 4}})
+
+		r, disconnect = connect(t, uid, strconv.FormatUint(last, 10))
+		defer disconnect()
+		// 元のidのまま順番に再送される
+		assert.Equal(t, id2, readTest(t, r, 2))
+		id3 := readTest(t, r, 3)
+		id4 := readTest(t, r, 4)
+		assert.True(t, id2 < id3 && id3 < id4)
+
+		// 再送したイベントは重複して配信されない
+		s.Multicast(uid, &EventData{EventType: "TEST", Payload: payload{N: 5}})
+		assert.Greater(t, readTest(t, r, 5), id4)
+	})
+
+	t.Run("resync required when events were discarded", func(t *testing.T) {
+		uid := uuid.Must(uuid.NewV4())
+
+		r, disconnect := connect(t, uid, "")
+		s.Multicast(uid, &EventData{EventType: "TEST", Payload: payload{N: 0}})
+		last := readTest(t, r, 0)
+		disconnect()
+
+		// 再送用バッファから溢れる数のイベント
+		for i := 1; i <= replayBufferSize+1; i++ {
+			s.Multicast(uid, &EventData{EventType: "TEST", Payload: payload{N: i}})
+		}
+
+		r, disconnect = connect(t, uid, strconv.FormatUint(last, 10))
+		defer disconnect()
+		ev := readEvent(t, r)
+		assert.Equal(t, "RESYNC_REQUIRED", ev.eventType)
+		assert.Empty(t, ev.id)
+	})
+
+	t.Run("resync required for invalid id", func(t *testing.T) {
+		uid := uuid.Must(uuid.NewV4())
+
+		r, disconnect := connect(t, uid, "invalid")
+		defer disconnect()
+		assert.Equal(t, "RESYNC_REQUIRED", readEvent(t, r).eventType)
+	})
+}
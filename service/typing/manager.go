@@ -0,0 +1,133 @@
+package typing
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/channel"
+	"sync"
+	"time"
+)
+
+const (
+	// Timeout 最後の入力通知から入力中状態が解除されるまでの時間
+	Timeout = 5 * time.Second
+	// expireInterval 入力中状態の期限切れを確認する間隔
+	expireInterval = time.Second
+)
+
+// ErrChannelNotAccessible チャンネルにアクセスできません
+var ErrChannelNotAccessible = errors.New("channel is not accessible")
+
+// Manager 入力中状態マネージャー
+//
+// 入力中状態は最後の入力通知からTimeoutが経過するか、メッセージを投稿すると解除されます。
+// 入力中状態が続いている間の入力通知ではイベントは発行されません。
+type Manager struct {
+	hub     *hub.Hub
+	cm      channel.Manager
+	expires map[key]time.Time
+	mu      sync.Mutex
+}
+
+type key struct {
+	userID    uuid.UUID
+	channelID uuid.UUID
+}
+
+// NewManager 入力中状態マネージャーを生成します
+func NewManager(hub *hub.Hub, cm channel.Manager) *Manager {
+	m := &Manager{
+		hub:     hub,
+		cm:      cm,
+		expires: map[key]time.Time{},
+	}
+
+	go func() {
+		for now := range time.NewTicker(expireInterval).C {
+			m.expire(now)
+		}
+	}()
+	go func() {
+		for ev := range hub.Subscribe(10, event.MessageCreated).Receiver {
+			msg := ev.Fields["message"].(*model.Message)
+			m.Stop(msg.UserID, msg.ChannelID)
+		}
+	}()
+	return m
+}
+
+// Typing 指定したユーザーが指定したチャンネル(DMを含む)で入力中であることを通知します
+//
+// 入力中状態はTimeoutの間維持されます。
+func (m *Manager) Typing(userID, channelID uuid.UUID) error {
+	ok, err := m.cm.IsChannelAccessibleToUser(userID, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to IsChannelAccessibleToUser: %w", err)
+	}
+	if !ok {
+		return ErrChannelNotAccessible
+	}
+
+	k := key{userID: userID, channelID: channelID}
+	m.mu.Lock()
+	_, typing := m.expires[k]
+	m.expires[k] = time.Now().Add(Timeout)
+	m.mu.Unlock()
+
+	if !typing {
+		m.publish(k, true)
+	}
+	return nil
+}
+
+// Stop 指定したユーザーの指定したチャンネルでの入力中状態を解除します
+func (m *Manager) Stop(userID, channelID uuid.UUID) {
+	k := key{userID: userID, channelID: channelID}
+	m.mu.Lock()
+	_, typing := m.expires[k]
+	delete(m.expires, k)
+	m.mu.Unlock()
+
+	if typing {
+		m.publish(k, false)
+	}
+}
+
+// IsTyping 指定したユーザーが指定したチャンネルで入力中かどうかを返します
+func (m *Manager) IsTyping(userID, channelID uuid.UUID) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.expires[key{userID: userID, channelID: channelID}]
+	return ok
+}
+
+func (m *Manager) expire(now time.Time) {
+	m.mu.Lock()
+	expired := make([]key, 0)
+	for k, t := range m.expires {
+		if now.After(t) {
+			expired = append(expired, k)
+			delete(m.expires, k)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, k := range expired {
+		m.publish(k, false)
+	}
+}
+
+func (m *Manager) publish(k key, typing bool) {
+	m.hub.Publish(hub.Message{
+		Name: event.UserTyping,
+		Fields: hub.Fields{
+			"user_id":    k.userID,
+			"channel_id": k.channelID,
+			"typing":     typing,
+		},
+	})
+}
@@ -0,0 +1,97 @@
+package typing
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/service/channel/mock_channel"
+	"testing"
+	"time"
+)
+
+func receiveTyping(t *testing.T, sub hub.Subscription) (hub.Message, bool) {
+	t.Helper()
+	select {
+	case m := <-sub.Receiver:
+		return m, true
+	case <-time.After(Timeout + 3*expireInterval):
+		return hub.Message{}, false
+	}
+}
+
+func TestManager_Typing(t *testing.T) {
+	t.Parallel()
+
+	uid := uuid.Must(uuid.NewV4())
+	cid := uuid.Must(uuid.NewV4())
+	dmID := uuid.Must(uuid.NewV4())
+
+	t.Run("debounce and expire", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		ctrl := gomock.NewController(t)
+		cm := mock_channel.NewMockManager(ctrl)
+		cm.EXPECT().IsChannelAccessibleToUser(uid, cid).Return(true, nil).AnyTimes()
+		h := hub.New()
+		sub := h.Subscribe(10, event.UserTyping)
+		m := NewManager(h, cm)
+
+		assert.NoError(m.Typing(uid, cid))
+		assert.NoError(m.Typing(uid, cid))
+		assert.True(m.IsTyping(uid, cid))
+
+		if ev, ok := receiveTyping(t, sub); assert.True(ok) {
+			assert.Equal(uid, ev.Fields["user_id"])
+			assert.Equal(cid, ev.Fields["channel_id"])
+			assert.Equal(true, ev.Fields["typing"])
+		}
+		// 入力中の間は発行されず、期限切れで解除される
+		if ev, ok := receiveTyping(t, sub); assert.True(ok) {
+			assert.Equal(false, ev.Fields["typing"])
+		}
+		assert.False(m.IsTyping(uid, cid))
+	})
+
+	t.Run("message created", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		ctrl := gomock.NewController(t)
+		cm := mock_channel.NewMockManager(ctrl)
+		cm.EXPECT().IsChannelAccessibleToUser(uid, dmID).Return(true, nil).AnyTimes()
+		h := hub.New()
+		sub := h.Subscribe(10, event.UserTyping)
+		m := NewManager(h, cm)
+
+		assert.NoError(m.Typing(uid, dmID))
+		_, _ = receiveTyping(t, sub)
+
+		h.Publish(hub.Message{
+			Name: event.MessageCreated,
+			Fields: hub.Fields{
+				"message": &model.Message{UserID: uid, ChannelID: dmID},
+			},
+		})
+		if ev, ok := receiveTyping(t, sub); assert.True(ok) {
+			assert.Equal(false, ev.Fields["typing"])
+		}
+		assert.False(m.IsTyping(uid, dmID))
+	})
+
+	t.Run("not accessible", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		ctrl := gomock.NewController(t)
+		cm := mock_channel.NewMockManager(ctrl)
+		cm.EXPECT().IsChannelAccessibleToUser(uid, dmID).Return(false, nil)
+		m := NewManager(hub.New(), cm)
+
+		assert.EqualError(m.Typing(uid, dmID), ErrChannelNotAccessible.Error())
+		assert.False(m.IsTyping(uid, dmID))
+	})
+}
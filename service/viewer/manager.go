@@ -13,7 +13,9 @@ type Manager struct {
 	hub      *hub.Hub
 	channels map[uuid.UUID]map[*viewer]struct{}
 	viewers  map[interface{}]*viewer
-	mu       sync.RWMutex
+	// remote 他のインスタンスのチャンネル閲覧者 (node -> channelID -> userID -> state)
+	remote map[string]map[uuid.UUID]map[uuid.UUID]StateWithTime
+	mu     sync.RWMutex
 }
 
 type viewer struct {
@@ -29,6 +31,7 @@ func NewManager(hub *hub.Hub) *Manager {
 		hub:      hub,
 		channels: map[uuid.UUID]map[*viewer]struct{}{},
 		viewers:  map[interface{}]*viewer{},
+		remote:   map[string]map[uuid.UUID]map[uuid.UUID]StateWithTime{},
 	}
 
 	go func() {
@@ -42,12 +45,90 @@ func NewManager(hub *hub.Hub) *Manager {
 }
 
 // GetChannelViewers 指定したチャンネルのチャンネル閲覧者状態を取得します
+//
+// 他のインスタンスのチャンネル閲覧者も含みます。
 func (vm *Manager) GetChannelViewers(channelID uuid.UUID) map[uuid.UUID]StateWithTime {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.channelViewers(channelID)
+}
+
+// GetLocalChannelViewers 指定したチャンネルのこのインスタンスでのチャンネル閲覧者状態を取得します
+func (vm *Manager) GetLocalChannelViewers(channelID uuid.UUID) map[uuid.UUID]StateWithTime {
 	vm.mu.RLock()
 	defer vm.mu.RUnlock()
 	return calculateChannelViewers(vm.channels[channelID])
 }
 
+// GetLocalSnapshot このインスタンスでの全チャンネルのチャンネル閲覧者状態を取得します
+func (vm *Manager) GetLocalSnapshot() map[uuid.UUID]map[uuid.UUID]StateWithTime {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	result := make(map[uuid.UUID]map[uuid.UUID]StateWithTime, len(vm.channels))
+	for cid, cv := range vm.channels {
+		if len(cv) > 0 {
+			result[cid] = calculateChannelViewers(cv)
+		}
+	}
+	return result
+}
+
+// SetRemoteChannelViewers 他のインスタンスでの指定したチャンネルのチャンネル閲覧者状態を設定します
+func (vm *Manager) SetRemoteChannelViewers(node string, channelID uuid.UUID, viewers map[uuid.UUID]StateWithTime) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	rc, ok := vm.remote[node]
+	if !ok {
+		rc = map[uuid.UUID]map[uuid.UUID]StateWithTime{}
+		vm.remote[node] = rc
+	}
+	if len(viewers) == 0 {
+		delete(rc, channelID)
+	} else {
+		rc[channelID] = viewers
+	}
+	vm.publishRemoteChange(node, channelID)
+}
+
+// ReplaceRemote 他のインスタンスでの全チャンネルのチャンネル閲覧者状態を置き換えます
+func (vm *Manager) ReplaceRemote(node string, snapshot map[uuid.UUID]map[uuid.UUID]StateWithTime) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	old := vm.remote[node]
+	if len(snapshot) == 0 {
+		delete(vm.remote, node)
+	} else {
+		vm.remote[node] = snapshot
+	}
+
+	for cid := range old {
+		if _, ok := snapshot[cid]; !ok {
+			vm.publishRemoteChange(node, cid)
+		}
+	}
+	for cid := range snapshot {
+		vm.publishRemoteChange(node, cid)
+	}
+}
+
+// RemoveNode 他のインスタンスのチャンネル閲覧者状態を全て削除します
+func (vm *Manager) RemoveNode(node string) {
+	vm.ReplaceRemote(node, nil)
+}
+
+func (vm *Manager) publishRemoteChange(node string, channelID uuid.UUID) {
+	vm.hub.Publish(hub.Message{
+		Name: event.ChannelViewersChanged,
+		Fields: hub.Fields{
+			"channel_id":      channelID,
+			"viewers":         vm.channelViewers(channelID),
+			event.FieldOrigin: node,
+		},
+	})
+}
+
 // SetViewer 指定したキーのチャンネル閲覧者状態を設定します
 func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.UUID, state State) {
 	vm.mu.Lock()
@@ -84,7 +165,7 @@ func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.U
 				Name: event.ChannelViewersChanged,
 				Fields: hub.Fields{
 					"channel_id": oldC,
-					"viewers":    calculateChannelViewers(old),
+					"viewers":    vm.channelViewers(oldC),
 				},
 			})
 		}
@@ -106,7 +187,7 @@ func (vm *Manager) SetViewer(key interface{}, userID uuid.UUID, channelID uuid.U
 		Name: event.ChannelViewersChanged,
 		Fields: hub.Fields{
 			"channel_id": channelID,
-			"viewers":    calculateChannelViewers(cv),
+			"viewers":    vm.channelViewers(channelID),
 		},
 	})
 }
@@ -129,7 +210,7 @@ func (vm *Manager) RemoveViewer(key interface{}) {
 		Name: event.ChannelViewersChanged,
 		Fields: hub.Fields{
 			"channel_id": v.channelID,
-			"viewers":    calculateChannelViewers(cv),
+			"viewers":    vm.channelViewers(v.channelID),
 		},
 	})
 }
@@ -143,6 +224,20 @@ func (vm *Manager) gc() {
 	}
 }
 
+// channelViewers 他のインスタンスを含めたチャンネル閲覧者状態を計算します
+func (vm *Manager) channelViewers(channelID uuid.UUID) map[uuid.UUID]StateWithTime {
+	result := calculateChannelViewers(vm.channels[channelID])
+	for _, rc := range vm.remote {
+		for uid, state := range rc[channelID] {
+			if s, ok := result[uid]; ok && s.State > state.State {
+				continue
+			}
+			result[uid] = state
+		}
+	}
+	return result
+}
+
 func calculateChannelViewers(vs map[*viewer]struct{}) map[uuid.UUID]StateWithTime {
 	result := make(map[uuid.UUID]StateWithTime, len(vs))
 	for v := range vs {
@@ -0,0 +1,233 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	messageTTLSeconds = 60 * 60 * 24 * 2 // 2日
+	numWorkers        = 8
+	queueSize         = 1000
+	requestTimeout    = 10 * time.Second
+)
+
+var webpushSendCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "traq",
+	Name:      "webpush_send_count_total",
+}, []string{"result"})
+
+// Config Web Push設定
+type Config struct {
+	// PrivateKey VAPID秘密鍵 (P-256)
+	PrivateKey *ecdsa.PrivateKey
+	// Subject VAPIDの連絡先 (`mailto:`または`https:`のURL)
+	Subject string
+}
+
+// Client Web Pushクライアント
+//
+// fcm.Clientを実装します。
+type Client struct {
+	repo          repository.Repository
+	logger        *zap.Logger
+	unreadCounter counter.UnreadMessageCounter
+	httpClient    *http.Client
+
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+
+	queue  chan *job
+	closed bool
+	mu     sync.RWMutex
+	wg     sync.WaitGroup
+}
+
+type job struct {
+	sub     *model.WebPushSubscription
+	payload []byte
+}
+
+// message Service Workerが受け取るプッシュメッセージ
+//
+// FCMのdataペイロードと同じ形式です。
+type message struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	Path   string `json:"path"`
+	Tag    string `json:"tag"`
+	Icon   string `json:"icon"`
+	Image  string `json:"image,omitempty"`
+	Unread string `json:"unread,omitempty"`
+}
+
+// NewClient Web Pushクライアントを生成します
+func NewClient(repo repository.Repository, logger *zap.Logger, unreadCounter counter.UnreadMessageCounter, config Config) (*Client, error) {
+	if config.PrivateKey == nil {
+		return nil, ErrInvalidVAPIDKey
+	}
+	if len(config.Subject) == 0 {
+		return nil, errors.New("VAPID subject is required")
+	}
+
+	c := &Client{
+		repo:          repo,
+		logger:        logger.Named("webpush"),
+		unreadCounter: unreadCounter,
+		httpClient:    &http.Client{Timeout: requestTimeout},
+		key:           config.PrivateKey,
+		publicKey:     encodeVAPIDPublicKey(&config.PrivateKey.PublicKey),
+		subject:       config.Subject,
+		queue:         make(chan *job, queueSize),
+	}
+	c.wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go c.worker()
+	}
+	return c, nil
+}
+
+// PublicKey クライアントがPushManager.subscribeのapplicationServerKeyに指定するVAPID公開鍵を返します
+func (c *Client) PublicKey() string {
+	return c.publicKey
+}
+
+// Send implements fcm.Client interface.
+func (c *Client) Send(targetUserIDs set.UUID, p *fcm.Payload, withUnreadCount bool) {
+	subs, err := c.repo.GetWebPushSubscriptions(targetUserIDs)
+	if err != nil {
+		c.logger.Error("failed to GetWebPushSubscriptions", zap.Error(err), zap.Strings("target_user_ids", targetUserIDs.StringArray()))
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	m := message{
+		Type:  p.Type,
+		Title: p.Title,
+		Body:  p.Body,
+		Path:  p.Path,
+		Tag:   p.Tag,
+		Icon:  p.Icon,
+	}
+	if p.Image.Valid {
+		m.Image = p.Image.String
+	}
+
+	payloads := map[uuid.UUID][]byte{}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	for _, sub := range subs {
+		payload, ok := payloads[sub.UserID]
+		if !ok {
+			if withUnreadCount {
+				m.Unread = strconv.Itoa(c.unreadCounter.Get(sub.UserID))
+			}
+			payload, _ = json.Marshal(m)
+			payloads[sub.UserID] = payload
+		}
+		c.queue <- &job{sub: sub, payload: payload}
+	}
+}
+
+// Close implements fcm.Client interface.
+//
+// キューに残っているメッセージを送信し終わるまでブロックします。
+func (c *Client) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.queue)
+	c.mu.Unlock()
+	c.wg.Wait()
+}
+
+func (c *Client) worker() {
+	defer c.wg.Done()
+	for j := range c.queue {
+		gone, err := c.push(j.sub, j.payload)
+		switch {
+		case gone:
+			webpushSendCounter.WithLabelValues("gone").Inc()
+			// 購読が期限切れ、或いは解除されている
+			if err := c.repo.DeleteWebPushSubscriptions([]string{j.sub.Endpoint}); err != nil {
+				c.logger.Error("failed to DeleteWebPushSubscriptions", zap.Error(err))
+			}
+		case err != nil:
+			webpushSendCounter.WithLabelValues("error").Inc()
+			c.logger.Warn("failed to push", zap.Error(err), zap.Stringer("userID", j.sub.UserID))
+		default:
+			webpushSendCounter.WithLabelValues("ok").Inc()
+		}
+	}
+}
+
+// push プッシュサービスにメッセージを送信します
+//
+// 購読が無効になっている場合はgoneにtrueを返します。
+func (c *Client) push(sub *model.WebPushSubscription, payload []byte) (gone bool, err error) {
+	body, err := encrypt(payload, sub.P256dh, sub.Auth)
+	if err != nil {
+		if err == ErrInvalidSubscriptionKey {
+			return true, err
+		}
+		return false, err
+	}
+	authorization, err := c.vapidAuthorization(sub.Endpoint, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(messageTTLSeconds))
+	req.Header.Set("Urgency", "high")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return true, nil
+	default:
+		return false, fmt.Errorf("push service responded with status %d", res.StatusCode)
+	}
+}
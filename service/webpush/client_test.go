@@ -0,0 +1,236 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gofrs/uuid"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/model"
+	"github.com/traPtitech/traQ/repository/mock_repository"
+	"github.com/traPtitech/traQ/service/counter"
+	"github.com/traPtitech/traQ/service/fcm"
+	"github.com/traPtitech/traQ/testutils"
+	"github.com/traPtitech/traQ/utils/set"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type Repo struct {
+	*mock_repository.MockWebPushSubscriptionRepository
+	testutils.EmptyTestRepository
+}
+
+type unreadCounter struct {
+	counter.UnreadMessageCounter
+	n int
+}
+
+func (c *unreadCounter) Get(uuid.UUID) int {
+	return c.n
+}
+
+// userAgent 購読を行うブラウザ
+type userAgent struct {
+	private []byte
+	public  []byte
+	auth    []byte
+}
+
+func newUserAgent(t *testing.T) *userAgent {
+	t.Helper()
+	priv, x, y, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	auth := make([]byte, 16)
+	_, err = rand.Read(auth)
+	require.NoError(t, err)
+	return &userAgent{private: priv, public: elliptic.Marshal(elliptic.P256(), x, y), auth: auth}
+}
+
+func (ua *userAgent) subscription(userID uuid.UUID, endpoint string) *model.WebPushSubscription {
+	return &model.WebPushSubscription{
+		Endpoint: endpoint,
+		UserID:   userID,
+		P256dh:   base64.RawURLEncoding.EncodeToString(ua.public),
+		Auth:     base64.RawURLEncoding.EncodeToString(ua.auth),
+	}
+}
+
+// decrypt RFC 8291に従ってボディを復号します
+func (ua *userAgent) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+	require.True(t, len(body) > 21)
+	salt := body[:16]
+	assert.EqualValues(t, recordSize, binary.BigEndian.Uint32(body[16:20]))
+	idLen := int(body[20])
+	asPublic := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	curve := elliptic.P256()
+	asX, asY := elliptic.Unmarshal(curve, asPublic)
+	require.NotNil(t, asX)
+	sx, _ := curve.ScalarMult(asX, asY, ua.private)
+	ecdhSecret := make([]byte, 32)
+	sb := sx.Bytes()
+	copy(ecdhSecret[len(ecdhSecret)-len(sb):], sb)
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), ua.public...), asPublic...)
+	ikm, err := hkdfExpand(ecdhSecret, ua.auth, keyInfo, 32)
+	require.NoError(t, err)
+	cek, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	require.NoError(t, err)
+	nonce, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, plaintext)
+	assert.EqualValues(t, 0x02, plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+func newTestClient(t *testing.T, repo *Repo) *Client {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	c, err := NewClient(repo, zap.NewNop(), &unreadCounter{n: 3}, Config{PrivateKey: key, Subject: "mailto:admin@example.com"})
+	require.NoError(t, err)
+	return c
+}
+
+// verifyVAPID Authorizationヘッダーのトークンをk=の公開鍵で検証します
+func verifyVAPID(t *testing.T, r *http.Request, audience string) {
+	t.Helper()
+	header := r.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(header, "vapid t="))
+	parts := strings.SplitN(strings.TrimPrefix(header, "vapid t="), ", k=", 2)
+	require.Len(t, parts, 2)
+
+	rawKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	x, y := elliptic.Unmarshal(elliptic.P256(), rawKey)
+	require.NotNil(t, x)
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+
+	var claims jwt.StandardClaims
+	_, err = jwt.ParseWithClaims(parts[0], &claims, func(token *jwt.Token) (interface{}, error) {
+		assert.Equal(t, jwt.SigningMethodES256, token.Method)
+		return pub, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, audience, claims.Audience)
+	assert.Equal(t, "mailto:admin@example.com", claims.Subject)
+}
+
+func TestClient_Send(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		ua := newUserAgent(t)
+		received := make(chan []byte, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			verifyVAPID(t, r, "http://"+r.Host)
+			assert.Equal(t, "aes128gcm", r.Header.Get("Content-Encoding"))
+			assert.NotEmpty(t, r.Header.Get("TTL"))
+			body, _ := ioutil.ReadAll(r.Body)
+			received <- ua.decrypt(t, body)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer ts.Close()
+
+		ctrl := gomock.NewController(t)
+		repo := &Repo{MockWebPushSubscriptionRepository: mock_repository.NewMockWebPushSubscriptionRepository(ctrl)}
+		c := newTestClient(t, repo)
+
+		userID := uuid.NewV3(uuid.Nil, "u1")
+		targets := set.UUIDSetFromArray([]uuid.UUID{userID})
+		repo.MockWebPushSubscriptionRepository.EXPECT().
+			GetWebPushSubscriptions(targets).
+			Return([]*model.WebPushSubscription{ua.subscription(userID, ts.URL+"/push/1")}, nil).
+			Times(1)
+
+		c.Send(targets, &fcm.Payload{Type: "new_message", Title: "#general", Body: "hello", Path: "/channels/general"}, true)
+		c.Close()
+
+		var m message
+		require.NoError(t, json.Unmarshal(<-received, &m))
+		assert.Equal(t, "new_message", m.Type)
+		assert.Equal(t, "#general", m.Title)
+		assert.Equal(t, "hello", m.Body)
+		assert.Equal(t, "/channels/general", m.Path)
+		assert.Equal(t, "3", m.Unread)
+	})
+
+	t.Run("gone", func(t *testing.T) {
+		t.Parallel()
+		ua := newUserAgent(t)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		}))
+		defer ts.Close()
+
+		ctrl := gomock.NewController(t)
+		repo := &Repo{MockWebPushSubscriptionRepository: mock_repository.NewMockWebPushSubscriptionRepository(ctrl)}
+		c := newTestClient(t, repo)
+
+		userID := uuid.NewV3(uuid.Nil, "u1")
+		targets := set.UUIDSetFromArray([]uuid.UUID{userID})
+		endpoint := ts.URL + "/push/1"
+		repo.MockWebPushSubscriptionRepository.EXPECT().
+			GetWebPushSubscriptions(targets).
+			Return([]*model.WebPushSubscription{ua.subscription(userID, endpoint)}, nil).
+			Times(1)
+		repo.MockWebPushSubscriptionRepository.EXPECT().
+			DeleteWebPushSubscriptions([]string{endpoint}).
+			Return(nil).
+			Times(1)
+
+		c.Send(targets, &fcm.Payload{Type: "new_message"}, false)
+		c.Close()
+	})
+}
+
+func TestEncrypt(t *testing.T) {
+	t.Parallel()
+
+	ua := newUserAgent(t)
+	sub := ua.subscription(uuid.Nil, "")
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+		body, err := encrypt([]byte("payload"), sub.P256dh, sub.Auth)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("payload"), ua.decrypt(t, body))
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		t.Parallel()
+		_, err := encrypt(make([]byte, maxPayloadSize+1), sub.P256dh, sub.Auth)
+		assert.Equal(t, ErrPayloadTooLarge, err)
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		t.Parallel()
+		_, err := encrypt([]byte("payload"), "invalid", sub.Auth)
+		assert.Equal(t, ErrInvalidSubscriptionKey, err)
+		_, err = encrypt([]byte("payload"), sub.P256dh, "AAAA")
+		assert.Equal(t, ErrInvalidSubscriptionKey, err)
+	})
+}
@@ -0,0 +1,119 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"strings"
+)
+
+const (
+	// recordSize aes128gcmのレコードサイズ
+	recordSize = 4096
+	// maxPayloadSize 1レコードに収まる平文の最大サイズ
+	//
+	// レコードサイズからヘッダー(86バイト)、認証タグ(16バイト)、パディング区切り(1バイト)を除いたもの
+	maxPayloadSize = recordSize - 86 - 16 - 1
+)
+
+var (
+	// ErrPayloadTooLarge ペイロードが大きすぎます
+	ErrPayloadTooLarge = errors.New("payload too large")
+	// ErrInvalidSubscriptionKey 購読の鍵が不正です
+	ErrInvalidSubscriptionKey = errors.New("invalid subscription key")
+)
+
+// decodeBase64URL パディングの有無に関わらずbase64urlをデコードします
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// encrypt RFC 8291に従ってペイロードを暗号化し、aes128gcm形式(RFC 8188)のリクエストボディを返します
+func encrypt(payload []byte, p256dh, auth string) ([]byte, error) {
+	if len(payload) > maxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	uaPublic, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, ErrInvalidSubscriptionKey
+	}
+	authSecret, err := decodeBase64URL(auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, ErrInvalidSubscriptionKey
+	}
+
+	curve := elliptic.P256()
+	uaX, uaY := elliptic.Unmarshal(curve, uaPublic)
+	if uaX == nil {
+		return nil, ErrInvalidSubscriptionKey
+	}
+
+	// サーバー側の一時鍵
+	asPrivate, asX, asY, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := elliptic.Marshal(curve, asX, asY)
+
+	sx, _ := curve.ScalarMult(uaX, uaY, asPrivate)
+	ecdhSecret := make([]byte, 32)
+	sb := sx.Bytes()
+	copy(ecdhSecret[len(ecdhSecret)-len(sb):], sb)
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublic...)
+	ikm, err := hkdfExpand(ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfExpand(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 最後のレコードであることを示す区切り文字を付与する
+	plaintext := append(append(make([]byte, 0, len(payload)+1), payload...), 0x02)
+
+	var body bytes.Buffer
+	body.Write(salt)
+	_ = binary.Write(&body, binary.BigEndian, uint32(recordSize))
+	body.WriteByte(byte(len(asPublic)))
+	body.Write(asPublic)
+	body.Write(gcm.Seal(nil, nonce, plaintext, nil))
+	return body.Bytes(), nil
+}
+
+func hkdfExpand(secret, salt, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,56 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"net/url"
+	"time"
+)
+
+// vapidTokenExpiry VAPIDトークンの有効期間 (RFC 8292により24時間以内)
+const vapidTokenExpiry = 12 * time.Hour
+
+// ErrInvalidVAPIDKey VAPID鍵が不正です
+var ErrInvalidVAPIDKey = errors.New("VAPID key must be a P-256 ECDSA private key")
+
+// ParseVAPIDPrivateKey PEM形式のVAPID秘密鍵をパースします
+func ParseVAPIDPrivateKey(pem []byte) (*ecdsa.PrivateKey, error) {
+	key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, err
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, ErrInvalidVAPIDKey
+	}
+	return key, nil
+}
+
+// encodeVAPIDPublicKey VAPID公開鍵をapplicationServerKeyとして使用できる形式(非圧縮形式のbase64url)にします
+func encodeVAPIDPublicKey(key *ecdsa.PublicKey) string {
+	return base64.RawURLEncoding.EncodeToString(elliptic.Marshal(key.Curve, key.X, key.Y))
+}
+
+// vapidAuthorization RFC 8292に従ってプッシュサービスへのリクエストのAuthorizationヘッダーを生成します
+func (c *Client) vapidAuthorization(endpoint string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return "", fmt.Errorf("invalid endpoint: %s", endpoint)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.StandardClaims{
+		Audience:  u.Scheme + "://" + u.Host,
+		ExpiresAt: now.Add(vapidTokenExpiry).Unix(),
+		Subject:   c.subject,
+	}).SignedString(c.key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", token, c.publicKey), nil
+}
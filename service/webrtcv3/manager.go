@@ -8,6 +8,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/traPtitech/traQ/event"
 	"sync"
+	"time"
 )
 
 var (
@@ -23,9 +24,15 @@ var (
 )
 
 // Manager WebRTCマネージャー
+//
+// 他のインスタンスのユーザー状態はSetRemote, ReplaceRemoteで統合され、
+// 状態の取得、SetStateでの占有判定、通話の開始・終了の判定に使われます。
 type Manager struct {
-	eventbus      *hub.Hub
-	userStates    map[uuid.UUID]*userState
+	eventbus   *hub.Hub
+	userStates map[uuid.UUID]*userState
+	// remoteStates 他のインスタンスのユーザー状態 (node -> userID -> state)
+	remoteStates map[string]map[uuid.UUID]*userState
+	// channelStates 他のインスタンスを含めたチャンネル状態
 	channelStates map[uuid.UUID]*channelState
 	statesLock    sync.RWMutex
 }
@@ -35,12 +42,13 @@ func NewManager(eventbus *hub.Hub) *Manager {
 	manager := &Manager{
 		eventbus:      eventbus,
 		userStates:    map[uuid.UUID]*userState{},
+		remoteStates:  map[string]map[uuid.UUID]*userState{},
 		channelStates: map[uuid.UUID]*channelState{},
 	}
 	return manager
 }
 
-// IterateStates 全状態をイテレートします
+// IterateStates 他のインスタンスを含めた全状態をイテレートします
 func (m *Manager) IterateStates(f func(state ChannelState)) {
 	m.statesLock.RLock()
 	defer m.statesLock.RUnlock()
@@ -59,6 +67,9 @@ func (m *Manager) SetState(connKey string, user, channel uuid.UUID, sessions map
 	defer m.statesLock.Unlock()
 
 	us, ok := m.userStates[user]
+	if ok && us.connKey != connKey || m.hasRemoteState(user) {
+		return ErrOccupied
+	}
 	if !ok {
 		us = &userState{
 			connKey: connKey,
@@ -68,23 +79,46 @@ func (m *Manager) SetState(connKey string, user, channel uuid.UUID, sessions map
 		webrtcUsingUsersCounter.Inc()
 	}
 
+	now := time.Now()
 	if us.valid() && us.channelID != channel {
-		m.channelStates[us.channelID].removeUser(user)
+		m.leaveCall(m.channelStates[us.channelID], user, now)
 	}
 
 	cs, ok := m.channelStates[channel]
 	if !ok {
 		cs = &channelState{
-			channelID: channel,
-			users:     map[uuid.UUID]*userState{},
+			channelID:    channel,
+			users:        map[uuid.UUID]*userState{},
+			callID:       uuid.Must(uuid.NewV4()),
+			startedAt:    now,
+			participants: map[uuid.UUID]struct{}{},
 		}
 		m.channelStates[channel] = cs
 		webrtcUsingChannelsCounter.Inc()
+		m.eventbus.Publish(hub.Message{
+			Name: event.WebRTCCallStarted,
+			Fields: hub.Fields{
+				"call_id":    cs.callID,
+				"channel_id": cs.channelID,
+				"started_at": cs.startedAt,
+			},
+		})
 	}
 
 	us.sessions = sessions
 	us.channelID = channel
-	cs.setUser(us)
+	if _, joined := cs.users[user]; !joined {
+		cs.setUser(us)
+		m.eventbus.Publish(hub.Message{
+			Name: event.WebRTCCallParticipantJoined,
+			Fields: hub.Fields{
+				"call_id":    cs.callID,
+				"channel_id": cs.channelID,
+				"user_id":    user,
+				"datetime":   now,
+			},
+		})
+	}
 
 	m.eventbus.Publish(hub.Message{
 		Name: event.UserWebRTCv3StateChanged,
@@ -113,12 +147,7 @@ func (m *Manager) ResetState(connKey string, user uuid.UUID) error {
 
 	delete(m.userStates, user)
 	webrtcUsingUsersCounter.Dec()
-	cs := m.channelStates[us.channelID]
-	cs.removeUser(user)
-	if !cs.valid() {
-		delete(m.channelStates, cs.channelID)
-		webrtcUsingChannelsCounter.Dec()
-	}
+	m.leaveCall(m.channelStates[us.channelID], user, time.Now())
 
 	m.eventbus.Publish(hub.Message{
 		Name: event.UserWebRTCv3StateChanged,
@@ -130,3 +159,207 @@ func (m *Manager) ResetState(connKey string, user uuid.UUID) error {
 	})
 	return nil
 }
+
+// leaveCall 指定したユーザーを通話から退出させ、参加者がいなくなった場合は通話を終了します
+//
+// statesLockを取得した状態で呼び出してください。
+func (m *Manager) leaveCall(cs *channelState, user uuid.UUID, now time.Time) {
+	cs.removeUser(user)
+	m.eventbus.Publish(hub.Message{
+		Name: event.WebRTCCallParticipantLeft,
+		Fields: hub.Fields{
+			"call_id":    cs.callID,
+			"channel_id": cs.channelID,
+			"user_id":    user,
+			"datetime":   now,
+		},
+	})
+
+	if cs.valid() {
+		return
+	}
+	delete(m.channelStates, cs.channelID)
+	webrtcUsingChannelsCounter.Dec()
+	m.eventbus.Publish(hub.Message{
+		Name: event.WebRTCCallEnded,
+		Fields: hub.Fields{
+			"call_id":      cs.callID,
+			"channel_id":   cs.channelID,
+			"started_at":   cs.startedAt,
+			"ended_at":     now,
+			"participants": cs.participantIDs(),
+		},
+	})
+}
+
+// GetLocalUserState このインスタンスでの指定したユーザーの状態を取得します
+//
+// 状態が存在しない場合はゼロ値を返します。
+func (m *Manager) GetLocalUserState(user uuid.UUID) UserStateSnapshot {
+	m.statesLock.RLock()
+	defer m.statesLock.RUnlock()
+	us, ok := m.userStates[user]
+	if !ok {
+		return UserStateSnapshot{}
+	}
+	return m.snapshot(us)
+}
+
+// GetLocalSnapshot このインスタンスでの全ユーザーの状態を取得します
+func (m *Manager) GetLocalSnapshot() map[uuid.UUID]UserStateSnapshot {
+	m.statesLock.RLock()
+	defer m.statesLock.RUnlock()
+	result := make(map[uuid.UUID]UserStateSnapshot, len(m.userStates))
+	for user, us := range m.userStates {
+		result[user] = m.snapshot(us)
+	}
+	return result
+}
+
+// SetRemote 他のインスタンスでの指定したユーザーの状態を設定します
+//
+// stateがゼロ値の場合、そのユーザーの状態を削除します。
+func (m *Manager) SetRemote(node string, user uuid.UUID, state UserStateSnapshot) {
+	m.statesLock.Lock()
+	defer m.statesLock.Unlock()
+	m.setRemote(node, user, state)
+}
+
+// ReplaceRemote 他のインスタンスでの全ユーザーの状態を置き換えます
+func (m *Manager) ReplaceRemote(node string, snapshot map[uuid.UUID]UserStateSnapshot) {
+	m.statesLock.Lock()
+	defer m.statesLock.Unlock()
+
+	for user := range m.remoteStates[node] {
+		if _, ok := snapshot[user]; !ok {
+			m.setRemote(node, user, UserStateSnapshot{})
+		}
+	}
+	for user, state := range snapshot {
+		m.setRemote(node, user, state)
+	}
+}
+
+// RemoveNode 他のインスタンスのユーザー状態を全て削除します
+//
+// 停止したインスタンスのユーザーのみが参加していた通話は、この時点で終了した扱いになります。
+// 通話の終了は記録されません。
+func (m *Manager) RemoveNode(node string) {
+	m.ReplaceRemote(node, nil)
+}
+
+// setRemote 他のインスタンスでのユーザーの状態を設定します
+//
+// 通話の開始・終了、参加・退出はそのユーザーが接続しているインスタンスで記録されるため、
+// ここではチャンネル状態の更新のみを行います。
+// statesLockを取得した状態で呼び出してください。
+func (m *Manager) setRemote(node string, user uuid.UUID, state UserStateSnapshot) {
+	us, ok := m.remoteStates[node][user]
+	if !ok && !state.valid() {
+		return
+	}
+
+	if ok && state.valid() && us.channelID == state.ChannelID {
+		// セッションだけ変更
+		us.sessions = state.Sessions
+	} else {
+		if ok {
+			m.removeRemoteUser(node, us)
+		}
+		if state.valid() {
+			m.addRemoteUser(node, user, state)
+		}
+	}
+
+	fields := hub.Fields{
+		"user_id":         user,
+		"channel_id":      state.ChannelID,
+		"sessions":        state.Sessions,
+		event.FieldOrigin: node,
+	}
+	if !state.valid() {
+		fields["channel_id"] = us.channelID
+		fields["sessions"] = map[string]string{}
+	}
+	m.eventbus.Publish(hub.Message{
+		Name:   event.UserWebRTCv3StateChanged,
+		Fields: fields,
+	})
+}
+
+// addRemoteUser 他のインスタンスのユーザーをチャンネル状態に追加します
+//
+// statesLockを取得した状態で呼び出してください。
+func (m *Manager) addRemoteUser(node string, user uuid.UUID, state UserStateSnapshot) {
+	rs, ok := m.remoteStates[node]
+	if !ok {
+		rs = map[uuid.UUID]*userState{}
+		m.remoteStates[node] = rs
+	}
+	us := &userState{
+		userID:    user,
+		channelID: state.ChannelID,
+		sessions:  state.Sessions,
+	}
+	rs[user] = us
+
+	cs, ok := m.channelStates[state.ChannelID]
+	if !ok {
+		// 通話は他のインスタンスで開始されているので、その通話IDを引き継ぐ
+		cs = &channelState{
+			channelID:    state.ChannelID,
+			users:        map[uuid.UUID]*userState{},
+			callID:       state.CallID,
+			startedAt:    state.StartedAt,
+			participants: map[uuid.UUID]struct{}{},
+		}
+		m.channelStates[state.ChannelID] = cs
+		webrtcUsingChannelsCounter.Inc()
+	}
+	cs.setUser(us)
+}
+
+// removeRemoteUser 他のインスタンスのユーザーをチャンネル状態から削除します
+//
+// statesLockを取得した状態で呼び出してください。
+func (m *Manager) removeRemoteUser(node string, us *userState) {
+	rs := m.remoteStates[node]
+	delete(rs, us.userID)
+	if len(rs) == 0 {
+		delete(m.remoteStates, node)
+	}
+
+	cs := m.channelStates[us.channelID]
+	cs.removeUser(us.userID)
+	if !cs.valid() {
+		delete(m.channelStates, cs.channelID)
+		webrtcUsingChannelsCounter.Dec()
+	}
+}
+
+// hasRemoteState 指定したユーザーの状態を他のインスタンスが保持しているかどうかを返します
+//
+// statesLockを取得した状態で呼び出してください。
+func (m *Manager) hasRemoteState(user uuid.UUID) bool {
+	for _, rs := range m.remoteStates {
+		if _, ok := rs[user]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot 他のインスタンスに送信するユーザーの状態を返します
+//
+// statesLockを取得した状態で呼び出してください。
+func (m *Manager) snapshot(us *userState) UserStateSnapshot {
+	s := UserStateSnapshot{
+		ChannelID: us.channelID,
+		Sessions:  us.sessions,
+	}
+	if cs, ok := m.channelStates[us.channelID]; ok {
+		s.CallID = cs.callID
+		s.StartedAt = cs.startedAt
+	}
+	return s
+}
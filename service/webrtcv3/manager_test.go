@@ -0,0 +1,91 @@
+package webrtcv3
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/event"
+	"testing"
+	"time"
+)
+
+func receiveCallEvent(t *testing.T, sub hub.Subscription) hub.Message {
+	t.Helper()
+	select {
+	case ev := <-sub.Receiver:
+		return ev
+	case <-time.After(time.Second):
+		require.FailNow(t, "event was not published")
+		return hub.Message{}
+	}
+}
+
+func TestManager_CallLifecycle(t *testing.T) {
+	t.Parallel()
+	assert, require := assert.New(t), require.New(t)
+
+	h := hub.New()
+	sub := h.Subscribe(100, event.WebRTCCallStarted, event.WebRTCCallEnded, event.WebRTCCallParticipantJoined, event.WebRTCCallParticipantLeft)
+	defer h.Unsubscribe(sub)
+	m := NewManager(h)
+
+	user1 := uuid.Must(uuid.NewV4())
+	user2 := uuid.Must(uuid.NewV4())
+	ch1 := uuid.Must(uuid.NewV4())
+	ch2 := uuid.Must(uuid.NewV4())
+	sessions := map[string]string{"s": "joined"}
+
+	// user1が参加して通話が開始される
+	require.NoError(m.SetState("c1", user1, ch1, sessions))
+	ev := receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallStarted, ev.Topic())
+	callID := ev.Fields["call_id"].(uuid.UUID)
+	assert.Equal(ch1, ev.Fields["channel_id"])
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallParticipantJoined, ev.Topic())
+	assert.Equal(callID, ev.Fields["call_id"])
+	assert.Equal(user1, ev.Fields["user_id"])
+
+	// セッションの変更は参加として扱わない
+	require.NoError(m.SetState("c1", user1, ch1, map[string]string{"s": "joined", "t": "joined"}))
+	require.NoError(m.SetState("c2", user2, ch1, sessions))
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallParticipantJoined, ev.Topic())
+	assert.Equal(user2, ev.Fields["user_id"])
+
+	// user1が別のチャンネルに移動する
+	require.NoError(m.SetState("c1", user1, ch2, sessions))
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallParticipantLeft, ev.Topic())
+	assert.Equal(callID, ev.Fields["call_id"])
+	assert.Equal(user1, ev.Fields["user_id"])
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallStarted, ev.Topic())
+	assert.Equal(ch2, ev.Fields["channel_id"])
+	callID2 := ev.Fields["call_id"].(uuid.UUID)
+	assert.NotEqual(callID, callID2)
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallParticipantJoined, ev.Topic())
+
+	// 最後の参加者が退出して通話が終了する
+	require.NoError(m.ResetState("c2", user2))
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallParticipantLeft, ev.Topic())
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallEnded, ev.Topic())
+	assert.Equal(callID, ev.Fields["call_id"])
+	assert.Equal(ch1, ev.Fields["channel_id"])
+	assert.ElementsMatch([]uuid.UUID{user1, user2}, ev.Fields["participants"])
+	assert.False(ev.Fields["ended_at"].(time.Time).Before(ev.Fields["started_at"].(time.Time)))
+
+	require.NoError(m.ResetState("c1", user1))
+	receiveCallEvent(t, sub)
+	ev = receiveCallEvent(t, sub)
+	require.Equal(event.WebRTCCallEnded, ev.Topic())
+	assert.Equal(callID2, ev.Fields["call_id"])
+
+	n := 0
+	m.IterateStates(func(ChannelState) { n++ })
+	assert.Zero(n)
+}
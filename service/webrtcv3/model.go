@@ -2,6 +2,7 @@ package webrtcv3
 
 import (
 	"github.com/gofrs/uuid"
+	"time"
 )
 
 // UserState WebRTCのユーザー状態
@@ -37,6 +38,20 @@ func (s *userState) valid() bool {
 	return s.channelID != uuid.Nil && len(s.sessions) != 0
 }
 
+// UserStateSnapshot 他のインスタンスと共有するユーザーの状態
+type UserStateSnapshot struct {
+	ChannelID uuid.UUID
+	Sessions  map[string]string
+	// CallID 参加している通話のID
+	CallID uuid.UUID
+	// StartedAt 参加している通話の開始日時
+	StartedAt time.Time
+}
+
+func (s UserStateSnapshot) valid() bool {
+	return s.ChannelID != uuid.Nil && len(s.Sessions) != 0
+}
+
 // ChannelState WebRTCのチャンネル状態
 type ChannelState interface {
 	ChannelID() uuid.UUID
@@ -46,6 +61,13 @@ type ChannelState interface {
 type channelState struct {
 	channelID uuid.UUID
 	users     map[uuid.UUID]*userState
+
+	// callID 通話ID
+	callID uuid.UUID
+	// startedAt 通話開始日時
+	startedAt time.Time
+	// participants 通話に一度でも参加したユーザー
+	participants map[uuid.UUID]struct{}
 }
 
 // ChannelID implements ChannelState interface.
@@ -68,8 +90,17 @@ func (s *channelState) valid() bool {
 
 func (s *channelState) setUser(us *userState) {
 	s.users[us.userID] = us
+	s.participants[us.userID] = struct{}{}
 }
 
 func (s *channelState) removeUser(user uuid.UUID) {
 	delete(s.users, user)
 }
+
+func (s *channelState) participantIDs() []uuid.UUID {
+	result := make([]uuid.UUID, 0, len(s.participants))
+	for id := range s.participants {
+		result = append(result, id)
+	}
+	return result
+}
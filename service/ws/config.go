@@ -11,7 +11,7 @@ const (
 	writeWait          = 10 * time.Second
 	pongWait           = 60 * time.Second
 	pingPeriod         = (pongWait * 9) / 10
-	maxReadMessageSize = 1 << 9 // 512B
+	maxReadMessageSize = 1 << 15 // 32KiB (WebRTCのSDPを含むため)
 	messageBufferSize  = 256
 )
 
@@ -21,5 +21,8 @@ var (
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 		CheckOrigin:     func(r *http.Request) bool { return true },
+		// permessage-deflate拡張をネゴシエートしたクライアントにはメッセージを圧縮して送信する
+		EnableCompression: true,
+		Subprotocols:      []string{subprotocolMessagePack},
 	}
 )
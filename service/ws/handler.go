@@ -3,8 +3,12 @@ package ws
 import (
 	"fmt"
 	"github.com/gofrs/uuid"
-	"github.com/gorilla/websocket"
+	"github.com/traPtitech/traQ/service/sfu"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"go.uber.org/zap"
+	"strconv"
 	"strings"
 )
 
@@ -102,7 +106,10 @@ Command:
 			sessions[session] = state
 		}
 
-		_ = s.streamer.webrtc.SetState(s.Key(), s.UserID(), cid, sessions)
+		if s.streamer.webrtc.SetState(s.Key(), s.UserID(), cid, sessions) != nil {
+			// 別のコネクションでロック中
+			s.sendErrorMessage("your webrtc state is locked by another ws connection")
+		}
 
 	case "timeline_streaming":
 		// timeline_streaming:(on|off|true|false)
@@ -124,15 +131,112 @@ Command:
 			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
 		}
 
+	case "rtcsignal":
+		// rtcsignal:{シグナリングメッセージ(JSON)}
+		if !s.rtcPermitted {
+			s.sendErrorMessage("you are not permitted to use webrtc")
+			break
+		}
+
+		// JSONは:を含むため、コマンド名以降をそのまま用いる
+		payload := strings.SplitN(strings.TrimSpace(cmd), ":", 2)
+		if len(payload) != 2 {
+			// 引数が不正
+			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			break
+		}
+
+		var sig sfu.Signal
+		if err := json.Unmarshal([]byte(payload[1]), &sig); err != nil {
+			// シグナリングメッセージが不正
+			s.sendErrorMessage(fmt.Sprintf("invalid signal: %s", err))
+			break
+		}
+
+		if err := s.streamer.sfu.HandleSignal(s, &sig); err != nil {
+			switch err {
+			case webrtcv3.ErrOccupied:
+				// 別のコネクションでロック中
+				s.sendErrorMessage("your webrtc state is locked by another ws connection")
+			case sfu.ErrChannelNotAccessible:
+				s.sendErrorMessage(fmt.Sprintf("channel not found: %s", sig.ChannelID))
+			case sfu.ErrNotJoined, sfu.ErrUnknownSignal:
+				s.sendErrorMessage(fmt.Sprintf("invalid signal: %s", err))
+			default:
+				s.streamer.logger.Warn("failed to handle webrtc signal", zap.Error(err), zap.Stringer("userID", s.userID))
+				s.sendErrorMessage(fmt.Sprintf("failed to handle signal: %s", sig.Type))
+			}
+		}
+
+	case "typing":
+		// typing:{チャンネルID}(:(on|off|true|false))
+		if len(args) < 2 || len(args) > 3 {
+			// 引数が不正
+			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			break
+		}
+
+		cid, err := uuid.FromString(args[1])
+		if err != nil {
+			// チャンネルIDが不正
+			s.sendErrorMessage(fmt.Sprintf("invalid id: %s", args[1]))
+			break
+		}
+
+		on := true
+		if len(args) == 3 {
+			switch strings.ToLower(args[2]) {
+			case "on", "true":
+			case "off", "false":
+				on = false
+			default:
+				// 引数が不正
+				s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+				break Command
+			}
+		}
+
+		if !on {
+			s.streamer.typing.Stop(s.UserID(), cid)
+			break
+		}
+		if err := s.streamer.typing.Typing(s.UserID(), cid); err != nil {
+			if err == typing.ErrChannelNotAccessible {
+				s.sendErrorMessage(fmt.Sprintf("channel not found: %s", args[1]))
+			} else {
+				s.streamer.logger.Error("failed to set typing state", zap.Error(err))
+			}
+		}
+
+	case "resume":
+		// resume:{最後に受信したイベントのシーケンス番号}
+		if len(args) != 2 {
+			// 引数が不正
+			s.sendErrorMessage(fmt.Sprintf("invalid args: %s", cmd))
+			break
+		}
+
+		seq, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			// シーケンス番号が不正
+			s.sendErrorMessage(fmt.Sprintf("invalid seq: %s", args[1]))
+			break
+		}
+
+		s.streamer.replay(s, seq)
+
 	default:
 		// 不明なコマンド
 		s.sendErrorMessage(fmt.Sprintf("unknown command: %s", cmd))
 	}
 }
 
+func (s *session) sendResyncRequired(seq uint64) {
+	_ = s.writeMessage(newEventMessage(makeMessage("RESYNC_REQUIRED", map[string]interface{}{
+		"seq": seq,
+	})))
+}
+
 func (s *session) sendErrorMessage(error string) {
-	_ = s.writeMessage(&rawMessage{
-		t:    websocket.TextMessage,
-		data: makeMessage("ERROR", error).toJSON(),
-	})
+	_ = s.writeMessage(newEventMessage(makeMessage("ERROR", error)))
 }
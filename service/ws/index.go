@@ -0,0 +1,69 @@
+package ws
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+type sessionSet map[*session]struct{}
+
+// sessionIndex 送信対象のセッションを解決するためのインデックス
+//
+// Streamer.muで保護されます。
+type sessionIndex struct {
+	all       sessionSet
+	byUser    map[uuid.UUID]sessionSet
+	byChannel map[uuid.UUID]sessionSet
+	timeline  sessionSet
+}
+
+func newSessionIndex() *sessionIndex {
+	return &sessionIndex{
+		all:       sessionSet{},
+		byUser:    map[uuid.UUID]sessionSet{},
+		byChannel: map[uuid.UUID]sessionSet{},
+		timeline:  sessionSet{},
+	}
+}
+
+func (idx *sessionIndex) contains(s *session) bool {
+	_, ok := idx.all[s]
+	return ok
+}
+
+func (idx *sessionIndex) add(s *session) {
+	cid, _ := s.ViewState()
+	idx.all[s] = struct{}{}
+	addToSet(idx.byUser, s.userID, s)
+	addToSet(idx.byChannel, cid, s)
+	if s.TimelineStreaming() {
+		idx.timeline[s] = struct{}{}
+	}
+}
+
+func (idx *sessionIndex) remove(s *session) {
+	cid, _ := s.ViewState()
+	delete(idx.all, s)
+	removeFromSet(idx.byUser, s.userID, s)
+	removeFromSet(idx.byChannel, cid, s)
+	delete(idx.timeline, s)
+}
+
+func addToSet(m map[uuid.UUID]sessionSet, id uuid.UUID, s *session) {
+	ss, ok := m[id]
+	if !ok {
+		ss = sessionSet{}
+		m[id] = ss
+	}
+	ss[s] = struct{}{}
+}
+
+func removeFromSet(m map[uuid.UUID]sessionSet, id uuid.UUID, s *session) {
+	ss, ok := m[id]
+	if !ok {
+		return
+	}
+	delete(ss, s)
+	if len(ss) == 0 {
+		delete(m, id)
+	}
+}
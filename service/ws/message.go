@@ -1,13 +1,81 @@
 package ws
 
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+	"sync"
+)
+
+// format セッションが受信するイベントの形式
+type format int
+
+const (
+	// formatJSON JSON形式のTextMessage
+	formatJSON format = iota
+	// formatMessagePack MessagePack形式のBinaryMessage
+	formatMessagePack
+
+	numFormats
+)
+
+// subprotocolMessagePack イベントをMessagePack形式で受信するためのサブプロトコル
+const subprotocolMessagePack = "msgpack"
+
+func formatFromSubprotocol(protocol string) format {
+	if protocol == subprotocolMessagePack {
+		return formatMessagePack
+	}
+	return formatJSON
+}
+
 type rawMessage struct {
 	t    int
 	data []byte
+
+	// event イベントメッセージかどうか
+	//
+	// イベントメッセージはセッションの形式に応じて送信され、形式毎に1度だけエンコード・圧縮されます。
+	event    bool
+	prepared [numFormats]struct {
+		once sync.Once
+		pm   *websocket.PreparedMessage
+		err  error
+	}
+}
+
+// newEventMessage イベントメッセージを生成します
+func newEventMessage(m *message) *rawMessage {
+	return &rawMessage{
+		t:     websocket.TextMessage,
+		data:  m.toJSON(),
+		event: true,
+	}
+}
+
+// prepare 指定した形式のメッセージを返します
+func (r *rawMessage) prepare(f format) (*websocket.PreparedMessage, error) {
+	p := &r.prepared[f]
+	p.once.Do(func() {
+		switch f {
+		case formatMessagePack:
+			var data []byte
+			data, p.err = jsonToMessagePack(r.data)
+			if p.err == nil {
+				p.pm, p.err = websocket.NewPreparedMessage(websocket.BinaryMessage, data)
+			}
+		default:
+			p.pm, p.err = websocket.NewPreparedMessage(r.t, r.data)
+		}
+	})
+	return p.pm, p.err
 }
 
 type message struct {
 	Type string      `json:"type"`
 	Body interface{} `json:"body"`
+	Seq  uint64      `json:"seq,omitempty"`
 }
 
 func makeMessage(t string, b interface{}) (m *message) {
@@ -17,7 +85,45 @@ func makeMessage(t string, b interface{}) (m *message) {
 	}
 }
 
+func (m *message) withSeq(seq uint64) *message {
+	m.Seq = seq
+	return m
+}
+
 func (m *message) toJSON() (b []byte) {
 	b, _ = json.Marshal(m)
 	return
 }
+
+// jsonToMessagePack JSONをMessagePackに変換します
+//
+// JSON形式と同じ構造になるように、JSONを経由して変換します。
+func jsonToMessagePack(data []byte) ([]byte, error) {
+	dec := stdjson.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(convertJSONNumbers(v))
+}
+
+func convertJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case stdjson.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = convertJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = convertJSONNumbers(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package ws
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/utils/ringbuf"
+	"time"
+)
+
+const (
+	// replayBufferSize ユーザー毎に保持する再送用イベントの最大数
+	replayBufferSize = 200
+	// replayRetention 全セッション切断後に再送用イベントを保持する期間
+	replayRetention = 5 * time.Minute
+	// replayGCInterval 期限切れの再送用バッファを破棄する間隔
+	replayGCInterval = time.Minute
+)
+
+// eventLog ユーザー毎の再送用イベントバッファ
+type eventLog struct {
+	buf *ringbuf.Buffer
+	// sessions このユーザーの接続中のセッション数
+	sessions int
+	// detached 全セッション切断時の最後のセッション状態
+	detached *detachedSession
+	// detachedAt 全セッションが切断された日時
+	detachedAt time.Time
+}
+
+func (l *eventLog) expired(now time.Time) bool {
+	return l.sessions == 0 && now.Sub(l.detachedAt) > replayRetention
+}
+
+// detachedSession 切断済みセッションの状態のスナップショット
+//
+// 切断中のユーザー宛のイベントを判定するために用います。
+type detachedSession struct {
+	key               string
+	userID            uuid.UUID
+	channelID         uuid.UUID
+	state             viewer.State
+	timelineStreaming bool
+}
+
+func snapshotSession(s *session) *detachedSession {
+	cid, state := s.ViewState()
+	return &detachedSession{
+		key:               s.Key(),
+		userID:            s.UserID(),
+		channelID:         cid,
+		state:             state,
+		timelineStreaming: s.TimelineStreaming(),
+	}
+}
+
+// Key implements Session interface.
+func (s *detachedSession) Key() string {
+	return s.key
+}
+
+// UserID implements Session interface.
+func (s *detachedSession) UserID() uuid.UUID {
+	return s.userID
+}
+
+// ViewState implements Session interface.
+func (s *detachedSession) ViewState() (uuid.UUID, viewer.State) {
+	return s.channelID, s.state
+}
+
+// TimelineStreaming implements Session interface.
+func (s *detachedSession) TimelineStreaming() bool {
+	return s.timelineStreaming
+}
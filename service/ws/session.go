@@ -3,9 +3,11 @@ package ws
 import (
 	"github.com/gofrs/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/traPtitech/traQ/service/sfu"
 	"github.com/traPtitech/traQ/service/viewer"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,19 +25,27 @@ type Session interface {
 type session struct {
 	key    string
 	userID uuid.UUID
+	// tokenID 接続に使用したOAuth2トークンのID セッションで認証している場合はuuid.Nil
+	tokenID uuid.UUID
 
 	viewState struct {
 		channelID uuid.UUID
 		state     viewer.State
 	}
 	enabledTimelineStreaming bool
+	// firstDeliveredSeq このセッションに最初に送信したイベントのシーケンス番号
+	firstDeliveredSeq uint64
 	sync.RWMutex
 
 	req      *http.Request
 	conn     *websocket.Conn
 	open     bool
 	streamer *Streamer
-	send     chan *rawMessage
+	// format このセッションが受信するイベントの形式
+	format format
+	// rtcPermitted このセッションのユーザーがWebRTCを利用できるかどうか
+	rtcPermitted bool
+	send         chan *rawMessage
 }
 
 func (s *session) readLoop() {
@@ -75,7 +85,7 @@ func (s *session) writeLoop() {
 				return
 			}
 
-			if err := s.write(msg.t, msg.data); err != nil {
+			if err := s.writeRaw(msg); err != nil {
 				return
 			}
 
@@ -108,6 +118,19 @@ func (s *session) writeMessage(msg *rawMessage) (err error) {
 	return nil
 }
 
+func (s *session) writeRaw(msg *rawMessage) error {
+	if !msg.event {
+		return s.write(msg.t, msg.data)
+	}
+
+	pm, err := msg.prepare(s.format)
+	if err != nil {
+		return err
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return s.conn.WritePreparedMessage(pm)
+}
+
 func (s *session) write(messageType int, data []byte) error {
 	_ = s.conn.SetWriteDeadline(time.Now().Add(writeWait))
 	return s.conn.WriteMessage(messageType, data)
@@ -155,14 +178,27 @@ func (s *session) TimelineStreaming() bool {
 }
 
 func (s *session) setViewState(cid uuid.UUID, state viewer.State) {
-	s.Lock()
-	defer s.Unlock()
-	s.viewState.channelID = cid
-	s.viewState.state = state
+	s.streamer.updateSession(s, func() {
+		s.viewState.channelID = cid
+		s.viewState.state = state
+	})
+}
+
+// SendSignal implements sfu.Peer interface.
+func (s *session) SendSignal(sig *sfu.Signal) error {
+	return s.writeMessage(newEventMessage(makeMessage("RTC_SIGNAL", sig)))
+}
+
+func (s *session) markDelivered(seq uint64) {
+	atomic.CompareAndSwapUint64(&s.firstDeliveredSeq, 0, seq)
+}
+
+func (s *session) deliveredSince() uint64 {
+	return atomic.LoadUint64(&s.firstDeliveredSeq)
 }
 
 func (s *session) setTimelineStreaming(enabled bool) {
-	s.Lock()
-	defer s.Unlock()
-	s.enabledTimelineStreaming = enabled
+	s.streamer.updateSession(s, func() {
+		s.enabledTimelineStreaming = enabled
+	})
 }
@@ -9,12 +9,18 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/traPtitech/traQ/event"
 	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/service/rbac/permission"
+	"github.com/traPtitech/traQ/service/sfu"
+	"github.com/traPtitech/traQ/service/typing"
 	"github.com/traPtitech/traQ/service/viewer"
 	"github.com/traPtitech/traQ/service/webrtcv3"
 	"github.com/traPtitech/traQ/utils/random"
+	"github.com/traPtitech/traQ/utils/ringbuf"
 	"go.uber.org/zap"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -31,26 +37,38 @@ var (
 
 // Streamer WebSocketストリーマー
 type Streamer struct {
-	hub        *hub.Hub
-	vm         *viewer.Manager
-	webrtc     *webrtcv3.Manager
-	logger     *zap.Logger
-	sessions   map[*session]struct{}
+	hub      *hub.Hub
+	vm       *viewer.Manager
+	webrtc   *webrtcv3.Manager
+	typing   *typing.Manager
+	sfu      *sfu.SFU
+	logger   *zap.Logger
+	sessions *sessionIndex
+	logs     map[uuid.UUID]*eventLog
+	// detached 全セッションが切断されたユーザーの再送用バッファ
+	detached   map[uuid.UUID]*eventLog
 	register   chan *session
 	unregister chan *session
 	stop       chan struct{}
 	open       bool
 	mu         sync.RWMutex
+	// seq 最後に発行したイベントのシーケンス番号
+	seq   uint64
+	seqMu sync.Mutex
 }
 
 // NewStreamer WebSocketストリーマーを生成し起動します
-func NewStreamer(hub *hub.Hub, vm *viewer.Manager, webrtc *webrtcv3.Manager, logger *zap.Logger) *Streamer {
+func NewStreamer(hub *hub.Hub, vm *viewer.Manager, webrtc *webrtcv3.Manager, typing *typing.Manager, sfu *sfu.SFU, logger *zap.Logger) *Streamer {
 	h := &Streamer{
 		hub:        hub,
 		vm:         vm,
 		webrtc:     webrtc,
+		typing:     typing,
+		sfu:        sfu,
 		logger:     logger.Named("ws"),
-		sessions:   make(map[*session]struct{}),
+		sessions:   newSessionIndex(),
+		logs:       make(map[uuid.UUID]*eventLog),
+		detached:   make(map[uuid.UUID]*eventLog),
 		register:   make(chan *session),
 		unregister: make(chan *session),
 		stop:       make(chan struct{}),
@@ -58,23 +76,53 @@ func NewStreamer(hub *hub.Hub, vm *viewer.Manager, webrtc *webrtcv3.Manager, log
 	}
 
 	go h.run()
+	go h.tokenRevocationSubscriber()
 	return h
 }
 
 func (s *Streamer) run() {
+	gc := time.NewTicker(replayGCInterval)
+	defer gc.Stop()
+
 	for {
 		select {
 		case session := <-s.register:
 			s.mu.Lock()
-			s.sessions[session] = struct{}{}
+			s.sessions.add(session)
+			l, ok := s.logs[session.userID]
+			if !ok {
+				l = &eventLog{buf: ringbuf.New(replayBufferSize, atomic.LoadUint64(&s.seq))}
+				s.logs[session.userID] = l
+			}
+			l.sessions++
+			l.detached = nil
+			delete(s.detached, session.userID)
 			s.mu.Unlock()
 
 		case session := <-s.unregister:
-			if _, ok := s.sessions[session]; ok {
-				s.mu.Lock()
-				delete(s.sessions, session)
-				s.mu.Unlock()
+			s.mu.Lock()
+			if s.sessions.contains(session) {
+				s.sessions.remove(session)
+				if l, ok := s.logs[session.userID]; ok {
+					l.sessions--
+					if l.sessions == 0 {
+						l.detached = snapshotSession(session)
+						l.detachedAt = time.Now()
+						s.detached[session.userID] = l
+					}
+				}
 			}
+			s.mu.Unlock()
+
+		case now := <-gc.C:
+			s.mu.Lock()
+			for userID, l := range s.detached {
+				if l.expired(now) {
+					delete(s.logs, userID)
+					delete(s.detached, userID)
+				}
+			}
+			s.mu.Unlock()
 
 		case <-s.stop:
 			s.mu.Lock()
@@ -82,11 +130,11 @@ func (s *Streamer) run() {
 				t:    websocket.CloseMessage,
 				data: websocket.FormatCloseMessage(websocket.CloseServiceRestart, "Server is stopping..."),
 			}
-			for session := range s.sessions {
+			for session := range s.sessions.all {
 				_ = session.writeMessage(m)
-				delete(s.sessions, session)
 				session.close()
 			}
+			s.sessions = newSessionIndex()
 			s.open = false
 			s.mu.Unlock()
 			return
@@ -95,25 +143,102 @@ func (s *Streamer) run() {
 }
 
 // WriteMessage 指定したセッションにメッセージを書き込みます
+//
+// メッセージには単調増加するシーケンス番号が付与され、再送用に対象ユーザーのバッファに保持されます。
+// メッセージのエンコードは1度だけ行われ、対象のセッションはインデックスから解決されます。
 func (s *Streamer) WriteMessage(t string, body interface{}, targetFunc TargetFunc) {
-	m := &rawMessage{
-		t:    websocket.TextMessage,
-		data: makeMessage(t, body).toJSON(),
-	}
+	// セッション毎のキューにシーケンス番号順に書き込まれるように、書き込みが完了するまでロックする
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	seq := atomic.AddUint64(&s.seq, 1)
+	m := newEventMessage(makeMessage(t, body).withSeq(seq))
+
+	// 対象の解決中のみセッション一覧をロックし、書き込み中はロックしない
 	s.mu.RLock()
-	for session := range s.sessions {
-		if targetFunc(session) {
-			if err := session.writeMessage(m); err != nil {
-				if err == ErrBufferIsFull {
-					s.logger.Warn("Discard a message because the session's buffer is full.",
-						zap.String("type", t), zap.Any("body", body),
-						zap.Stringer("userID", session.userID))
-					continue
-				}
+	targets := make([]*session, 0)
+	targetFunc.resolve(s.sessions, func(session *session) {
+		targets = append(targets, session)
+	})
+	logs := make(map[*eventLog]struct{})
+	for _, session := range targets {
+		if l, ok := s.logs[session.userID]; ok {
+			logs[l] = struct{}{}
+		}
+	}
+	for _, l := range s.detached {
+		if targetFunc.Match(l.detached) {
+			logs[l] = struct{}{}
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, session := range targets {
+		session.markDelivered(seq)
+		if err := session.writeMessage(m); err != nil {
+			if err == ErrBufferIsFull {
+				s.logger.Warn("Discard a message because the session's buffer is full.",
+					zap.String("type", t), zap.Any("body", body),
+					zap.Stringer("userID", session.userID))
+				continue
 			}
 		}
 	}
+	for l := range logs {
+		l.buf.Push(seq, m)
+	}
+}
+
+// updateSession セッションの状態を更新し、インデックスに反映します
+func (s *Streamer) updateSession(session *session, update func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registered := s.sessions.contains(session)
+	if registered {
+		s.sessions.remove(session)
+	}
+	session.Lock()
+	update()
+	session.Unlock()
+	if registered {
+		s.sessions.add(session)
+	}
+}
+
+// replay 指定したシーケンス番号より後の未受信のイベントをセッションに再送します
+//
+// 欠落があり再送できない場合は、RESYNC_REQUIREDイベントを送信します。
+func (s *Streamer) replay(session *session, lastSeq uint64) {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	current := atomic.LoadUint64(&s.seq)
+	s.mu.RLock()
+	l, ok := s.logs[session.userID]
 	s.mu.RUnlock()
+	if !ok || lastSeq > current {
+		// サーバーが再起動したなど
+		session.sendResyncRequired(current)
+		return
+	}
+
+	entries, ok := l.buf.After(lastSeq)
+	if !ok || len(entries) > cap(session.send)-len(session.send) {
+		session.sendResyncRequired(current)
+		return
+	}
+
+	// このセッションに既に送信済みのイベントは再送しない
+	delivered := session.deliveredSince()
+	for _, e := range entries {
+		if delivered != 0 && e.Seq >= delivered {
+			break
+		}
+		if err := session.writeMessage(e.Value.(*rawMessage)); err != nil {
+			return
+		}
+	}
 }
 
 // ServeHTTP http.Handlerインターフェイスの実装
@@ -134,9 +259,14 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		conn:     conn,
 		open:     true,
 		streamer: s,
-		send:     make(chan *rawMessage, messageBufferSize),
-		userID:   r.Context().Value(extension.CtxUserIDKey).(uuid.UUID),
+		format:   formatFromSubprotocol(conn.Subprotocol()),
+		// WebRTCの権限はecho.Contextを参照できないため、リクエストのcontextから取得する
+		rtcPermitted: extension.IsPermissionGranted(r.Context(), permission.WebRTC),
+		send:         make(chan *rawMessage, messageBufferSize),
+		userID:       r.Context().Value(extension.CtxUserIDKey).(uuid.UUID),
 	}
+	// OAuth2トークンで認証している場合、トークンの無効化時に切断するため保持する
+	session.tokenID, _ = r.Context().Value(extension.CtxOAuth2TokenIDKey).(uuid.UUID)
 
 	s.register <- session
 	wsConnectionCounter.Inc()
@@ -152,6 +282,7 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	session.readLoop()
 
 	s.vm.RemoveViewer(session)
+	s.sfu.Leave(session)
 	_ = s.webrtc.ResetState(session.Key(), session.UserID())
 	s.hub.Publish(hub.Message{
 		Name: event.WSDisconnected,
@@ -165,6 +296,46 @@ func (s *Streamer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	session.close()
 }
 
+// tokenRevocationSubscriber 無効化されたOAuth2トークンで接続しているセッションを切断します
+func (s *Streamer) tokenRevocationSubscriber() {
+	for m := range s.hub.Subscribe(10, event.OAuth2TokensRevoked).Receiver {
+		ids, _ := m.Fields["token_ids"].([]uuid.UUID)
+		s.disconnectByTokenIDs(ids)
+	}
+}
+
+// disconnectByTokenIDs 指定したOAuth2トークンで接続しているセッションを切断します
+func (s *Streamer) disconnectByTokenIDs(ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+	revoked := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		revoked[id] = struct{}{}
+	}
+
+	targets := make([]*session, 0)
+	s.mu.RLock()
+	for session := range s.sessions.all {
+		if session.tokenID == uuid.Nil {
+			continue
+		}
+		if _, ok := revoked[session.tokenID]; ok {
+			targets = append(targets, session)
+		}
+	}
+	s.mu.RUnlock()
+
+	m := &rawMessage{
+		t:    websocket.CloseMessage,
+		data: websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "The token has been revoked."),
+	}
+	for _, session := range targets {
+		_ = session.writeMessage(m)
+		session.close()
+	}
+}
+
 // IsClosed ストリーマーが停止しているかどうか
 func (s *Streamer) IsClosed() bool {
 	s.mu.RLock()
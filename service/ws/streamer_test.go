@@ -0,0 +1,499 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/leandro-lugaresi/hub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traPtitech/traQ/event"
+	"github.com/traPtitech/traQ/router/extension"
+	"github.com/traPtitech/traQ/service/sfu"
+	"github.com/traPtitech/traQ/service/viewer"
+	"github.com/traPtitech/traQ/service/webrtcv3"
+	"github.com/traPtitech/traQ/utils/set"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestStreamer() *Streamer {
+	return &Streamer{
+		logger:   zap.NewNop(),
+		sessions: newSessionIndex(),
+		logs:     map[uuid.UUID]*eventLog{},
+		detached: map[uuid.UUID]*eventLog{},
+		open:     true,
+	}
+}
+
+func (s *Streamer) addTestSession(userID uuid.UUID) *session {
+	session := &session{
+		key:      uuid.Must(uuid.NewV4()).String(),
+		userID:   userID,
+		open:     true,
+		streamer: s,
+		send:     make(chan *rawMessage, messageBufferSize),
+	}
+	s.mu.Lock()
+	s.sessions.add(session)
+	s.mu.Unlock()
+	return session
+}
+
+func received(s *session) int {
+	n := len(s.send)
+	for i := 0; i < n; i++ {
+		<-s.send
+	}
+	return n
+}
+
+func TestStreamer_WriteMessage(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStreamer()
+	u1 := uuid.Must(uuid.NewV4())
+	u2 := uuid.Must(uuid.NewV4())
+	u3 := uuid.Must(uuid.NewV4())
+	cid := uuid.Must(uuid.NewV4())
+
+	s1a := s.addTestSession(u1)
+	s1b := s.addTestSession(u1)
+	s2 := s.addTestSession(u2)
+	s3 := s.addTestSession(u3)
+	s1b.setViewState(cid, viewer.StateMonitoring)
+	s2.setViewState(cid, viewer.StateEditing)
+	s3.setTimelineStreaming(true)
+
+	tests := []struct {
+		name   string
+		target TargetFunc
+		want   map[*session]int
+	}{
+		{"TargetAll", TargetAll(), map[*session]int{s1a: 1, s1b: 1, s2: 1, s3: 1}},
+		{"TargetUsers", TargetUsers(u1, u1), map[*session]int{s1a: 1, s1b: 1}},
+		{"TargetUserSets", TargetUserSets(set.UUIDSetFromArray([]uuid.UUID{u1, u2}), set.UUIDSetFromArray([]uuid.UUID{u2})), map[*session]int{s1a: 1, s1b: 1, s2: 1}},
+		{"TargetChannelViewers", TargetChannelViewers(cid), map[*session]int{s1b: 1, s2: 1}},
+		{"TargetTimelineStreamingEnabled", TargetTimelineStreamingEnabled(), map[*session]int{s3: 1}},
+		{"Or", Or(TargetChannelViewers(cid), TargetUsers(u2, u3)), map[*session]int{s1b: 1, s2: 1, s3: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.WriteMessage("TEST", nil, tt.target)
+			for _, session := range []*session{s1a, s1b, s2, s3} {
+				assert.Equal(t, tt.want[session], received(session), session.userID)
+			}
+		})
+	}
+
+	t.Run("view state changed", func(t *testing.T) {
+		s1b.setViewState(uuid.Nil, viewer.StateNone)
+		s.WriteMessage("TEST", nil, TargetChannelViewers(cid))
+		assert.Equal(t, 0, received(s1b))
+		assert.Equal(t, 1, received(s2))
+	})
+}
+
+func TestStreamer_UnregisterWhileUpdating(t *testing.T) {
+	t.Parallel()
+
+	s := newTestStreamer()
+	s.register = make(chan *session)
+	s.unregister = make(chan *session)
+	s.stop = make(chan struct{})
+	go s.run()
+
+	cid := uuid.Must(uuid.NewV4())
+	updating := s.addTestSession(uuid.Must(uuid.NewV4()))
+
+	// 他のセッションの登録解除中に、別のセッションの閲覧状態を変更する
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				updating.setViewState(cid, viewer.StateMonitoring)
+			} else {
+				updating.setTimelineStreaming(i%4 == 1)
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		session := &session{
+			key:      uuid.Must(uuid.NewV4()).String(),
+			userID:   uuid.Must(uuid.NewV4()),
+			open:     true,
+			streamer: s,
+			send:     make(chan *rawMessage, messageBufferSize),
+		}
+		s.register <- session
+		s.unregister <- session
+	}
+	close(stop)
+	<-done
+
+	// 最後の登録解除の反映を待つ
+	require.Eventually(t, func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.sessions.all) == 1
+	}, time.Second, 10*time.Millisecond)
+	s.mu.RLock()
+	assert.True(t, s.sessions.contains(updating))
+	s.mu.RUnlock()
+
+	s.unregister <- updating
+	close(s.stop)
+}
+
+// writeMessageByScan 全セッションを走査してメッセージを書き込みます(比較用)
+func (s *Streamer) writeMessageByScan(t string, body interface{}, targetFunc TargetFunc) {
+	m := &rawMessage{
+		t:    websocket.TextMessage,
+		data: makeMessage(t, body).toJSON(),
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for session := range s.sessions.all {
+		if targetFunc.Match(session) {
+			_ = session.writeMessage(m)
+		}
+	}
+}
+
+func BenchmarkStreamer_WriteMessage(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		s := newTestStreamer()
+		users := make([]uuid.UUID, n/2)
+		for i := range users {
+			users[i] = uuid.Must(uuid.NewV4())
+		}
+		channels := make([]uuid.UUID, 50)
+		for i := range channels {
+			channels[i] = uuid.Must(uuid.NewV4())
+		}
+
+		stop := make(chan struct{})
+		for i := 0; i < n; i++ {
+			session := s.addTestSession(users[i%len(users)])
+			session.setViewState(channels[i%len(channels)], viewer.StateMonitoring)
+			go func() {
+				for {
+					select {
+					case <-session.send:
+					case <-stop:
+						return
+					}
+				}
+			}()
+		}
+
+		targets := map[string]TargetFunc{
+			"TargetUsers":          TargetUsers(users[0], users[1]),
+			"TargetUserSets":       TargetUserSets(set.UUIDSetFromArray(users[:10])),
+			"TargetChannelViewers": TargetChannelViewers(channels[0]),
+		}
+		body := struct {
+			ID uuid.UUID `json:"id"`
+		}{ID: users[0]}
+		for name, target := range targets {
+			target := target
+			b.Run(fmt.Sprintf("%s/sessions=%d/indexed", name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					s.WriteMessage("TEST", body, target)
+				}
+			})
+			b.Run(fmt.Sprintf("%s/sessions=%d/scan", name, n), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					s.writeMessageByScan("TEST", body, target)
+				}
+			})
+		}
+		close(stop)
+	}
+}
+
+func TestStreamer_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	h := hub.New()
+	webrtc := webrtcv3.NewManager(h)
+	sfuServer, err := sfu.NewSFU(h, webrtc, nil, sfu.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	s := NewStreamer(h, viewer.NewManager(h), webrtc, nil, sfuServer, zap.NewNop())
+	uid := uuid.Must(uuid.NewV4())
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		s.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), extension.CtxUserIDKey, uid)))
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	type body struct {
+		ID    uuid.UUID    `json:"id"`
+		State viewer.State `json:"state"`
+		Count int          `json:"count"`
+	}
+	sent := body{ID: uid, State: viewer.StateEditing, Count: 3}
+
+	dial := func(t *testing.T, dialer *websocket.Dialer) *websocket.Conn {
+		t.Helper()
+		conn, _, err := dialer.Dial(url, nil)
+		require.NoError(t, err)
+		// セッションが登録されるまで待つ
+		require.Eventually(t, func() bool {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			return len(s.sessions.byUser[uid]) > 0
+		}, time.Second, 10*time.Millisecond)
+		return conn
+	}
+
+	t.Run("json", func(t *testing.T) {
+		conn := dial(t, &websocket.Dialer{})
+		defer conn.Close()
+		s.WriteMessage("TEST", sent, TargetUsers(uid))
+
+		typ, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.TextMessage, typ)
+		assert.Contains(t, string(data), `"type":"TEST"`)
+		assert.Contains(t, string(data), `"state":"editing"`)
+	})
+
+	t.Run("msgpack with compression", func(t *testing.T) {
+		conn := dial(t, &websocket.Dialer{
+			Subprotocols:      []string{subprotocolMessagePack},
+			EnableCompression: true,
+		})
+		defer conn.Close()
+		assert.Equal(t, subprotocolMessagePack, conn.Subprotocol())
+		s.WriteMessage("TEST", sent, TargetUsers(uid))
+
+		typ, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.BinaryMessage, typ)
+
+		var m struct {
+			Type string `msgpack:"type"`
+			Body struct {
+				ID    string `msgpack:"id"`
+				State string `msgpack:"state"`
+				Count int    `msgpack:"count"`
+			} `msgpack:"body"`
+			Seq uint64 `msgpack:"seq"`
+		}
+		require.NoError(t, msgpack.Unmarshal(data, &m))
+		assert.Equal(t, "TEST", m.Type)
+		assert.Equal(t, uid.String(), m.Body.ID)
+		assert.Equal(t, "editing", m.Body.State)
+		assert.Equal(t, 3, m.Body.Count)
+		assert.NotZero(t, m.Seq)
+	})
+}
+
+func TestStreamer_TokenRevocation(t *testing.T) {
+	t.Parallel()
+
+	h := hub.New()
+	webrtc := webrtcv3.NewManager(h)
+	sfuServer, err := sfu.NewSFU(h, webrtc, nil, sfu.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	s := NewStreamer(h, viewer.NewManager(h), webrtc, nil, sfuServer, zap.NewNop())
+	uid := uuid.Must(uuid.NewV4())
+	revokedToken := uuid.Must(uuid.NewV4())
+	otherToken := uuid.Must(uuid.NewV4())
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), extension.CtxUserIDKey, uid)
+		if tokenID, err := uuid.FromString(r.URL.Query().Get("token")); err == nil {
+			ctx = context.WithValue(ctx, extension.CtxOAuth2TokenIDKey, tokenID)
+		}
+		s.ServeHTTP(rw, r.WithContext(ctx))
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	dial := func(t *testing.T, query string) *websocket.Conn {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial(url+query, nil)
+		require.NoError(t, err)
+		return conn
+	}
+	revoked := dial(t, "?token="+revokedToken.String())
+	defer revoked.Close()
+	other := dial(t, "?token="+otherToken.String())
+	defer other.Close()
+	cookie := dial(t, "")
+	defer cookie.Close()
+	require.Eventually(t, func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.sessions.byUser[uid]) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	h.Publish(hub.Message{
+		Name: event.OAuth2TokensRevoked,
+		Fields: hub.Fields{
+			"token_ids": []uuid.UUID{revokedToken},
+		},
+	})
+
+	_ = revoked.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = revoked.ReadMessage()
+	assert.Error(t, err)
+	require.Eventually(t, func() bool {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.sessions.byUser[uid]) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	s.WriteMessage("TEST", nil, TargetUsers(uid))
+	for _, conn := range []*websocket.Conn{other, cookie} {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := conn.ReadMessage()
+		if assert.NoError(t, err) {
+			assert.Contains(t, string(data), `"type":"TEST"`)
+		}
+	}
+}
+
+func TestStreamer_Resume(t *testing.T) {
+	t.Parallel()
+
+	h := hub.New()
+	webrtc := webrtcv3.NewManager(h)
+	sfuServer, err := sfu.NewSFU(h, webrtc, nil, sfu.Config{}, zap.NewNop())
+	require.NoError(t, err)
+	s := NewStreamer(h, viewer.NewManager(h), webrtc, nil, sfuServer, zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		uid := uuid.FromStringOrNil(r.URL.Query().Get("user"))
+		s.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), extension.CtxUserIDKey, uid)))
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	sessions := func(uid uuid.UUID) int {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		return len(s.sessions.byUser[uid])
+	}
+	dial := func(t *testing.T, uid uuid.UUID) *websocket.Conn {
+		t.Helper()
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?user="+uid.String(), nil)
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return sessions(uid) > 0 }, time.Second, 10*time.Millisecond)
+		return conn
+	}
+	disconnect := func(t *testing.T, uid uuid.UUID, conn *websocket.Conn) {
+		t.Helper()
+		require.NoError(t, conn.Close())
+		require.Eventually(t, func() bool { return sessions(uid) == 0 }, time.Second, 10*time.Millisecond)
+	}
+
+	type received struct {
+		Type string      `json:"type"`
+		Body interface{} `json:"body"`
+		Seq  uint64      `json:"seq"`
+	}
+	read := func(t *testing.T, conn *websocket.Conn) received {
+		t.Helper()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		var m received
+		require.NoError(t, conn.ReadJSON(&m))
+		return m
+	}
+	readTest := func(t *testing.T, conn *websocket.Conn, n int) uint64 {
+		t.Helper()
+		m := read(t, conn)
+		require.Equal(t, "TEST", m.Type)
+		require.EqualValues(t, n, m.Body)
+		return m.Seq
+	}
+
+	t.Run("replay missed events", func(t *testing.T) {
+		uid := uuid.Must(uuid.NewV4())
+
+		conn := dial(t, uid)
+		s.WriteMessage("TEST", 1, TargetUsers(uid))
+		s.WriteMessage("TEST", 2, TargetUsers(uid))
+		last := readTest(t, conn, 1)
+		seq2 := readTest(t, conn, 2)
+		disconnect(t, uid, conn)
+
+		// 切断中のイベント
+		s.WriteMessage("TEST", 3, TargetUsers(uid))
+		s.WriteMessage("TEST", 4, TargetUsers(uid))
+
+		conn = dial(t, uid)
+		defer conn.Close()
+		// 再接続後、resumeより前に受信したイベント
+		s.WriteMessage("TEST", 5, TargetUsers(uid))
+		seq5 := readTest(t, conn, 5)
+
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("resume:%d", last))))
+		// 元のseqのまま順番に再送され、受信済みの5は再送されない
+		assert.Equal(t, seq2, readTest(t, conn, 2))
+		seq3 := readTest(t, conn, 3)
+		seq4 := readTest(t, conn, 4)
+		assert.True(t, seq2 < seq3 && seq3 < seq4 && seq4 < seq5)
+
+		s.WriteMessage("TEST", 6, TargetUsers(uid))
+		assert.Greater(t, readTest(t, conn, 6), seq5)
+	})
+
+	t.Run("resync required when events were discarded", func(t *testing.T) {
+		uid := uuid.Must(uuid.NewV4())
+
+		conn := dial(t, uid)
+		s.WriteMessage("TEST", 0, TargetUsers(uid))
+		last := readTest(t, conn, 0)
+		disconnect(t, uid, conn)
+
+		// 再送用バッファから溢れる数のイベント
+		for i := 1; i <= replayBufferSize+1; i++ {
+			s.WriteMessage("TEST", i, TargetUsers(uid))
+		}
+
+		conn = dial(t, uid)
+		defer conn.Close()
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("resume:%d", last))))
+		m := read(t, conn)
+		assert.Equal(t, "RESYNC_REQUIRED", m.Type)
+		assert.EqualValues(t, map[string]interface{}{"seq": float64(atomic.LoadUint64(&s.seq))}, m.Body)
+	})
+
+	t.Run("resync required for unknown seq", func(t *testing.T) {
+		uid := uuid.Must(uuid.NewV4())
+
+		conn := dial(t, uid)
+		defer conn.Close()
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("resume:18446744073709551615")))
+		assert.Equal(t, "RESYNC_REQUIRED", read(t, conn).Type)
+	})
+}
+
+func TestRawMessage_prepare(t *testing.T) {
+	t.Parallel()
+
+	m := newEventMessage(makeMessage("TEST", nil))
+	pm1, err := m.prepare(formatMessagePack)
+	require.NoError(t, err)
+	pm2, err := m.prepare(formatMessagePack)
+	require.NoError(t, err)
+	// 同じ形式では1度だけエンコードされる
+	assert.Same(t, pm1, pm2)
+}
@@ -5,63 +5,190 @@ import (
 	"github.com/traPtitech/traQ/utils/set"
 )
 
-// TargetFunc メッセージ送信対象関数
-type TargetFunc func(s Session) bool
+// TargetFunc メッセージ送信対象
+//
+// 送信時には、セッションを全て走査せずにインデックスから直接対象のセッションが解決されます。
+type TargetFunc interface {
+	// Match 指定したセッションが送信対象かどうかを返します
+	Match(s Session) bool
+	// resolve 送信対象のセッションを列挙します
+	//
+	// 同じセッションが複数回列挙されることはありません。
+	resolve(idx *sessionIndex, f func(s *session))
+}
+
+type targetAll struct{}
 
 // TargetAll 全セッションを対象に送信します
 func TargetAll() TargetFunc {
-	return func(_ Session) bool {
-		return true
+	return targetAll{}
+}
+
+func (targetAll) Match(_ Session) bool {
+	return true
+}
+
+func (targetAll) resolve(idx *sessionIndex, f func(s *session)) {
+	for s := range idx.all {
+		f(s)
 	}
 }
 
+type targetUsers []uuid.UUID
+
 // TargetUsers 指定したユーザーを対象に送信します
 func TargetUsers(userID ...uuid.UUID) TargetFunc {
-	return func(s Session) bool {
-		for _, u := range userID {
-			if u == s.UserID() {
-				return true
-			}
+	return targetUsers(userID)
+}
+
+func (t targetUsers) Match(s Session) bool {
+	for _, u := range t {
+		if u == s.UserID() {
+			return true
+		}
+	}
+	return false
+}
+
+func (t targetUsers) resolve(idx *sessionIndex, f func(s *session)) {
+	for i, u := range t {
+		if containsUUID(t[:i], u) {
+			continue
+		}
+		for s := range idx.byUser[u] {
+			f(s)
 		}
-		return false
 	}
 }
 
+type targetUserSets []set.UUID
+
 // TargetUserSets 指定したユーザーを対象に送信します
 func TargetUserSets(sets ...set.UUID) TargetFunc {
-	return func(s Session) bool {
-		for _, set := range sets {
-			if set.Contains(s.UserID()) {
-				return true
+	return targetUserSets(sets)
+}
+
+func (t targetUserSets) Match(s Session) bool {
+	for _, set := range t {
+		if set.Contains(s.UserID()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t targetUserSets) resolve(idx *sessionIndex, f func(s *session)) {
+	total := 0
+	for _, set := range t {
+		total += len(set)
+	}
+
+	if total > len(idx.byUser) {
+		// 接続中のユーザーの方が少ない場合は、接続中のユーザーを走査する
+		for u, ss := range idx.byUser {
+			if t.contains(u) {
+				for s := range ss {
+					f(s)
+				}
+			}
+		}
+		return
+	}
+
+	for i, set := range t {
+		for u := range set {
+			if t[:i].contains(u) {
+				continue
+			}
+			for s := range idx.byUser[u] {
+				f(s)
 			}
 		}
-		return false
 	}
 }
 
+func (t targetUserSets) contains(u uuid.UUID) bool {
+	for _, set := range t {
+		if set.Contains(u) {
+			return true
+		}
+	}
+	return false
+}
+
+type targetChannelViewers uuid.UUID
+
 // TargetChannelViewers 指定したチャンネルの閲覧者を対象に送信します
 func TargetChannelViewers(channelID uuid.UUID) TargetFunc {
-	return func(s Session) bool {
-		c, _ := s.ViewState()
-		return c == channelID
+	return targetChannelViewers(channelID)
+}
+
+func (t targetChannelViewers) Match(s Session) bool {
+	c, _ := s.ViewState()
+	return c == uuid.UUID(t)
+}
+
+func (t targetChannelViewers) resolve(idx *sessionIndex, f func(s *session)) {
+	for s := range idx.byChannel[uuid.UUID(t)] {
+		f(s)
 	}
 }
 
+type targetTimelineStreamingEnabled struct{}
+
 // TargetTimelineStreamingEnabled タイムラインストリーミングが有効なコネクションを対象に送信します
 func TargetTimelineStreamingEnabled() TargetFunc {
-	return func(s Session) bool {
-		return s.TimelineStreaming()
+	return targetTimelineStreamingEnabled{}
+}
+
+func (targetTimelineStreamingEnabled) Match(s Session) bool {
+	return s.TimelineStreaming()
+}
+
+func (targetTimelineStreamingEnabled) resolve(idx *sessionIndex, f func(s *session)) {
+	for s := range idx.timeline {
+		f(s)
 	}
 }
 
+type targetOr []TargetFunc
+
 // Or いずれかのTargetFuncの条件に該当する対象に送信します
 func Or(funcs ...TargetFunc) TargetFunc {
-	return func(s Session) bool {
-		for _, f := range funcs {
-			if f(s) {
-				return true
+	return targetOr(funcs)
+}
+
+func (t targetOr) Match(s Session) bool {
+	for _, f := range t {
+		if f.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t targetOr) resolve(idx *sessionIndex, f func(s *session)) {
+	if len(t) == 1 {
+		t[0].resolve(idx, f)
+		return
+	}
+
+	seen := sessionSet{}
+	for _, target := range t {
+		target.resolve(idx, func(s *session) {
+			if _, ok := seen[s]; !ok {
+				seen[s] = struct{}{}
+				f(s)
 			}
+		})
+	}
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
 		}
-		return false
 	}
+	return false
 }
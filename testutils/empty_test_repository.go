@@ -22,6 +22,14 @@ type EmptyTestRepository struct {
 	repository.BotRepository
 	repository.ClipRepository
 	repository.OgpCacheRepository
+	repository.CallRepository
+	repository.WebPushSubscriptionRepository
+	repository.UserEmailSettingRepository
+	repository.NotificationRepository
+	repository.KeywordAlertRepository
+	repository.UserDNDSettingRepository
+	repository.PersonalAccessTokenRepository
+	repository.SAMLAssertionRepository
 }
 
 func (*EmptyTestRepository) Sync() (init bool, err error) {
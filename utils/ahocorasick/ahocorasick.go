@@ -0,0 +1,108 @@
+// Package ahocorasick Aho-Corasick法による複数パターンの文字列検索
+package ahocorasick
+
+// Matcher 複数のパターンを入力文字列から一度の走査で検索するマッチャー
+//
+// 構築後は読み取り専用なので、複数のgoroutineから同時に使用できます。
+type Matcher struct {
+	nodes []node
+	// patterns パターンの数
+	patterns int
+}
+
+type node struct {
+	next map[byte]int32
+	fail int32
+	// outputs このノードで終わるパターンのインデックス (failリンク先のものを含む)
+	outputs []int
+}
+
+// New 指定したパターンのマッチャーを構築します
+//
+// パターンはバイト列として比較されます。空文字列のパターンはマッチしません。
+func New(patterns []string) *Matcher {
+	m := &Matcher{
+		nodes:    []node{{next: map[byte]int32{}}},
+		patterns: len(patterns),
+	}
+
+	// トライ木の構築
+	for i, p := range patterns {
+		if len(p) == 0 {
+			continue
+		}
+		cur := int32(0)
+		for j := 0; j < len(p); j++ {
+			nxt, ok := m.nodes[cur].next[p[j]]
+			if !ok {
+				nxt = int32(len(m.nodes))
+				m.nodes = append(m.nodes, node{next: map[byte]int32{}})
+				m.nodes[cur].next[p[j]] = nxt
+			}
+			cur = nxt
+		}
+		m.nodes[cur].outputs = append(m.nodes[cur].outputs, i)
+	}
+
+	// 幅優先探索でfailリンクを張る
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		m.nodes[child].fail = 0
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for c, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for {
+				if nxt, ok := m.nodes[f].next[c]; ok && nxt != child {
+					m.nodes[child].fail = nxt
+					break
+				}
+				if f == 0 {
+					m.nodes[child].fail = 0
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			m.nodes[child].outputs = append(m.nodes[child].outputs, m.nodes[m.nodes[child].fail].outputs...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// Match textに含まれる全てのパターンのインデックスを返します
+//
+// 各インデックスは1度だけ含まれます。順番は不定です。
+func (m *Matcher) Match(text string) []int {
+	var (
+		result []int
+		seen   map[int]struct{}
+	)
+	cur := int32(0)
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		for {
+			if nxt, ok := m.nodes[cur].next[c]; ok {
+				cur = nxt
+				break
+			}
+			if cur == 0 {
+				break
+			}
+			cur = m.nodes[cur].fail
+		}
+		for _, idx := range m.nodes[cur].outputs {
+			if seen == nil {
+				seen = make(map[int]struct{}, m.patterns)
+			}
+			if _, ok := seen[idx]; !ok {
+				seen[idx] = struct{}{}
+				result = append(result, idx)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+package ahocorasick
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	t.Parallel()
+
+	m := New([]string{"he", "she", "his", "hers", "", "障害", "障害対応"})
+	cases := []struct {
+		text string
+		want []int
+	}{
+		{"ushers", []int{0, 1, 3}},
+		{"ahishers", []int{0, 1, 2, 3}},
+		{"nothing", nil},
+		{"", nil},
+		{"hehehe", []int{0}},
+		{"本番で障害対応中", []int{5, 6}},
+		{"障", nil},
+	}
+	for _, c := range cases {
+		got := m.Match(c.text)
+		sort.Ints(got)
+		assert.Equal(t, c.want, got, c.text)
+	}
+}
+
+func TestMatcher_Match_Naive(t *testing.T) {
+	t.Parallel()
+
+	patterns := []string{"a", "ab", "bab", "bc", "bca", "c", "caa", "aaa"}
+	m := New(patterns)
+	for _, text := range []string{"abccab", "aaaa", "bcaab", "cbabca", "xyz"} {
+		var want []int
+		for i, p := range patterns {
+			if strings.Contains(text, p) {
+				want = append(want, i)
+			}
+		}
+		got := m.Match(text)
+		sort.Ints(got)
+		assert.Equal(t, want, got, text)
+	}
+}
+
+func BenchmarkMatcher_Match(b *testing.B) {
+	patterns := make([]string, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		patterns = append(patterns, strings.Repeat(string(rune('a'+i%26)), 1+i%7)+string(rune('A'+i%26)))
+	}
+	m := New(patterns)
+	text := strings.Repeat("the quick brown fox jumps over the lazy dog. ", 50)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(text)
+	}
+}
@@ -0,0 +1,63 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// JSONWebKey JSON Web Key (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// JSONWebKeySet JSON Web Key Set (RFC 7517)
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS 署名検証用の公開鍵をJWK Setとして返します
+func JWKS() JSONWebKeySet {
+	if pub == nil {
+		return JSONWebKeySet{Keys: []JSONWebKey{}}
+	}
+	x, y := encodeCoordinates(pub)
+	return JSONWebKeySet{Keys: []JSONWebKey{{
+		Kty: "EC",
+		Crv: "P-256",
+		X:   x,
+		Y:   y,
+		Use: "sig",
+		Alg: "ES256",
+		Kid: KeyID(),
+	}}}
+}
+
+// KeyID 署名鍵のKey ID (RFC 7638 JWK Thumbprint) を返します
+func KeyID() string {
+	if pub == nil {
+		return ""
+	}
+	x, y := encodeCoordinates(pub)
+	// メンバーは辞書順に並べる必要がある
+	thumbprint := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, x, y)))
+	return base64.RawURLEncoding.EncodeToString(thumbprint[:])
+}
+
+func encodeCoordinates(key *ecdsa.PublicKey) (x, y string) {
+	const size = 32 // P-256
+	xb := make([]byte, size)
+	yb := make([]byte, size)
+	bx := key.X.Bytes()
+	by := key.Y.Bytes()
+	copy(xb[size-len(bx):], bx)
+	copy(yb[size-len(by):], by)
+	return base64.RawURLEncoding.EncodeToString(xb), base64.RawURLEncoding.EncodeToString(yb)
+}
@@ -26,7 +26,9 @@ func SetupSigner(privRaw []byte) error {
 
 // Sign JWTの発行を行う
 func Sign(claims jwt.Claims) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(priv)
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = KeyID()
+	return token.SignedString(priv)
 }
 
 // Verify JWTの検証を行う
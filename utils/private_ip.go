@@ -6,13 +6,20 @@ var privateIPBlocks []*net.IPNet
 
 func init() {
 	for _, cidr := range []string{
+		"0.0.0.0/8",      // "this" network
 		"127.0.0.0/8",    // IPv4 loopback
 		"10.0.0.0/8",     // RFC1918
+		"100.64.0.0/10",  // RFC6598 (CGN)
+		"169.254.0.0/16", // IPv4 link-local
 		"172.16.0.0/12",  // RFC1918
 		"192.168.0.0/16", // RFC1918
+		"224.0.0.0/4",    // IPv4 multicast
+		"240.0.0.0/4",    // IPv4 reserved, broadcast
+		"::/128",         // IPv6 unspecified
 		"::1/128",        // IPv6 loopback
 		"fe80::/10",      // IPv6 link-local
 		"fc00::/7",       // IPv6 unique local addr
+		"ff00::/8",       // IPv6 multicast
 	} {
 		_, block, _ := net.ParseCIDR(cidr)
 		privateIPBlocks = append(privateIPBlocks, block)
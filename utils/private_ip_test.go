@@ -11,6 +11,10 @@ func TestIsPrivateIP(t *testing.T) {
 	assert := assert.New(t)
 
 	assert.True(IsPrivateIP(net.ParseIP("127.0.0.1")))
+	assert.True(IsPrivateIP(net.ParseIP("169.254.169.254")))
+	assert.True(IsPrivateIP(net.ParseIP("0.0.0.0")))
+	assert.True(IsPrivateIP(net.ParseIP("::ffff:10.0.0.1")))
+	assert.True(IsPrivateIP(net.ParseIP("fe80::1")))
 	assert.False(IsPrivateIP(net.ParseIP("8.8.8.8")))
 }
 
@@ -0,0 +1,80 @@
+package ringbuf
+
+import "sync"
+
+// Entry シーケンス番号付きのバッファ要素
+type Entry struct {
+	Seq   uint64
+	Value interface{}
+}
+
+// Buffer シーケンス番号付きの要素を保持する固定長リングバッファ
+//
+// 容量を超えた場合は古い要素から破棄されます。
+type Buffer struct {
+	mu      sync.Mutex
+	entries []Entry
+	head    int
+	size    int
+	since   uint64
+}
+
+// New Bufferを生成します
+//
+// sinceにはバッファの生成時点で既に発行済みの最後のシーケンス番号を指定します。
+// since以前の要素はこのバッファに含まれません。
+func New(capacity int, since uint64) *Buffer {
+	if capacity <= 0 {
+		panic("ringbuf: capacity must be positive")
+	}
+	return &Buffer{
+		entries: make([]Entry, capacity),
+		since:   since,
+	}
+}
+
+// Push 要素を追加します
+//
+// seqは単調増加である必要があります。
+func (b *Buffer) Push(seq uint64, v interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.size == len(b.entries) {
+		// 最も古い要素を破棄
+		b.since = b.entries[b.head].Seq
+		b.head = (b.head + 1) % len(b.entries)
+		b.size--
+	}
+	b.entries[(b.head+b.size)%len(b.entries)] = Entry{Seq: seq, Value: v}
+	b.size++
+}
+
+// After seqより後の要素を古い順に返します
+//
+// seqより後の要素の一部が既に破棄されている場合、またはバッファ生成以前のseqが指定された場合は、
+// 欠落があるためfalseを返します。
+func (b *Buffer) After(seq uint64) ([]Entry, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if seq < b.since {
+		return nil, false
+	}
+
+	result := make([]Entry, 0)
+	for i := 0; i < b.size; i++ {
+		e := b.entries[(b.head+i)%len(b.entries)]
+		if e.Seq > seq {
+			result = append(result, e)
+		}
+	}
+	return result, true
+}
+
+// Len バッファに保持されている要素数を返します
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
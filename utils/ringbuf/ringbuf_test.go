@@ -0,0 +1,81 @@
+package ringbuf
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() { New(0, 0) })
+	b := New(3, 5)
+	if assert.NotNil(t, b) {
+		assert.Equal(t, 0, b.Len())
+		assert.EqualValues(t, 5, b.since)
+	}
+}
+
+func TestBuffer_After(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no gap", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		b := New(3, 0)
+		b.Push(1, "a")
+		b.Push(2, "b")
+
+		entries, ok := b.After(0)
+		if assert.True(ok) {
+			assert.Equal([]Entry{{Seq: 1, Value: "a"}, {Seq: 2, Value: "b"}}, entries)
+		}
+
+		entries, ok = b.After(1)
+		if assert.True(ok) {
+			assert.Equal([]Entry{{Seq: 2, Value: "b"}}, entries)
+		}
+
+		entries, ok = b.After(2)
+		if assert.True(ok) {
+			assert.Empty(entries)
+		}
+	})
+
+	t.Run("sparse sequence", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		b := New(3, 10)
+		b.Push(12, "a")
+		b.Push(15, "b")
+
+		entries, ok := b.After(11)
+		if assert.True(ok) {
+			assert.Equal([]Entry{{Seq: 12, Value: "a"}, {Seq: 15, Value: "b"}}, entries)
+		}
+
+		_, ok = b.After(9)
+		assert.False(ok)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		t.Parallel()
+		assert := assert.New(t)
+
+		b := New(3, 0)
+		for i := uint64(1); i <= 5; i++ {
+			b.Push(i, i)
+		}
+		assert.Equal(3, b.Len())
+
+		_, ok := b.After(1)
+		assert.False(ok)
+
+		entries, ok := b.After(2)
+		if assert.True(ok) {
+			assert.Equal([]Entry{{Seq: 3, Value: uint64(3)}, {Seq: 4, Value: uint64(4)}, {Seq: 5, Value: uint64(5)}}, entries)
+		}
+	})
+}
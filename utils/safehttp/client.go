@@ -0,0 +1,269 @@
+// Package safehttp ユーザーが指定したURLに安全にアクセスするためのHTTPクライアント
+//
+// 名前解決を自前で行い、プライベートネットワーク空間のアドレスへの接続を全てのリダイレクトで拒否することで、
+// サーバーを経由した内部ネットワークへのアクセス(SSRF)を防ぎます。
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/traPtitech/traQ/utils"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxRedirects デフォルトのリダイレクトの最大回数
+	DefaultMaxRedirects = 5
+	// DefaultMaxBodySize デフォルトのレスポンスボディの最大バイト数
+	DefaultMaxBodySize = 5 << 20 // 5MiB
+	// DefaultTimeout デフォルトのリクエストタイムアウト
+	DefaultTimeout = 5 * time.Second
+)
+
+var (
+	// ErrForbiddenHost アクセスが禁止されたホストです
+	ErrForbiddenHost = errors.New("forbidden host")
+	// ErrUnsupportedScheme http, https以外のスキームです
+	ErrUnsupportedScheme = errors.New("unsupported scheme")
+	// ErrTooManyRedirects リダイレクトの回数が上限を超えました
+	ErrTooManyRedirects = errors.New("too many redirects")
+	// ErrBodyTooLarge レスポンスボディの大きさが上限を超えました
+	ErrBodyTooLarge = errors.New("response body too large")
+)
+
+// Config クライアント設定
+type Config struct {
+	// MaxRedirects リダイレクトの最大回数 (0の場合はDefaultMaxRedirects, 負の場合はリダイレクトしない)
+	MaxRedirects int
+	// MaxBodySize レスポンスボディの最大バイト数 (0の場合はDefaultMaxBodySize)
+	MaxBodySize int64
+	// Timeout リクエストタイムアウト (0の場合はDefaultTimeout)
+	Timeout time.Duration
+	// AllowHosts プライベートネットワーク空間のアドレスであってもアクセスを許可するホスト
+	//
+	// `.example.com`のように先頭を`.`にすると、そのドメインとサブドメインにマッチします。
+	AllowHosts []string
+	// DenyHosts アクセスを拒否するホスト (AllowHostsより優先)
+	//
+	// AllowHostsと同じ形式です。
+	DenyHosts []string
+}
+
+// Client SSRF対策済みHTTPクライアント
+type Client struct {
+	client      *http.Client
+	maxBodySize int64
+	allow       hostList
+	deny        hostList
+	resolver    *net.Resolver
+	// isForbiddenIP 接続を拒否するIPアドレスかどうか (テスト用に差し替え可能)
+	isForbiddenIP func(ip net.IP) bool
+}
+
+// NewClient 新しいクライアントを生成します
+func NewClient(config Config) *Client {
+	c := &Client{
+		maxBodySize:   config.MaxBodySize,
+		allow:         newHostList(config.AllowHosts),
+		deny:          newHostList(config.DenyHosts),
+		resolver:      net.DefaultResolver,
+		isForbiddenIP: utils.IsPrivateIP,
+	}
+	if c.maxBodySize == 0 {
+		c.maxBodySize = DefaultMaxBodySize
+	}
+	maxRedirects := config.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := &http.Transport{
+		Proxy: nil, // プロキシを経由すると接続先を検証できない
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			ips, err := c.lookup(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			// 検証済みのアドレスに直接接続することで、DNSリバインディングを防ぐ
+			var lastErr error
+			for _, ip := range ips {
+				conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+				if err == nil {
+					return conn, nil
+				}
+				lastErr = err
+			}
+			return nil, lastErr
+		},
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+	}
+	c.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return c.checkURL(req.URL)
+		},
+	}
+	return c
+}
+
+// Get 指定したURLにGETリクエストを送信します
+//
+// レスポンスボディはMaxBodySizeを超えて読み込もうとするとErrBodyTooLargeを返します。
+// 呼び出し側でレスポンスボディを閉じる必要があります。
+func (c *Client) Get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do 指定したリクエストを送信します
+//
+// レスポンスボディはMaxBodySizeを超えて読み込もうとするとErrBodyTooLargeを返します。
+// 呼び出し側でレスポンスボディを閉じる必要があります。
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if err := c.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, err
+	}
+	if resp.ContentLength > c.maxBodySize {
+		resp.Body.Close()
+		return nil, ErrBodyTooLarge
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: c.maxBodySize}
+	return resp, nil
+}
+
+// checkURL URLのスキームとホストがアクセス可能かどうかを検証します
+func (c *Client) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedScheme
+	}
+	host := u.Hostname()
+	if len(host) == 0 || c.deny.contains(host) {
+		return ErrForbiddenHost
+	}
+	return nil
+}
+
+// lookup ホストを名前解決し、接続可能なアドレスを返します
+func (c *Client) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if c.deny.contains(host) {
+		return nil, ErrForbiddenHost
+	}
+	allowed := c.allow.contains(host)
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := c.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	result := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if !allowed && c.isForbiddenIP(ip) {
+			continue
+		}
+		result = append(result, ip)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrForbiddenHost, host)
+	}
+	return result, nil
+}
+
+// hostList ホスト名のリスト
+type hostList struct {
+	exact    map[string]struct{}
+	suffixes []string
+}
+
+func newHostList(hosts []string) hostList {
+	l := hostList{exact: map[string]struct{}{}}
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		switch {
+		case len(h) == 0:
+			continue
+		case strings.HasPrefix(h, "."):
+			l.suffixes = append(l.suffixes, h)
+			l.exact[h[1:]] = struct{}{}
+		default:
+			l.exact[h] = struct{}{}
+		}
+	}
+	return l
+}
+
+func (l hostList) contains(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if _, ok := l.exact[host]; ok {
+		return true
+	}
+	for _, s := range l.suffixes {
+		if strings.HasSuffix(host, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBody 読み込めるバイト数を制限したレスポンスボディ
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// ちょうど上限で終わっているかどうかを確認する
+		var buf [1]byte
+		if n, _ := b.ReadCloser.Read(buf[:]); n > 0 {
+			return 0, ErrBodyTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
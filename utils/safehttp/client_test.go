@@ -0,0 +1,115 @@
+package safehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClient_Get(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 20)))
+	})
+	mux.HandleFunc("/large-chunked", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 10)))
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte(strings.Repeat("a", 10)))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/redirect-localhost", func(w http.ResponseWriter, r *http.Request) {
+		u, _ := url.Parse(server.URL)
+		http.Redirect(w, r, fmt.Sprintf("http://localhost:%s/ok", u.Port()), http.StatusFound)
+	})
+
+	ctx := context.Background()
+
+	t.Run("private address", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{})
+		_, err := c.Get(ctx, server.URL+"/ok")
+		assert.True(t, errors.Is(err, ErrForbiddenHost))
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{})
+		_, err := c.Get(ctx, "file:///etc/passwd")
+		assert.Equal(t, ErrUnsupportedScheme, err)
+	})
+
+	t.Run("deny list", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{DenyHosts: []string{".example.com"}, AllowHosts: []string{"a.example.com"}})
+		_, err := c.Get(ctx, "https://a.example.com/")
+		assert.Equal(t, ErrForbiddenHost, err)
+		_, err = c.Get(ctx, "https://EXAMPLE.com/")
+		assert.Equal(t, ErrForbiddenHost, err)
+	})
+
+	t.Run("allow list", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{AllowHosts: []string{"127.0.0.1"}})
+		resp, err := c.Get(ctx, server.URL+"/ok")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", string(b))
+	})
+
+	t.Run("redirect to private address", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{AllowHosts: []string{"127.0.0.1"}})
+		_, err := c.Get(ctx, server.URL+"/redirect-localhost")
+		assert.True(t, errors.Is(err, ErrForbiddenHost))
+	})
+
+	t.Run("too many redirects", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{AllowHosts: []string{"127.0.0.1"}, MaxRedirects: 2})
+		_, err := c.Get(ctx, server.URL+"/loop")
+		assert.Equal(t, ErrTooManyRedirects, err)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{AllowHosts: []string{"127.0.0.1"}, MaxBodySize: 10})
+		_, err := c.Get(ctx, server.URL+"/large")
+		assert.Equal(t, ErrBodyTooLarge, err)
+
+		resp, err := c.Get(ctx, server.URL+"/large-chunked")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		_, err = ioutil.ReadAll(resp.Body)
+		assert.Equal(t, ErrBodyTooLarge, err)
+	})
+
+	t.Run("body just fits", func(t *testing.T) {
+		t.Parallel()
+		c := NewClient(Config{AllowHosts: []string{"127.0.0.1"}, MaxBodySize: 2})
+		resp, err := c.Get(ctx, server.URL+"/ok")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "ok", string(b))
+	})
+}
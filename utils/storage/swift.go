@@ -160,5 +160,5 @@ func (fs *SwiftFileStorage) getCacheFilePath(key string) string {
 }
 
 func (fs *SwiftFileStorage) cacheable(fileType model.FileType) bool {
-	return fileType == model.FileTypeIcon || fileType == model.FileTypeStamp || fileType == model.FileTypeThumbnail
+	return fileType == model.FileTypeIcon || fileType == model.FileTypeStamp || fileType == model.FileTypeThumbnail || fileType == model.FileTypeOgpImage
 }